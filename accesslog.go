@@ -0,0 +1,188 @@
+// This file contains the access log of the middleware, writing one structured entry per request in JSON or the Apache combined format, with per-vhost destinations.
+package fibervhosts
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// AccessLogFormat selects how access log entries are written
+type AccessLogFormat int
+
+const (
+	// AccessLogCombined writes entries in the Apache combined log format, followed by the quoted request ID when there is one
+	AccessLogCombined AccessLogFormat = iota
+	// AccessLogJSON writes every entry as a JSON object on its own line
+	AccessLogJSON
+)
+
+// AccessLog defines an access log destination. Set it on Config for all hostnames, or on HostConfig to override it for a single registration.
+type AccessLog struct {
+	// Format selects the entry format. Defaults to AccessLogCombined.
+	Format AccessLogFormat
+	// Output receives the entries. Defaults to os.Stdout.
+	Output io.Writer
+	// Disabled turns off access logging, like for a single noisy hostname
+	Disabled bool
+
+	mu sync.Mutex
+}
+
+// AccessLogEntry is a single request written to the access log. Bytes is the size of the response body, or its Content-Length for a streamed body, which is -1 while unknown.
+type AccessLogEntry struct {
+	Time      time.Time     `json:"time"`
+	Hostname  string        `json:"hostname"`
+	Pattern   string        `json:"pattern,omitempty"`
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Protocol  string        `json:"protocol"`
+	Status    int           `json:"status"`
+	Bytes     int           `json:"bytes"`
+	Latency   time.Duration `json:"latency_ns"`
+	RemoteIP  string        `json:"remote_ip"`
+	Referer   string        `json:"referer,omitempty"`
+	UserAgent string        `json:"user_agent,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// defaultAccessLog is used when logging is enabled without an access log configured
+var defaultAccessLog = &AccessLog{}
+
+// write writes an entry in the format of the log
+func (l *AccessLog) write(entry AccessLogEntry) error {
+	var line []byte
+	if l.Format == AccessLogJSON {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		line = append(data, '\n')
+	} else {
+		line = entry.appendCombined(nil)
+	}
+
+	out := l.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := out.Write(line)
+	return err
+}
+
+// appendCombined appends the entry in the Apache combined log format
+func (e AccessLogEntry) appendCombined(b []byte) []byte {
+	b = append(b, e.RemoteIP...)
+	b = append(b, " - - ["...)
+	b = e.Time.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+	b = append(b, "] \""...)
+	b = append(b, e.Method...)
+	b = append(b, ' ')
+	b = append(b, e.Path...)
+	b = append(b, ' ')
+	b = append(b, e.Protocol...)
+	b = append(b, "\" "...)
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, ' ')
+	if e.Bytes > 0 {
+		b = strconv.AppendInt(b, int64(e.Bytes), 10)
+	} else {
+		b = append(b, '-')
+	}
+	b = append(b, ' ')
+	b = strconv.AppendQuote(b, orDash(e.Referer))
+	b = append(b, ' ')
+	b = strconv.AppendQuote(b, orDash(e.UserAgent))
+	// The request ID follows the combined fields, so parsers of the combined format still read the line
+	if e.RequestID != "" {
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, e.RequestID)
+	}
+	return append(b, '\n')
+}
+
+// responseStatus returns the status of the response to a request, taking into account the error returned by the middleware, which the error handler only turns into the response later on
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// orDash returns "-" for empty values, like Apache does
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// responseSize returns the size of the body of resp. Streamed bodies are not read, as that would buffer them, so their size is the Content-Length.
+func responseSize(resp *fasthttp.Response) int {
+	if resp.IsBodyStream() {
+		return resp.Header.ContentLength()
+	}
+	return len(resp.Body())
+}
+
+// accessLogFor returns the access log for a request matched to r, which is nil for unmatched requests. It returns nil when the request is not logged.
+func (m *VhostsManager) accessLogFor(r *route) *AccessLog {
+	l := m.accessLog
+	if r != nil && r.config.AccessLog != nil {
+		l = r.config.AccessLog
+	}
+	if l == nil && m.enableLog {
+		l = defaultAccessLog
+	}
+	if l == nil || l.Disabled {
+		return nil
+	}
+	return l
+}
+
+// logAccess writes the access log entry of a finished request. err is the error returned by the middleware.
+func (m *VhostsManager) logAccess(c *fiber.Ctx, r *route, start time.Time, err error) {
+	l := m.accessLogFor(r)
+	if l == nil {
+		return
+	}
+
+	status := responseStatus(c, err)
+	entry := AccessLogEntry{
+		Time:      start,
+		Hostname:  c.Hostname(),
+		Method:    c.Method(),
+		Path:      string(c.Request().URI().RequestURI()),
+		Protocol:  string(c.Request().Header.Protocol()),
+		Status:    status,
+		Bytes:     responseSize(c.Response()),
+		Latency:   time.Since(start),
+		RemoteIP:  c.IP(),
+		Referer:   c.Get(fiber.HeaderReferer),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+	entry.RequestID, _ = c.Locals(LocalsRequestIDKey).(string)
+	if r != nil {
+		entry.Pattern = r.info.Pattern
+		if r.info.Type == EntryDefault {
+			entry.Pattern = defaultAppName
+		}
+	}
+	if err := l.write(entry); err != nil {
+		m.logger.Warn("Writing access log failed", "error", err)
+	}
+}
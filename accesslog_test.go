@@ -0,0 +1,47 @@
+package fibervhosts
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test every request is written to the access log of the manager or the overriding one of its hostname.
+func TestVhostMiddleware_AccessLog(t *testing.T) {
+	api := fiber.New()
+	api.Get("/users", func(c *fiber.Ctx) error { return c.SendString("users") })
+
+	var shared, own bytes.Buffer
+	manager := NewVhostsManager(Config{AccessLog: &AccessLog{Output: &shared}})
+	assert.NoError(t, manager.AddHostname("api.example.com", api))
+	assert.NoError(t, manager.AddHostnameWithConfig("json.example.com", api, HostConfig{AccessLog: &AccessLog{Format: AccessLogJSON, Output: &own}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("quiet.example.com", api, HostConfig{AccessLog: &AccessLog{Disabled: true}}))
+
+	app := fiber.New()
+	app.Use(VhostMiddleware(manager))
+
+	for _, target := range []string{"http://api.example.com/users?page=2", "http://json.example.com/users", "http://quiet.example.com/users", "http://unknown.example.com/"} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("User-Agent", "test-agent")
+		_, err := app.Test(req)
+		assert.NoError(t, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(shared.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Regexp(t, `^0\.0\.0\.0 - - \[.+\] "GET /users\?page=2 HTTP/1\.1" 200 5 "-" "test-agent"$`, lines[0])
+	assert.Contains(t, lines[1], `"GET / HTTP/1.1" 404 -`)
+
+	var entry AccessLogEntry
+	assert.NoError(t, json.Unmarshal(own.Bytes(), &entry))
+	assert.Equal(t, "json.example.com", entry.Hostname)
+	assert.Equal(t, "json.example.com", entry.Pattern)
+	assert.Equal(t, 200, entry.Status)
+	assert.Equal(t, 5, entry.Bytes)
+	assert.Equal(t, "/users", entry.Path)
+}
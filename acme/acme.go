@@ -0,0 +1,148 @@
+// Package acme obtains and renews certificates through ACME (like Let's Encrypt) for the hostnames registered in a fibervhosts.VhostsManager, using autocert.
+//
+// The host policy is derived from the live vhost table, so certificates are only requested for hostnames that are currently registered, and a hostname stops being eligible as soon as it is removed. Certificates attached to registrations with VhostsManager.SetCertificate take precedence over ACME.
+//
+// Use Manager.TLSConfig for the TLS listener. The http-01 challenge is answered by Manager.HTTPHandler, which can be mounted on the plain HTTP app with fiber's adaptor package.
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Config defines the config for the ACME manager
+type Config struct {
+	// Cache stores the obtained certificates and the account key. Defaults to autocert.DirCache("acme-certs").
+	Cache autocert.Cache
+	// Email is the contact address of the ACME account. Optional.
+	Email string
+	// DirectoryURL is the ACME directory, like the Let's Encrypt staging environment. Defaults to Let's Encrypt production.
+	DirectoryURL string
+	// AllowWildcardMatches also allows certificates for hostnames only matched by a wildcard registration like "*.example.com". Every such hostname gets its own certificate, so only enable this when the names under the wildcard are trusted. Defaults to false.
+	AllowWildcardMatches bool
+	// OnDemand obtains certificates for hostnames that are not registered yet and registers them once the certificate was obtained. Nil disables on-demand TLS.
+	OnDemand *OnDemand
+}
+
+// OnDemandTag is the metadata tag of registrations made by on-demand TLS
+const OnDemandTag = "on-demand"
+
+// OnDemand defines on-demand TLS, where the first handshake for an allowed hostname obtains its certificate and registers it, like custom domains of SaaS tenants
+type OnDemand struct {
+	// Allow decides whether an unregistered hostname may get a certificate. It runs during the TLS handshake and is the only protection against certificate requests for arbitrary names, so it should check the hostname against a list of expected domains. Required.
+	Allow func(hostname string) bool
+	// App is registered for the hostname once its certificate was obtained. Required.
+	App *fiber.App
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Cache: autocert.DirCache("acme-certs"),
+}
+
+// Manager obtains certificates for the registered hostnames of a VhostsManager
+type Manager struct {
+	*autocert.Manager
+
+	vhosts   *fibervhosts.VhostsManager
+	onDemand *OnDemand
+}
+
+// New creates an ACME manager for the registered hostnames of the manager
+func New(vhosts *fibervhosts.VhostsManager, config Config) *Manager {
+	if config.Cache == nil {
+		config.Cache = ConfigDefault.Cache
+	}
+
+	policy := HostPolicy(vhosts, config.AllowWildcardMatches)
+	if onDemand := config.OnDemand; onDemand != nil {
+		registered := policy
+		policy = func(ctx context.Context, host string) error {
+			err := registered(ctx, host)
+			if err != nil && onDemand.Allow(strings.ToLower(host)) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      config.Cache,
+		Email:      config.Email,
+		HostPolicy: policy,
+	}
+	if config.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: config.DirectoryURL}
+	}
+	return &Manager{Manager: m, vhosts: vhosts, onDemand: config.OnDemand}
+}
+
+// HostPolicy returns an autocert.HostPolicy allowing the hostnames registered in the manager. Only exact registrations are allowed unless allowWildcardMatches is set; the default app never makes a hostname eligible.
+func HostPolicy(vhosts *fibervhosts.VhostsManager, allowWildcardMatches bool) autocert.HostPolicy {
+	return func(_ context.Context, host string) error {
+		e, ok := vhosts.Match(strings.ToLower(host))
+		switch {
+		case !ok || e.Type == fibervhosts.EntryDefault:
+			return fmt.Errorf("acme: host %q is not registered", host)
+		case e.Type == fibervhosts.EntryWildcard && !allowWildcardMatches:
+			return fmt.Errorf("acme: host %q is only matched by wildcard %q", host, e.Pattern)
+		}
+		return nil
+	}
+}
+
+// GetCertificate serves the certificate attached to the matching registration if there is one, and otherwise obtains or renews one through ACME. With on-demand TLS, a hostname without a registration is registered once its certificate was obtained. It can be used as tls.Config.GetCertificate.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if !isChallenge(hello) {
+		if cert, ok := m.vhosts.HostCertificate(hello.ServerName); ok {
+			return cert, nil
+		}
+	}
+	cert, err := m.Manager.GetCertificate(hello)
+	if err == nil && m.onDemand != nil && !isChallenge(hello) {
+		m.register(hello.ServerName)
+	}
+	if err != nil && !isChallenge(hello) {
+		// Fall back to the default certificate of the manager, if any
+		if fallback, fallbackErr := m.vhosts.GetCertificate(hello); fallbackErr == nil {
+			return fallback, nil
+		}
+	}
+	return cert, err
+}
+
+// register adds an on-demand hostname with the template app unless a registration already serves it
+func (m *Manager) register(serverName string) {
+	hostname := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if e, ok := m.vhosts.Match(hostname); ok && e.Type != fibervhosts.EntryDefault {
+		return
+	}
+	if err := m.vhosts.AddHostname(hostname, m.onDemand.App); err != nil {
+		// Registered meanwhile by a concurrent handshake
+		return
+	}
+	_ = m.vhosts.SetMetadata(hostname, fibervhosts.Metadata{Tags: []string{OnDemandTag}})
+	log.Infof("acme: registered on-demand hostname %s", hostname)
+}
+
+// TLSConfig returns a TLS config serving the certificates of the manager, supporting the tls-alpn-01 challenge and per-vhost client authentication
+func (m *Manager) TLSConfig() *tls.Config {
+	config := m.Manager.TLSConfig()
+	config.GetCertificate = m.GetCertificate
+	m.vhosts.EnableClientAuth(config)
+	return config
+}
+
+// isChallenge reports whether the handshake is a tls-alpn-01 challenge
+func isChallenge(hello *tls.ClientHelloInfo) bool {
+	return len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto
+}
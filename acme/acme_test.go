@@ -0,0 +1,114 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Test the host policy follows the live vhost table.
+func TestHostPolicy(t *testing.T) {
+	app := fiber.New()
+	vhosts := fibervhosts.NewVhostsManager(fibervhosts.Config{DefaultApp: app})
+	assert.NoError(t, vhosts.AddHostname("www.example.com", app))
+	assert.NoError(t, vhosts.AddHostname("*.example.org", app))
+
+	policy := HostPolicy(vhosts, false)
+	ctx := context.Background()
+	assert.NoError(t, policy(ctx, "www.example.com"))
+	assert.Error(t, policy(ctx, "shop.example.org"))
+	assert.Error(t, policy(ctx, "unknown.example.net"))
+
+	assert.NoError(t, HostPolicy(vhosts, true)(ctx, "shop.example.org"))
+
+	// Removed hostnames are no longer eligible
+	assert.NoError(t, vhosts.RemoveHostname("www.example.com"))
+	assert.Error(t, policy(ctx, "www.example.com"))
+}
+
+// Test attached certificates are preferred over ACME.
+func TestManager_GetCertificate(t *testing.T) {
+	vhosts := fibervhosts.NewVhostsManager()
+	assert.NoError(t, vhosts.AddHostname("www.example.com", fiber.New()))
+	cert := &tls.Certificate{}
+	assert.NoError(t, vhosts.SetCertificate("www.example.com", cert))
+
+	m := New(vhosts, Config{Cache: autocert.DirCache(t.TempDir())})
+	got, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+	assert.NoError(t, err)
+	assert.Same(t, cert, got)
+
+	// Unregistered hostnames are refused before contacting the ACME server
+	_, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"})
+	assert.Error(t, err)
+
+	assert.Contains(t, m.TLSConfig().NextProtos, "acme-tls/1")
+}
+
+// cacheCertificate stores a self-signed certificate for host in the autocert cache format, so it is served without contacting an ACME server
+func cacheCertificate(t *testing.T, cache autocert.Cache, host string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	pem.Encode(&buf, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	assert.NoError(t, cache.Put(context.Background(), host, buf.Bytes()))
+}
+
+// Test on-demand TLS registers allowed hostnames once their certificate is available.
+func TestManager_OnDemand(t *testing.T) {
+	tenant := fiber.New()
+	vhosts := fibervhosts.NewVhostsManager()
+	cache := autocert.DirCache(t.TempDir())
+	cacheCertificate(t, cache, "shop.customer.com")
+
+	m := New(vhosts, Config{Cache: cache, OnDemand: &OnDemand{
+		Allow: func(hostname string) bool { return strings.HasSuffix(hostname, ".customer.com") },
+		App:   tenant,
+	}})
+
+	ctx := context.Background()
+	assert.NoError(t, m.HostPolicy(ctx, "new.customer.com"))
+	assert.Error(t, m.HostPolicy(ctx, "evil.example.com"))
+
+	hello := &tls.ClientHelloInfo{
+		ServerName:       "shop.customer.com",
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		SupportedCurves:  []tls.CurveID{tls.CurveP256},
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+	}
+	_, err := m.GetCertificate(hello)
+	assert.NoError(t, err)
+
+	app, exists := vhosts.GetHostname("shop.customer.com")
+	assert.True(t, exists)
+	assert.Equal(t, tenant, app)
+	md, _ := vhosts.GetMetadata("shop.customer.com")
+	assert.True(t, md.HasTag(OnDemandTag))
+}
@@ -0,0 +1,129 @@
+// This file contains shared registrations, a single registration serving many hostnames as its aliases, like a SaaS app serving the custom domains of all its customers with one dispatch chain, one set of stats and one HostConfig.
+package fibervhosts
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AddSharedApp registers app for all hostnames as a single registration: the first hostname is registered like by AddHostnameWithConfig, the others become its aliases, see AddAliases
+func (m *VhostsManager) AddSharedApp(hostnames []string, app *fiber.App, config ...HostConfig) error {
+	if len(hostnames) == 0 {
+		return ErrInvalidHostname
+	}
+	var cfg HostConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if err := m.AddHostnameWithConfig(hostnames[0], app, cfg); err != nil {
+		return err
+	}
+	if err := m.AddAliases(hostnames[0], hostnames[1:]...); err != nil {
+		_ = m.RemoveHostname(hostnames[0])
+		return err
+	}
+	return nil
+}
+
+// AddAliases adds hostnames served by the registration of an exact hostname, without registrations of their own. Requests for an alias are dispatched like the requests for the hostname, with its HostConfig, counting towards its stats; the alias they were matched by is exposed under LocalsHostnameKey. Aliases are dropped with the registration, and a registration of the same hostname takes precedence over an alias. The certificate of the registration is not served for its aliases.
+func (m *VhostsManager) AddAliases(hostname string, aliases ...string) error {
+	if isWildcard(hostname) {
+		return fmt.Errorf("%w: aliases need an exact hostname, not %s", ErrInvalidHostname, hostname)
+	}
+	return m.update(func() ([]ChangeEvent, error) {
+		e, exists := m.hosts[hostname]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+		seen := make(map[string]struct{}, len(aliases))
+		for _, alias := range aliases {
+			if alias == "" || isWildcard(alias) {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidHostname, alias)
+			}
+			_, registered := m.hosts[alias]
+			_, aliased := m.aliases[alias]
+			_, repeated := seen[alias]
+			if registered || aliased || repeated {
+				return nil, fmt.Errorf("%w: %s", ErrHostExists, alias)
+			}
+			seen[alias] = struct{}{}
+		}
+		if len(aliases) == 0 {
+			return nil, nil
+		}
+
+		previous := e.toEntry()
+		for _, alias := range aliases {
+			m.aliases[alias] = e
+		}
+		e.aliases = append(e.aliases, aliases...)
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
+
+// RemoveAlias removes an alias added by AddAliases
+func (m *VhostsManager) RemoveAlias(alias string) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		e, exists := m.aliases[alias]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+
+		previous := e.toEntry()
+		delete(m.aliases, alias)
+		e.aliases = slices.DeleteFunc(e.aliases, func(a string) bool { return a == alias })
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
+
+// Aliases returns the aliases of a registered hostname, sorted
+func (m *VhostsManager) Aliases(hostname string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, exists := m.hosts[hostname]
+	if !exists {
+		return nil
+	}
+	return e.sortedAliases()
+}
+
+// sortedAliases returns a sorted copy of the aliases of e, or nil without aliases. The caller must hold the lock.
+func (e *entry) sortedAliases() []string {
+	if len(e.aliases) == 0 {
+		return nil
+	}
+	aliases := slices.Clone(e.aliases)
+	slices.Sort(aliases)
+	return aliases
+}
+
+// restoreAliases gives the registrations of a snapshot their aliases back, see Rollback. Aliases taken by another registration since are not restored. The caller must hold the lock.
+func (m *VhostsManager) restoreAliases(desired []Entry) []ChangeEvent {
+	var changes []ChangeEvent
+	for _, d := range desired {
+		e, exists := m.hosts[d.Pattern]
+		if d.Type != EntryHost || !exists || slices.Equal(e.sortedAliases(), d.Aliases) {
+			continue
+		}
+
+		previous := e.toEntry()
+		for _, alias := range e.aliases {
+			delete(m.aliases, alias)
+		}
+		e.aliases = nil
+		for _, alias := range d.Aliases {
+			_, registered := m.hosts[alias]
+			// Registrations removed by the rollback drop their aliases once the table is published
+			if other, aliased := m.aliases[alias]; registered || aliased && m.hosts[other.pattern] == other {
+				continue
+			}
+			m.aliases[alias] = e
+			e.aliases = append(e.aliases, alias)
+		}
+		changes = append(changes, updated(previous, e))
+	}
+	return changes
+}
@@ -0,0 +1,111 @@
+package fibervhosts
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test a shared registration serves all its aliases with one route, and its aliases follow its changes.
+func TestVhostsManager_AddSharedApp(t *testing.T) {
+	newApp := func(name string) *fiber.App {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.SendString(fmt.Sprint(name, " ", c.Locals(LocalsHostnameKey), " ", c.Locals(LocalsPatternKey)))
+		})
+		return app
+	}
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddSharedApp([]string{"app.example.com", "shop.acme.com", "store.globex.com"}, newApp("saas")))
+	assert.NoError(t, manager.AddHostname("www.example.com", newApp("www")))
+	assert.ErrorIs(t, manager.AddAliases("app.example.com", "www.example.com"), ErrHostExists)
+	assert.ErrorIs(t, manager.AddAliases("app.example.com", "shop.acme.com"), ErrHostExists)
+	assert.ErrorIs(t, manager.AddAliases("app.example.com", "*.acme.com"), ErrInvalidHostname)
+	assert.ErrorIs(t, manager.AddAliases("missing.example.com", "a.example.com"), ErrHostNotFound)
+	assert.ErrorIs(t, manager.AddSharedApp([]string{"new.example.com", "shop.acme.com"}, newApp("new")), ErrHostExists)
+	_, exists := manager.GetHostname("new.example.com")
+	assert.False(t, exists)
+	assert.Equal(t, []string{"shop.acme.com", "store.globex.com"}, manager.Aliases("app.example.com"))
+	assert.Len(t, manager.ListEntries(), 2)
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(target string) string {
+		resp, err := main.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.Equal(t, "saas app.example.com app.example.com", request("http://app.example.com/"))
+	assert.Equal(t, "saas shop.acme.com app.example.com", request("http://Shop.Acme.com:8080/"))
+	r := manager.lookup.Load()
+	shared, _ := r.lookupHost("app.example.com")
+	alias, _ := r.lookupHost("store.globex.com")
+	assert.Same(t, shared, alias)
+
+	// Aliases follow app swaps and renames of their registration
+	assert.NoError(t, manager.UpdateHostname("app.example.com", newApp("saas2")))
+	assert.Equal(t, "saas2 store.globex.com app.example.com", request("http://store.globex.com/"))
+	assert.NoError(t, manager.RenameHostname("app.example.com", "app.example.net"))
+	assert.Equal(t, "saas2 store.globex.com app.example.net", request("http://store.globex.com/"))
+	assert.Equal(t, "Not Found", request("http://app.example.com/"))
+	assert.Equal(t, uint64(4), manager.Stats()[0].Requests)
+
+	// A registration takes precedence over an alias of the same hostname
+	assert.NoError(t, manager.AddHostname("shop.acme.com", newApp("acme")))
+	assert.Equal(t, "acme shop.acme.com shop.acme.com", request("http://shop.acme.com/"))
+	assert.NoError(t, manager.RemoveHostname("shop.acme.com"))
+	assert.Equal(t, "saas2 shop.acme.com app.example.net", request("http://shop.acme.com/"))
+
+	assert.NoError(t, manager.RemoveAlias("shop.acme.com"))
+	assert.ErrorIs(t, manager.RemoveAlias("shop.acme.com"), ErrHostNotFound)
+	assert.Equal(t, "Not Found", request("http://shop.acme.com/"))
+
+	// Aliases are dropped with their registration
+	assert.NoError(t, manager.RemoveHostname("app.example.net"))
+	assert.Equal(t, "Not Found", request("http://store.globex.com/"))
+	assert.NoError(t, manager.AddHostname("store.globex.com", newApp("globex")))
+	assert.NoError(t, manager.RemoveHostname("store.globex.com"))
+	assert.Equal(t, "Not Found", request("http://store.globex.com/"))
+	assert.Empty(t, manager.aliases)
+}
+
+// Test alias changes bump the version, are reported to watchers and are restored by rollbacks.
+func TestVhostsManager_AliasChanges(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("shared") })
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("app.example.com", app))
+	events, cancel := manager.Watch()
+	defer cancel()
+
+	version := manager.Version()
+	assert.NoError(t, manager.AddAliases("app.example.com", "shop.acme.com", "store.globex.com"))
+	assert.Equal(t, version+1, manager.Version())
+	ev := receive(t, events)
+	assert.Equal(t, ChangeUpdated, ev.Type)
+	assert.Empty(t, ev.Previous.Aliases)
+	assert.Equal(t, []string{"shop.acme.com", "store.globex.com"}, ev.Entry.Aliases)
+
+	snap := manager.Snapshot()
+	assert.NoError(t, manager.RemoveAlias("shop.acme.com"))
+	ev = receive(t, events)
+	assert.Equal(t, []string{"store.globex.com"}, ev.Entry.Aliases)
+	resp, err := manager.Test("shop.acme.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	assert.NoError(t, manager.Rollback(snap.Version))
+	ev = receive(t, events)
+	assert.Equal(t, []string{"shop.acme.com", "store.globex.com"}, ev.Entry.Aliases)
+	assert.Equal(t, []string{"shop.acme.com", "store.globex.com"}, manager.Aliases("app.example.com"))
+	resp, err = manager.Test("shop.acme.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,75 @@
+// This file contains the per-vhost authentication gate, which must pass before a request reaches the sub-app, like to protect staging hostnames with a password while production ones stay open.
+package fibervhosts
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuthFunc authenticates a request before it is dispatched to the sub-app. A non-nil error rejects the request and is passed on like the other errors of dispatching, so it should be a *fiber.Error like fiber.ErrUnauthorized.
+type AuthFunc func(c *fiber.Ctx) error
+
+// BasicAuth returns an AuthFunc checking HTTP basic auth credentials against users, a map of usernames to passwords. Rejected requests are answered with 401 Unauthorized and a challenge for realm.
+func BasicAuth(realm string, users map[string]string) AuthFunc {
+	// Comparing hashes keeps the comparison constant time regardless of the password lengths
+	hashed := make(map[string][sha256.Size]byte, len(users))
+	for user, password := range users {
+		hashed[user] = sha256.Sum256([]byte(password))
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+
+	return func(c *fiber.Ctx) error {
+		if user, password, ok := parseBasicAuth(c.Get(fiber.HeaderAuthorization)); ok {
+			want, exists := hashed[user]
+			got := sha256.Sum256([]byte(password))
+			if subtle.ConstantTimeCompare(want[:], got[:]) == 1 && exists {
+				return nil
+			}
+		}
+		c.Set(fiber.HeaderWWWAuthenticate, challenge)
+		return fiber.ErrUnauthorized
+	}
+}
+
+// parseBasicAuth returns the credentials of a basic auth Authorization header
+func parseBasicAuth(header string) (user, password string, ok bool) {
+	const prefix = "Basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
+// APIKeyAuth returns an AuthFunc accepting requests whose header carries one of keys, like "X-API-Key". Rejected requests are answered with 401 Unauthorized.
+func APIKeyAuth(header string, keys ...string) AuthFunc {
+	hashed := make([][sha256.Size]byte, len(keys))
+	for i, key := range keys {
+		hashed[i] = sha256.Sum256([]byte(key))
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(header)
+		if key == "" {
+			return fiber.ErrUnauthorized
+		}
+		got := sha256.Sum256([]byte(key))
+		valid := 0
+		// Every key is compared, so the time taken does not tell which key matched
+		for _, want := range hashed {
+			valid |= subtle.ConstantTimeCompare(want[:], got[:])
+		}
+		if valid != 1 {
+			return fiber.ErrUnauthorized
+		}
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the auth gate of a hostname rejects requests before they reach the sub-app, while other hostnames stay open.
+func TestVhostMiddleware_Auth(t *testing.T) {
+	dispatched := 0
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		dispatched++
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("staging.example.com", app, HostConfig{Auth: BasicAuth("staging", map[string]string{"admin": "secret"})}))
+	assert.NoError(t, manager.AddHostnameWithConfig("api.example.com", app, HostConfig{Auth: APIKeyAuth("X-API-Key", "key-1", "key-2")}))
+	assert.NoError(t, manager.AddHostnameWithConfig("custom.example.com", app, HostConfig{Auth: func(c *fiber.Ctx) error {
+		if c.Query("token") != "letmein" {
+			return fiber.ErrForbidden
+		}
+		return nil
+	}}))
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		header string
+		value  string
+		want   int
+	}{
+		{"basic auth", "http://staging.example.com/", fiber.HeaderAuthorization, "Basic YWRtaW46c2VjcmV0", fiber.StatusNoContent},
+		{"wrong password", "http://staging.example.com/", fiber.HeaderAuthorization, "Basic YWRtaW46d3Jvbmc=", fiber.StatusUnauthorized},
+		{"unknown user", "http://staging.example.com/", fiber.HeaderAuthorization, "Basic Z3Vlc3Q6c2VjcmV0", fiber.StatusUnauthorized},
+		{"no credentials", "http://staging.example.com/", "", "", fiber.StatusUnauthorized},
+		{"api key", "http://api.example.com/", "X-API-Key", "key-2", fiber.StatusNoContent},
+		{"wrong api key", "http://api.example.com/", "X-API-Key", "key-3", fiber.StatusUnauthorized},
+		{"custom", "http://custom.example.com/?token=letmein", "", "", fiber.StatusNoContent},
+		{"custom rejected", "http://custom.example.com/", "", "", fiber.StatusForbidden},
+		{"open", "http://www.example.com/", "", "", fiber.StatusNoContent},
+	} {
+		req := httptest.NewRequest("GET", tc.target, nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		resp, err := main.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, resp.StatusCode, tc.name)
+		if tc.name == "no credentials" {
+			assert.Equal(t, `Basic realm="staging"`, resp.Header.Get(fiber.HeaderWWWAuthenticate))
+		}
+	}
+	assert.Equal(t, 4, dispatched)
+}
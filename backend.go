@@ -0,0 +1,87 @@
+// This file contains the backends serving the requests of registrations. Fiber sub-apps, wrapped net/http handlers and plain fasthttp handlers are dispatched to alike through the backend interface.
+package fibervhosts
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// BackendKind describes what serves the requests of a registration, see Entry.Backend
+type BackendKind string
+
+const (
+	// BackendApp is a fiber sub-app
+	BackendApp BackendKind = ""
+	// BackendLazy is a sub-app built by a factory, see AddHostnameFactory
+	BackendLazy BackendKind = "lazy"
+	// BackendHandler is a plain fasthttp handler, see AddRequestHandler
+	BackendHandler BackendKind = "handler"
+	// BackendRedirect is a redirect, see AddRedirect
+	BackendRedirect BackendKind = "redirect"
+)
+
+// backendKind returns what serves the requests of the entry
+func (e *entry) backendKind() BackendKind {
+	switch {
+	case e.config.Redirect != nil:
+		return BackendRedirect
+	case e.factory != nil:
+		return BackendLazy
+	case e.app == nil && e.handler != nil:
+		return BackendHandler
+	}
+	return BackendApp
+}
+
+// backend serves the requests of a registration
+type backend interface {
+	// requestHandler returns the handler serving the next request
+	requestHandler() fasthttp.RequestHandler
+}
+
+// appBackend serves requests with a fiber sub-app
+type appBackend struct {
+	app *fiber.App
+}
+
+// requestHandler is fetched on every request, as app.Handler builds the route tree of routes added to the app after registration
+func (b appBackend) requestHandler() fasthttp.RequestHandler {
+	return b.app.Handler()
+}
+
+// handlerBackend serves requests with a plain fasthttp handler
+type handlerBackend fasthttp.RequestHandler
+
+func (b handlerBackend) requestHandler() fasthttp.RequestHandler {
+	return fasthttp.RequestHandler(b)
+}
+
+// backend returns the backend of the entry, or nil while a lazily built app was not built yet. A sub-app takes precedence over a plain handler.
+func (e *entry) backend() backend {
+	switch {
+	case e.app != nil:
+		return appBackend{e.app}
+	case e.handler != nil:
+		return handlerBackend(*e.handler)
+	}
+	return nil
+}
+
+// AddRequestHandler adds a plain fasthttp handler for a given hostname, for backends that do not need fiber. Requests are dispatched to it without the routing of a sub-app; the per-vhost settings apply as for sub-apps. The registration has no App in ListEntries and GetHostname.
+func (m *VhostsManager) AddRequestHandler(hostname string, handler fasthttp.RequestHandler) error {
+	if hostname == "" {
+		return ErrInvalidHostname
+	}
+
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		if _, exists := table[key]; exists {
+			return nil, ErrHostExists
+		}
+
+		e := newEntry(hostname, nil)
+		e.handler = &handler
+		table[key] = e
+		return []ChangeEvent{added(e)}, nil
+	})
+}
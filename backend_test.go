@@ -0,0 +1,66 @@
+package fibervhosts
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// Test plain fasthttp handlers are dispatched to with the per-vhost settings, like sub-apps.
+func TestVhostsManager_AddRequestHandler(t *testing.T) {
+	handler := func(ctx *fasthttp.RequestCtx) {
+		switch string(ctx.Path()) {
+		case "/health":
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+		case "/slow":
+			<-ctx.Done()
+		default:
+			ctx.SetStatusCode(fasthttp.StatusAccepted)
+			ctx.SetBodyString("fasthttp " + string(ctx.Host()))
+		}
+	}
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddRequestHandler("fast.example.com", handler))
+	assert.ErrorIs(t, manager.AddRequestHandler("fast.example.com", handler), ErrHostExists)
+	assert.NoError(t, manager.AddRequestHandler("legacy.example.com", handler))
+	assert.NoError(t, manager.SetHostConfig("legacy.example.com", HostConfig{RewriteHost: "app.internal", RequestTimeout: 20 * time.Millisecond}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	resp, err := main.Test(httptest.NewRequest("GET", "http://fast.example.com/", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "fasthttp fast.example.com", string(body))
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://legacy.example.com/", nil))
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "fasthttp app.internal", string(body))
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://legacy.example.com/slow", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+
+	report := manager.CheckHealth(context.Background(), HealthConfig{Path: "/health"})
+	for _, host := range report.Hosts {
+		assert.Equal(t, HealthOK, host.Status, host.Pattern)
+	}
+
+	// Swapping in a sub-app replaces the handler
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("fiber") })
+	assert.NoError(t, manager.UpdateHostname("fast.example.com", app))
+	resp, err = main.Test(httptest.NewRequest("GET", "http://fast.example.com/", nil))
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "fiber", string(body))
+}
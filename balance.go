@@ -0,0 +1,109 @@
+// This file contains the load balancing of proxy hosts, which spread the requests for a hostname over a pool of upstream servers, like the instances of a backend serving a custom domain.
+package fibervhosts
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BalanceStrategy selects the upstream of a proxy pool each request is forwarded to
+type BalanceStrategy int
+
+const (
+	// BalanceRoundRobin forwards requests to the upstreams in turn
+	BalanceRoundRobin BalanceStrategy = iota
+	// BalanceLeastConnections forwards requests to the upstream with the fewest requests in flight, taking turns on ties
+	BalanceLeastConnections
+	// BalanceRandom forwards requests to a random upstream
+	BalanceRandom
+)
+
+// proxyPool holds the upstreams of a proxy host
+type proxyPool struct {
+	// app is the sub-app forwarding to the pool
+	app       *fiber.App
+	upstreams []*upstream
+	strategy  BalanceStrategy
+	check     *UpstreamCheck
+	next      atomic.Uint64
+}
+
+// newProxyPool parses the upstreams of a proxy host
+func newProxyPool(upstreams []string, config ProxyConfig) (*proxyPool, error) {
+	if len(upstreams) == 0 {
+		return nil, fmt.Errorf("%w: no upstreams", ErrInvalidUpstream)
+	}
+	p := &proxyPool{strategy: config.Balance, check: config.HealthCheck}
+	for _, raw := range upstreams {
+		up, err := newUpstream(raw, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		p.upstreams = append(p.upstreams, up)
+	}
+	return p, nil
+}
+
+// pick returns the upstream the next request is forwarded to. Upstreams out of rotation are skipped, unless all of them are.
+func (p *proxyPool) pick() *upstream {
+	n := uint64(len(p.upstreams))
+	if n == 1 {
+		return p.upstreams[0]
+	}
+	skipDown := false
+	for _, up := range p.upstreams {
+		if !up.down.Load() {
+			skipDown = true
+			break
+		}
+	}
+
+	start := p.next.Add(1) - 1
+	if p.strategy == BalanceRandom {
+		start = rand.Uint64N(n)
+	}
+	var best *upstream
+	for i := uint64(0); i < n; i++ {
+		up := p.upstreams[(start+i)%n]
+		if skipDown && up.down.Load() {
+			continue
+		}
+		if p.strategy != BalanceLeastConnections {
+			return up
+		}
+		// Ties go to the first upstream from start, so they take turns
+		if best == nil || up.active.Load() < best.active.Load() {
+			best = up
+		}
+	}
+	if best == nil {
+		return p.upstreams[start%n]
+	}
+	return best
+}
+
+// AddProxyPool adds a hostname whose requests are spread over several upstreams with the strategy of ProxyConfig.Balance, like a pool of backend instances serving one custom domain. The upstreams and the forwarding are those of AddProxyHost.
+func (m *VhostsManager) AddProxyPool(hostname string, upstreams []string, config ...ProxyConfig) error {
+	if hostname == "" {
+		return ErrInvalidHostname
+	}
+	pool, err := m.newProxy(upstreams, config...)
+	if err != nil {
+		return err
+	}
+
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		if _, exists := table[key]; exists {
+			return nil, ErrHostExists
+		}
+
+		e := newEntry(hostname, pool.app)
+		e.pool = pool
+		table[key] = e
+		return []ChangeEvent{added(e)}, nil
+	})
+}
@@ -0,0 +1,67 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test proxy pools spread requests over their upstreams with each strategy.
+func TestVhostsManager_AddProxyPool(t *testing.T) {
+	blocked := make(chan string, 1)
+	release := make(chan struct{})
+	var upstreams []string
+	for _, name := range []string{"a", "b", "c"} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				blocked <- name
+				<-release
+			}
+			io.WriteString(w, name)
+		}))
+		defer server.Close()
+		upstreams = append(upstreams, server.URL)
+	}
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddProxyPool("rr.example.com", upstreams))
+	assert.NoError(t, manager.AddProxyPool("least.example.com", upstreams, ProxyConfig{Balance: BalanceLeastConnections}))
+	assert.NoError(t, manager.AddProxyPool("random.example.com", upstreams, ProxyConfig{Balance: BalanceRandom}))
+	assert.ErrorIs(t, manager.AddProxyPool("empty.example.com", nil), ErrInvalidUpstream)
+	assert.ErrorIs(t, manager.AddProxyPool("bad.example.com", []string{upstreams[0], "ftp://example.com"}), ErrInvalidUpstream)
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	get := func(target string) string {
+		resp, err := main.Test(httptest.NewRequest("GET", target, nil), -1)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	var served string
+	for i := 0; i < 6; i++ {
+		served += get("http://rr.example.com/")
+	}
+	assert.Equal(t, "abcabc", served)
+
+	for i := 0; i < 10; i++ {
+		assert.Contains(t, []string{"a", "b", "c"}, get("http://random.example.com/"))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		get("http://least.example.com/slow")
+		close(done)
+	}()
+	busy := <-blocked
+	for i := 0; i < 6; i++ {
+		assert.NotEqual(t, busy, get("http://least.example.com/"))
+	}
+	close(release)
+	<-done
+}
@@ -0,0 +1,155 @@
+package fibervhosts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// benchSizes are the numbers of exact hostnames the benchmarks run against. Every table also holds a tenth as many wildcards.
+var benchSizes = []int{100, 10_000, 100_000}
+
+// benchMixes are the request hostname mixes, each returning the hostname of the i-th request against a table of n hosts
+var benchMixes = []struct {
+	name     string
+	hostname func(i, n int) string
+}{
+	{"exact", func(i, n int) string { return benchHost(i % n) }},
+	{"wildcard", func(i, n int) string { return benchWildcardHost(i % (n / 10)) }},
+	{"miss", func(i, n int) string { return fmt.Sprintf("missing%d.example.net", i%n) }},
+	{"mixed", func(i, n int) string {
+		// 80% exact hits, 15% wildcard hits and 5% misses
+		switch i % 20 {
+		case 0:
+			return fmt.Sprintf("missing%d.example.net", i%n)
+		case 1, 2, 3:
+			return benchWildcardHost(i % (n / 10))
+		default:
+			return benchHost(i % n)
+		}
+	}},
+}
+
+func benchHost(i int) string         { return fmt.Sprintf("host%d.example.com", i) }
+func benchWildcard(i int) string     { return fmt.Sprintf("*.tenant%d.example.org", i) }
+func benchWildcardHost(i int) string { return fmt.Sprintf("www.tenant%d.example.org", i) }
+
+// newBenchManager creates a manager with n exact hostnames and n/10 wildcards, all served by one app
+func newBenchManager(b *testing.B, n int, config ...Config) *VhostsManager {
+	b.Helper()
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	apps := make(map[string]*fiber.App, n+n/10)
+	for i := 0; i < n; i++ {
+		apps[benchHost(i)] = app
+	}
+	for i := 0; i < n/10; i++ {
+		apps[benchWildcard(i)] = app
+	}
+	manager := NewVhostsManager(config...)
+	if err := manager.AddHostnames(apps); err != nil {
+		b.Fatal(err)
+	}
+	return manager
+}
+
+// benchHostnames precomputes the request hostnames of a mix, so the benchmarks do not measure formatting them
+func benchHostnames(n int, hostname func(i, n int) string) []string {
+	hostnames := make([]string, 1024)
+	for i := range hostnames {
+		hostnames[i] = hostname(i*7919, n)
+	}
+	return hostnames
+}
+
+// Benchmark matching request hostnames against the lookup table.
+func BenchmarkMatch(b *testing.B) {
+	for _, n := range benchSizes {
+		manager := newBenchManager(b, n)
+		table := manager.lookup.Load()
+		for _, mix := range benchMixes {
+			hostnames := benchHostnames(n, mix.hostname)
+			b.Run(fmt.Sprintf("hosts=%d/%s", n, mix.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					table.match(hostnames[i%len(hostnames)])
+				}
+			})
+		}
+	}
+}
+
+// Benchmark matching request hostnames through the lookup cache.
+func BenchmarkMatchCached(b *testing.B) {
+	for _, n := range benchSizes {
+		manager := newBenchManager(b, n, Config{LookupCacheSize: 4096})
+		table := manager.lookup.Load()
+		for _, mix := range benchMixes {
+			hostnames := benchHostnames(n, mix.hostname)
+			b.Run(fmt.Sprintf("hosts=%d/%s", n, mix.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					table.resolve(hostnames[i%len(hostnames)])
+				}
+			})
+		}
+	}
+}
+
+// Benchmark dispatching requests through the middleware to the sub-apps, sequentially and in parallel.
+func BenchmarkMiddleware(b *testing.B) {
+	for _, n := range benchSizes {
+		manager := newBenchManager(b, n)
+		main := fiber.New()
+		main.Use(VhostMiddleware(manager))
+		handler := main.Handler()
+
+		for _, mix := range benchMixes {
+			hostnames := benchHostnames(n, mix.hostname)
+			b.Run(fmt.Sprintf("hosts=%d/%s", n, mix.name), func(b *testing.B) {
+				b.ReportAllocs()
+				fctx := &fasthttp.RequestCtx{}
+				for i := 0; i < b.N; i++ {
+					benchRequest(fctx, handler, hostnames[i%len(hostnames)])
+				}
+			})
+			b.Run(fmt.Sprintf("hosts=%d/%s/parallel", n, mix.name), func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					fctx := &fasthttp.RequestCtx{}
+					for i := 0; pb.Next(); i++ {
+						benchRequest(fctx, handler, hostnames[i%len(hostnames)])
+					}
+				})
+			})
+		}
+	}
+}
+
+// benchRequest sends a GET request for hostname through handler, reusing fctx
+func benchRequest(fctx *fasthttp.RequestCtx, handler fasthttp.RequestHandler, hostname string) {
+	fctx.Request.Reset()
+	fctx.Response.Reset()
+	fctx.Request.Header.SetMethod(fiber.MethodGet)
+	fctx.Request.SetRequestURI("/")
+	fctx.Request.Header.SetHost(hostname)
+	handler(fctx)
+}
+
+// Benchmark registering and removing a hostname in a table of the given size, which measures the cost of publishing the lookup table.
+func BenchmarkAddRemoveHostname(b *testing.B) {
+	app := fiber.New()
+	for _, n := range benchSizes {
+		manager := newBenchManager(b, n)
+		b.Run(fmt.Sprintf("hosts=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = manager.AddHostname("bench.example.net", app)
+				_ = manager.RemoveHostname("bench.example.net")
+			}
+		})
+	}
+}
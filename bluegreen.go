@@ -0,0 +1,79 @@
+// This file contains the blue-green deployment of sub-apps: a new version is staged next to the live app of a hostname and promoted atomically, with the replaced app kept for an instant rollback.
+package fibervhosts
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// ErrNothingStaged is returned by Promote when no app is staged for the hostname
+	ErrNothingStaged = errors.New("no staged app")
+	// ErrNoPreviousApp is returned by RollbackApp when the hostname has no promoted app to roll back
+	ErrNoPreviousApp = errors.New("no previous app")
+)
+
+// StageApp registers a new version of the sub-app of a registered hostname or wildcard pattern next to the live one. It receives no traffic until Promote. Staging again replaces the staged app; passing nil unstages it.
+func (m *VhostsManager) StageApp(hostname string, app *fiber.App) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+
+		previous := e.toEntry()
+		e.staged = app
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
+
+// StagedApp returns the app staged for a registered hostname or wildcard pattern
+func (m *VhostsManager) StagedApp(hostname string) (*fiber.App, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists || e.staged == nil {
+		return nil, false
+	}
+	return e.staged, true
+}
+
+// Promote atomically makes the staged app of a hostname the live one, so there is no window where the hostname is missing. The replaced app is kept for RollbackApp. The OnUpdate hooks are invoked like for SwapApp.
+func (m *VhostsManager) Promote(hostname string) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+		if e.staged == nil {
+			return nil, ErrNothingStaged
+		}
+
+		previous := e.toEntry()
+		e.previous, e.app, e.factory, e.staged = e.app, e.staged, nil, nil
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
+
+// RollbackApp atomically puts the app replaced by the last Promote back live. The rolled back app is kept in turn, so calling RollbackApp again rolls forward.
+func (m *VhostsManager) RollbackApp(hostname string) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+		if e.previous == nil {
+			return nil, ErrNoPreviousApp
+		}
+
+		previous := e.toEntry()
+		e.app, e.previous = e.previous, e.app
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
@@ -0,0 +1,55 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test a staged app goes live on Promote and can be rolled back and forward.
+func TestVhostsManager_StagePromote(t *testing.T) {
+	newApp := func(version string) *fiber.App {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendString(version) })
+		return app
+	}
+	blue, green := newApp("blue"), newApp("green")
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("api.example.com", blue))
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	serving := func() string {
+		resp, err := main.Test(httptest.NewRequest("GET", "http://api.example.com/", nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.ErrorIs(t, manager.Promote("api.example.com"), ErrNothingStaged)
+	assert.ErrorIs(t, manager.RollbackApp("api.example.com"), ErrNoPreviousApp)
+	assert.ErrorIs(t, manager.StageApp("missing.example.com", green), ErrHostNotFound)
+
+	assert.NoError(t, manager.StageApp("api.example.com", green))
+	staged, ok := manager.StagedApp("api.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, green, staged)
+	assert.Equal(t, "blue", serving())
+
+	var updates int
+	manager.Hooks().OnUpdate(func(previous, current Entry) { updates++ })
+
+	assert.NoError(t, manager.Promote("api.example.com"))
+	assert.Equal(t, "green", serving())
+	_, ok = manager.StagedApp("api.example.com")
+	assert.False(t, ok)
+
+	assert.NoError(t, manager.RollbackApp("api.example.com"))
+	assert.Equal(t, "blue", serving())
+	assert.NoError(t, manager.RollbackApp("api.example.com"))
+	assert.Equal(t, "green", serving())
+	assert.Equal(t, 3, updates)
+}
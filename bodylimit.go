@@ -0,0 +1,28 @@
+// This file contains the per-vhost request body limit, as fiber.Config.BodyLimit applies to the main app as a whole and cannot differ per hostname.
+package fibervhosts
+
+import "github.com/gofiber/fiber/v2"
+
+// bodyLimitFor returns the request body limit of r; bodies are not limited when it is not positive
+func (m *VhostsManager) bodyLimitFor(r *route) int {
+	if r.config.BodyLimit != 0 {
+		return r.config.BodyLimit
+	}
+	return m.bodyLimit
+}
+
+// checkBodyLimit returns 413 Request Entity Too Large when the body of a request to r exceeds its limit. The Content-Length header is checked first; chunked bodies are measured as read by the server.
+func (m *VhostsManager) checkBodyLimit(c *fiber.Ctx, r *route) error {
+	limit := m.bodyLimitFor(r)
+	if limit <= 0 {
+		return nil
+	}
+	size := c.Request().Header.ContentLength()
+	if size < 0 {
+		size = len(c.Request().Body())
+	}
+	if size > limit {
+		return fiber.ErrRequestEntityTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test request bodies over the limit of their hostname are rejected with 413 before dispatch.
+func TestVhostMiddleware_BodyLimit(t *testing.T) {
+	dispatched := 0
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		dispatched++
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	manager := NewVhostsManager(Config{BodyLimit: 16})
+	assert.NoError(t, manager.AddHostname("small.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("large.example.com", app, HostConfig{BodyLimit: 64}))
+	assert.NoError(t, manager.AddHostnameWithConfig("unlimited.example.com", app, HostConfig{BodyLimit: -1}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	for _, tc := range []struct {
+		hostname string
+		size     int
+		want     int
+	}{
+		{"small.example.com", 16, fiber.StatusNoContent},
+		{"small.example.com", 17, fiber.StatusRequestEntityTooLarge},
+		{"large.example.com", 64, fiber.StatusNoContent},
+		{"large.example.com", 65, fiber.StatusRequestEntityTooLarge},
+		{"unlimited.example.com", 1024, fiber.StatusNoContent},
+	} {
+		req := httptest.NewRequest("POST", "http://"+tc.hostname+"/", strings.NewReader(strings.Repeat("x", tc.size)))
+		resp, err := main.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, resp.StatusCode, "%s with %d bytes", tc.hostname, tc.size)
+	}
+	assert.Equal(t, 3, dispatched)
+}
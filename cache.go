@@ -0,0 +1,270 @@
+// This file contains the per-vhost response cache of the dispatch chain, which answers repeated requests from stored responses without running the sub-app, like an edge cache in process for mostly static tenant sites.
+package fibervhosts
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderXCache tells whether a response was served from the cache, "HIT", or stored in it, "MISS"
+const HeaderXCache = "X-Cache"
+
+// Cache defines the response cache of a hostname. Set it on Config for all hostnames, or on HostConfig to override it for a single registration.
+//
+// Responses are stored per method, hostname and request URI, unless Key says otherwise; cookies are not part of the key. Requests with an Authorization header bypass the cache, and responses setting cookies, streamed, server-sent events, encoded like with gzip, varying by request headers not in VaryBy or marked with Cache-Control no-store, no-cache or private are not stored. The compression of the manager runs in front of the cache, so it compresses cached responses as well. The per-vhost middleware and policies like Auth run in front of the cache, so they apply to cached responses as well.
+type Cache struct {
+	// TTL is how long responses are served from the cache. Defaults to one minute.
+	TTL time.Duration
+	// Methods lists the request methods whose responses are cached. Defaults to GET and HEAD.
+	Methods []string
+	// StatusCodes lists the statuses of the responses cached. Defaults to 200, 203, 204, 301, 404 and 410.
+	StatusCodes []int
+	// Key returns the cache key of a request, like to add a header the responses vary by. Defaults to the method, the hostname and the request URI.
+	Key func(c *fiber.Ctx) string
+	// VaryBy lists the request headers Key folds into the cache key, so responses whose Vary header names them are stored. Defaults to Host, which the default key holds. Accept-Encoding is ignored, as encoded responses are not stored.
+	VaryBy []string
+	// Store keeps the responses, like in Redis to share them between instances. Defaults to an in-memory store.
+	Store CacheStore
+	// MaxEntries caps the number of responses in the in-memory store; further responses are not stored until others expired. Defaults to 10000.
+	MaxEntries int
+	// Disabled turns off caching, like for a single dynamic hostname
+	Disabled bool
+
+	once   sync.Once
+	memory *memoryCacheStore
+}
+
+// CacheStore keeps the responses of the response cache. Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the value of key, or nil if it does not exist or expired
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value of key, which expires after ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// defaultCacheStatusCodes are the statuses cached by a Cache without StatusCodes
+var defaultCacheStatusCodes = []int{
+	fiber.StatusOK,
+	fiber.StatusNonAuthoritativeInformation,
+	fiber.StatusNoContent,
+	fiber.StatusMovedPermanently,
+	fiber.StatusNotFound,
+	fiber.StatusGone,
+}
+
+// cachedResponse is a response as kept in the store
+type cachedResponse struct {
+	Status int         `json:"status"`
+	Header [][2]string `json:"header"`
+	Body   []byte      `json:"body"`
+	Stored time.Time   `json:"stored"`
+}
+
+// cacheFor returns the response cache of a registration with config, or nil when its responses are not cached
+func (m *VhostsManager) cacheFor(config HostConfig) *Cache {
+	p := m.cache
+	if config.Cache != nil {
+		p = config.Cache
+	}
+	if p == nil || p.Disabled {
+		return nil
+	}
+	return p
+}
+
+// store returns the store of the cache, creating the in-memory store on first use
+func (p *Cache) store() CacheStore {
+	if p.Store != nil {
+		return p.Store
+	}
+	p.once.Do(func() {
+		maxEntries := p.MaxEntries
+		if maxEntries <= 0 {
+			maxEntries = 10000
+		}
+		p.memory = newMemoryCacheStore(maxEntries)
+	})
+	return p.memory
+}
+
+// key returns the cache key of a request
+func (p *Cache) key(c *fiber.Ctx) string {
+	if p.Key != nil {
+		return p.Key(c)
+	}
+	return c.Method() + " " + c.Hostname() + string(c.Request().URI().RequestURI())
+}
+
+// cacheable reports whether the response to a request may be looked up in and stored in the cache
+func (p *Cache) cacheable(c *fiber.Ctx) bool {
+	methods := p.Methods
+	if len(methods) == 0 {
+		methods = []string{fiber.MethodGet, fiber.MethodHead}
+	}
+	return slices.Contains(methods, c.Method()) && len(c.Request().Header.Peek(fiber.HeaderAuthorization)) == 0
+}
+
+// storable reports whether the response of the sub-app may be stored
+func (p *Cache) storable(c *fiber.Ctx) bool {
+	resp := c.Response()
+	statuses := p.StatusCodes
+	if len(statuses) == 0 {
+		statuses = defaultCacheStatusCodes
+	}
+	if !slices.Contains(statuses, resp.StatusCode()) || resp.IsBodyStream() || len(resp.Header.Peek(fiber.HeaderSetCookie)) > 0 || strings.HasPrefix(string(resp.Header.ContentType()), mimeEventStream) {
+		return false
+	}
+	if encoding := resp.Header.ContentEncoding(); len(encoding) > 0 && string(encoding) != "identity" {
+		return false
+	}
+	control := strings.ToLower(string(resp.Header.Peek(fiber.HeaderCacheControl)))
+	return !strings.Contains(control, "no-store") && !strings.Contains(control, "no-cache") && !strings.Contains(control, "private") && p.keyed(string(resp.Header.Peek(fiber.HeaderVary)))
+}
+
+// keyed reports whether the cache key holds every request header named by the Vary header of a response
+func (p *Cache) keyed(vary string) bool {
+	varyBy := p.VaryBy
+	if varyBy == nil {
+		varyBy = []string{fiber.HeaderHost}
+	}
+	for _, name := range strings.Split(vary, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.EqualFold(name, fiber.HeaderAcceptEncoding) {
+			continue
+		}
+		if !slices.ContainsFunc(varyBy, func(header string) bool { return strings.EqualFold(header, name) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// cached answers requests to next from the cache of p and stores the responses of next in it. Requests are dispatched to next when the store fails, so an outage of a shared store does not take down the hostnames.
+func (m *VhostsManager) cached(next dispatchFunc, p *Cache) dispatchFunc {
+	ttl := p.TTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+
+	return func(c *fiber.Ctx) error {
+		if !p.cacheable(c) {
+			return next(c)
+		}
+		key := p.key(c)
+		store := p.store()
+
+		value, err := store.Get(c.UserContext(), key)
+		if err != nil {
+			m.logger.Warn("Cache store failed", "hostname", strings.Clone(c.Hostname()), "error", err)
+		}
+		var cached cachedResponse
+		if value != nil && json.Unmarshal(value, &cached) == nil {
+			resp := c.Response()
+			resp.SetStatusCode(cached.Status)
+			for _, header := range cached.Header {
+				resp.Header.Add(header[0], header[1])
+			}
+			resp.SetBody(cached.Body)
+			c.Set(fiber.HeaderAge, strconv.Itoa(int(time.Since(cached.Stored).Seconds())))
+			c.Set(HeaderXCache, "HIT")
+			return nil
+		}
+
+		// Headers set in front of the cache, like the request ID, belong to this request only
+		preset := make(map[string]struct{})
+		c.Response().Header.VisitAll(func(name, value []byte) {
+			preset[string(name)+"\x00"+string(value)] = struct{}{}
+		})
+		if err := next(c); err != nil {
+			return err
+		}
+		if !p.storable(c) {
+			return nil
+		}
+		cached = cachedResponse{Status: c.Response().StatusCode(), Body: c.Response().Body(), Stored: time.Now()}
+		c.Response().Header.VisitAll(func(name, value []byte) {
+			switch string(name) {
+			case fiber.HeaderContentLength, fiber.HeaderDate, fiber.HeaderConnection:
+				return
+			}
+			if _, exists := preset[string(name)+"\x00"+string(value)]; exists {
+				return
+			}
+			cached.Header = append(cached.Header, [2]string{string(name), string(value)})
+		})
+		value, err = json.Marshal(cached)
+		if err == nil {
+			err = store.Set(c.UserContext(), key, value, ttl)
+		}
+		if err != nil {
+			m.logger.Warn("Cache store failed", "hostname", strings.Clone(c.Hostname()), "error", err)
+			return nil
+		}
+		c.Set(HeaderXCache, "MISS")
+		return nil
+	}
+}
+
+// memoryCacheStore is the default CacheStore, keeping the responses of a single instance
+type memoryCacheStore struct {
+	mu         sync.Mutex
+	entries    map[string]cacheEntry
+	maxEntries int
+	// swept is when expired entries were dropped last, sweep when they are dropped next
+	swept, sweep time.Time
+}
+
+// cacheEntry is a value of the in-memory store
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// newMemoryCacheStore creates an empty in-memory store holding up to maxEntries values
+func newMemoryCacheStore(maxEntries int) *memoryCacheStore {
+	return &memoryCacheStore{entries: make(map[string]cacheEntry), maxEntries: maxEntries}
+}
+
+// Get returns the value of key
+func (s *memoryCacheStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[key]
+	if !exists || time.Now().After(entry.expires) {
+		return nil, nil
+	}
+	return entry.value, nil
+}
+
+// Set stores the value of key, unless the store is full
+func (s *memoryCacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, exists := s.entries[key]; !exists {
+		full := len(s.entries) >= s.maxEntries
+		// A full store is swept up to once a second, to make room again soon
+		if now.After(s.sweep) || (full && now.Sub(s.swept) > time.Second) {
+			for k, entry := range s.entries {
+				if now.After(entry.expires) {
+					delete(s.entries, k)
+				}
+			}
+			s.swept, s.sweep = now, now.Add(time.Minute)
+		}
+		if len(s.entries) >= s.maxEntries {
+			return nil
+		}
+	}
+	s.entries[key] = cacheEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
@@ -0,0 +1,187 @@
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingCacheStore is a CacheStore that is down
+type failingCacheStore struct{}
+
+func (failingCacheStore) Get(context.Context, string) ([]byte, error) {
+	return nil, errors.New("store down")
+}
+
+func (failingCacheStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("store down")
+}
+
+// Test responses are served from the cache until they expire, and uncacheable requests and responses bypass it.
+func TestVhostMiddleware_Cache(t *testing.T) {
+	calls := 0
+	app := fiber.New()
+	app.All("/", func(c *fiber.Ctx) error {
+		calls++
+		c.Set("X-Custom", "yes")
+		return c.SendString(fmt.Sprintf("page %d", calls))
+	})
+	app.Get("/cookie", func(c *fiber.Ctx) error {
+		calls++
+		c.Cookie(&fiber.Cookie{Name: "session", Value: "1"})
+		return c.SendString("cookie")
+	})
+	app.Get("/private", func(c *fiber.Ctx) error {
+		calls++
+		c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+		return c.SendString("private")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		calls++
+		return c.SendStatus(fiber.StatusInternalServerError)
+	})
+
+	logger := &recordingLogger{}
+	manager := NewVhostsManager(Config{Logger: logger, RequestID: &RequestID{}, Cache: &Cache{TTL: 100 * time.Millisecond}})
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("live.example.com", app, HostConfig{Cache: &Cache{Disabled: true}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("down.example.com", app, HostConfig{Cache: &Cache{Store: failingCacheStore{}}}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(method, target string, header ...string) (*http.Response, string) {
+		req := httptest.NewRequest(method, target, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := main.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	first, body := request("GET", "http://www.example.com/")
+	assert.Equal(t, "page 1", body)
+	assert.Equal(t, "MISS", first.Header.Get(HeaderXCache))
+
+	resp, body := request("GET", "http://www.example.com/")
+	assert.Equal(t, "page 1", body)
+	assert.Equal(t, "HIT", resp.Header.Get(HeaderXCache))
+	assert.Equal(t, []string{"yes"}, resp.Header.Values("X-Custom"))
+	assert.Equal(t, "0", resp.Header.Get(fiber.HeaderAge))
+	if assert.Len(t, resp.Header.Values("X-Request-ID"), 1) {
+		assert.NotEqual(t, first.Header.Get("X-Request-ID"), resp.Header.Get("X-Request-ID"))
+	}
+	assert.Equal(t, 1, calls)
+
+	_, body = request("GET", "http://www.example.com/?page=2")
+	assert.Equal(t, "page 2", body)
+	_, body = request("POST", "http://www.example.com/")
+	assert.Equal(t, "page 3", body)
+	_, body = request("GET", "http://www.example.com/", fiber.HeaderAuthorization, "Bearer token")
+	assert.Equal(t, "page 4", body)
+	_, body = request("GET", "http://live.example.com/")
+	assert.Equal(t, "page 5", body)
+
+	calls = 0
+	for _, path := range []string{"/cookie", "/private", "/fail"} {
+		request("GET", "http://www.example.com"+path)
+		resp, _ = request("GET", "http://www.example.com"+path)
+		assert.Empty(t, resp.Header.Get(HeaderXCache))
+	}
+	assert.Equal(t, 6, calls)
+
+	time.Sleep(150 * time.Millisecond)
+	resp, body = request("GET", "http://www.example.com/")
+	assert.Equal(t, "MISS", resp.Header.Get(HeaderXCache))
+	assert.Equal(t, "page 7", body)
+
+	resp, body = request("GET", "http://down.example.com/")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "page 8", body)
+	assert.Equal(t, "Cache store failed", logger.records[0].msg)
+}
+
+// Test encoded responses are not stored, so clients not accepting the encoding never get them from the cache.
+func TestVhostMiddleware_CacheContentEncoding(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		if c.Get(fiber.HeaderAcceptEncoding) == "gzip" {
+			c.Set(fiber.HeaderContentEncoding, "gzip")
+			return c.Send([]byte{0x1f, 0x8b})
+		}
+		return c.SendString("plain")
+	})
+	manager := NewVhostsManager(Config{Cache: &Cache{}})
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+
+	req := NewTestRequest("GET", "", "/", nil)
+	req.Header.Set(fiber.HeaderAcceptEncoding, "gzip")
+	resp, err := manager.Test("www.example.com", req)
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Header.Get(HeaderXCache))
+	resp, err = manager.Test("www.example.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "plain", string(body))
+	assert.Empty(t, resp.Header.Get(fiber.HeaderContentEncoding))
+}
+
+// Test responses varying by request headers are only stored when the cache key holds them.
+func TestVhostMiddleware_CacheVary(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Vary(fiber.HeaderAcceptLanguage)
+		return c.SendString(c.Get(fiber.HeaderAcceptLanguage))
+	})
+	manager := NewVhostsManager(Config{Cache: &Cache{}})
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("keyed.example.com", app, HostConfig{Cache: &Cache{
+		Key: func(c *fiber.Ctx) string {
+			return c.Hostname() + c.OriginalURL() + " " + c.Get(fiber.HeaderAcceptLanguage)
+		},
+		VaryBy: []string{fiber.HeaderAcceptLanguage},
+	}}))
+
+	request := func(hostname, language string) (string, string) {
+		req := NewTestRequest("GET", "", "/", nil)
+		req.Header.Set(fiber.HeaderAcceptLanguage, language)
+		resp, err := manager.Test(hostname, req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), resp.Header.Get(HeaderXCache)
+	}
+	for _, hostname := range []string{"www.example.com", "keyed.example.com"} {
+		request(hostname, "en")
+		body, _ := request(hostname, "de")
+		assert.Equal(t, "de", body, hostname)
+	}
+	_, cache := request("keyed.example.com", "de")
+	assert.Equal(t, "HIT", cache)
+	_, cache = request("www.example.com", "de")
+	assert.Empty(t, cache)
+}
+
+// Test the in-memory store stops storing when full until entries expire.
+func TestMemoryCacheStore_MaxEntries(t *testing.T) {
+	store := newMemoryCacheStore(2)
+	ctx := context.Background()
+	assert.NoError(t, store.Set(ctx, "a", []byte("a"), 50*time.Millisecond))
+	assert.NoError(t, store.Set(ctx, "b", []byte("b"), time.Minute))
+	assert.NoError(t, store.Set(ctx, "c", []byte("c"), time.Minute))
+	value, _ := store.Get(ctx, "c")
+	assert.Nil(t, value)
+
+	time.Sleep(time.Second + 100*time.Millisecond)
+	assert.NoError(t, store.Set(ctx, "c", []byte("c"), time.Minute))
+	value, _ = store.Get(ctx, "c")
+	assert.Equal(t, []byte("c"), value)
+}
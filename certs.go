@@ -0,0 +1,110 @@
+// This file contains the per-vhost TLS certificate store and the GetCertificate callback serving the certificate of a registration by SNI.
+package fibervhosts
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoCertificate is returned by GetCertificate when no certificate matches the requested server name
+var ErrNoCertificate = errors.New("no certificate for server name")
+
+// SetCertificate attaches a TLS certificate to a registered hostname or wildcard pattern. The certificate lives as long as the registration: it is dropped when the hostname is removed or renamed. Passing nil removes the certificate.
+func (m *VhostsManager) SetCertificate(hostname string, cert *tls.Certificate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists {
+		return ErrHostNotFound
+	}
+	e.cert, e.certFiles, e.certSource = cert, nil, nil
+	return nil
+}
+
+// LoadCertificate reads a PEM encoded certificate and key pair and attaches it to a registered hostname or wildcard pattern, see SetCertificate. The files are remembered so the certificate can be reloaded when they change, see WatchCertificates.
+func (m *VhostsManager) LoadCertificate(hostname, certFile, keyFile string) error {
+	files := &certFiles{certFile: certFile, keyFile: keyFile}
+	cert, err := files.load()
+	if err != nil {
+		return fmt.Errorf("load certificate for %s: %w", hostname, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists {
+		return ErrHostNotFound
+	}
+	e.cert, e.certFiles, e.certSource = cert, files, nil
+	return nil
+}
+
+// GetCertificateFor returns the certificate attached to a registered hostname or wildcard pattern
+func (m *VhostsManager) GetCertificateFor(hostname string) (*tls.Certificate, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists || e.cert == nil {
+		return nil, false
+	}
+	return e.cert, true
+}
+
+// SetDefaultCertificate sets the certificate served when no registration has a certificate for the requested server name, or when the client sends no SNI. Passing nil removes it.
+func (m *VhostsManager) SetDefaultCertificate(cert *tls.Certificate) {
+	m.mu.Lock()
+	m.defaultCert = cert
+	m.mu.Unlock()
+}
+
+// GetCertificate selects the certificate for a TLS handshake by SNI and can be used as tls.Config.GetCertificate. The certificate of the exact hostname is preferred, then the one of the matching wildcard, then the default certificate.
+func (m *VhostsManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+
+	if cert, ok := m.HostCertificate(name); ok {
+		return cert, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.defaultCert != nil {
+		return m.defaultCert, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNoCertificate, name)
+}
+
+// HostCertificate returns the certificate GetCertificate serves for a server name, without falling back to the default certificate: the certificate of the exact hostname, or else the one of the matching wildcard
+func (m *VhostsManager) HostCertificate(serverName string) (*tls.Certificate, bool) {
+	name := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if name == "" {
+		return nil, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if e, exists := m.hosts[name]; exists && e.cert != nil {
+		return e.cert, true
+	}
+	if e := m.findWildcard(name); e != nil && e.cert != nil {
+		return e.cert, true
+	}
+	return nil, false
+}
+
+// TLSConfig returns a TLS config serving the certificates of the manager and requesting client certificates for hostnames requiring them, ready to be passed to a TLS listener like fiber's app.Listener
+func (m *VhostsManager) TLSConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: m.GetCertificate,
+	}
+	m.EnableClientAuth(config)
+	return config
+}
@@ -0,0 +1,64 @@
+// This file contains the expiry monitoring of the certificates attached to registrations.
+package fibervhosts
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+)
+
+// defaultCertExpiryWarning is how long before expiry a certificate is reported by default
+const defaultCertExpiryWarning = 30 * 24 * time.Hour
+
+// CertExpiry describes a certificate close to or past its expiry
+type CertExpiry struct {
+	Entry    Entry
+	NotAfter time.Time
+}
+
+// CheckCertificateExpiry returns the registrations whose certificate expires within Config.CertExpiryWarning, including expired ones, sorted like ListEntries. The OnCertExpiring hooks are executed for every certificate reported for the first time. WatchCertificates runs this check on every tick.
+func (m *VhostsManager) CheckCertificateExpiry() []CertExpiry {
+	deadline := time.Now().Add(m.certExpiryWarning)
+
+	var expiring, fresh []CertExpiry
+	m.mu.Lock()
+	for _, table := range []map[string]*entry{m.hosts, m.wildcards} {
+		for _, e := range table {
+			leaf := certLeaf(e.cert)
+			if leaf == nil || leaf.NotAfter.After(deadline) {
+				continue
+			}
+			report := CertExpiry{Entry: e.toEntry(), NotAfter: leaf.NotAfter}
+			expiring = append(expiring, report)
+			if e.certWarned != e.cert {
+				e.certWarned = e.cert
+				fresh = append(fresh, report)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, report := range fresh {
+		m.hooks.executeOnCertExpiring(report.Entry, report.NotAfter)
+	}
+	sortByEntry(expiring, func(c CertExpiry) (EntryType, string) { return c.Entry.Type, c.Entry.Pattern })
+	return expiring
+}
+
+// certLeaf returns the parsed leaf of a certificate, or nil if there is none
+func certLeaf(cert *tls.Certificate) *x509.Certificate {
+	if cert == nil {
+		return nil
+	}
+	if cert.Leaf != nil {
+		return cert.Leaf
+	}
+	if len(cert.Certificate) == 0 {
+		return nil
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil
+	}
+	return leaf
+}
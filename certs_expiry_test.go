@@ -0,0 +1,54 @@
+package fibervhosts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test expiring certificates are reported in the stats and through the hook once per certificate.
+func TestVhostsManager_CheckCertificateExpiry(t *testing.T) {
+	app := fiber.New()
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostname("plain.example.com", app))
+
+	// The test certificates expire within an hour, so well within the default window
+	cert, _, _ := testCertificate(t, "www.example.com")
+	assert.NoError(t, manager.SetCertificate("www.example.com", cert))
+
+	var fired []string
+	manager.Hooks().OnCertExpiring(func(e Entry, notAfter time.Time) {
+		assert.Equal(t, cert.Leaf.NotAfter, notAfter)
+		fired = append(fired, e.Pattern)
+	})
+
+	expiring := manager.CheckCertificateExpiry()
+	assert.Len(t, expiring, 1)
+	assert.Equal(t, "www.example.com", expiring[0].Entry.Pattern)
+	assert.Len(t, manager.CheckCertificateExpiry(), 1)
+	assert.Equal(t, []string{"www.example.com"}, fired)
+
+	// A new certificate is reported again
+	renewed, _, _ := testCertificate(t, "www.example.com")
+	assert.NoError(t, manager.SetCertificate("www.example.com", renewed))
+	manager.CheckCertificateExpiry()
+	assert.Len(t, fired, 2)
+
+	stats := manager.Stats()
+	assert.Equal(t, "plain.example.com", stats[0].Pattern)
+	assert.True(t, stats[0].CertExpiresAt.IsZero())
+	assert.Equal(t, renewed.Leaf.NotAfter, stats[1].CertExpiresAt)
+}
+
+// Test certificates outside the warning window are not reported.
+func TestVhostsManager_CheckCertificateExpiry_Window(t *testing.T) {
+	manager := NewVhostsManager(Config{CertExpiryWarning: time.Minute})
+	assert.NoError(t, manager.AddHostname("www.example.com", fiber.New()))
+	cert, _, _ := testCertificate(t, "www.example.com")
+	assert.NoError(t, manager.SetCertificate("www.example.com", cert))
+
+	assert.Empty(t, manager.CheckCertificateExpiry())
+}
@@ -0,0 +1,125 @@
+// This file contains the hot reload of certificates loaded from files, so rotations by external tooling like certbot or Vault agent are picked up without restarting the listeners.
+package fibervhosts
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// certFiles remembers where the certificate of an entry was loaded from
+type certFiles struct {
+	certFile string
+	keyFile  string
+	modTime  time.Time
+}
+
+// load reads the key pair and records the modification time of the files
+func (f *certFiles) load() (*tls.Certificate, error) {
+	modTime, err := f.lastModified()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	f.modTime = modTime
+	return &cert, nil
+}
+
+// lastModified returns the latest modification time of the certificate and key files
+func (f *certFiles) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{f.certFile, f.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// ReloadCertificates reloads the certificates loaded with LoadCertificate whose files changed since they were last read. A certificate that fails to load keeps being served as before; the errors of all failed reloads are returned joined.
+func (m *VhostsManager) ReloadCertificates() error {
+	return m.reloadCertificates(false)
+}
+
+// WatchCertificates checks the files of the certificates loaded with LoadCertificate every interval and reloads the ones that changed. Certificates attached with SetCertificateSource are fetched again when their lease expires before the next check. On SIGHUP every certificate is reloaded, changed or not. After every check the expiry of the certificates is checked as well, see CheckCertificateExpiry. Reload errors are logged and the previous certificate keeps being served. WatchCertificates blocks until ctx is done.
+func (m *VhostsManager) WatchCertificates(ctx context.Context, interval time.Duration) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		force := false
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-hup:
+			force = true
+		}
+		if err := m.reloadCertificates(force); err != nil {
+			m.logger.Warn("Reloading certificates failed", "error", err)
+		}
+		if err := m.refreshCertificates(ctx, time.Now().Add(interval), force); err != nil {
+			m.logger.Warn("Refreshing certificates failed", "error", err)
+		}
+		m.CheckCertificateExpiry()
+	}
+}
+
+// reloadCertificates reloads the file backed certificates that changed, or all of them when force is set. The files are read without holding the lock.
+func (m *VhostsManager) reloadCertificates(force bool) error {
+	type pending struct {
+		entry   *entry
+		pattern string
+		files   *certFiles
+	}
+
+	m.mu.RLock()
+	var candidates []pending
+	for _, table := range []map[string]*entry{m.hosts, m.wildcards} {
+		for _, e := range table {
+			if e.certFiles != nil {
+				candidates = append(candidates, pending{e, e.pattern, e.certFiles})
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	var errs []error
+	for _, c := range candidates {
+		modTime, err := c.files.lastModified()
+		if err == nil && !force && !modTime.After(c.files.modTime) {
+			continue
+		}
+
+		files := &certFiles{certFile: c.files.certFile, keyFile: c.files.keyFile}
+		cert, err := files.load()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.pattern, err))
+			continue
+		}
+
+		m.mu.Lock()
+		// Skip entries whose certificate was replaced or removed meanwhile
+		if c.entry.certFiles == c.files {
+			c.entry.cert, c.entry.certFiles = cert, files
+		}
+		m.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
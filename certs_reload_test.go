@@ -0,0 +1,78 @@
+package fibervhosts
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCertificate writes a new certificate for name and moves the modification time forward
+func writeCertificate(t *testing.T, certFile, keyFile, name string, age time.Duration) {
+	t.Helper()
+	_, certPEM, keyPEM := testCertificate(t, name)
+	assert.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+	modTime := time.Now().Add(age)
+	assert.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	assert.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+// Test changed certificate files are reloaded and broken ones keep the previous certificate.
+func TestVhostsManager_ReloadCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, "first.example.com", -time.Hour)
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("www.example.com", fiber.New()))
+	assert.NoError(t, manager.LoadCertificate("www.example.com", certFile, keyFile))
+
+	serving := func() string {
+		cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+		assert.NoError(t, err)
+		return cert.Leaf.Subject.CommonName
+	}
+
+	// Unchanged files are not reloaded
+	assert.NoError(t, manager.ReloadCertificates())
+	assert.Equal(t, "first.example.com", serving())
+
+	writeCertificate(t, certFile, keyFile, "second.example.com", 0)
+	assert.NoError(t, manager.ReloadCertificates())
+	assert.Equal(t, "second.example.com", serving())
+
+	assert.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	assert.NoError(t, os.Chtimes(certFile, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+	assert.Error(t, manager.ReloadCertificates())
+	assert.Equal(t, "second.example.com", serving())
+}
+
+// Test WatchCertificates picks up rotated files.
+func TestVhostsManager_WatchCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, "first.example.com", -time.Hour)
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("www.example.com", fiber.New()))
+	assert.NoError(t, manager.LoadCertificate("www.example.com", certFile, keyFile))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- manager.WatchCertificates(ctx, 5*time.Millisecond) }()
+
+	writeCertificate(t, certFile, keyFile, "rotated.example.com", 0)
+	assert.Eventually(t, func() bool {
+		cert, _ := manager.HostCertificate("www.example.com")
+		return cert.Leaf.Subject.CommonName == "rotated.example.com"
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,113 @@
+// This file contains certificates fetched from an external store like a secrets manager, which are cached on the registration and fetched again once their lease expires.
+package fibervhosts
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CertificateSource fetches the certificate of a hostname from an external store, see SetCertificateSource
+type CertificateSource interface {
+	// FetchCertificate returns the certificate for a hostname or wildcard pattern and how long it may be cached. A zero lease caches the certificate until it is replaced.
+	FetchCertificate(ctx context.Context, hostname string) (*tls.Certificate, time.Duration, error)
+}
+
+// certSource remembers where the certificate of an entry was fetched from
+type certSource struct {
+	source  CertificateSource
+	expires time.Time
+}
+
+// fetch fetches the certificate of hostname and records when its lease expires
+func (s *certSource) fetch(ctx context.Context, hostname string) (*tls.Certificate, error) {
+	cert, lease, err := s.source.FetchCertificate(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, ErrNoCertificate
+	}
+	s.expires = time.Time{}
+	if lease > 0 {
+		s.expires = time.Now().Add(lease)
+	}
+	return cert, nil
+}
+
+// expired reports whether the lease ends before the given time
+func (s *certSource) expired(at time.Time) bool {
+	return !s.expires.IsZero() && !s.expires.After(at)
+}
+
+// SetCertificateSource fetches the certificate of a registered hostname or wildcard pattern from source and attaches it, see SetCertificate. The source is remembered so the certificate is fetched again once its lease expires, see RefreshCertificates and WatchCertificates.
+func (m *VhostsManager) SetCertificateSource(ctx context.Context, hostname string, source CertificateSource) error {
+	m.mu.RLock()
+	table, key := m.tableFor(hostname)
+	_, exists := table[key]
+	m.mu.RUnlock()
+	if !exists {
+		return ErrHostNotFound
+	}
+
+	src := &certSource{source: source}
+	cert, err := src.fetch(ctx, hostname)
+	if err != nil {
+		return fmt.Errorf("fetch certificate for %s: %w", hostname, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, exists := table[key]
+	if !exists {
+		return ErrHostNotFound
+	}
+	e.cert, e.certFiles, e.certSource = cert, nil, src
+	return nil
+}
+
+// RefreshCertificates fetches the certificates attached with SetCertificateSource whose lease expired. A certificate that fails to be fetched keeps being served as before; the errors of all failed fetches are returned joined.
+func (m *VhostsManager) RefreshCertificates(ctx context.Context) error {
+	return m.refreshCertificates(ctx, time.Now(), false)
+}
+
+// refreshCertificates fetches the source backed certificates whose lease ends before the given time, or all of them when force is set. The sources are called without holding the lock.
+func (m *VhostsManager) refreshCertificates(ctx context.Context, before time.Time, force bool) error {
+	type pending struct {
+		entry   *entry
+		pattern string
+		source  *certSource
+	}
+
+	m.mu.RLock()
+	var candidates []pending
+	for _, table := range []map[string]*entry{m.hosts, m.wildcards} {
+		for _, e := range table {
+			if e.certSource != nil && (force || e.certSource.expired(before)) {
+				candidates = append(candidates, pending{e, e.pattern, e.certSource})
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	var errs []error
+	for _, c := range candidates {
+		src := &certSource{source: c.source.source}
+		cert, err := src.fetch(ctx, c.pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.pattern, err))
+			continue
+		}
+
+		m.mu.Lock()
+		// Skip entries whose certificate was replaced or removed meanwhile
+		if c.entry.certSource == c.source {
+			c.entry.cert, c.entry.certSource = cert, src
+		}
+		m.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
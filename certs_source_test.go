@@ -0,0 +1,68 @@
+package fibervhosts
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCertSource serves a fresh certificate named after the fetch count on every fetch
+type fakeCertSource struct {
+	t       *testing.T
+	lease   time.Duration
+	fetches atomic.Int32
+	err     error
+}
+
+func (s *fakeCertSource) FetchCertificate(ctx context.Context, hostname string) (*tls.Certificate, time.Duration, error) {
+	n := s.fetches.Add(1)
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+	cert, _, _ := testCertificate(s.t, hostname, string(rune('a'+n-1))+"."+hostname)
+	return cert, s.lease, nil
+}
+
+// Test certificates from a source are fetched again only once their lease expired.
+func TestVhostsManager_SetCertificateSource(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("www.example.com", fiber.New()))
+
+	source := &fakeCertSource{t: t, lease: time.Hour}
+	ctx := context.Background()
+	assert.ErrorIs(t, manager.SetCertificateSource(ctx, "missing.example.com", source), ErrHostNotFound)
+	assert.NoError(t, manager.SetCertificateSource(ctx, "www.example.com", source))
+
+	serving := func() string {
+		cert, ok := manager.GetCertificateFor("www.example.com")
+		assert.True(t, ok)
+		return cert.Leaf.DNSNames[1]
+	}
+	assert.Equal(t, "a.www.example.com", serving())
+
+	// The lease is still valid
+	assert.NoError(t, manager.RefreshCertificates(ctx))
+	assert.Equal(t, int32(1), source.fetches.Load())
+
+	// Checking ahead of the lease end fetches the certificate again
+	assert.NoError(t, manager.refreshCertificates(ctx, time.Now().Add(2*time.Hour), false))
+	assert.Equal(t, "b.www.example.com", serving())
+
+	// A failed fetch keeps the previous certificate
+	source.err = errors.New("sealed")
+	assert.Error(t, manager.refreshCertificates(ctx, time.Now(), true))
+	assert.Equal(t, "b.www.example.com", serving())
+
+	// Setting a certificate directly detaches the source
+	cert, _, _ := testCertificate(t, "www.example.com")
+	assert.NoError(t, manager.SetCertificate("www.example.com", cert))
+	source.err = nil
+	assert.NoError(t, manager.refreshCertificates(ctx, time.Now(), true))
+	assert.Equal(t, int32(3), source.fetches.Load())
+}
@@ -0,0 +1,102 @@
+package fibervhosts
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCertificate creates a self-signed certificate for the given names and returns it with its PEM encoded certificate and key
+func testCertificate(t *testing.T, names ...string) (*tls.Certificate, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+	return &cert, certPEM, keyPEM
+}
+
+// Test certificates are selected by SNI with wildcard and default fallbacks.
+func TestVhostsManager_GetCertificate(t *testing.T) {
+	app := fiber.New()
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("api.example.com", app))
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostname("*.example.com", app))
+
+	api, _, _ := testCertificate(t, "api.example.com")
+	wildcard, _, _ := testCertificate(t, "*.example.com")
+	fallback, _, _ := testCertificate(t, "fallback")
+	assert.NoError(t, manager.SetCertificate("api.example.com", api))
+	assert.NoError(t, manager.SetCertificate("*.example.com", wildcard))
+	assert.Equal(t, ErrHostNotFound, manager.SetCertificate("missing.example.com", api))
+
+	get := func(name string) *tls.Certificate {
+		cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+		if err != nil {
+			return nil
+		}
+		return cert
+	}
+	assert.Same(t, api, get("API.example.com"))
+	assert.Same(t, wildcard, get("www.example.com"))
+	assert.Same(t, wildcard, get("other.example.com"))
+	assert.Nil(t, get("example.org"))
+
+	manager.SetDefaultCertificate(fallback)
+	assert.Same(t, fallback, get("example.org"))
+	assert.Same(t, fallback, get(""))
+
+	// The certificate goes away with the registration
+	assert.NoError(t, manager.RemoveHostname("api.example.com"))
+	assert.Same(t, wildcard, get("api.example.com"))
+}
+
+// Test loading a certificate from PEM files and serving it over TLS.
+func TestVhostsManager_LoadCertificate(t *testing.T) {
+	_, certPEM, keyPEM := testCertificate(t, "secure.example.com")
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("secure.example.com", fiber.New()))
+	assert.NoError(t, manager.LoadCertificate("secure.example.com", certFile, keyFile))
+	assert.Error(t, manager.LoadCertificate("secure.example.com", certFile, certFile))
+
+	_, exists := manager.GetCertificateFor("secure.example.com")
+	assert.True(t, exists)
+
+	config := manager.TLSConfig()
+	cert, err := config.GetCertificate(&tls.ClientHelloInfo{ServerName: "secure.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "secure.example.com", cert.Leaf.Subject.CommonName)
+}
@@ -0,0 +1,84 @@
+// This file contains the per-vhost response compression of the dispatch chain, so the sub-apps do not each have to set up compression.
+package fibervhosts
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// defaultCompressTypes are the content types compressed by a Compression without ContentTypes
+var defaultCompressTypes = []string{"text/", "application/json", "application/javascript", "application/xml", "image/svg+xml"}
+
+// Compression defines the response compression of a hostname. Responses are compressed with gzip or deflate, whichever the client accepts first.
+type Compression struct {
+	// Level is the compression level from 1, the fastest, to 9, the smallest. Defaults to 6.
+	Level int
+	// MinSize is the size in bytes below which responses are sent uncompressed. Defaults to 1024.
+	MinSize int
+	// ContentTypes lists the prefixes of the content types compressed, like "text/" or "application/json". Defaults to text, JSON, JavaScript, XML and SVG.
+	ContentTypes []string
+	// Disabled turns off compression, like for a hostname serving already compressed downloads
+	Disabled bool
+}
+
+// compressionFor returns the compression of a registration with config, or nil when its responses are not compressed
+func (m *VhostsManager) compressionFor(config HostConfig) *Compression {
+	p := m.compression
+	if config.Compression != nil {
+		p = config.Compression
+	}
+	if p == nil || p.Disabled {
+		return nil
+	}
+	return p
+}
+
+// compress compresses the responses of next. Responses which failed, are streamed, already encoded or too small are sent as they are, and so are server-sent events, which compression would hold back.
+func (p *Compression) compress(next dispatchFunc) dispatchFunc {
+	level := p.Level
+	if level < 1 || level > 9 {
+		level = fasthttp.CompressDefaultCompression
+	}
+	minSize := p.MinSize
+	if minSize <= 0 {
+		minSize = 1024
+	}
+	types := p.ContentTypes
+	if len(types) == 0 {
+		types = defaultCompressTypes
+	}
+
+	return func(c *fiber.Ctx) error {
+		if err := next(c); err != nil {
+			return err
+		}
+		resp := c.Response()
+		contentType := string(resp.Header.ContentType())
+		if c.Method() == fiber.MethodHead || resp.IsBodyStream() || len(resp.Header.ContentEncoding()) > 0 || len(resp.Body()) < minSize || !hasTypePrefix(contentType, types) || strings.HasPrefix(contentType, mimeEventStream) {
+			return nil
+		}
+
+		c.Vary(fiber.HeaderAcceptEncoding)
+		switch {
+		case c.Request().Header.HasAcceptEncoding("gzip"):
+			resp.SetBodyRaw(fasthttp.AppendGzipBytesLevel(nil, resp.Body(), level))
+			resp.Header.SetContentEncoding("gzip")
+		case c.Request().Header.HasAcceptEncoding("deflate"):
+			resp.SetBodyRaw(fasthttp.AppendDeflateBytesLevel(nil, resp.Body(), level))
+			resp.Header.SetContentEncoding("deflate")
+		}
+		return nil
+	}
+}
+
+// hasTypePrefix reports whether a content type starts with one of the prefixes
+func hasTypePrefix(contentType string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package fibervhosts
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test responses are compressed with the settings of their hostname.
+func TestVhostMiddleware_Compression(t *testing.T) {
+	page := strings.Repeat("<p>hello</p>", 200)
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Type("html")
+		return c.SendString(page)
+	})
+	app.Get("/small", func(c *fiber.Ctx) error {
+		return c.SendString("small")
+	})
+	app.Get("/binary", func(c *fiber.Ctx) error {
+		c.Type("png")
+		return c.SendString(page)
+	})
+	app.Get("/events", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		return c.SendString(page)
+	})
+
+	manager := NewVhostsManager(Config{Compression: &Compression{}})
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("tuned.example.com", app, HostConfig{Compression: &Compression{Level: 9, MinSize: 1, ContentTypes: []string{"image/"}}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("plain.example.com", app, HostConfig{Compression: &Compression{Disabled: true}}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(target, encoding string) (string, string) {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set(fiber.HeaderAcceptEncoding, encoding)
+		resp, err := main.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		body := resp.Body
+		if resp.Header.Get(fiber.HeaderContentEncoding) == "gzip" {
+			body, err = gzip.NewReader(resp.Body)
+			assert.NoError(t, err)
+		}
+		data, err := io.ReadAll(body)
+		assert.NoError(t, err)
+		return resp.Header.Get(fiber.HeaderContentEncoding), string(data)
+	}
+
+	for _, tc := range []struct {
+		target   string
+		encoding string
+		want     string
+	}{
+		{"http://www.example.com/", "gzip, deflate", "gzip"},
+		{"http://www.example.com/", "deflate", "deflate"},
+		{"http://www.example.com/", "", ""},
+		{"http://www.example.com/small", "gzip", ""},
+		{"http://www.example.com/binary", "gzip", ""},
+		{"http://www.example.com/events", "gzip", ""},
+		{"http://tuned.example.com/binary", "gzip", "gzip"},
+		{"http://tuned.example.com/", "gzip", ""},
+		{"http://plain.example.com/", "gzip", ""},
+	} {
+		encoding, body := request(tc.target, tc.encoding)
+		assert.Equal(t, tc.want, encoding, "%s with %q", tc.target, tc.encoding)
+		if tc.want != "deflate" && !strings.HasSuffix(tc.target, "/small") {
+			assert.Equal(t, page, body, tc.target)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// This file contains the declarative config file loader populating the manager from JSON, YAML or TOML files.
+package fibervhosts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gofiber/fiber/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedFormat is returned when a config file has an unknown extension
+var ErrUnsupportedFormat = errors.New("unsupported config format")
+
+// AppResolver maps a named app identifier used in declarative configs to a *fiber.App. It returns nil for unknown names.
+type AppResolver func(name string) *fiber.App
+
+// MapResolver returns an AppResolver looking up apps by name in the given map
+func MapResolver(apps map[string]*fiber.App) AppResolver {
+	return func(name string) *fiber.App {
+		return apps[name]
+	}
+}
+
+// withFallback returns a resolver that resolves name to app and defers every other name to resolver
+func withFallback(resolver AppResolver, name string, app *fiber.App) AppResolver {
+	return func(n string) *fiber.App {
+		if n == name {
+			return app
+		}
+		return resolver(n)
+	}
+}
+
+// LoadFromFile reads a declarative vhost config and makes it the current table. The format is chosen by the file extension: .json, .yaml, .yml or .toml. The file uses the same layout as ExportJSON:
+//
+//	default: fallback
+//	hosts:
+//	  - hostname: api.example.com
+//	    app: api
+//	    metadata:
+//	      values: {owner: team-a}
+//	      tags: [production]
+//	wildcards:
+//	  - hostname: "*.example.org"
+//	    app: web
+//	    suspended: true
+//
+// App names are mapped to apps by the resolver; an unknown name aborts the load with ErrAppNotFound and leaves the current table untouched.
+func (m *VhostsManager) LoadFromFile(path string, resolver AppResolver) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var t tableFile
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &t)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &t)
+	case ".toml":
+		err = toml.Unmarshal(data, &t)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return m.applyTable(t, resolver)
+}
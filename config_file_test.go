@@ -0,0 +1,89 @@
+package fibervhosts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeConfig writes a config file into a temporary directory and returns its path
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// Test loading the same table from every supported format.
+func TestVhostsManager_LoadFromFile(t *testing.T) {
+	api := fiber.New()
+	web := fiber.New()
+	fallback := fiber.New()
+	resolver := MapResolver(map[string]*fiber.App{"api": api, "web": web, "fallback": fallback})
+
+	files := map[string]string{
+		"vhosts.json": `{
+  "default": "fallback",
+  "hosts": [{"hostname": "api.example.com", "app": "api", "metadata": {"tags": ["production"]}}],
+  "wildcards": [{"hostname": "*.example.org", "app": "web", "suspended": true}]
+}`,
+		"vhosts.yaml": `
+default: fallback
+hosts:
+  - hostname: api.example.com
+    app: api
+    metadata:
+      tags: [production]
+wildcards:
+  - hostname: "*.example.org"
+    app: web
+    suspended: true
+`,
+		"vhosts.toml": `
+default = "fallback"
+
+[[hosts]]
+hostname = "api.example.com"
+app = "api"
+metadata = { tags = ["production"] }
+
+[[wildcards]]
+hostname = "*.example.org"
+app = "web"
+suspended = true
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			manager := NewVhostsManager()
+			assert.NoError(t, manager.LoadFromFile(writeConfig(t, name, content), resolver))
+
+			app, exists := manager.GetHostname("api.example.com")
+			assert.True(t, exists)
+			assert.Equal(t, api, app)
+			md, _ := manager.GetMetadata("api.example.com")
+			assert.True(t, md.HasTag("production"))
+			assert.True(t, manager.IsSuspended("*.example.org"))
+			assert.Equal(t, fallback, manager.defaultApp.app)
+		})
+	}
+}
+
+// Test load errors for unknown formats and apps.
+func TestVhostsManager_LoadFromFile_Errors(t *testing.T) {
+	manager := NewVhostsManager()
+	resolver := MapResolver(map[string]*fiber.App{})
+
+	err := manager.LoadFromFile(writeConfig(t, "vhosts.ini", ""), resolver)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	err = manager.LoadFromFile(writeConfig(t, "vhosts.yml", "hosts:\n  - hostname: example.com\n    app: missing\n"), resolver)
+	assert.ErrorIs(t, err, ErrAppNotFound)
+
+	err = manager.LoadFromFile(filepath.Join(t.TempDir(), "missing.json"), resolver)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
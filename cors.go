@@ -0,0 +1,92 @@
+// This file contains the per-vhost CORS policy, answering preflight requests and adding the CORS headers to the responses of a hostname so its sub-app does not have to.
+package fibervhosts
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultCORSMethods are the methods allowed by a CORS policy without AllowMethods
+var defaultCORSMethods = []string{fiber.MethodGet, fiber.MethodPost, fiber.MethodHead, fiber.MethodPut, fiber.MethodDelete, fiber.MethodPatch}
+
+// CORS defines the cross-origin resource sharing policy of a hostname
+type CORS struct {
+	// AllowOrigins lists the origins allowed to access the hostname, like "https://app.example.com". "*" allows any origin, and a wildcard like "https://*.example.com" any subdomain.
+	AllowOrigins []string
+	// AllowMethods lists the methods allowed in cross-origin requests. Defaults to GET, POST, HEAD, PUT, DELETE and PATCH.
+	AllowMethods []string
+	// AllowHeaders lists the request headers allowed in cross-origin requests. Defaults to the headers asked for by the preflight request.
+	AllowHeaders []string
+	// ExposeHeaders lists the response headers browsers expose to cross-origin requests
+	ExposeHeaders []string
+	// AllowCredentials lets cross-origin requests include cookies and credentials. The allowed origin is then always named, even when AllowOrigins has "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the result of a preflight request. Zero sends no Access-Control-Max-Age header.
+	MaxAge time.Duration
+}
+
+// allowsOrigin reports whether the policy allows origin
+func (p *CORS) allowsOrigin(origin string) bool {
+	for _, allowed := range p.AllowOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		// "https://*.example.com" matches "https://app.example.com" but not "https://example.com"
+		if scheme, suffix, found := strings.Cut(allowed, "*."); found &&
+			strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, "."+suffix) && len(origin) > len(scheme)+len(suffix)+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// handle adds the CORS headers to the response of a cross-origin request and answers preflight requests. It reports whether the request was answered. Requests from origins not allowed are passed on without headers, so browsers block their responses.
+func (p *CORS) handle(c *fiber.Ctx) (bool, error) {
+	origin := c.Get(fiber.HeaderOrigin)
+	preflight := c.Method() == fiber.MethodOptions && c.Get(fiber.HeaderAccessControlRequestMethod) != ""
+	if origin == "" {
+		return false, nil
+	}
+	c.Vary(fiber.HeaderOrigin)
+	if !p.allowsOrigin(origin) {
+		if preflight {
+			return true, c.SendStatus(fiber.StatusNoContent)
+		}
+		return false, nil
+	}
+
+	if !p.AllowCredentials && slices.Contains(p.AllowOrigins, "*") {
+		c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+	} else {
+		c.Set(fiber.HeaderAccessControlAllowOrigin, origin)
+	}
+	if p.AllowCredentials {
+		c.Set(fiber.HeaderAccessControlAllowCredentials, "true")
+	}
+	if !preflight {
+		if len(p.ExposeHeaders) > 0 {
+			c.Set(fiber.HeaderAccessControlExposeHeaders, strings.Join(p.ExposeHeaders, ", "))
+		}
+		return false, nil
+	}
+
+	methods := p.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	c.Set(fiber.HeaderAccessControlAllowMethods, strings.Join(methods, ", "))
+	if len(p.AllowHeaders) > 0 {
+		c.Set(fiber.HeaderAccessControlAllowHeaders, strings.Join(p.AllowHeaders, ", "))
+	} else if requested := c.Get(fiber.HeaderAccessControlRequestHeaders); requested != "" {
+		c.Vary(fiber.HeaderAccessControlRequestHeaders)
+		c.Set(fiber.HeaderAccessControlAllowHeaders, requested)
+	}
+	if p.MaxAge > 0 {
+		c.Set(fiber.HeaderAccessControlMaxAge, strconv.FormatInt(int64(p.MaxAge/time.Second), 10))
+	}
+	return true, c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,70 @@
+package fibervhosts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the CORS policy of a hostname answers preflight requests and adds the headers to its responses.
+func TestVhostMiddleware_CORS(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("api.example.com", app, HostConfig{CORS: &CORS{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.tenant.example.com"},
+		AllowHeaders:     []string{"Authorization"},
+		ExposeHeaders:    []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("public.example.com", app, HostConfig{CORS: &CORS{AllowOrigins: []string{"*"}}}))
+	assert.NoError(t, manager.AddHostname("plain.example.com", app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(method, hostname, origin string) *http.Response {
+		req := httptest.NewRequest(method, "http://"+hostname+"/", nil)
+		if origin != "" {
+			req.Header.Set(fiber.HeaderOrigin, origin)
+		}
+		if method == fiber.MethodOptions {
+			req.Header.Set(fiber.HeaderAccessControlRequestMethod, fiber.MethodGet)
+		}
+		resp, err := main.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	preflight := request(fiber.MethodOptions, "api.example.com", "https://app.example.com")
+	assert.Equal(t, fiber.StatusNoContent, preflight.StatusCode)
+	assert.Equal(t, "https://app.example.com", preflight.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "GET, POST, HEAD, PUT, DELETE, PATCH", preflight.Header.Get(fiber.HeaderAccessControlAllowMethods))
+	assert.Equal(t, "Authorization", preflight.Header.Get(fiber.HeaderAccessControlAllowHeaders))
+	assert.Equal(t, "true", preflight.Header.Get(fiber.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, "3600", preflight.Header.Get(fiber.HeaderAccessControlMaxAge))
+
+	simple := request(fiber.MethodGet, "api.example.com", "https://acme.tenant.example.com")
+	assert.Equal(t, fiber.StatusOK, simple.StatusCode)
+	assert.Equal(t, "https://acme.tenant.example.com", simple.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "X-Request-Id", simple.Header.Get(fiber.HeaderAccessControlExposeHeaders))
+
+	// Origins not allowed get no headers, so browsers block the response
+	for _, origin := range []string{"https://evil.example.net", "https://tenant.example.com"} {
+		denied := request(fiber.MethodGet, "api.example.com", origin)
+		assert.Equal(t, fiber.StatusOK, denied.StatusCode)
+		assert.Empty(t, denied.Header.Get(fiber.HeaderAccessControlAllowOrigin), origin)
+	}
+	denied := request(fiber.MethodOptions, "api.example.com", "https://evil.example.net")
+	assert.Equal(t, fiber.StatusNoContent, denied.StatusCode)
+	assert.Empty(t, denied.Header.Get(fiber.HeaderAccessControlAllowMethods))
+
+	assert.Equal(t, "*", request(fiber.MethodGet, "public.example.com", "https://anywhere.example.org").Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	assert.Empty(t, request(fiber.MethodGet, "plain.example.com", "https://app.example.com").Header.Get(fiber.HeaderAccessControlAllowOrigin))
+}
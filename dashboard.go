@@ -0,0 +1,223 @@
+// This file contains the optional HTML dashboard of a manager, an admin app listing the registrations with their hit counts, health and certificate status, with buttons to suspend, drain, resume and remove them.
+package fibervhosts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DashboardConfig defines the config of DashboardApp
+type DashboardConfig struct {
+	// Title is shown in the header of the page. Defaults to "Virtual hosts".
+	Title string
+	// Health configures the health checks run for every page view, see CheckHealth
+	Health HealthConfig
+	// DrainTimeout is how long the drain button waits for the in-flight requests of a hostname. The hostname stays drained when they do not finish in time. Defaults to 10 seconds.
+	DrainTimeout time.Duration
+}
+
+// defaultDrainTimeout is the default DashboardConfig.DrainTimeout
+const defaultDrainTimeout = 10 * time.Second
+
+// Certificate statuses shown on the dashboard
+const (
+	certStatusNone     = "none"
+	certStatusValid    = "valid"
+	certStatusExpiring = "expiring"
+	certStatusExpired  = "expired"
+)
+
+// dashboardRow is a registration shown on the dashboard
+type dashboardRow struct {
+	Entry       Entry
+	Stats       HostStats
+	Draining    bool
+	Maintenance bool
+	InFlight    int64
+	Health      HostHealth
+	CertStatus  string
+	// Removable is false for the default app, which is changed with SetDefaultApp instead
+	Removable bool
+}
+
+// dashboardData is the data the dashboard template is rendered with
+type dashboardData struct {
+	Title   string
+	Base    string
+	Healthy bool
+	Rows    []dashboardRow
+	Now     time.Time
+}
+
+// dashboardTemplate renders the dashboard page
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: .4em .6em; text-align: left; }
+form { display: inline; }
+.ok, .valid { color: #2a7a2a; }
+.failing, .expired { color: #b22; }
+.suspended, .pending, .expiring, .draining { color: #b70; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{len .Rows}} registrations, {{if .Healthy}}<span class="ok">healthy</span>{{else}}<span class="failing">failing</span>{{end}}, as of {{.Now.Format "2006-01-02 15:04:05 MST"}}</p>
+<table>
+<tr><th>Pattern</th><th>Match</th><th>App</th><th>Hits</th><th>Errors</th><th>In flight</th><th>Last access</th><th>Health</th><th>Certificate</th><th>State</th><th></th></tr>
+{{- range .Rows}}
+<tr>
+<td>{{if .Entry.Pattern}}{{.Entry.Pattern}}{{else}}(default){{end}}</td>
+<td>{{.Entry.Type}}</td>
+<td>{{.Entry.AppName}}</td>
+<td>{{.Stats.Requests}}</td>
+<td>{{.Stats.Errors}}</td>
+<td>{{.InFlight}}</td>
+<td>{{if .Stats.LastAccess.IsZero}}never{{else}}{{.Stats.LastAccess.Format "2006-01-02 15:04:05"}}{{end}}</td>
+<td class="{{.Health.Status}}" title="{{.Health.Error}}">{{.Health.Status}}</td>
+<td class="{{.CertStatus}}">{{.CertStatus}}{{if not .Stats.CertExpiresAt.IsZero}} ({{.Stats.CertExpiresAt.Format "2006-01-02"}}){{end}}</td>
+<td>{{if .Entry.Suspended}}<span class="suspended">suspended</span>{{else if .Draining}}<span class="draining">draining</span>{{else if .Maintenance}}<span class="pending">maintenance</span>{{else if .Entry.Pending}}<span class="pending">pending</span>{{else}}<span class="ok">live</span>{{end}}</td>
+<td>{{if .Removable}}{{$pattern := .Entry.Pattern}}
+{{- if or .Entry.Suspended .Draining}}<form method="post" action="{{$.Base}}/actions"><input type="hidden" name="pattern" value="{{$pattern}}"><button name="action" value="resume">Resume</button></form>
+{{- else}}<form method="post" action="{{$.Base}}/actions"><input type="hidden" name="pattern" value="{{$pattern}}"><button name="action" value="suspend">Suspend</button> <button name="action" value="drain">Drain</button></form>{{end}}
+<form method="post" action="{{$.Base}}/actions" onsubmit="return confirm('Remove {{$pattern}}?')"><input type="hidden" name="pattern" value="{{$pattern}}"><button name="action" value="remove">Remove</button></form>{{end}}</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// DashboardApp returns an admin app serving an HTML dashboard of the registrations: their match type, hit counts, in-flight requests, health and certificate status, with buttons to suspend, drain, resume and remove them. Serve it on its own listener or mount it on an admin app, like app.Mount("/admin/vhosts", manager.DashboardApp()). The buttons change the manager without authentication, so only expose it on an internal admin app or behind auth; requests from other sites are refused.
+func (m *VhostsManager) DashboardApp(config ...DashboardConfig) *fiber.App {
+	cfg := DashboardConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Title == "" {
+		cfg.Title = "Virtual hosts"
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
+	}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/", func(c *fiber.Ctx) error {
+		var body bytes.Buffer
+		data := m.dashboardData(c.UserContext(), cfg)
+		// Mounted apps see the routes with their prefix, which the forms post to
+		data.Base = strings.TrimSuffix(c.Route().Path, "/")
+		if err := dashboardTemplate.Execute(&body, data); err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		c.Type("html", "utf-8")
+		return c.Send(body.Bytes())
+	})
+	app.Post("/actions", func(c *fiber.Ctx) error {
+		if c.Get("Sec-Fetch-Site") == "cross-site" {
+			return fiber.ErrForbidden
+		}
+		if err := m.dashboardAction(c.FormValue("action"), c.FormValue("pattern"), cfg); err != nil {
+			if errors.Is(err, ErrHostNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, err.Error())
+			}
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return c.Redirect(strings.TrimSuffix(c.Route().Path, "/actions")+"/", fiber.StatusSeeOther)
+	})
+	return app
+}
+
+// dashboardAction applies a button of the dashboard to the registration of pattern
+func (m *VhostsManager) dashboardAction(action, pattern string, config DashboardConfig) error {
+	if pattern == "" {
+		return ErrInvalidHostname
+	}
+	switch action {
+	case "suspend":
+		return m.SuspendHostname(pattern)
+	case "resume":
+		return m.ResumeHostname(pattern)
+	case "drain":
+		// The hostname stays drained past the timeout, which the dashboard shows with its in-flight requests
+		if err := m.DrainHostname(pattern, config.DrainTimeout); err != nil && !errors.Is(err, ErrDrainTimeout) {
+			return err
+		}
+		return nil
+	case "remove":
+		return m.RemoveHostname(pattern)
+	}
+	return errors.New("unknown action " + action)
+}
+
+// dashboardData collects the rows of the dashboard, sorted like ListEntries
+func (m *VhostsManager) dashboardData(ctx context.Context, config DashboardConfig) dashboardData {
+	report := m.CheckHealth(ctx, config.Health)
+	now := time.Now()
+
+	m.mu.RLock()
+	var rows []dashboardRow
+	collect := func(e *entry) {
+		row := dashboardRow{
+			Entry:       e.toEntry(),
+			Stats:       e.stats.snapshot(e),
+			Draining:    e.draining.Load(),
+			Maintenance: e.maintenance.Load() != nil,
+			InFlight:    e.inflight.Load(),
+			CertStatus:  certStatusNone,
+			Removable:   e.kind != EntryDefault,
+		}
+		if expires := row.Stats.CertExpiresAt; !expires.IsZero() {
+			switch {
+			case now.After(expires):
+				row.CertStatus = certStatusExpired
+			case now.Add(m.certExpiryWarning).After(expires):
+				row.CertStatus = certStatusExpiring
+			default:
+				row.CertStatus = certStatusValid
+			}
+		}
+		rows = append(rows, row)
+	}
+	for _, e := range m.hosts {
+		collect(e)
+	}
+	for _, e := range m.wildcards {
+		collect(e)
+	}
+	if m.defaultApp != nil {
+		collect(m.defaultApp)
+	}
+	m.mu.RUnlock()
+
+	// The health checks ran before the rows were collected, so registrations added since are shown unchecked
+	health := make(map[EntryType]map[string]HostHealth)
+	for _, h := range report.Hosts {
+		if health[h.Type] == nil {
+			health[h.Type] = make(map[string]HostHealth)
+		}
+		health[h.Type][h.Pattern] = h
+	}
+	for i := range rows {
+		h, ok := health[rows[i].Entry.Type][rows[i].Entry.Pattern]
+		if !ok {
+			h = HostHealth{Type: rows[i].Entry.Type, Pattern: rows[i].Entry.Pattern, Status: HealthUnchecked}
+		}
+		rows[i].Health = h
+	}
+
+	sortByEntry(rows, func(r dashboardRow) (EntryType, string) { return r.Entry.Type, r.Entry.Pattern })
+	return dashboardData{Title: config.Title, Healthy: report.Healthy, Rows: rows, Now: now}
+}
@@ -0,0 +1,72 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the dashboard lists the registrations and its buttons change them, also when mounted below a prefix.
+func TestVhostsManager_DashboardApp(t *testing.T) {
+	site := fiber.New()
+	site.Get("/", func(c *fiber.Ctx) error { return c.SendString("site") })
+
+	manager := NewVhostsManager(Config{DefaultApp: fiber.New()})
+	assert.NoError(t, manager.AddHostname("www.example.com", site))
+	assert.NoError(t, manager.AddHostname("*.example.org", site))
+	_, err := manager.Test("www.example.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+
+	admin := fiber.New()
+	admin.Mount("/admin", manager.DashboardApp(DashboardConfig{Title: "Edge hosts"}))
+
+	resp, err := admin.Test(httptest.NewRequest("GET", "/admin/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+	assert.Contains(t, page, "<h1>Edge hosts</h1>")
+	assert.Contains(t, page, "<td>www.example.com</td>")
+	assert.Contains(t, page, "<td>*.example.org</td>")
+	assert.Contains(t, page, "(default)")
+	assert.Contains(t, page, `action="/admin/actions"`)
+	assert.Less(t, strings.Index(page, "www.example.com"), strings.Index(page, "*.example.org"))
+
+	post := func(action, pattern string, header ...string) int {
+		form := url.Values{"action": {action}, "pattern": {pattern}}
+		req := httptest.NewRequest("POST", "/admin/actions", strings.NewReader(form.Encode()))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := admin.Test(req)
+		assert.NoError(t, err)
+		if resp.StatusCode == fiber.StatusSeeOther {
+			assert.Equal(t, "/admin/", resp.Header.Get(fiber.HeaderLocation))
+		}
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusSeeOther, post("suspend", "www.example.com"))
+	assert.True(t, manager.IsSuspended("www.example.com"))
+	assert.Equal(t, fiber.StatusSeeOther, post("resume", "www.example.com"))
+	assert.False(t, manager.IsSuspended("www.example.com"))
+	assert.Equal(t, fiber.StatusSeeOther, post("drain", "*.example.org"))
+	assert.True(t, manager.IsDraining("*.example.org"))
+	assert.Equal(t, fiber.StatusForbidden, post("remove", "www.example.com", "Sec-Fetch-Site", "cross-site"))
+	assert.Equal(t, fiber.StatusSeeOther, post("remove", "www.example.com"))
+	assert.Equal(t, []string{}, manager.GetHostnames())
+	assert.Equal(t, fiber.StatusNotFound, post("remove", "www.example.com"))
+	assert.Equal(t, fiber.StatusBadRequest, post("restart", "*.example.org"))
+
+	resp, err = admin.Test(httptest.NewRequest("GET", "/admin/", nil))
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.NotContains(t, string(body), "www.example.com")
+	assert.Contains(t, string(body), "draining")
+}
@@ -0,0 +1,107 @@
+// This file contains the per-host dispatch chains, which wrap the handler of a sub-app with features like panic recovery, per-vhost middleware, host rewriting, request timeouts, response caching and compression. They are composed once when the lookup table is built instead of modifying the sub-app on every request.
+package fibervhosts
+
+import (
+	"runtime/debug"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// dispatchFunc dispatches a matched request to a sub-app
+type dispatchFunc func(c *fiber.Ctx) error
+
+// PanicHandler responds to a request whose sub-app panicked with the recovered value, see Config.PanicHandler
+type PanicHandler func(c *fiber.Ctx, recovered any) error
+
+// compose builds the dispatch chain of a backend with the settings of its registration
+func (m *VhostsManager) compose(b backend, config HostConfig) dispatchFunc {
+	dispatch := func(c *fiber.Ctx) error {
+		b.requestHandler()(c.Context())
+		return nil
+	}
+	if timeout := m.timeoutFor(config); timeout > 0 {
+		dispatch = m.dispatchWithTimeout(b, timeout)
+	}
+	if cache := m.cacheFor(config); cache != nil {
+		dispatch = m.cached(dispatch, cache)
+	}
+	if compression := m.compressionFor(config); compression != nil {
+		dispatch = compression.compress(dispatch)
+	}
+	if host := config.RewriteHost; host != "" {
+		dispatch = rewriteHost(dispatch, host)
+	}
+	if len(config.Middleware) > 0 {
+		dispatch = chain(config.Middleware, dispatch)
+	}
+	if handler := config.PanicHandler; handler != nil {
+		dispatch = m.recoverPanics(dispatch, handler)
+	} else if m.recoverFromPanic {
+		dispatch = m.recoverPanics(dispatch, m.panicHandler)
+	}
+	return dispatch
+}
+
+// recoverPanics logs panics of the sub-app together with the stack and responds with handler, or with 500 Internal Server Error when handler is nil
+func (m *VhostsManager) recoverPanics(next dispatchFunc, handler PanicHandler) dispatchFunc {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			m.logger.Error("Recovered from panic", "hostname", strings.Clone(c.Hostname()), "panic", p, "stack", string(debug.Stack()))
+			if handler == nil {
+				err = fiber.ErrInternalServerError
+				return
+			}
+			err = handler(c, p)
+		}()
+		return next(c)
+	}
+}
+
+// chainErrorKey is the Locals key the error of a middleware chain is handed back with
+type chainErrorKey struct{}
+
+// chain runs the per-vhost middleware in front of next. Fiber handlers continue with c.Next, so they run in an app of their own on the same request; its error handler hands their errors back to the vhost middleware.
+func chain(middleware []fiber.Handler, next dispatchFunc) dispatchFunc {
+	wrapper := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			c.Locals(chainErrorKey{}, err)
+			return nil
+		},
+	})
+	for _, handler := range middleware {
+		wrapper.Use(handler)
+	}
+	wrapper.Use(func(c *fiber.Ctx) error {
+		return next(c)
+	})
+
+	return func(c *fiber.Ctx) error {
+		wrapper.Handler()(c.Context())
+		if err, ok := c.Locals(chainErrorKey{}).(error); ok {
+			c.Locals(chainErrorKey{}, nil)
+			return err
+		}
+		return nil
+	}
+}
+
+// rewriteHost dispatches requests to next with the Host header and the host of the URI replaced by host, and restores them afterwards for the access log
+func rewriteHost(next dispatchFunc, host string) dispatchFunc {
+	return func(c *fiber.Ctx) error {
+		req := c.Request()
+		header, uri := string(req.Header.Host()), string(req.URI().Host())
+		req.Header.SetHost(host)
+		req.URI().SetHost(host)
+		defer func() {
+			req.Header.SetHost(header)
+			req.URI().SetHost(uri)
+		}()
+		return next(c)
+	}
+}
@@ -0,0 +1,159 @@
+package fibervhosts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test panics of a sub-app are recovered when enabled, without modifying the sub-app per request.
+func TestVhostMiddleware_RecoverSubAppPanic(t *testing.T) {
+	manager := NewVhostsManager(Config{RecoverFromPanic: true})
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		panic("test panic")
+	})
+	handlers := app.HandlersCount()
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	for i := 0; i < 3; i++ {
+		resp, err := main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+	}
+	assert.Equal(t, handlers, app.HandlersCount())
+
+	assert.Equal(t, uint64(3), manager.Stats()[0].Errors)
+}
+
+// Test panics are answered by the panic handler of the manager or of the registration.
+func TestVhostMiddleware_PanicHandler(t *testing.T) {
+	panicking := func() *fiber.App {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			panic("tenant state corrupted")
+		})
+		return app
+	}
+	respond := func(prefix string) PanicHandler {
+		return func(c *fiber.Ctx, recovered any) error {
+			return c.Status(fiber.StatusBadGateway).SendString(fmt.Sprint(prefix, ": ", recovered))
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		config Config
+		host   HostConfig
+		want   string
+	}{
+		"manager":      {Config{RecoverFromPanic: true, PanicHandler: respond("manager")}, HostConfig{}, "manager: tenant state corrupted"},
+		"registration": {Config{}, HostConfig{PanicHandler: respond("host")}, "host: tenant state corrupted"},
+		"override":     {Config{RecoverFromPanic: true, PanicHandler: respond("manager")}, HostConfig{PanicHandler: respond("host")}, "host: tenant state corrupted"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			manager := NewVhostsManager(tc.config)
+			assert.NoError(t, manager.AddHostnameWithConfig("www.example.com", panicking(), tc.host))
+
+			main := fiber.New()
+			main.Use(VhostMiddleware(manager))
+			resp, err := main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+			assert.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+			assert.Equal(t, tc.want, string(body))
+		})
+	}
+}
+
+// Test middleware of a registration runs in front of its sub-app only, and its errors reach the error handler of the registration.
+func TestVhostsManager_AddHostnameWithMiddleware(t *testing.T) {
+	manager := NewVhostsManager()
+	newApp := func() *fiber.App {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.SendString(c.Get("X-Tenant"))
+		})
+		return app
+	}
+	app := newApp()
+	handlers := app.HandlersCount()
+	tenant := func(c *fiber.Ctx) error {
+		c.Request().Header.Set("X-Tenant", "acme")
+		c.Set("X-Served-By", "vhosts")
+		return c.Next()
+	}
+	auth := func(c *fiber.Ctx) error {
+		if c.Get(fiber.HeaderAuthorization) == "" {
+			return fiber.ErrUnauthorized
+		}
+		return c.Next()
+	}
+	assert.NoError(t, manager.AddHostnameWithMiddleware("www.example.com", app, tenant, auth))
+	assert.NoError(t, manager.AddHostname("other.example.com", newApp()))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	req := httptest.NewRequest("GET", "http://www.example.com/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer token")
+	resp, err := main.Test(req)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "acme", string(body))
+	assert.Equal(t, "vhosts", resp.Header.Get("X-Served-By"))
+	assert.Equal(t, handlers, app.HandlersCount())
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://other.example.com/", nil))
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Empty(t, string(body))
+	assert.Empty(t, resp.Header.Get("X-Served-By"))
+
+	// The error handler of the registration receives the errors of its middleware
+	config, _ := manager.GetHostConfig("www.example.com")
+	config.ErrorHandler = func(c *fiber.Ctx, err error) error {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
+	assert.NoError(t, manager.SetHostConfig("www.example.com", config))
+	resp, err = main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "Unauthorized", string(body))
+}
+
+// Test the sub-app of a registration with RewriteHost sees the rewritten host, while the access log keeps the requested one.
+func TestVhostMiddleware_RewriteHost(t *testing.T) {
+	var logs bytes.Buffer
+	manager := NewVhostsManager(Config{AccessLog: &AccessLog{Output: &logs, Format: AccessLogJSON}})
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Hostname() + " " + c.Get(fiber.HeaderHost))
+	})
+	assert.NoError(t, manager.AddHostnameWithConfig("legacy.example.com", app, HostConfig{RewriteHost: "app.internal"}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	resp, err := main.Test(httptest.NewRequest("GET", "http://legacy.example.com/", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "app.internal app.internal", string(body))
+	var entry AccessLogEntry
+	assert.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, "legacy.example.com", entry.Hostname)
+}
@@ -0,0 +1,75 @@
+// This file contains the drain mode of a hostname, which stops new requests, waits for the in-flight ones tracked by the middleware to finish and then fires the OnDrained hooks.
+package fibervhosts
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrDrainTimeout is returned by DrainHostname when requests are still in flight once the timeout elapsed
+var ErrDrainTimeout = errors.New("drain timeout")
+
+// drainPollInterval is how often DrainHostname checks the in-flight requests
+const drainPollInterval = 10 * time.Millisecond
+
+// drainRetryAfter is the Retry-After in seconds sent for draining hostnames
+const drainRetryAfter = "5"
+
+// DrainHostname stops dispatching new requests to a hostname and waits up to timeout for its in-flight requests to finish. New requests are answered by Config.DrainHandler, or with 503 Service Unavailable and a Retry-After header by default. Once drained the OnDrained hooks fire, so the app can be replaced or maintained safely; if requests are still in flight after timeout, ErrDrainTimeout is returned and the hooks do not fire. The hostname stays drained until ResumeHostname.
+func (m *VhostsManager) DrainHostname(hostname string, timeout time.Duration) error {
+	m.mu.RLock()
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if exists {
+		e.draining.Store(true)
+	}
+	m.mu.RUnlock()
+	if !exists {
+		return ErrHostNotFound
+	}
+
+	deadline := time.Now().Add(timeout)
+	for e.inflight.Load() > 0 {
+		if time.Now().After(deadline) {
+			return ErrDrainTimeout
+		}
+		time.Sleep(drainPollInterval)
+	}
+
+	m.mu.RLock()
+	drained := e.toEntry()
+	m.mu.RUnlock()
+	m.hooks.executeOnDrained(drained)
+	return nil
+}
+
+// IsDraining reports whether a hostname is registered and drained or draining
+func (m *VhostsManager) IsDraining(hostname string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	return exists && e.draining.Load()
+}
+
+// InFlight returns the number of requests currently dispatched to a registered hostname
+func (m *VhostsManager) InFlight(hostname string) int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, key := m.tableFor(hostname)
+	if e, exists := table[key]; exists {
+		return e.inflight.Load()
+	}
+	return 0
+}
+
+// respondDraining answers a request for a draining hostname
+func (m *VhostsManager) respondDraining(c *fiber.Ctx) error {
+	if m.drainHandler != nil {
+		return m.drainHandler(c)
+	}
+	c.Set(fiber.HeaderRetryAfter, drainRetryAfter)
+	return fiber.ErrServiceUnavailable
+}
@@ -0,0 +1,60 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test draining rejects new requests, waits for in-flight ones and fires the OnDrained hooks.
+func TestVhostsManager_DrainHostname(t *testing.T) {
+	release := make(chan struct{})
+	app := fiber.New()
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		<-release
+		return c.SendString("done")
+	})
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("api.example.com", app))
+	var drained []string
+	manager.Hooks().OnDrained(func(e Entry) { drained = append(drained, e.Pattern) })
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	slow := make(chan int)
+	go func() {
+		resp, err := main.Test(httptest.NewRequest("GET", "http://api.example.com/slow", nil), -1)
+		assert.NoError(t, err)
+		slow <- resp.StatusCode
+	}()
+	assert.Eventually(t, func() bool { return manager.InFlight("api.example.com") == 1 }, time.Second, time.Millisecond)
+
+	assert.ErrorIs(t, manager.DrainHostname("api.example.com", 20*time.Millisecond), ErrDrainTimeout)
+	assert.Empty(t, drained)
+	assert.True(t, manager.IsDraining("api.example.com"))
+
+	resp, err := main.Test(httptest.NewRequest("GET", "http://api.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+
+	done := make(chan error)
+	go func() { done <- manager.DrainHostname("api.example.com", time.Second) }()
+	close(release)
+	assert.Equal(t, fiber.StatusOK, <-slow)
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"api.example.com"}, drained)
+
+	assert.NoError(t, manager.ResumeHostname("api.example.com"))
+	resp, err = main.Test(httptest.NewRequest("GET", "http://api.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	assert.ErrorIs(t, manager.DrainHostname("missing.example.com", time.Second), ErrHostNotFound)
+}
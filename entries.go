@@ -0,0 +1,329 @@
+// This file contains the Entry type describing a single registration in the vhost table and the listing API built on it.
+package fibervhosts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EntryType describes how a registration is matched against the request hostname
+type EntryType string
+
+const (
+	// EntryHost is an exact hostname registration
+	EntryHost EntryType = "host"
+	// EntryWildcard is a wildcard registration like "*.example.com"
+	EntryWildcard EntryType = "wildcard"
+	// EntryDefault is the default app used when nothing else matches
+	EntryDefault EntryType = "default"
+)
+
+// Entry describes a single registration in the vhost table
+type Entry struct {
+	Type      EntryType
+	Pattern   string
+	App       *fiber.App
+	AppName   string
+	Suspended bool
+	// Pending is set while the registration is warming up, see AddHostnameWithWarmUp
+	Pending   bool
+	ExpiresAt time.Time
+	Metadata  Metadata
+	// Source identifies who manages the registration, like a provider. It is empty for registrations made directly through the manager API.
+	Source string
+	// Aliases are the hostnames served by the registration besides its pattern, sorted, see AddAliases
+	Aliases []string
+	// Redirect answers the requests of redirect registrations, which have no App, see AddRedirect
+	Redirect *Redirect
+	// Backend is what serves the requests of the registration. It is empty for sub-apps. ApplyEntries ignores it: desired entries are served by their App or Redirect.
+	Backend BackendKind
+
+	// state is the state of the registration restored by Rollback, only set for the entries of snapshots
+	state *entryState
+}
+
+// ListEntries returns every registration in the manager, including wildcards and the default app. Hosts are listed first, then wildcards, then the default app, each group sorted by pattern.
+func (m *VhostsManager) ListEntries() []Entry {
+	var entries []Entry
+	m.Range(func(e Entry) bool {
+		entries = append(entries, e)
+		return true
+	})
+
+	sortByEntry(entries, func(e Entry) (EntryType, string) { return e.Type, e.Pattern })
+	return entries
+}
+
+// Range calls fn for every registration in the manager, including wildcards and the default app, without building a listing first. Iteration stops when fn returns false. Like sync.Map.Range, no particular order is guaranteed. Range holds the read lock while iterating, so fn must not modify the manager.
+func (m *VhostsManager) Range(fn func(Entry) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.hosts {
+		if !fn(e.toEntry()) {
+			return
+		}
+	}
+	for _, e := range m.wildcards {
+		if !fn(e.toEntry()) {
+			return
+		}
+	}
+	if m.defaultApp != nil {
+		fn(m.defaultApp.toEntry())
+	}
+}
+
+// Match returns the registration the middleware would dispatch a request for hostname to, including the default app. It reports false if no registration matches.
+func (m *VhostsManager) Match(hostname string) (Entry, bool) {
+	r := m.lookup.Load().match(hostname)
+	if r == nil {
+		return Entry{}, false
+	}
+	return r.toEntry(), true
+}
+
+// FilterEntries returns the registrations for which keep returns true, in the same order as ListEntries
+func (m *VhostsManager) FilterEntries(keep func(Entry) bool) []Entry {
+	entries := m.ListEntries()
+	filtered := entries[:0]
+	for _, e := range entries {
+		if keep(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// WithTag returns a FilterEntries predicate matching registrations carrying the given tag
+func WithTag(tag string) func(Entry) bool {
+	return func(e Entry) bool {
+		return e.Metadata.HasTag(tag)
+	}
+}
+
+// WithMetadata returns a FilterEntries predicate matching registrations whose metadata holds the given key and value
+func WithMetadata(key, value string) func(Entry) bool {
+	return func(e Entry) bool {
+		v, ok := e.Metadata.Values[key]
+		return ok && v == value
+	}
+}
+
+// ApplyEntries makes the registrations owned by source match the given entries: missing entries are added, changed ones updated in place and owned entries that are no longer listed are removed. Registrations with a different source are left alone; if any entry collides with one of them, ErrHostExists is returned and nothing is applied. This is the building block for keeping the table in sync with an external source of truth.
+func (m *VhostsManager) ApplyEntries(source string, entries []Entry) error {
+	_, err := m.applyEntries(source, entries)
+	return err
+}
+
+// applyEntries implements ApplyEntries and returns the changes it made
+func (m *VhostsManager) applyEntries(source string, entries []Entry) ([]ChangeEvent, error) {
+	desired := make([]Entry, len(entries))
+	for i, e := range entries {
+		if e.Type != EntryDefault {
+			e.Type = EntryHost
+			if isWildcard(e.Pattern) {
+				e.Type = EntryWildcard
+			}
+		}
+		e.Source = source
+		desired[i] = e
+	}
+	if err := validateEntries(desired); err != nil {
+		return nil, err
+	}
+
+	var changes []ChangeEvent
+	owned := func(e *entry) bool { return e.source == source }
+	err := m.update(func() ([]ChangeEvent, error) {
+		for _, d := range desired {
+			current := m.defaultApp
+			if d.Type != EntryDefault {
+				table, key := m.tableFor(d.Pattern)
+				current = table[key]
+			}
+			if current != nil && !owned(current) {
+				return nil, fmt.Errorf("%w: %s", ErrHostExists, d.Pattern)
+			}
+		}
+		changes = m.converge(desired, owned)
+		return changes, nil
+	})
+	return changes, err
+}
+
+// converge changes the table to match the desired registrations: missing entries are added, changed ones updated in place (keeping their state like stats) and entries that are not desired are removed. When owned is set, only entries it returns true for are removed. The desired list must be valid, see validateEntries. The caller must hold the lock.
+func (m *VhostsManager) converge(desired []Entry, owned func(*entry) bool) []ChangeEvent {
+	if owned == nil {
+		owned = func(*entry) bool { return true }
+	}
+
+	var changes []ChangeEvent
+	wanted := make(map[string]Entry, len(desired))
+	var wantedDefault *Entry
+	for i, d := range desired {
+		if d.Type == EntryDefault {
+			wantedDefault = &desired[i]
+			continue
+		}
+		wanted[d.Pattern] = d
+	}
+
+	// Remove what is no longer desired
+	for _, table := range []map[string]*entry{m.hosts, m.wildcards} {
+		for key, e := range table {
+			if _, ok := wanted[e.pattern]; !ok && owned(e) {
+				delete(table, key)
+				e.stopExpiry()
+				changes = append(changes, removed(e))
+			}
+		}
+	}
+
+	// Add or update the rest
+	for _, d := range desired {
+		if d.Type == EntryDefault {
+			continue
+		}
+		table, key := m.tableFor(d.Pattern)
+		e, exists := table[key]
+		if !exists {
+			e = newEntry(d.Pattern, d.App)
+			e.assign(d)
+			table[key] = e
+			changes = append(changes, added(e))
+			continue
+		}
+		if !e.matches(d) {
+			previous := e.toEntry()
+			e.assign(d)
+			changes = append(changes, updated(previous, e))
+		}
+	}
+
+	switch {
+	case wantedDefault == nil && m.defaultApp != nil && owned(m.defaultApp):
+		changes = append(changes, removed(m.defaultApp))
+		m.defaultApp = nil
+	case wantedDefault != nil && m.defaultApp == nil:
+		m.defaultApp = newDefaultEntry(wantedDefault.App)
+		m.defaultApp.assign(*wantedDefault)
+		changes = append(changes, added(m.defaultApp))
+	case wantedDefault != nil && !m.defaultApp.matches(*wantedDefault):
+		previous := m.defaultApp.toEntry()
+		m.defaultApp.assign(*wantedDefault)
+		changes = append(changes, updated(previous, m.defaultApp))
+	}
+	return changes
+}
+
+// validateEntries checks a list of desired registrations for empty patterns, missing backends and duplicates
+func validateEntries(entries []Entry) error {
+	seen := make(map[string]struct{}, len(entries))
+	defaults := 0
+	for _, e := range entries {
+		if e.App == nil && !e.hasBackend() {
+			return fmt.Errorf("%w: %s for %s", ErrAppNotFound, e.AppName, e.describe())
+		}
+		if e.Redirect != nil {
+			if err := e.Redirect.validate(); err != nil {
+				return err
+			}
+		}
+		if e.Type == EntryDefault {
+			if defaults++; defaults > 1 {
+				return fmt.Errorf("%w: default", ErrHostExists)
+			}
+			continue
+		}
+		if e.Pattern == "" || e.Pattern == "*." {
+			return ErrInvalidHostname
+		}
+		if _, dup := seen[e.Pattern]; dup {
+			return fmt.Errorf("%w: %s", ErrHostExists, e.Pattern)
+		}
+		seen[e.Pattern] = struct{}{}
+	}
+	return nil
+}
+
+// hasBackend reports whether a desired entry without an App serves its requests otherwise: with a redirect, or with the factory or handler restored from a snapshot
+func (d Entry) hasBackend() bool {
+	return d.Redirect != nil || d.state != nil && (d.state.factory != nil || d.state.handler != nil)
+}
+
+// describe names the registration of a desired entry in errors, like "lazy registration shop.example.com"
+func (d Entry) describe() string {
+	name := "registration " + d.Pattern
+	if d.Type == EntryDefault {
+		name = "default app"
+	}
+	if d.Backend != BackendApp {
+		name = string(d.Backend) + " " + name
+	}
+	return name
+}
+
+// assign copies the registration settings of a desired entry onto the live entry
+func (e *entry) assign(d Entry) {
+	switch {
+	case d.state != nil:
+		e.restore(d)
+	case !e.keepsBackend(d):
+		e.app, e.handler, e.factory = d.App, nil, nil
+	}
+	if d.state == nil && !e.redirects(d) {
+		config := e.config
+		config.Redirect = d.Redirect
+		e.setConfig(config)
+	}
+	e.metadata = d.Metadata.clone()
+	e.suspended.Store(d.Suspended)
+	e.source = d.Source
+}
+
+// matches reports whether the live entry already has the registration settings of the desired entry
+func (e *entry) matches(d Entry) bool {
+	backend := e.keepsBackend(d) && e.redirects(d)
+	if d.state != nil {
+		backend = d.state.restored(e, d)
+	}
+	return backend && e.suspended.Load() == d.Suspended && e.source == d.Source && e.metadata.equal(d.Metadata)
+}
+
+// keepsBackend reports whether the live entry keeps its backend for the desired entry: the same app, or no app, like for a lazily built app, whose factory builds it again, or for a plain handler, see AddRequestHandler
+func (e *entry) keepsBackend(d Entry) bool {
+	return d.App == e.app || d.App == nil && (e.factory != nil || e.handler != nil)
+}
+
+// redirects reports whether the live entry redirects like a desired entry. Desired entries without a redirect keep the redirect of the HostConfig, which is set with SetHostConfig.
+func (e *entry) redirects(d Entry) bool {
+	return d.Redirect == nil || e.config.Redirect != nil && *e.config.Redirect == *d.Redirect
+}
+
+// sortByEntry sorts items in listing order: hosts first, then wildcards, then the default app, each group sorted by pattern
+func sortByEntry[T any](items []T, key func(T) (EntryType, string)) {
+	sort.SliceStable(items, func(i, j int) bool {
+		ti, pi := key(items[i])
+		tj, pj := key(items[j])
+		if ti != tj {
+			return entryTypeOrder(ti) < entryTypeOrder(tj)
+		}
+		return pi < pj
+	})
+}
+
+// entryTypeOrder returns the listing position of an entry type
+func entryTypeOrder(t EntryType) int {
+	switch t {
+	case EntryHost:
+		return 0
+	case EntryWildcard:
+		return 1
+	default:
+		return 2
+	}
+}
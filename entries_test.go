@@ -0,0 +1,109 @@
+package fibervhosts
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test ListEntries includes hosts, wildcards and the default app.
+func TestVhostsManager_ListEntries(t *testing.T) {
+	defaultApp := fiber.New()
+	api := fiber.New(fiber.Config{AppName: "api"})
+	manager := NewVhostsManager(Config{DefaultApp: defaultApp})
+	assert.NoError(t, manager.AddHostname("b.example.com", api))
+	assert.NoError(t, manager.AddHostname("a.example.com", api))
+	assert.NoError(t, manager.AddHostname("*.example.org", api))
+
+	entries := manager.ListEntries()
+	assert.Equal(t, []Entry{
+		{Type: EntryHost, Pattern: "a.example.com", App: api, AppName: "api"},
+		{Type: EntryHost, Pattern: "b.example.com", App: api, AppName: "api"},
+		{Type: EntryWildcard, Pattern: "*.example.org", App: api, AppName: "api"},
+		{Type: EntryDefault, App: defaultApp, AppName: "default"},
+	}, entries)
+}
+
+// Test Range visits every registration and stops early when asked.
+func TestVhostsManager_Range(t *testing.T) {
+	app := fiber.New()
+	manager := NewVhostsManager(Config{DefaultApp: app})
+	assert.NoError(t, manager.AddHostname("a.example.com", app))
+	assert.NoError(t, manager.AddHostname("b.example.com", app))
+	assert.NoError(t, manager.AddHostname("*.example.org", app))
+
+	var patterns []string
+	types := map[EntryType]int{}
+	manager.Range(func(e Entry) bool {
+		patterns = append(patterns, e.Pattern)
+		types[e.Type]++
+		return true
+	})
+	assert.ElementsMatch(t, []string{"a.example.com", "b.example.com", "*.example.org", ""}, patterns)
+	assert.Equal(t, map[EntryType]int{EntryHost: 2, EntryWildcard: 1, EntryDefault: 1}, types)
+
+	visited := 0
+	manager.Range(func(e Entry) bool {
+		visited++
+		return false
+	})
+	assert.Equal(t, 1, visited)
+}
+
+// Test ApplyEntries only manages registrations of its own source.
+func TestVhostsManager_ApplyEntries(t *testing.T) {
+	app := fiber.New()
+	other := fiber.New()
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("manual.com", app))
+
+	err := manager.ApplyEntries("sync", []Entry{
+		{Pattern: "a.com", App: app},
+		{Pattern: "*.b.com", App: app},
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"manual.com", "a.com"}, manager.GetHostnames())
+	assert.Len(t, manager.FilterEntries(func(e Entry) bool { return e.Source == "sync" }), 2)
+
+	// Converge: update a.com, drop *.b.com, keep manual.com
+	err = manager.ApplyEntries("sync", []Entry{{Pattern: "a.com", App: other}})
+	assert.NoError(t, err)
+	retrieved, _ := manager.GetHostname("a.com")
+	assert.Equal(t, other, retrieved)
+	assert.Len(t, manager.ListEntries(), 2)
+
+	// Entries owned by someone else are never taken over
+	err = manager.ApplyEntries("sync", []Entry{{Pattern: "manual.com", App: other}})
+	assert.ErrorIs(t, err, ErrHostExists)
+	retrieved, _ = manager.GetHostname("manual.com")
+	assert.Equal(t, app, retrieved)
+
+	// Unresolved apps are rejected
+	err = manager.ApplyEntries("sync", []Entry{{Pattern: "c.com", AppName: "missing"}})
+	assert.ErrorIs(t, err, ErrAppNotFound)
+}
+
+// Test Match returns the registration serving a hostname.
+func TestVhostsManager_Match(t *testing.T) {
+	app := fiber.New()
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostname("*.example.com", app))
+
+	e, ok := manager.Match("www.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, EntryHost, e.Type)
+
+	e, ok = manager.Match("shop.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "*.example.com", e.Pattern)
+
+	_, ok = manager.Match("example.org")
+	assert.False(t, ok)
+
+	manager.SetDefaultApp(app)
+	e, ok = manager.Match("example.org")
+	assert.True(t, ok)
+	assert.Equal(t, EntryDefault, e.Type)
+}
@@ -0,0 +1,70 @@
+// This file contains bootstrapping of the manager from environment variables, for container deployments where mounting config files is inconvenient.
+package fibervhosts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// EnvVhosts is the environment variable holding the vhost table, e.g. "app1=example.com,api.example.com;app2=*.example.org"
+	EnvVhosts = "VHOSTS"
+	// EnvVhostsDefault is the environment variable holding the name of the default app
+	EnvVhostsDefault = "VHOSTS_DEFAULT"
+)
+
+// ErrInvalidEnv is returned when the VHOSTS environment variable can't be parsed
+var ErrInvalidEnv = errors.New("invalid VHOSTS environment variable")
+
+// NewVhostsManagerFromEnv creates a new VhostsManager populated from the VHOSTS and VHOSTS_DEFAULT environment variables. VHOSTS holds semicolon separated groups of an app name and a comma separated list of hostnames or wildcard patterns, e.g. "app1=example.com,api.example.com;app2=*.example.org". VHOSTS_DEFAULT optionally names the default app. App names are mapped to apps by the resolver.
+func NewVhostsManagerFromEnv(resolver AppResolver, config ...Config) (*VhostsManager, error) {
+	t, err := parseVhostsEnv(os.Getenv(EnvVhosts))
+	if err != nil {
+		return nil, err
+	}
+	t.Default = strings.TrimSpace(os.Getenv(EnvVhostsDefault))
+
+	m := NewVhostsManager(config...)
+	if t.Default == "" && m.defaultApp != nil {
+		// Keep the default app from the config
+		t.Default = m.defaultApp.toEntry().AppName
+		resolver = withFallback(resolver, t.Default, m.defaultApp.app)
+	}
+	if err := m.applyTable(t, resolver); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseVhostsEnv parses the value of the VHOSTS environment variable
+func parseVhostsEnv(value string) (tableFile, error) {
+	var t tableFile
+	for _, group := range strings.Split(value, ";") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+
+		name, hostnames, ok := strings.Cut(group, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return t, fmt.Errorf("%w: %q", ErrInvalidEnv, group)
+		}
+
+		for _, hostname := range strings.Split(hostnames, ",") {
+			hostname = strings.TrimSpace(hostname)
+			if hostname == "" {
+				continue
+			}
+			te := EntrySpec{Hostname: hostname, App: name}
+			if isWildcard(hostname) {
+				t.Wildcards = append(t.Wildcards, te)
+			} else {
+				t.Hosts = append(t.Hosts, te)
+			}
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,51 @@
+package fibervhosts
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test bootstrapping the manager from environment variables.
+func TestNewVhostsManagerFromEnv(t *testing.T) {
+	app1 := fiber.New()
+	app2 := fiber.New()
+	app0 := fiber.New()
+	resolver := MapResolver(map[string]*fiber.App{"app0": app0, "app1": app1, "app2": app2})
+
+	t.Setenv(EnvVhosts, "app1=example.com, api.example.com; app2=*.example.org;")
+	t.Setenv(EnvVhostsDefault, "app0")
+
+	manager, err := NewVhostsManagerFromEnv(resolver, Config{EnableLogging: true})
+	assert.NoError(t, err)
+	assert.True(t, manager.enableLog)
+	assert.ElementsMatch(t, []string{"example.com", "api.example.com"}, manager.GetHostnames())
+	assert.Equal(t, app2, manager.wildcards["example.org"].app)
+	assert.Equal(t, app0, manager.defaultApp.app)
+}
+
+// Test the default app from the config is kept when VHOSTS_DEFAULT is unset.
+func TestNewVhostsManagerFromEnv_ConfigDefault(t *testing.T) {
+	app1 := fiber.New()
+	fallback := fiber.New()
+	t.Setenv(EnvVhosts, "app1=example.com")
+	t.Setenv(EnvVhostsDefault, "")
+
+	manager, err := NewVhostsManagerFromEnv(MapResolver(map[string]*fiber.App{"app1": app1}), Config{DefaultApp: fallback})
+	assert.NoError(t, err)
+	assert.Equal(t, fallback, manager.defaultApp.app)
+}
+
+// Test malformed values and unknown apps are rejected.
+func TestNewVhostsManagerFromEnv_Errors(t *testing.T) {
+	resolver := MapResolver(map[string]*fiber.App{"app1": fiber.New()})
+
+	t.Setenv(EnvVhosts, "example.com")
+	_, err := NewVhostsManagerFromEnv(resolver)
+	assert.ErrorIs(t, err, ErrInvalidEnv)
+
+	t.Setenv(EnvVhosts, "app9=example.com")
+	_, err = NewVhostsManagerFromEnv(resolver)
+	assert.ErrorIs(t, err, ErrAppNotFound)
+}
@@ -0,0 +1,75 @@
+// This file contains the per-vhost error pages, which replace the error responses of a hostname with pages rendered from its own templates, like branded pages for white-label customers.
+package fibervhosts
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// ErrorPages renders the error responses of a hostname from templates. Create it with NewErrorPages.
+type ErrorPages struct {
+	templates map[int]*template.Template
+}
+
+// ErrorPageData is the data the error page templates are rendered with
+type ErrorPageData struct {
+	Status    int
+	Message   string
+	Hostname  string
+	Path      string
+	RequestID string
+}
+
+// NewErrorPages parses html/templates for the error pages of a hostname, rendered with ErrorPageData. templates maps response statuses like 404 or 500 to their template; the template of status 0 renders every other status of 400 and above. It returns the parse error of the first invalid template.
+func NewErrorPages(templates map[int]string) (*ErrorPages, error) {
+	p := &ErrorPages{templates: make(map[int]*template.Template, len(templates))}
+	for status, text := range templates {
+		tmpl, err := template.New(strconv.Itoa(status)).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("error page %d: %w", status, err)
+		}
+		p.templates[status] = tmpl
+	}
+	return p, nil
+}
+
+// template returns the template of a response status, or nil if it is not replaced
+func (p *ErrorPages) template(status int) *template.Template {
+	if tmpl, exists := p.templates[status]; exists {
+		return tmpl
+	}
+	if status >= fiber.StatusBadRequest {
+		return p.templates[0]
+	}
+	return nil
+}
+
+// render replaces the response of a dispatched request with the error page of its status. err is the error of dispatching; it is returned as it is when the status has no page or rendering fails, and nil once the page replaced the response.
+func (p *ErrorPages) render(c *fiber.Ctx, err error, logger Logger) error {
+	status := responseStatus(c, err)
+	tmpl := p.template(status)
+	if tmpl == nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	data := ErrorPageData{
+		Status:   status,
+		Message:  utils.StatusMessage(status),
+		Hostname: strings.Clone(c.Hostname()),
+		Path:     string(c.Request().URI().Path()),
+	}
+	data.RequestID, _ = c.Locals(LocalsRequestIDKey).(string)
+	if renderErr := tmpl.Execute(&body, data); renderErr != nil {
+		logger.Error("Rendering error page failed", "hostname", data.Hostname, "status", status, "error", renderErr)
+		return err
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(status).Send(body.Bytes())
+}
@@ -0,0 +1,61 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the error responses of a sub-app are replaced by the error pages of its registration.
+func TestVhostMiddleware_ErrorPages(t *testing.T) {
+	_, err := NewErrorPages(map[int]string{404: "{{.Status"})
+	assert.Error(t, err)
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("home")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusInternalServerError).SendString("stack trace")
+	})
+	app.Get("/teapot", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusTeapot)
+	})
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		panic("boom")
+	})
+
+	pages, err := NewErrorPages(map[int]string{
+		404: `<h1>Acme: {{.Path}} not found on {{.Hostname}}</h1>`,
+		0:   `<h1>Acme: {{.Status}} {{.Message}}</h1>`,
+	})
+	assert.NoError(t, err)
+	manager := NewVhostsManager(Config{RecoverFromPanic: true, Logger: &recordingLogger{}})
+	assert.NoError(t, manager.AddHostnameWithConfig("acme.example.com", app, HostConfig{ErrorPages: pages}))
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	for _, tc := range []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"http://acme.example.com/", fiber.StatusOK, "home"},
+		{"http://acme.example.com/missing", fiber.StatusNotFound, "<h1>Acme: /missing not found on acme.example.com</h1>"},
+		{"http://acme.example.com/fail", fiber.StatusInternalServerError, "<h1>Acme: 500 Internal Server Error</h1>"},
+		{"http://acme.example.com/teapot", fiber.StatusTeapot, "<h1>Acme: 418 I&#39;m a teapot</h1>"},
+		{"http://acme.example.com/panic", fiber.StatusInternalServerError, "<h1>Acme: 500 Internal Server Error</h1>"},
+		{"http://www.example.com/fail", fiber.StatusInternalServerError, "stack trace"},
+	} {
+		resp, err := main.Test(httptest.NewRequest("GET", tc.target, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.target)
+		assert.Equal(t, tc.body, string(body), tc.target)
+	}
+}
@@ -0,0 +1,115 @@
+// This file contains the rolling error rate of the registrations, which fires the OnErrorRate hooks when the share of server errors of a hostname crosses its threshold.
+package fibervhosts
+
+import (
+	"sync"
+	"time"
+)
+
+// ErrorRatePolicy defines when the error rate of a hostname is considered too high. Set it on Config for all hostnames, or on HostConfig to override it for a single registration.
+type ErrorRatePolicy struct {
+	// Threshold is the share of server errors (5xx) between 0 and 1 at which the OnErrorRate hooks fire, like 0.05 for 5%. Required.
+	Threshold float64
+	// Window is the rolling window the rate is computed over. Defaults to 1 minute.
+	Window time.Duration
+	// MinRequests is the number of requests within the window below which the rate is not evaluated, so a single failure of a quiet hostname does not trip it. Defaults to 20.
+	MinRequests int
+}
+
+// Defaults of ErrorRatePolicy
+const (
+	defaultErrorRateWindow      = time.Minute
+	defaultErrorRateMinRequests = 20
+)
+
+// errorRateBuckets is the number of buckets the rolling window is split into
+const errorRateBuckets = 10
+
+// errorWindow counts the requests and errors of an entry over a rolling window of buckets
+type errorWindow struct {
+	mu       sync.Mutex
+	buckets  [errorRateBuckets]errorBucket
+	exceeded bool
+}
+
+// errorBucket counts the requests of one slot of the window
+type errorBucket struct {
+	slot     int64
+	requests int
+	errors   int
+}
+
+// record counts a request at now and returns the error rate and number of requests within the window
+func (w *errorWindow) record(now time.Time, window time.Duration, failed bool) (float64, int) {
+	width := int64(window / errorRateBuckets)
+	if width <= 0 {
+		width = 1
+	}
+	slot := now.UnixNano() / width
+
+	b := &w.buckets[slot%errorRateBuckets]
+	if b.slot != slot {
+		*b = errorBucket{slot: slot}
+	}
+	b.requests++
+	if failed {
+		b.errors++
+	}
+
+	var requests, errors int
+	for _, b := range w.buckets {
+		if b.slot > slot-errorRateBuckets {
+			requests += b.requests
+			errors += b.errors
+		}
+	}
+	return float64(errors) / float64(requests), requests
+}
+
+// errorRatePolicy returns the error rate policy of r, or nil when its error rate is not tracked
+func (m *VhostsManager) errorRatePolicy(r *route) *ErrorRatePolicy {
+	if r.config.ErrorRate != nil {
+		return r.config.ErrorRate
+	}
+	return m.errorRate
+}
+
+// trackErrorRate records the response status of a request dispatched to r, and fires the OnErrorRate or OnErrorRateRecovered hooks when the error rate crosses the threshold
+func (m *VhostsManager) trackErrorRate(r *route, status int) {
+	policy := m.errorRatePolicy(r)
+	if policy == nil || policy.Threshold <= 0 {
+		return
+	}
+	window := policy.Window
+	if window <= 0 {
+		window = defaultErrorRateWindow
+	}
+	minRequests := policy.MinRequests
+	if minRequests <= 0 {
+		minRequests = defaultErrorRateMinRequests
+	}
+
+	w := &r.entry.errorRate
+	w.mu.Lock()
+	rate, requests := w.record(time.Now(), window, status >= 500)
+	crossed := false
+	switch {
+	case requests < minRequests:
+	case !w.exceeded && rate >= policy.Threshold:
+		w.exceeded, crossed = true, true
+	case w.exceeded && rate < policy.Threshold:
+		w.exceeded, crossed = false, true
+	}
+	exceeded := w.exceeded
+	w.mu.Unlock()
+
+	if !crossed {
+		return
+	}
+	current := r.toEntry()
+	if exceeded {
+		m.hooks.executeOnErrorRate(current, rate)
+	} else {
+		m.hooks.executeOnErrorRateRecovered(current, rate)
+	}
+}
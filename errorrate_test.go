@@ -0,0 +1,78 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the error rate hooks fire once when the threshold is crossed and again on recovery.
+func TestVhostMiddleware_ErrorRate(t *testing.T) {
+	failing := true
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		if failing {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	manager := NewVhostsManager(Config{ErrorRate: &ErrorRatePolicy{Threshold: 0.5, MinRequests: 4}})
+	assert.NoError(t, manager.AddHostname("api.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("tolerant.example.com", app, HostConfig{ErrorRate: &ErrorRatePolicy{Threshold: 1.1}}))
+
+	var exceeded, recovered []string
+	manager.Hooks().OnErrorRate(func(e Entry, rate float64) {
+		exceeded = append(exceeded, e.Pattern)
+		assert.GreaterOrEqual(t, rate, 0.5)
+		// Automatic suspension of the misbehaving app
+		assert.NoError(t, manager.SuspendHostname(e.Pattern))
+	})
+	manager.Hooks().OnErrorRateRecovered(func(e Entry, rate float64) {
+		recovered = append(recovered, e.Pattern)
+	})
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(host string) {
+		_, err := main.Test(httptest.NewRequest("GET", "http://"+host+"/", nil))
+		assert.NoError(t, err)
+	}
+
+	// Below MinRequests the rate is not evaluated
+	for range 3 {
+		request("api.example.com")
+		request("tolerant.example.com")
+	}
+	assert.Empty(t, exceeded)
+
+	request("api.example.com")
+	request("tolerant.example.com")
+	assert.Equal(t, []string{"api.example.com"}, exceeded)
+	assert.True(t, manager.IsSuspended("api.example.com"))
+
+	assert.NoError(t, manager.ResumeHostname("api.example.com"))
+	failing = false
+	for range 5 {
+		request("api.example.com")
+	}
+	assert.Equal(t, []string{"api.example.com"}, recovered)
+	assert.Len(t, exceeded, 1)
+}
+
+// Test requests older than the window are no longer counted.
+func TestErrorWindow_Record(t *testing.T) {
+	var w errorWindow
+	now := time.Now()
+	w.record(now, time.Second, true)
+	rate, requests := w.record(now.Add(500*time.Millisecond), time.Second, false)
+	assert.Equal(t, 0.5, rate)
+	assert.Equal(t, 2, requests)
+
+	rate, requests = w.record(now.Add(1200*time.Millisecond), time.Second, false)
+	assert.Equal(t, 0.0, rate)
+	assert.Equal(t, 2, requests)
+}
@@ -0,0 +1,87 @@
+// This file contains the JSON export and import of the vhost table so the routing table can be persisted and restored across restarts.
+package fibervhosts
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultAppName is the name used for the default app when it has no AppName configured
+const defaultAppName = "default"
+
+// tableFile is the serialized form of the vhost table
+type tableFile struct {
+	Hosts     []EntrySpec `json:"hosts,omitempty" yaml:"hosts,omitempty" toml:"hosts,omitempty"`
+	Wildcards []EntrySpec `json:"wildcards,omitempty" yaml:"wildcards,omitempty" toml:"wildcards,omitempty"`
+	Default   string      `json:"default,omitempty" yaml:"default,omitempty" toml:"default,omitempty"`
+}
+
+// appName returns the name identifying an app in an exported table. The fiber AppName is used when set, otherwise the fallback is returned.
+func appName(app *fiber.App, fallback string) string {
+	if app != nil {
+		if name := app.Config().AppName; name != "" {
+			return name
+		}
+	}
+	return fallback
+}
+
+// ExportJSON writes the full routing table (hosts, wildcards, default app, suspension and metadata) as JSON to w. Apps are identified by their fiber AppName, falling back to the hostname (or "default" for the default app) when no AppName is configured. Lazily built apps and plain handlers are exported by that name with their backend kind, redirects with their target.
+func (m *VhostsManager) ExportJSON(w io.Writer) error {
+	var t tableFile
+	for _, e := range m.ListEntries() {
+		switch e.Type {
+		case EntryHost:
+			t.Hosts = append(t.Hosts, newEntrySpec(e))
+		case EntryWildcard:
+			t.Wildcards = append(t.Wildcards, newEntrySpec(e))
+		case EntryDefault:
+			t.Default = e.AppName
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// ImportJSON reads a routing table previously written by ExportJSON from r and replaces the current table with it. Registrations that exist in both keep their state like stats. The appFactory is called once per distinct app name to obtain the app instance, including for lazily built apps and plain handlers, which are imported as sub-apps; returning nil aborts the import with ErrAppNotFound naming the registration and leaves the current table untouched. Redirects are imported as they are.
+func (m *VhostsManager) ImportJSON(r io.Reader, appFactory AppResolver) error {
+	var t tableFile
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return err
+	}
+	return m.applyTable(t, appFactory)
+}
+
+// applyTable resolves the serialized table and makes it the current table
+func (m *VhostsManager) applyTable(t tableFile, resolve AppResolver) error {
+	entries := t.entries(resolve)
+	if err := validateEntries(entries); err != nil {
+		return err
+	}
+
+	return m.update(func() ([]ChangeEvent, error) {
+		return m.converge(entries, nil), nil
+	})
+}
+
+// entries resolves the serialized table into registrations, see ResolveSpecs
+func (t tableFile) entries(resolve AppResolver) []Entry {
+	specs := append(append([]EntrySpec(nil), t.Hosts...), t.Wildcards...)
+	hostnames := len(specs)
+	if t.Default != "" {
+		specs = append(specs, EntrySpec{App: t.Default})
+	}
+
+	entries := ResolveSpecs(specs, resolve)
+	for i := range entries[:hostnames] {
+		// A listed hostname without a name is invalid rather than the default app
+		if entries[i].Type == EntryDefault {
+			entries[i].Type = EntryHost
+		}
+	}
+	return entries
+}
@@ -0,0 +1,100 @@
+package fibervhosts
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// Test exporting and re-importing the full routing table.
+func TestVhostsManager_ExportImportJSON(t *testing.T) {
+	api := fiber.New(fiber.Config{AppName: "api"})
+	web := fiber.New(fiber.Config{AppName: "web"})
+	fallback := fiber.New(fiber.Config{AppName: "fallback"})
+
+	manager := NewVhostsManager(Config{DefaultApp: fallback})
+	assert.NoError(t, manager.AddHostname("api.example.com", api))
+	assert.NoError(t, manager.AddHostname("www.example.com", web))
+	assert.NoError(t, manager.AddHostname("*.example.org", web))
+
+	var buf bytes.Buffer
+	assert.NoError(t, manager.ExportJSON(&buf))
+	assert.Contains(t, buf.String(), `"hostname": "*.example.org"`)
+	assert.Contains(t, buf.String(), `"default": "fallback"`)
+
+	apps := map[string]*fiber.App{"api": api, "web": web, "fallback": fallback}
+	restored := NewVhostsManager()
+	err := restored.ImportJSON(&buf, func(name string) *fiber.App {
+		return apps[name]
+	})
+	assert.NoError(t, err)
+
+	app, exists := restored.GetHostname("api.example.com")
+	assert.True(t, exists)
+	assert.Equal(t, api, app)
+	assert.ElementsMatch(t, []string{"api.example.com", "www.example.com"}, restored.GetHostnames())
+	assert.Equal(t, web, restored.wildcards["example.org"].app)
+	assert.Equal(t, fallback, restored.defaultApp.app)
+}
+
+// Test that an unknown app name aborts the import without touching the table.
+func TestVhostsManager_ImportJSON_UnknownApp(t *testing.T) {
+	manager := NewVhostsManager()
+	app := fiber.New()
+	assert.NoError(t, manager.AddHostname("keep.example.com", app))
+
+	input := `{"hosts":[{"hostname":"example.com","app":"missing"}]}`
+	err := manager.ImportJSON(strings.NewReader(input), func(name string) *fiber.App {
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrAppNotFound)
+
+	_, exists := manager.GetHostname("keep.example.com")
+	assert.True(t, exists)
+}
+
+// Test that a listed hostname without a name is rejected.
+func TestVhostsManager_ImportJSON_EmptyHostname(t *testing.T) {
+	manager := NewVhostsManager()
+	input := `{"hosts":[{"hostname":"","app":"app"}]}`
+	err := manager.ImportJSON(strings.NewReader(input), func(name string) *fiber.App {
+		return fiber.New()
+	})
+	assert.Equal(t, ErrInvalidHostname, err)
+}
+
+// Test lazily built apps, plain handlers and redirects are exported with their backend kind and imported again.
+func TestVhostsManager_ExportImportJSON_Backends(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameFactory("lazy.example.com", func() (*fiber.App, error) { return fiber.New(), nil }))
+	assert.NoError(t, manager.AddRequestHandler("fast.example.com", func(ctx *fasthttp.RequestCtx) {}))
+	assert.NoError(t, manager.AddRedirect("www.example.com", "https://example.com", fiber.StatusFound))
+
+	var buf bytes.Buffer
+	assert.NoError(t, manager.ExportJSON(&buf))
+	assert.Contains(t, buf.String(), `"backend": "lazy"`)
+	assert.Contains(t, buf.String(), `"backend": "handler"`)
+	assert.Contains(t, buf.String(), `"target": "https://example.com"`)
+	exported := buf.String()
+
+	// Plain handlers cannot be imported without an app of their name
+	restored := NewVhostsManager()
+	lazy := fiber.New()
+	err := restored.ImportJSON(strings.NewReader(exported), MapResolver(map[string]*fiber.App{"lazy.example.com": lazy}))
+	assert.ErrorIs(t, err, ErrAppNotFound)
+	assert.ErrorContains(t, err, "handler registration fast.example.com")
+	assert.Empty(t, restored.ListEntries())
+
+	fast := fiber.New()
+	assert.NoError(t, restored.ImportJSON(strings.NewReader(exported), MapResolver(map[string]*fiber.App{"lazy.example.com": lazy, "fast.example.com": fast})))
+	app, _ := restored.GetHostname("lazy.example.com")
+	assert.Equal(t, lazy, app)
+	app, _ = restored.GetHostname("fast.example.com")
+	assert.Equal(t, fast, app)
+	config, _ := restored.GetHostConfig("www.example.com")
+	assert.Equal(t, &Redirect{Target: "https://example.com", Status: fiber.StatusFound}, config.Redirect)
+}
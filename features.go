@@ -0,0 +1,80 @@
+// This file contains the per-vhost feature flags, which turn tenant-specific features of a shared sub-app on and off at runtime without redeploying it.
+package fibervhosts
+
+import (
+	"maps"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FeatureFlags maps feature names to whether they are enabled
+type FeatureFlags map[string]bool
+
+// Enabled reports whether flag is set and enabled
+func (f FeatureFlags) Enabled(flag string) bool {
+	return f[flag]
+}
+
+// Features returns the feature flags of the registration matched by a request, see SetFeatures. The flags must not be modified.
+func Features(c *fiber.Ctx) FeatureFlags {
+	flags, _ := c.Locals(LocalsFeaturesKey).(FeatureFlags)
+	return flags
+}
+
+// FeatureEnabled reports whether flag is enabled for the registration matched by a request
+func FeatureEnabled(c *fiber.Ctx, flag string) bool {
+	return Features(c).Enabled(flag)
+}
+
+// SetFeatures replaces the feature flags of a registered hostname or wildcard pattern. Flags it does not set keep the value of Config.Features. Requests see the new flags as soon as it returns.
+func (m *VhostsManager) SetFeatures(hostname string, flags FeatureFlags) error {
+	return m.updateFeatures(hostname, flags, false)
+}
+
+// SetFeature turns a single feature flag of a registered hostname or wildcard pattern on or off
+func (m *VhostsManager) SetFeature(hostname, flag string, enabled bool) error {
+	return m.updateFeatures(hostname, FeatureFlags{flag: enabled}, true)
+}
+
+// GetFeatures returns the feature flags of a registered hostname or wildcard pattern, including those of Config.Features
+func (m *VhostsManager) GetFeatures(hostname string) (FeatureFlags, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists {
+		return nil, false
+	}
+	return maps.Clone(m.featuresOf(e)), true
+}
+
+// featuresOf returns the feature flags of an entry
+func (m *VhostsManager) featuresOf(e *entry) FeatureFlags {
+	if flags := e.features.Load(); flags != nil {
+		return *flags
+	}
+	return m.features
+}
+
+// updateFeatures sets flags on top of Config.Features or, with keep, on top of the current flags of a registration. The flags of an entry are replaced, never modified, as requests read them without the lock.
+func (m *VhostsManager) updateFeatures(hostname string, flags FeatureFlags, keep bool) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+		base := m.features
+		if keep {
+			base = m.featuresOf(e)
+		}
+		next := make(FeatureFlags, len(base)+len(flags))
+		maps.Copy(next, base)
+		maps.Copy(next, flags)
+
+		previous := e.toEntry()
+		e.features.Store(&next)
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
@@ -0,0 +1,53 @@
+package fibervhosts
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test feature flags default to those of the manager and are toggled per hostname at runtime.
+func TestVhostsManager_Features(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(fmt.Sprint(FeatureEnabled(c, "checkout"), " ", FeatureEnabled(c, "beta"), " ", len(Features(c))))
+	})
+
+	manager := NewVhostsManager(Config{Features: FeatureFlags{"checkout": true}})
+	assert.NoError(t, manager.AddHostname("acme.example.com", app))
+	assert.NoError(t, manager.AddHostname("*.example.org", app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(target string) string {
+		resp, err := main.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.Equal(t, "true false 1", request("http://acme.example.com/"))
+	assert.NoError(t, manager.SetFeature("acme.example.com", "beta", true))
+	assert.Equal(t, "true true 2", request("http://acme.example.com/"))
+	assert.NoError(t, manager.SetFeature("acme.example.com", "checkout", false))
+	assert.Equal(t, "false true 2", request("http://acme.example.com/"))
+	assert.Equal(t, "true false 1", request("http://shop.example.org/"))
+
+	// Replacing the flags starts over from the flags of the manager
+	assert.NoError(t, manager.SetFeatures("*.example.org", FeatureFlags{"beta": true}))
+	assert.Equal(t, "true true 2", request("http://shop.example.org/"))
+	assert.NoError(t, manager.SetFeatures("acme.example.com", nil))
+	flags, ok := manager.GetFeatures("acme.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, FeatureFlags{"checkout": true}, flags)
+	flags["checkout"] = false
+	assert.Equal(t, "true false 1", request("http://acme.example.com/"))
+
+	assert.ErrorIs(t, manager.SetFeature("missing.example.com", "beta", true), ErrHostNotFound)
+	_, ok = manager.GetFeatures("missing.example.com")
+	assert.False(t, ok)
+}
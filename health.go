@@ -0,0 +1,186 @@
+// This file contains the aggregated health check, which checks every registration through its health function or a health path of its sub-app and reports the results as one JSON document.
+package fibervhosts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// HealthCheck reports whether the sub-app of a registration is healthy, see HostConfig.HealthCheck
+type HealthCheck func(ctx context.Context) error
+
+// Health statuses of a registration
+const (
+	HealthOK        = "ok"
+	HealthFailing   = "failing"
+	HealthSuspended = "suspended"
+	HealthPending   = "pending"
+	HealthUnchecked = "unchecked"
+)
+
+// HealthConfig defines the config for CheckHealth and HealthHandler
+type HealthConfig struct {
+	// Path is requested on the sub-apps of registrations without a HealthCheck. A response below 400 means healthy. Defaults to "", which leaves those registrations unchecked, like lazily built apps that were not requested yet.
+	Path string
+	// Timeout bounds every single check. Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// defaultHealthTimeout is the default timeout of a single health check
+const defaultHealthTimeout = 5 * time.Second
+
+// HostHealth is the health of a single registration
+type HostHealth struct {
+	Type    EntryType     `json:"type"`
+	Pattern string        `json:"pattern,omitempty"`
+	Status  string        `json:"status"`
+	Latency time.Duration `json:"latency_ns"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// HealthReport is the health of all registrations. Healthy is false as soon as one registration is failing; suspended, pending and unchecked ones do not count.
+type HealthReport struct {
+	Healthy bool         `json:"healthy"`
+	Hosts   []HostHealth `json:"hosts"`
+}
+
+// healthTarget is a registration to check, captured under the lock
+type healthTarget struct {
+	kind      EntryType
+	pattern   string
+	backend   backend
+	check     HealthCheck
+	suspended bool
+	pending   bool
+}
+
+// CheckHealth checks every registration concurrently and returns the report, in the same order as ListEntries
+func (m *VhostsManager) CheckHealth(ctx context.Context, config ...HealthConfig) HealthReport {
+	cfg := HealthConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHealthTimeout
+	}
+
+	m.mu.RLock()
+	var targets []healthTarget
+	collect := func(e *entry) {
+		targets = append(targets, healthTarget{e.kind, e.pattern, e.backend(), e.config.HealthCheck, e.suspended.Load(), e.pending.Load()})
+	}
+	for _, e := range m.hosts {
+		collect(e)
+	}
+	for _, e := range m.wildcards {
+		collect(e)
+	}
+	if m.defaultApp != nil {
+		collect(m.defaultApp)
+	}
+	m.mu.RUnlock()
+
+	report := HealthReport{Healthy: true, Hosts: make([]HostHealth, len(targets))}
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Hosts[i] = target.run(ctx, cfg)
+		}()
+	}
+	wg.Wait()
+
+	for _, h := range report.Hosts {
+		if h.Status == HealthFailing {
+			report.Healthy = false
+		}
+	}
+	sortByEntry(report.Hosts, func(h HostHealth) (EntryType, string) { return h.Type, h.Pattern })
+	return report
+}
+
+// HealthHandler returns a handler responding with the HealthReport as JSON, with status 200 when healthy and 503 otherwise, ready for load balancer checks
+func (m *VhostsManager) HealthHandler(config ...HealthConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report := m.CheckHealth(c.UserContext(), config...)
+		status := fiber.StatusOK
+		if !report.Healthy {
+			status = fiber.StatusServiceUnavailable
+		}
+		return c.Status(status).JSON(report)
+	}
+}
+
+// run checks the target
+func (t healthTarget) run(ctx context.Context, config HealthConfig) HostHealth {
+	health := HostHealth{Type: t.kind, Pattern: t.pattern, Status: HealthOK}
+	switch {
+	case t.suspended:
+		health.Status = HealthSuspended
+		return health
+	case t.pending:
+		health.Status = HealthPending
+		return health
+	case t.check == nil && (config.Path == "" || t.backend == nil):
+		health.Status = HealthUnchecked
+		return health
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	if t.check != nil {
+		err = t.check(ctx)
+	} else {
+		err = t.request(ctx, config.Path)
+	}
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Status = HealthFailing
+		health.Error = err.Error()
+	}
+	return health
+}
+
+// request requests the health path from the sub-app of the target, giving up when ctx is done
+func (t healthTarget) request(ctx context.Context, path string) error {
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.Header.SetMethod(fiber.MethodGet)
+	fctx.Request.SetRequestURI(path)
+	// Wildcards are checked with the name of their parent domain
+	host := strings.TrimPrefix(t.pattern, "*.")
+	if host == "" {
+		host = defaultAppName
+	}
+	fctx.Request.Header.SetHost(host)
+
+	done := make(chan struct{})
+	var panicked any
+	go func() {
+		defer close(done)
+		defer func() { panicked = recover() }()
+		t.backend.requestHandler()(fctx)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+	}
+	if panicked != nil {
+		return fmt.Errorf("%s panicked: %v", path, panicked)
+	}
+	if status := fctx.Response.StatusCode(); status >= fiber.StatusBadRequest {
+		return fmt.Errorf("%s responded with status %d", path, status)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package fibervhosts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the health report combines health functions, health paths and suspended registrations.
+func TestVhostsManager_HealthHandler(t *testing.T) {
+	healthy := fiber.New()
+	healthy.Get("/healthz", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	broken := fiber.New()
+	broken.Get("/healthz", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusInternalServerError) })
+	slow := fiber.New()
+	slow.Get("/healthz", func(c *fiber.Ctx) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+
+	manager := NewVhostsManager(Config{DefaultApp: healthy})
+	assert.NoError(t, manager.AddHostname("api.example.com", healthy))
+	assert.NoError(t, manager.AddHostname("*.example.org", broken))
+	assert.NoError(t, manager.AddHostname("slow.example.com", slow))
+	assert.NoError(t, manager.AddHostnameWithConfig("db.example.com", broken, HostConfig{
+		HealthCheck: func(ctx context.Context) error { return errors.New("database down") },
+	}))
+	assert.NoError(t, manager.AddHostname("paused.example.com", broken))
+	assert.NoError(t, manager.SuspendHostname("paused.example.com"))
+
+	report := manager.CheckHealth(context.Background(), HealthConfig{Path: "/healthz", Timeout: 50 * time.Millisecond})
+	assert.False(t, report.Healthy)
+
+	statuses := map[string]string{}
+	for _, h := range report.Hosts {
+		statuses[h.Pattern] = h.Status
+	}
+	assert.Equal(t, map[string]string{
+		"api.example.com":    HealthOK,
+		"db.example.com":     HealthFailing,
+		"paused.example.com": HealthSuspended,
+		"slow.example.com":   HealthFailing,
+		"*.example.org":      HealthFailing,
+		"":                   HealthOK,
+	}, statuses)
+
+	// Without a path only health functions are run
+	app := fiber.New()
+	app.Get("/health", manager.HealthHandler())
+	resp, err := app.Test(httptest.NewRequest("GET", "/health", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	var decoded HealthReport
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	for _, h := range decoded.Hosts {
+		if h.Pattern == "db.example.com" {
+			assert.Equal(t, "database down", h.Error)
+		} else if h.Status != HealthSuspended {
+			assert.Equal(t, HealthUnchecked, h.Status)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// This file contains the hooks system of the manager, used to run callbacks when hostnames are added, updated or removed and when requests are matched or not matched by the middleware.
+package fibervhosts
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handlers define functions to be executed on manager events
+type (
+	OnAddHandler     = func(Entry)
+	OnUpdateHandler  = func(previous, current Entry)
+	OnRemoveHandler  = func(Entry)
+	OnMatchHandler   = func(c *fiber.Ctx, e Entry)
+	OnNoMatchHandler = func(c *fiber.Ctx)
+
+	OnCertExpiringHandler = func(e Entry, notAfter time.Time)
+	OnErrorRateHandler    = func(e Entry, rate float64)
+	OnDrainedHandler      = func(e Entry)
+
+	OnUpstreamHealthHandler = func(e Entry, upstream UpstreamHealth)
+)
+
+// Hooks is a struct to use it with VhostsManager. Table hooks (OnAdd, OnUpdate, OnRemove) are executed after the change has been applied and the manager lock has been released, so they may safely call back into the manager. They are executed one change at a time, in the order the changes were applied; the changes made by a hook are notified once it returns. Request hooks (OnMatch, OnNoMatch) are executed by the middleware before dispatching.
+type Hooks struct {
+	mu sync.RWMutex
+
+	onAdd     []OnAddHandler
+	onUpdate  []OnUpdateHandler
+	onRemove  []OnRemoveHandler
+	onMatch   []OnMatchHandler
+	onNoMatch []OnNoMatchHandler
+
+	onCertExpiring       []OnCertExpiringHandler
+	onErrorRate          []OnErrorRateHandler
+	onErrorRateRecovered []OnErrorRateHandler
+	onDrained            []OnDrainedHandler
+	onUpstreamHealth     []OnUpstreamHealthHandler
+}
+
+// newHooks creates an empty set of hooks
+func newHooks() *Hooks {
+	return &Hooks{}
+}
+
+// Hooks returns the hooks of the manager
+func (m *VhostsManager) Hooks() *Hooks {
+	return m.hooks
+}
+
+// OnAdd is a hook to execute user functions after a registration is added
+func (h *Hooks) OnAdd(handler ...OnAddHandler) {
+	h.mu.Lock()
+	h.onAdd = append(h.onAdd, handler...)
+	h.mu.Unlock()
+}
+
+// OnUpdate is a hook to execute user functions after a registration is changed in place, like an app swap or a metadata update
+func (h *Hooks) OnUpdate(handler ...OnUpdateHandler) {
+	h.mu.Lock()
+	h.onUpdate = append(h.onUpdate, handler...)
+	h.mu.Unlock()
+}
+
+// OnRemove is a hook to execute user functions after a registration is removed, including removals by Clear and expiry
+func (h *Hooks) OnRemove(handler ...OnRemoveHandler) {
+	h.mu.Lock()
+	h.onRemove = append(h.onRemove, handler...)
+	h.mu.Unlock()
+}
+
+// OnMatch is a hook to execute user functions when the middleware matches a request to a registration
+func (h *Hooks) OnMatch(handler ...OnMatchHandler) {
+	h.mu.Lock()
+	h.onMatch = append(h.onMatch, handler...)
+	h.mu.Unlock()
+}
+
+// OnNoMatch is a hook to execute user functions when the middleware finds no registration for a request
+func (h *Hooks) OnNoMatch(handler ...OnNoMatchHandler) {
+	h.mu.Lock()
+	h.onNoMatch = append(h.onNoMatch, handler...)
+	h.mu.Unlock()
+}
+
+// OnCertExpiring is a hook to execute user functions when the certificate of a registration gets within Config.CertExpiryWarning of its expiry. It fires once per certificate, see CheckCertificateExpiry.
+func (h *Hooks) OnCertExpiring(handler ...OnCertExpiringHandler) {
+	h.mu.Lock()
+	h.onCertExpiring = append(h.onCertExpiring, handler...)
+	h.mu.Unlock()
+}
+
+// OnErrorRate is a hook to execute user functions when the error rate of a registration reaches the threshold of its ErrorRatePolicy, like to alert or to suspend a misbehaving app. It fires once per crossing and runs on the goroutine of the request that crossed it.
+func (h *Hooks) OnErrorRate(handler ...OnErrorRateHandler) {
+	h.mu.Lock()
+	h.onErrorRate = append(h.onErrorRate, handler...)
+	h.mu.Unlock()
+}
+
+// OnErrorRateRecovered is a hook to execute user functions when the error rate of a registration drops below the threshold again after OnErrorRate fired
+func (h *Hooks) OnErrorRateRecovered(handler ...OnErrorRateHandler) {
+	h.mu.Lock()
+	h.onErrorRateRecovered = append(h.onErrorRateRecovered, handler...)
+	h.mu.Unlock()
+}
+
+// OnDrained is a hook to execute user functions once DrainHostname finished draining a registration
+func (h *Hooks) OnDrained(handler ...OnDrainedHandler) {
+	h.mu.Lock()
+	h.onDrained = append(h.onDrained, handler...)
+	h.mu.Unlock()
+}
+
+// OnUpstreamHealth is a hook to execute user functions when an upstream of a proxy host goes out of or back into rotation, see CheckUpstreams
+func (h *Hooks) OnUpstreamHealth(handler ...OnUpstreamHealthHandler) {
+	h.mu.Lock()
+	h.onUpstreamHealth = append(h.onUpstreamHealth, handler...)
+	h.mu.Unlock()
+}
+
+// added returns the event for a newly registered entry
+func added(e *entry) ChangeEvent {
+	return ChangeEvent{Type: ChangeAdded, Entry: e.toEntry()}
+}
+
+// updated returns the event for an entry modified in place
+func updated(previous Entry, e *entry) ChangeEvent {
+	return ChangeEvent{Type: ChangeUpdated, Entry: e.toEntry(), Previous: previous}
+}
+
+// removed returns the event for an entry removed from the table
+func removed(e *entry) ChangeEvent {
+	return ChangeEvent{Type: ChangeRemoved, Entry: e.toEntry(), entry: e}
+}
+
+// notifyPending notifies the queued changes in the order they were applied. One goroutine notifies at a time: changes queued meanwhile, by concurrent updates or by hooks changing the manager, are notified by it once the current ones are, so they may be notified after their update returned.
+func (m *VhostsManager) notifyPending() {
+	m.notifyMu.Lock()
+	if m.notifying {
+		m.notifyMu.Unlock()
+		return
+	}
+	m.notifying = true
+	done := false
+	// A panicking hook leaves the rest of the queue to the next update
+	defer func() {
+		if !done {
+			m.notifyMu.Lock()
+			m.notifying = false
+			m.notifyMu.Unlock()
+		}
+	}()
+
+	for len(m.pending) > 0 {
+		changes := m.pending[0]
+		m.pending = m.pending[1:]
+		m.notifyMu.Unlock()
+		m.notify(changes)
+		m.notifyMu.Lock()
+	}
+	m.pending = nil
+	m.notifying = false
+	done = true
+	m.notifyMu.Unlock()
+}
+
+// notify executes the table hooks for the given events and publishes them to the watchers
+func (m *VhostsManager) notify(events []ChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	h := m.hooks
+	h.mu.RLock()
+	onAdd, onUpdate, onRemove := h.onAdd, h.onUpdate, h.onRemove
+	h.mu.RUnlock()
+
+	for _, ev := range events {
+		switch ev.Type {
+		case ChangeAdded:
+			for _, fn := range onAdd {
+				fn(ev.Entry)
+			}
+		case ChangeUpdated:
+			for _, fn := range onUpdate {
+				fn(ev.Previous, ev.Entry)
+			}
+		case ChangeRemoved:
+			for _, fn := range onRemove {
+				fn(ev.Entry)
+			}
+		}
+	}
+
+	m.stopRemoved(events)
+	m.publish(events)
+}
+
+// executeOnMatch executes the OnMatch hooks
+func (h *Hooks) executeOnMatch(c *fiber.Ctx, r *route) {
+	h.mu.RLock()
+	handlers := h.onMatch
+	h.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		return
+	}
+	matched := r.toEntry()
+	for _, fn := range handlers {
+		fn(c, matched)
+	}
+}
+
+// executeOnCertExpiring executes the OnCertExpiring hooks
+func (h *Hooks) executeOnCertExpiring(e Entry, notAfter time.Time) {
+	h.mu.RLock()
+	handlers := h.onCertExpiring
+	h.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(e, notAfter)
+	}
+}
+
+// executeOnErrorRate executes the OnErrorRate hooks
+func (h *Hooks) executeOnErrorRate(e Entry, rate float64) {
+	h.mu.RLock()
+	handlers := h.onErrorRate
+	h.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(e, rate)
+	}
+}
+
+// executeOnErrorRateRecovered executes the OnErrorRateRecovered hooks
+func (h *Hooks) executeOnErrorRateRecovered(e Entry, rate float64) {
+	h.mu.RLock()
+	handlers := h.onErrorRateRecovered
+	h.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(e, rate)
+	}
+}
+
+// executeOnDrained executes the OnDrained hooks
+func (h *Hooks) executeOnDrained(e Entry) {
+	h.mu.RLock()
+	handlers := h.onDrained
+	h.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
+
+// executeOnUpstreamHealth executes the OnUpstreamHealth hooks
+func (h *Hooks) executeOnUpstreamHealth(e Entry, upstream UpstreamHealth) {
+	h.mu.RLock()
+	handlers := h.onUpstreamHealth
+	h.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(e, upstream)
+	}
+}
+
+// executeOnNoMatch executes the OnNoMatch hooks
+func (h *Hooks) executeOnNoMatch(c *fiber.Ctx) {
+	h.mu.RLock()
+	handlers := h.onNoMatch
+	h.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(c)
+	}
+}
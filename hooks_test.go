@@ -0,0 +1,89 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test table hooks fire for adds, updates and removals.
+func TestHooks_TableChanges(t *testing.T) {
+	manager := NewVhostsManager()
+	app := fiber.New()
+	other := fiber.New()
+
+	var events []string
+	manager.Hooks().OnAdd(func(e Entry) {
+		events = append(events, "add "+e.Pattern)
+	})
+	manager.Hooks().OnUpdate(func(previous, current Entry) {
+		assert.Equal(t, app, previous.App)
+		assert.Equal(t, other, current.App)
+		events = append(events, "update "+current.Pattern)
+	})
+	manager.Hooks().OnRemove(func(e Entry) {
+		events = append(events, "remove "+e.Pattern)
+	})
+
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.NoError(t, manager.UpdateHostname("example.com", other))
+	assert.NoError(t, manager.RenameHostname("example.com", "example.net"))
+	assert.NoError(t, manager.RemoveHostname("example.net"))
+
+	// Failed operations don't fire hooks
+	assert.Error(t, manager.RemoveHostname("example.net"))
+
+	assert.NoError(t, manager.AddHostname("*.example.org", app))
+	manager.Clear()
+
+	assert.Equal(t, []string{
+		"add example.com",
+		"update example.com",
+		"remove example.com",
+		"add example.net",
+		"remove example.net",
+		"add *.example.org",
+		"remove *.example.org",
+	}, events)
+}
+
+// Test hooks may call back into the manager without deadlocking.
+func TestHooks_Reentrant(t *testing.T) {
+	manager := NewVhostsManager()
+	manager.Hooks().OnAdd(func(e Entry) {
+		_, exists := manager.GetHostname(e.Pattern)
+		assert.True(t, exists)
+	})
+	assert.NoError(t, manager.AddHostname("example.com", fiber.New()))
+}
+
+// Test request hooks fire for matched and unmatched requests.
+func TestHooks_MatchAndNoMatch(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("*.example.com", fiber.New()))
+
+	var matched []string
+	var unmatched []string
+	manager.Hooks().OnMatch(func(c *fiber.Ctx, e Entry) {
+		matched = append(matched, c.Hostname()+" "+e.Pattern)
+	})
+	manager.Hooks().OnNoMatch(func(c *fiber.Ctx) {
+		unmatched = append(unmatched, c.Hostname())
+	})
+
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+
+	for _, host := range []string{"www.example.com", "unknown.com"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		resp, err := mainApp.Test(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, []string{"www.example.com *.example.com"}, matched)
+	assert.Equal(t, []string{"unknown.com"}, unmatched)
+}
@@ -0,0 +1,159 @@
+// This file contains HostConfig, the per-vhost settings applied by the middleware and the TLS layer to a single registration.
+package fibervhosts
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HostConfig holds the per-vhost settings of a registration. Like certificates, it belongs to the registration: it survives updates like app swaps and provider syncs, and is dropped when the registration is removed. Rollback restores the config of the snapshot.
+type HostConfig struct {
+	// Settings parameterizes a sub-app shared by many hostnames, like the theme or the API keys of a tenant. The sub-app reads them with Settings and Setting; SetSettings replaces them at runtime. The map must not be modified once registered.
+	Settings map[string]any
+	// ClientAuth requires TLS client certificates for the hostname. Nil disables client authentication.
+	ClientAuth *ClientAuth
+	// Redirect answers all requests for the hostname with a redirect instead of dispatching them, see AddRedirect. Nil dispatches the requests to the sub-app.
+	Redirect *Redirect
+	// IPFilter rejects requests for the hostname from client IPs it does not allow with 403 Forbidden, see NewIPFilter. The client IP is c.IP(), so it honors the proxy settings of the main app. Nil lets every IP through.
+	IPFilter *IPFilter
+	// Auth must accept a request for the hostname before it reaches the sub-app, like BasicAuth for a staging hostname. CORS preflight requests are answered before it. Nil lets every request through.
+	Auth AuthFunc
+	// HTTPSRedirect redirects plaintext requests for the hostname to https. Nil keeps serving plain HTTP.
+	HTTPSRedirect *HTTPSRedirect
+	// CORS answers preflight requests and adds the CORS headers to the responses for the hostname. Nil leaves CORS to the sub-app.
+	CORS *CORS
+	// HSTS adds a Strict-Transport-Security header to https responses for the hostname. Nil sends no header.
+	HSTS *HSTS
+	// RequestID overrides the request IDs of the manager for the hostname, like to use another header or to disable them. Nil uses Config.RequestID.
+	RequestID *RequestID
+	// AccessLog overrides the access log of the manager for the hostname, like to write it to its own file or to disable it. Nil uses Config.AccessLog.
+	AccessLog *AccessLog
+	// HealthCheck reports the health of the hostname to CheckHealth and HealthHandler. Nil requests HealthConfig.Path from the sub-app instead.
+	HealthCheck HealthCheck
+	// SlowRequestThreshold logs a warning for requests to the hostname taking longer than this. Zero uses Config.SlowRequestThreshold, a negative value disables the warning.
+	SlowRequestThreshold time.Duration
+	// Compression overrides the response compression of the manager for the hostname, like to tune it or to disable it. Nil uses Config.Compression.
+	Compression *Compression
+	// Cache overrides the response cache of the manager for the hostname, like to cache a mostly static site longer or to disable caching. Nil uses Config.Cache.
+	Cache *Cache
+	// RequestTimeout answers requests for the hostname with 504 Gateway Timeout when the sub-app takes longer than this. Zero uses Config.RequestTimeout, a negative value disables the timeout.
+	RequestTimeout time.Duration
+	// BodyLimit is the maximum size in bytes of the request bodies for the hostname, answering larger ones with 413 Request Entity Too Large before they reach the sub-app. Zero uses Config.BodyLimit, a negative value disables the limit.
+	BodyLimit int
+	// Tenants overrides the tenant resolution of the manager for the hostname, like to map its requests to a tenant of their own or to disable it. Nil uses Config.Tenants.
+	Tenants *TenantResolver
+	// RateLimit overrides the rate limit of the manager for the hostname, like to give a customer a higher limit or to disable it. Nil uses Config.RateLimit.
+	RateLimit *RateLimit
+	// Quota overrides the quota of the manager for the hostname, like to give a customer the allowance of their plan or to disable it. Nil uses Config.Quota.
+	Quota *Quota
+	// Logging overrides the log level and sampling rate of the manager for the requests of the hostname. Nil uses Config.Logging.
+	Logging *LogPolicy
+	// ErrorRate overrides the error rate policy of the manager for the hostname. Nil uses Config.ErrorRate.
+	ErrorRate *ErrorRatePolicy
+	// ErrorPages replaces the error responses of the sub-app of the hostname, like its 404 and 500 pages, with pages rendered from the templates of the registration, see NewErrorPages. Nil sends the responses of the sub-app.
+	ErrorPages *ErrorPages
+	// ErrorHandler handles the errors of requests for the hostname instead of the error handler of the main app, like to answer with JSON for one tenant and HTML pages for another. It receives the errors of dispatching, like 503 for suspended hostnames or a recovered panic of the sub-app; errors returned by the routes of the sub-app are handled by the ErrorHandler in its own config. Nil passes the errors on to the main app.
+	ErrorHandler fiber.ErrorHandler
+	// RewriteHost replaces the Host header of the requests for the hostname before they reach the sub-app, like to serve legacy.example.com by a sub-app expecting the canonical app.internal. The per-vhost middleware and the access log see the requested hostname. Empty keeps the Host header.
+	RewriteHost string
+	// Middleware runs in front of the sub-app of the hostname, like auth or headers shared by many sub-apps, without adding it to each of them. The handlers continue with c.Next as usual; their errors are handled like the errors of dispatching.
+	Middleware []fiber.Handler
+	// PanicHandler recovers panics of the sub-app of the hostname and responds to the request, even without Config.RecoverFromPanic. Nil uses the panic recovery of the manager.
+	PanicHandler PanicHandler
+	// OnStart runs after the registration was added by AddHostnameWithConfig. If it fails, the registration is removed again and AddHostnameWithConfig returns the error.
+	OnStart LifecycleHook
+	// OnStop runs once the registration was removed, however it was removed, or when the manager shuts down. It runs at most once per registration.
+	OnStop LifecycleHook
+}
+
+// AddHostnameWithConfig adds a sub-app for a given hostname together with its per-vhost settings
+func (m *VhostsManager) AddHostnameWithConfig(hostname string, app *fiber.App, config HostConfig) error {
+	return m.addStarted(hostname, app, config, nil)
+}
+
+// addStarted adds a registration with config, lets prepare set up the rest of the entry before it is published, and runs its OnStart callback
+func (m *VhostsManager) addStarted(hostname string, app *fiber.App, config HostConfig, prepare func(e *entry)) error {
+	if hostname == "" {
+		return ErrInvalidHostname
+	}
+
+	var e *entry
+	err := m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		if _, exists := table[key]; exists {
+			return nil, ErrHostExists
+		}
+
+		e = newEntry(hostname, app)
+		e.setConfig(config)
+		if prepare != nil {
+			prepare(e)
+		}
+		table[key] = e
+		return []ChangeEvent{added(e)}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := m.startEntry(e); err != nil {
+		// A registration that never started is not stopped
+		e.stopped.Store(true)
+		_ = m.update(func() ([]ChangeEvent, error) {
+			table, key := m.tableFor(hostname)
+			if table[key] != e {
+				return nil, nil
+			}
+			delete(table, key)
+			e.stopExpiry()
+			return []ChangeEvent{removed(e)}, nil
+		})
+		return fmt.Errorf("start %s: %w", hostname, err)
+	}
+	return nil
+}
+
+// AddHostnameWithMiddleware adds a sub-app for a given hostname with middleware running in front of it, see HostConfig.Middleware
+func (m *VhostsManager) AddHostnameWithMiddleware(hostname string, app *fiber.App, middleware ...fiber.Handler) error {
+	return m.AddHostnameWithConfig(hostname, app, HostConfig{Middleware: middleware})
+}
+
+// SetHostConfig replaces the per-vhost settings of a registered hostname or wildcard pattern
+func (m *VhostsManager) SetHostConfig(hostname string, config HostConfig) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+
+		previous := e.toEntry()
+		e.setConfig(config)
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
+
+// configRevisions numbers the HostConfigs given to entries, see entry.configRevision
+var configRevisions atomic.Uint64
+
+// setConfig replaces the HostConfig of the entry
+func (e *entry) setConfig(config HostConfig) {
+	e.config = config
+	e.configRevision = configRevisions.Add(1)
+}
+
+// GetHostConfig returns the per-vhost settings of a registered hostname or wildcard pattern
+func (m *VhostsManager) GetHostConfig(hostname string) (HostConfig, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists {
+		return HostConfig{}, false
+	}
+	return e.config, true
+}
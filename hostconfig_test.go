@@ -0,0 +1,64 @@
+package fibervhosts
+
+import (
+	"crypto/x509"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test per-vhost settings belong to the registration.
+func TestVhostsManager_HostConfig(t *testing.T) {
+	app := fiber.New()
+	config := HostConfig{ClientAuth: &ClientAuth{CAs: x509.NewCertPool()}}
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("api.example.com", app, config))
+	assert.Equal(t, ErrHostExists, manager.AddHostnameWithConfig("api.example.com", app, config))
+	assert.Equal(t, ErrInvalidHostname, manager.AddHostnameWithConfig("", app, config))
+	assert.Equal(t, ErrHostNotFound, manager.SetHostConfig("missing.example.com", config))
+
+	// Updates keep the settings
+	assert.NoError(t, manager.UpdateHostname("api.example.com", fiber.New()))
+	got, exists := manager.GetHostConfig("api.example.com")
+	assert.True(t, exists)
+	assert.Same(t, config.ClientAuth, got.ClientAuth)
+
+	assert.NoError(t, manager.RemoveHostname("api.example.com"))
+	_, exists = manager.GetHostConfig("api.example.com")
+	assert.False(t, exists)
+}
+
+// Test the error handler of a registration handles the errors of its requests only.
+func TestVhostsManager_HostErrorHandler(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("api.example.com", fiber.New(), HostConfig{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		},
+	}))
+	assert.NoError(t, manager.AddHostname("www.example.com", fiber.New()))
+	assert.NoError(t, manager.SuspendHostname("api.example.com"))
+	assert.NoError(t, manager.SuspendHostname("www.example.com"))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(host string) (int, string, string) {
+		resp, err := main.Test(httptest.NewRequest("GET", "http://"+host+"/", nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), string(body)
+	}
+
+	status, contentType, body := request("api.example.com")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, fiber.MIMEApplicationJSON, contentType)
+	assert.JSONEq(t, `{"error":"Service Unavailable"}`, body)
+
+	status, contentType, _ = request("www.example.com")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, fiber.MIMETextPlainCharsetUTF8, contentType)
+}
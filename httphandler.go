@@ -0,0 +1,185 @@
+// This file contains the adapter mounting net/http handlers, so existing net/http services like chi or gorilla routers can be served per hostname without rewriting them for fiber.
+package fibervhosts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// HTTPApp wraps a net/http handler into a sub-app, for use with AddHostnameWithConfig and the other registration methods. The handler sees the request as a *http.Request. Its response is buffered until it returns, unless it flushes the response with http.Flusher: from then on it is streamed, like a gRPC-Web stream or server-sent events.
+func HTTPApp(handler http.Handler) *fiber.App {
+	h := httpHandler(handler)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(func(c *fiber.Ctx) error {
+		h(c.Context())
+		return nil
+	})
+	return app
+}
+
+// AddHTTPHandler adds a net/http handler for a given hostname, like a chi or gorilla router, dispatched to as a plain handler, see AddRequestHandler. The response is buffered or streamed as with HTTPApp, which mounts the handler with the other registration methods.
+func (m *VhostsManager) AddHTTPHandler(hostname string, handler http.Handler) error {
+	return m.AddRequestHandler(hostname, httpHandler(handler))
+}
+
+// httpHandler adapts a net/http handler to fasthttp. Unlike fasthttpadaptor.NewFastHTTPHandler, which buffers the whole response, it streams the response once the handler flushes it: the handler runs in a goroutine of its own and keeps writing to the body stream of the response after the fasthttp handler returned.
+func httpHandler(handler http.Handler) fasthttp.RequestHandler {
+	return func(fctx *fasthttp.RequestCtx) {
+		// The http.Request refers to the memory of the context it is converted from, while the handler outlives fctx once it flushes and fctx is reused by the server, so it is converted from a private copy. The copy is only converted, so it needs no logger.
+		req := new(fasthttp.RequestCtx)
+		req.Init2(fctx.Conn(), nil, true)
+		fctx.Request.CopyTo(&req.Request)
+		var r http.Request
+		if err := fasthttpadaptor.ConvertRequest(req, &r, true); err != nil {
+			fctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+			return
+		}
+		// The context ends with the handler, when the client is gone while streaming, or when the server shuts down. fctx.Done reads the shutdown channel of the server unsynchronized, which is safe while the request is served, as Shutdown only resets it once the connections are closed; it is read once here rather than by the goroutines outliving this handler. Watching it takes a goroutine per request, except for contexts without a server, whose channel is nil.
+		shutdown := fctx.Done()
+		ctx, cancel := context.WithCancel(context.Background())
+		if shutdown != nil {
+			go func() {
+				select {
+				case <-shutdown:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+		}
+		w := &httpResponseWriter{header: http.Header{}, status: http.StatusOK, flushed: make(chan struct{})}
+
+		done := make(chan any, 1)
+		go func() {
+			defer func() {
+				p := recover()
+				cancel()
+				if w.pw != nil {
+					// A panic after the response was flushed can only abort the stream
+					if p != nil {
+						_ = w.pw.CloseWithError(fmt.Errorf("handler panicked: %v", p))
+					} else {
+						_ = w.pw.Close()
+					}
+				}
+				done <- p
+			}()
+			handler.ServeHTTP(w, r.WithContext(ctx))
+		}()
+
+		var p any
+		select {
+		case p = <-done:
+		case <-w.flushed:
+		}
+		if w.pr == nil {
+			if p != nil {
+				panic(p)
+			}
+			w.writeHeader(&fctx.Response)
+			fctx.Response.SetBody(w.buf.Bytes())
+			return
+		}
+
+		w.writeHeader(&fctx.Response)
+		buffered := w.buf.Bytes()
+		fctx.SetBodyStreamWriter(func(bw *bufio.Writer) {
+			if err := w.stream(bw, buffered); err != nil {
+				_ = w.pr.CloseWithError(err)
+				cancel()
+			}
+		})
+	}
+}
+
+// httpResponseWriter is the http.ResponseWriter of httpHandler. It buffers the response until the first flush; from then on writes go to a pipe read by the body stream of the response.
+type httpResponseWriter struct {
+	header  http.Header
+	status  int
+	written bool
+	buf     bytes.Buffer
+	// pr and pw are set by the first flush, which then closes flushed
+	pr      *io.PipeReader
+	pw      *io.PipeWriter
+	flushed chan struct{}
+}
+
+func (w *httpResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *httpResponseWriter) WriteHeader(status int) {
+	if w.written || status < 200 {
+		return
+	}
+	w.status = status
+	w.written = true
+}
+
+func (w *httpResponseWriter) Write(p []byte) (int, error) {
+	w.written = true
+	if w.pw != nil {
+		return w.pw.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush starts streaming the response; the header is sent as it is now
+func (w *httpResponseWriter) Flush() {
+	if w.pw != nil {
+		return
+	}
+	w.written = true
+	w.pr, w.pw = io.Pipe()
+	close(w.flushed)
+}
+
+// writeHeader sets the status and the header of resp. Like net/http, it detects the Content-Type from the body written so far when the handler did not set it.
+func (w *httpResponseWriter) writeHeader(resp *fasthttp.Response) {
+	resp.SetStatusCode(w.status)
+	for name, values := range w.header {
+		for _, value := range values {
+			resp.Header.Add(name, value)
+		}
+	}
+	if w.header.Get(fasthttp.HeaderContentType) == "" {
+		b := w.buf.Bytes()
+		resp.Header.SetContentType(http.DetectContentType(b[:min(len(b), 512)]))
+	}
+}
+
+// stream writes the body written before the first flush and then everything the handler writes to bw, flushing after every write
+func (w *httpResponseWriter) stream(bw *bufio.Writer, buffered []byte) error {
+	if _, err := bw.Write(buffered); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := w.pr.Read(buf)
+		if n > 0 {
+			if _, err := bw.Write(buf[:n]); err != nil {
+				return err
+			}
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package fibervhosts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// Test net/http handlers are served per hostname next to fiber sub-apps.
+func TestVhostsManager_AddHTTPHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "net/http")
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "%s %s on %s", r.Method, r.URL.RequestURI(), r.Host)
+	})
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("fiber")
+	})
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHTTPHandler("legacy.example.com", mux))
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("locked.example.com", HTTPApp(mux), HostConfig{Auth: APIKeyAuth("X-API-Key", "key")}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	resp, err := main.Test(httptest.NewRequest("PUT", "http://legacy.example.com/users/42?full=1", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "net/http", resp.Header.Get("X-Handler"))
+	assert.Equal(t, "PUT /users/42?full=1 on legacy.example.com", string(body))
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://legacy.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "fiber", string(body))
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://locked.example.com/users/1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+// Test responses of net/http handlers are streamed once flushed, even with a request timeout shorter than the stream, and buffered otherwise.
+func TestHTTPApp_Stream(t *testing.T) {
+	next := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc-web+proto")
+		fmt.Fprint(w, "first\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-next:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, "second\n")
+	})
+	mux.HandleFunc("/buffered", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, "<html>buffered</html>")
+	})
+
+	manager := NewVhostsManager(Config{RequestTimeout: 20 * time.Millisecond, AccessLog: &AccessLog{Output: io.Discard}})
+	assert.NoError(t, manager.AddHTTPHandler("grpc.example.com", mux))
+	assert.NoError(t, manager.AddHostname("app.example.com", HTTPApp(mux)))
+
+	main := fiber.New(fiber.Config{DisableStartupMessage: true})
+	main.Use(VhostMiddleware(manager))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go main.Listener(ln)
+	defer main.Shutdown()
+
+	get := func(host, path string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+path, nil)
+		assert.NoError(t, err)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	for _, host := range []string{"grpc.example.com", "app.example.com"} {
+		resp := get(host, "/stream")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+		br := bufio.NewReader(resp.Body)
+		line, err := br.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "first\n", line)
+
+		// The stream outlives the request timeout
+		time.Sleep(50 * time.Millisecond)
+		next <- struct{}{}
+		rest, err := io.ReadAll(br)
+		assert.NoError(t, err)
+		assert.Equal(t, "second\n", string(rest))
+		resp.Body.Close()
+
+		resp = get(host, "/buffered")
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "<html>buffered</html>", string(body))
+	}
+}
+
+// Test net/http handlers still see their request after the fasthttp handler returned on a flush and the server reused the context.
+func TestHTTPApp_RequestOutlivesContext(t *testing.T) {
+	next := make(chan struct{})
+	seen := make(chan string, 1)
+	h := httpHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		<-next
+		body, _ := io.ReadAll(r.Body)
+		seen <- fmt.Sprintf("%s %s %s %s", r.Host, r.URL.RequestURI(), r.Header.Get("X-Test"), body)
+	}))
+
+	fctx := new(fasthttp.RequestCtx)
+	fctx.Init(&fasthttp.Request{}, nil, nil)
+	fctx.Request.SetRequestURI("http://aaaa.example.com/aaaa?q=a")
+	fctx.Request.Header.Set("X-Test", "aaaa")
+	fctx.Request.SetBodyString("aaaa")
+	h(fctx)
+
+	fctx.Request.SetRequestURI("http://bbbb.example.com/bbbb?q=b")
+	fctx.Request.Header.Set("X-Test", "bbbb")
+	fctx.Request.SetBodyString("bbbb")
+	close(next)
+	assert.Equal(t, "aaaa.example.com /aaaa?q=a aaaa aaaa", <-seen)
+}
@@ -0,0 +1,83 @@
+// This file contains the per-vhost https policies: the redirect of plaintext requests to https and HSTS.
+package fibervhosts
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HTTPSRedirect defines the https redirect policy of a hostname
+type HTTPSRedirect struct {
+	// Status is the redirect status, 301 Moved Permanently or 308 Permanent Redirect. Defaults to 308, which keeps the method and body of the request.
+	Status int
+	// Port is the https port in the redirect target. Defaults to 443.
+	Port int
+	// ExcludePaths lists path prefixes still served over plain HTTP, like "/.well-known/acme-challenge/".
+	ExcludePaths []string
+}
+
+// redirect answers plaintext requests with a redirect to https. It reports whether the request was redirected.
+func (r *HTTPSRedirect) redirect(c *fiber.Ctx) (bool, error) {
+	if c.Protocol() == "https" {
+		return false, nil
+	}
+	path := c.Path()
+	for _, prefix := range r.ExcludePaths {
+		if strings.HasPrefix(path, prefix) {
+			return false, nil
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = fiber.StatusPermanentRedirect
+	}
+	host := c.Hostname()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	// IPv6 hosts keep their brackets in the URL, with or without a port
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	target := "https://" + host
+	if r.Port != 0 && r.Port != 443 {
+		target += ":" + strconv.Itoa(r.Port)
+	}
+	return true, c.Redirect(target+string(c.Request().URI().RequestURI()), status)
+}
+
+// HSTS defines the HTTP Strict Transport Security policy of a hostname
+type HSTS struct {
+	// MaxAge is the time browsers remember to only use https. Required.
+	MaxAge time.Duration
+	// IncludeSubDomains extends the policy to all subdomains of the hostname
+	IncludeSubDomains bool
+	// Preload marks the hostname as eligible for browser preload lists
+	Preload bool
+}
+
+// header returns the Strict-Transport-Security header value of the policy
+func (h *HSTS) header() string {
+	value := "max-age=" + strconv.FormatInt(int64(h.MaxAge/time.Second), 10)
+	if h.IncludeSubDomains {
+		value += "; includeSubDomains"
+	}
+	if h.Preload {
+		value += "; preload"
+	}
+	return value
+}
+
+// apply adds the header to an https response, unless the app already set one. Browsers ignore the header over plain HTTP, so it is not sent there.
+func (h *HSTS) apply(c *fiber.Ctx) {
+	if c.Protocol() != "https" || len(c.Response().Header.Peek(fiber.HeaderStrictTransportSecurity)) > 0 {
+		return
+	}
+	c.Set(fiber.HeaderStrictTransportSecurity, h.header())
+}
@@ -0,0 +1,104 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test plaintext requests are redirected to https per hostname.
+func TestVhostMiddleware_HTTPSRedirect(t *testing.T) {
+	sub := fiber.New()
+	sub.All("/*", func(c *fiber.Ctx) error {
+		return c.SendString("served")
+	})
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("secure.example.com", sub, HostConfig{
+		HTTPSRedirect: &HTTPSRedirect{ExcludePaths: []string{"/.well-known/acme-challenge/"}},
+	}))
+	assert.NoError(t, manager.AddHostnameWithConfig("legacy.example.com", sub, HostConfig{
+		HTTPSRedirect: &HTTPSRedirect{Status: fiber.StatusMovedPermanently, Port: 8443},
+	}))
+	assert.NoError(t, manager.AddHostname("plain.example.com", sub))
+
+	app := fiber.New()
+	app.Use(VhostMiddleware(manager))
+
+	req := httptest.NewRequest("POST", "http://secure.example.com/login?next=%2F", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusPermanentRedirect, resp.StatusCode)
+	assert.Equal(t, "https://secure.example.com/login?next=%2F", resp.Header.Get("Location"))
+
+	req = httptest.NewRequest("GET", "http://legacy.example.com/", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://legacy.example.com:8443/", resp.Header.Get("Location"))
+
+	// IPv6 hosts keep their brackets
+	assert.NoError(t, manager.AddHostnameWithConfig("[::1]", sub, HostConfig{HTTPSRedirect: &HTTPSRedirect{}}))
+	for target, location := range map[string]string{"http://[::1]:80/x": "https://[::1]/x", "http://[::1]/": "https://[::1]/"} {
+		resp, err = app.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, location, resp.Header.Get("Location"), target)
+	}
+	assert.NoError(t, manager.SetHostConfig("[::1]", HostConfig{HTTPSRedirect: &HTTPSRedirect{Port: 8443}}))
+	resp, err = app.Test(httptest.NewRequest("GET", "http://[::1]:80/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://[::1]:8443/", resp.Header.Get("Location"))
+
+	// Excluded paths and hostnames without a policy stay on plain HTTP
+	for _, target := range []string{"http://secure.example.com/.well-known/acme-challenge/token", "http://plain.example.com/"} {
+		resp, err = app.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode, target)
+	}
+
+	// Requests that already arrived over https are served
+	req = httptest.NewRequest("GET", "http://secure.example.com/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+// Test the HSTS header is added to https responses per hostname.
+func TestVhostMiddleware_HSTS(t *testing.T) {
+	sub := fiber.New()
+	sub.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("served")
+	})
+	own := fiber.New()
+	own.Get("/", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderStrictTransportSecurity, "max-age=60")
+		return nil
+	})
+
+	manager := NewVhostsManager()
+	hsts := HostConfig{HSTS: &HSTS{MaxAge: 365 * 24 * time.Hour, IncludeSubDomains: true, Preload: true}}
+	assert.NoError(t, manager.AddHostnameWithConfig("shop.example.com", sub, hsts))
+	assert.NoError(t, manager.AddHostnameWithConfig("own.example.com", own, hsts))
+	assert.NoError(t, manager.AddHostname("tools.example.com", sub))
+
+	app := fiber.New()
+	app.Use(VhostMiddleware(manager))
+
+	header := func(target string, https bool) string {
+		req := httptest.NewRequest("GET", target, nil)
+		if https {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.Header.Get(fiber.HeaderStrictTransportSecurity)
+	}
+	assert.Equal(t, "max-age=31536000; includeSubDomains; preload", header("http://shop.example.com/", true))
+	assert.Empty(t, header("http://shop.example.com/", false))
+	assert.Empty(t, header("http://tools.example.com/", true))
+	assert.Equal(t, "max-age=60", header("http://own.example.com/", true))
+}
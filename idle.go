@@ -0,0 +1,69 @@
+// This file contains the eviction of idle sub-apps: apps built by a factory whose hostname received no traffic for a while are shut down and dropped, and built again by the factory on the next request.
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EvictIdle shuts down and drops the sub-apps built by a factory, see AddHostnameFactory, whose hostname received no request for at least idle and has none in flight. The registrations are kept, so the next request builds the app again. Like building the app, evictions are versioned and reported as updates, see Watch. Registrations without a factory are never evicted. It returns the number of evicted apps and the joined shutdown errors.
+func (m *VhostsManager) EvictIdle(ctx context.Context, idle time.Duration) (int, error) {
+	cutoff := time.Now().Add(-idle).UnixNano()
+
+	var evicted []*fiber.App
+	var patterns []string
+	_ = m.update(func() ([]ChangeEvent, error) {
+		var changes []ChangeEvent
+		for _, table := range []map[string]*entry{m.hosts, m.wildcards} {
+			for _, e := range table {
+				if e.factory == nil || e.app == nil || e.stats.lastAccess.Load() > cutoff {
+					continue
+				}
+				// Requests count themselves in flight before they check for evictions, so either they see this one and build the app again, or it sees them and keeps the app
+				e.evictions.Add(1)
+				if e.inflight.Load() > 0 {
+					e.evictions.Add(^uint64(0))
+					continue
+				}
+				previous := e.toEntry()
+				evicted = append(evicted, e.app)
+				patterns = append(patterns, e.pattern)
+				e.app = nil
+				changes = append(changes, updated(previous, e))
+			}
+		}
+		return changes, nil
+	})
+
+	var errs []error
+	for i, app := range evicted {
+		if err := app.ShutdownWithContext(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", patterns[i], err))
+		}
+	}
+	return len(evicted), errors.Join(errs...)
+}
+
+// RunIdleEviction calls EvictIdle every interval until ctx is done, so long-tail tenants do not pin memory forever. Shutdown errors are logged.
+func (m *VhostsManager) RunIdleEviction(ctx context.Context, idle, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if n, err := m.EvictIdle(ctx, idle); err != nil {
+			m.logger.Warn("Evicting idle apps failed", "evicted", n, "error", err)
+		} else if n > 0 {
+			m.logger.Info("Evicted idle apps", "evicted", n)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package fibervhosts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test idle apps built by a factory are shut down, dropped and built again on the next request.
+func TestVhostsManager_EvictIdle(t *testing.T) {
+	var builds, shutdowns atomic.Int32
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameFactory("tenant.example.com", func() (*fiber.App, error) {
+		builds.Add(1)
+		app := fiber.New()
+		app.Hooks().OnShutdown(func() error {
+			shutdowns.Add(1)
+			return nil
+		})
+		return app, nil
+	}))
+	assert.NoError(t, manager.AddHostname("static.example.com", fiber.New()))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(host string) {
+		_, err := main.Test(httptest.NewRequest("GET", "http://"+host+"/", nil))
+		assert.NoError(t, err)
+	}
+	request("tenant.example.com")
+	request("static.example.com")
+
+	// Recently used apps are kept
+	n, err := manager.EvictIdle(context.Background(), time.Hour)
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	time.Sleep(5 * time.Millisecond)
+	events, cancel := manager.Watch()
+	defer cancel()
+	version := manager.Version()
+	n, err = manager.EvictIdle(context.Background(), time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, version+1, manager.Version())
+	ev := receive(t, events)
+	assert.Equal(t, ChangeUpdated, ev.Type)
+	assert.NotNil(t, ev.Previous.App)
+	assert.Nil(t, ev.Entry.App)
+	assert.Equal(t, int32(1), shutdowns.Load())
+
+	app, exists := manager.GetHostname("tenant.example.com")
+	assert.True(t, exists)
+	assert.Nil(t, app)
+	app, _ = manager.GetHostname("static.example.com")
+	assert.NotNil(t, app)
+
+	request("tenant.example.com")
+	assert.Equal(t, int32(2), builds.Load())
+}
+
+// Test requests matched before an eviction but dispatched after it get a new app instead of the evicted one.
+func TestVhostsManager_EvictIdleMatchedRequest(t *testing.T) {
+	var builds atomic.Int32
+	var evict atomic.Bool
+	manager := NewVhostsManager()
+	manager.requestID = &RequestID{Generator: func() string {
+		// Runs after the request was matched, before it is counted in flight
+		if evict.Load() {
+			n, err := manager.EvictIdle(context.Background(), 0)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, n)
+		}
+		return "id"
+	}}
+	assert.NoError(t, manager.AddHostnameFactory("tenant.example.com", func() (*fiber.App, error) {
+		build := builds.Add(1)
+		stopped := false
+		app := fiber.New()
+		app.Hooks().OnShutdown(func() error {
+			stopped = true
+			return nil
+		})
+		app.Get("/", func(c *fiber.Ctx) error {
+			if stopped {
+				return c.SendString("stopped")
+			}
+			return c.SendString(fmt.Sprint("build ", build))
+		})
+		return app, nil
+	}))
+
+	request := func() string {
+		resp, err := manager.Test("tenant.example.com", NewTestRequest("GET", "", "/", nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	assert.Equal(t, "build 1", request())
+	evict.Store(true)
+	assert.Equal(t, "build 2", request())
+}
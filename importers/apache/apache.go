@@ -0,0 +1,220 @@
+// Package apache imports Apache <VirtualHost> sections into a fibervhosts.VhostsManager to support lift-and-shift migrations.
+//
+// The ServerName and every ServerAlias of a virtual host become registrations for the same app, so aliases keep sharing one app like they share one virtual host in Apache. Wildcard aliases like "*.example.com" are imported as is; other glob patterns have no equivalent and are skipped with a warning. Like in Apache, the first virtual host is the default one, unless a virtual host is declared for the "_default_" address.
+package apache
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Metadata keys set on imported registrations
+const (
+	MetadataProxyPass    = "apache.proxy_pass"
+	MetadataDocumentRoot = "apache.document_root"
+)
+
+// VirtualHost describes a single Apache <VirtualHost> section
+type VirtualHost struct {
+	// Addresses holds the addresses of the section, like "*:80"
+	Addresses []string
+	// ServerName is the primary name of the virtual host
+	ServerName string
+	// Aliases holds the ServerAlias names
+	Aliases []string
+	// ProxyPass is the target of the ProxyPass directive for "/", or of the first ProxyPass
+	ProxyPass string
+	// DocumentRoot is the DocumentRoot directive of the virtual host
+	DocumentRoot string
+}
+
+// Backend returns the identifier used to resolve the app of the virtual host: the ProxyPass target when set, otherwise the server name
+func (v VirtualHost) Backend() string {
+	if v.ProxyPass != "" {
+		return v.ProxyPass
+	}
+	if name := hostname(v.ServerName); name != "" {
+		return name
+	}
+	return "default"
+}
+
+// Names returns the server name followed by the aliases, normalized to bare hostnames
+func (v VirtualHost) Names() []string {
+	var names []string
+	for _, name := range append([]string{v.ServerName}, v.Aliases...) {
+		if name = hostname(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isDefault reports whether the virtual host is declared for the "_default_" address
+func (v VirtualHost) isDefault() bool {
+	for _, addr := range v.Addresses {
+		if strings.HasPrefix(addr, "_default_") {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads the virtual hosts of an Apache configuration, including those nested in sections like <IfModule>. Include directives are not followed, see ParseFile.
+func Parse(r io.Reader) ([]VirtualHost, error) {
+	directives, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return collect(directives, ""), nil
+}
+
+// ParseFile reads the virtual hosts of an Apache configuration file, following Include and IncludeOptional directives relative to the directory of the file
+func ParseFile(path string) ([]VirtualHost, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	directives, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return collect(directives, filepath.Dir(path)), nil
+}
+
+// collect walks the directive tree and returns its virtual hosts. When dir is set, includes are resolved relative to it.
+func collect(directives []directive, dir string) []VirtualHost {
+	var hosts []VirtualHost
+	for _, d := range directives {
+		switch {
+		case d.name == "virtualhost":
+			hosts = append(hosts, newVirtualHost(d))
+		case d.name == "include" || d.name == "includeoptional":
+			if dir == "" || len(d.args) == 0 {
+				continue
+			}
+			pattern := d.args[0]
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(dir, pattern)
+			}
+			matches, _ := filepath.Glob(pattern)
+			for _, path := range matches {
+				included, err := ParseFile(path)
+				if err != nil {
+					log.Warnf("apache importer: include %s: %v", path, err)
+					continue
+				}
+				hosts = append(hosts, included...)
+			}
+		case d.block != nil:
+			hosts = append(hosts, collect(d.block, dir)...)
+		}
+	}
+	return hosts
+}
+
+// newVirtualHost converts a <VirtualHost> section into a VirtualHost
+func newVirtualHost(section directive) VirtualHost {
+	v := VirtualHost{Addresses: section.args}
+	for _, d := range section.block {
+		switch d.name {
+		case "servername":
+			if len(d.args) > 0 {
+				v.ServerName = d.args[0]
+			}
+		case "serveralias":
+			v.Aliases = append(v.Aliases, d.args...)
+		case "documentroot":
+			if len(d.args) > 0 {
+				v.DocumentRoot = d.args[0]
+			}
+		case "proxypass":
+			if len(d.args) < 2 {
+				continue
+			}
+			if d.args[0] == "/" || v.ProxyPass == "" {
+				v.ProxyPass = d.args[1]
+			}
+		}
+	}
+	return v
+}
+
+// hostname normalizes a ServerName or ServerAlias value, dropping the scheme and port
+func hostname(name string) string {
+	name = strings.ToLower(name)
+	if _, rest, ok := strings.Cut(name, "://"); ok {
+		name = rest
+	}
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// Specs converts virtual hosts into registrations, with the app named by VirtualHost.Backend and the ProxyPass target and DocumentRoot kept as metadata. When several virtual hosts claim the same hostname, the first one wins, like in Apache.
+func Specs(hosts []VirtualHost) []fibervhosts.EntrySpec {
+	defaultHost := -1
+	for i, v := range hosts {
+		if v.isDefault() {
+			defaultHost = i
+			break
+		}
+	}
+	if defaultHost < 0 && len(hosts) > 0 {
+		defaultHost = 0
+	}
+
+	var specs []fibervhosts.EntrySpec
+	seen := make(map[string]bool)
+	add := func(hostname string, v VirtualHost) {
+		if seen[hostname] {
+			return
+		}
+		seen[hostname] = true
+
+		spec := fibervhosts.EntrySpec{Hostname: hostname, App: v.Backend()}
+		values := make(map[string]string)
+		if v.ProxyPass != "" {
+			values[MetadataProxyPass] = v.ProxyPass
+		}
+		if v.DocumentRoot != "" {
+			values[MetadataDocumentRoot] = v.DocumentRoot
+		}
+		if len(values) > 0 {
+			spec.Metadata = &fibervhosts.Metadata{Values: values}
+		}
+		specs = append(specs, spec)
+	}
+
+	for i, v := range hosts {
+		if i == defaultHost {
+			add("", v)
+		}
+		for _, name := range v.Names() {
+			if strings.ContainsAny(strings.TrimPrefix(name, "*."), "*?[") {
+				log.Warnf("apache importer: skipping glob server alias %q", name)
+				continue
+			}
+			add(name, v)
+		}
+	}
+	return specs
+}
+
+// Import parses an Apache configuration and applies its virtual hosts to the manager under the source "apache", see VhostsManager.ApplyEntries. Backends are resolved into apps by the resolver; an unknown backend aborts the import with fibervhosts.ErrAppNotFound and leaves the table untouched.
+func Import(manager *fibervhosts.VhostsManager, r io.Reader, resolver fibervhosts.AppResolver) error {
+	hosts, err := Parse(r)
+	if err != nil {
+		return err
+	}
+	return manager.ApplyEntries("apache", fibervhosts.ResolveSpecs(Specs(hosts), resolver))
+}
@@ -0,0 +1,111 @@
+package apache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const config = `
+# Sites
+<VirtualHost *:80>
+    ServerName www.example.com:80
+    ServerAlias example.com *.example.com \
+                www?.example.net
+    DocumentRoot "/var/www/example"
+</VirtualHost>
+
+<IfModule mod_proxy.c>
+    <VirtualHost *:80>
+        ServerName https://api.example.com
+        ProxyPass /metrics !
+        ProxyPass / http://127.0.0.1:8080/
+    </VirtualHost>
+</IfModule>
+
+<VirtualHost _default_:80>
+    DocumentRoot /var/www/default
+</VirtualHost>
+`
+
+// Test virtual hosts are parsed with their names, aliases and proxy target.
+func TestParse(t *testing.T) {
+	hosts, err := Parse(strings.NewReader(config))
+	assert.NoError(t, err)
+	assert.Len(t, hosts, 3)
+
+	assert.Equal(t, []string{"www.example.com", "example.com", "*.example.com", "www?.example.net"}, hosts[0].Names())
+	assert.Equal(t, "/var/www/example", hosts[0].DocumentRoot)
+	assert.Equal(t, "www.example.com", hosts[0].Backend())
+
+	assert.Equal(t, "http://127.0.0.1:8080/", hosts[1].ProxyPass)
+	assert.Equal(t, []string{"api.example.com"}, hosts[1].Names())
+	assert.True(t, hosts[2].isDefault())
+}
+
+// Test invalid configurations are rejected.
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{"<VirtualHost *:80>", "</VirtualHost>", `ServerName "open`, "<VirtualHost *:80"} {
+		_, err := Parse(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+}
+
+// Test names and aliases are converted into registrations sharing one app.
+func TestSpecs(t *testing.T) {
+	hosts, err := Parse(strings.NewReader(config))
+	assert.NoError(t, err)
+
+	specs := Specs(hosts)
+	var hostnames []string
+	for _, spec := range specs {
+		hostnames = append(hostnames, spec.Hostname)
+	}
+	assert.Equal(t, []string{"www.example.com", "example.com", "*.example.com", "api.example.com", ""}, hostnames)
+	assert.Equal(t, "default", specs[4].App)
+	assert.Equal(t, "/var/www/default", specs[4].Metadata.Get(MetadataDocumentRoot))
+
+	// Without a _default_ host the first one is the default
+	specs = Specs(hosts[:2])
+	assert.Equal(t, "", specs[0].Hostname)
+	assert.Equal(t, "www.example.com", specs[0].App)
+}
+
+// Test ParseFile follows Include directives.
+func TestParseFile_Include(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sites-enabled"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "httpd.conf"), []byte("IncludeOptional sites-enabled/*.conf\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sites-enabled", "a.conf"), []byte("<VirtualHost *:80>\nServerName a.example.com\n</VirtualHost>\n"), 0o644))
+
+	hosts, err := ParseFile(filepath.Join(dir, "httpd.conf"))
+	assert.NoError(t, err)
+	assert.Len(t, hosts, 1)
+	assert.Equal(t, "a.example.com", hosts[0].ServerName)
+}
+
+// Test importing into a manager keeps aliases on the same app.
+func TestImport(t *testing.T) {
+	site := fiber.New()
+	api := fiber.New()
+	fallback := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{
+		"www.example.com":        site,
+		"http://127.0.0.1:8080/": api,
+		"default":                fallback,
+	})
+
+	manager := fibervhosts.NewVhostsManager()
+	assert.NoError(t, Import(manager, strings.NewReader(config), resolver))
+
+	www, _ := manager.GetHostname("www.example.com")
+	alias, _ := manager.GetHostname("example.com")
+	assert.Equal(t, site, www)
+	assert.Same(t, www, alias)
+	assert.Len(t, manager.ListEntries(), 5)
+}
@@ -0,0 +1,153 @@
+package apache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// directive is a single parsed Apache directive. Sections like <VirtualHost> hold their children in block.
+type directive struct {
+	name  string
+	args  []string
+	block []directive
+}
+
+// parse reads an Apache configuration into its directive tree. Directive and section names are lowercased.
+func parse(r io.Reader) ([]directive, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lines: lines}
+	directives, err := p.block("")
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line, err)
+	}
+	return directives, nil
+}
+
+// logicalLine is a configuration line with continuations joined
+type logicalLine struct {
+	number int
+	text   string
+}
+
+// readLines splits the configuration into logical lines, joining lines ending with a backslash and dropping comments and blank lines
+func readLines(r io.Reader) ([]logicalLine, error) {
+	var lines []logicalLine
+	var current strings.Builder
+	start := 0
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if current.Len() == 0 {
+			start = n
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+		}
+		if strings.HasSuffix(text, "\\") {
+			current.WriteString(strings.TrimSuffix(text, "\\"))
+			current.WriteByte(' ')
+			continue
+		}
+		current.WriteString(text)
+		lines = append(lines, logicalLine{number: start, text: current.String()})
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		lines = append(lines, logicalLine{number: start, text: current.String()})
+	}
+	return lines, scanner.Err()
+}
+
+// parser builds the directive tree from logical lines
+type parser struct {
+	lines []logicalLine
+	pos   int
+	line  int
+}
+
+// block parses directives until the end of the input, or the closing tag of section when set
+func (p *parser) block(section string) ([]directive, error) {
+	var directives []directive
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		p.pos++
+		p.line = l.number
+
+		text := l.text
+		if strings.HasPrefix(text, "</") {
+			name := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(text, "</"), ">"))
+			if name != section {
+				return nil, fmt.Errorf("unexpected closing tag %q", text)
+			}
+			return directives, nil
+		}
+
+		opening := strings.HasPrefix(text, "<")
+		if opening {
+			if !strings.HasSuffix(text, ">") {
+				return nil, fmt.Errorf("malformed section %q", text)
+			}
+			text = text[1 : len(text)-1]
+		}
+
+		fields, err := split(text)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		d := directive{name: strings.ToLower(fields[0]), args: fields[1:]}
+		if opening {
+			if d.block, err = p.block(d.name); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, d)
+	}
+	if section != "" {
+		return nil, fmt.Errorf("missing </%s>", section)
+	}
+	return directives, nil
+}
+
+// split splits a directive line into words, honouring double quotes
+func split(text string) ([]string, error) {
+	var fields []string
+	var b strings.Builder
+	inWord, quoted := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+			inWord = true
+		case c == '\\' && quoted && i+1 < len(text) && text[i+1] == '"':
+			i++
+			b.WriteByte('"')
+		case !quoted && (c == ' ' || c == '\t'):
+			if inWord {
+				fields = append(fields, b.String())
+				b.Reset()
+				inWord = false
+			}
+		default:
+			b.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated string")
+	}
+	if inWord {
+		fields = append(fields, b.String())
+	}
+	return fields, nil
+}
@@ -0,0 +1,217 @@
+// Package nginx imports nginx server blocks into a fibervhosts.VhostsManager, easing migrations from nginx front-ends to a Fiber-based gateway.
+//
+// Every server_name of a server block becomes a registration. Exact names and leading wildcards like "*.example.com" are imported as is, and ".example.com" registers both "example.com" and "*.example.com". Regular expression names and trailing wildcards have no equivalent and are skipped with a warning. A server with "listen ... default_server" becomes the default app.
+package nginx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Metadata keys set on imported registrations
+const (
+	MetadataProxyPass = "nginx.proxy_pass"
+	MetadataRoot      = "nginx.root"
+)
+
+// Server describes a single nginx server block
+type Server struct {
+	// Names holds the server_name values as written in the configuration
+	Names []string
+	// Default is set when the server is the default_server of a listen directive
+	Default bool
+	// ProxyPass is the proxy_pass target of the "/" location, or of the first location using one
+	ProxyPass string
+	// Root is the root directive of the server
+	Root string
+}
+
+// Backend returns the identifier used to resolve the app of the server: the proxy_pass target when set, otherwise the first server name
+func (s Server) Backend() string {
+	if s.ProxyPass != "" {
+		return s.ProxyPass
+	}
+	for _, name := range s.Names {
+		if name != "" && name != "_" {
+			return name
+		}
+	}
+	return "default"
+}
+
+// Parse reads the server blocks of an nginx configuration, at the top level or nested in http blocks. Include directives are not followed, see ParseFile.
+func Parse(r io.Reader) ([]Server, error) {
+	directives, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return collect(directives, ""), nil
+}
+
+// ParseFile reads the server blocks of an nginx configuration file, following include directives relative to the directory of the file
+func ParseFile(path string) ([]Server, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	directives, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return collect(directives, filepath.Dir(path)), nil
+}
+
+// collect walks the directive tree and returns its server blocks. When dir is set, includes are resolved relative to it.
+func collect(directives []directive, dir string) []Server {
+	var servers []Server
+	for _, d := range directives {
+		switch d.name {
+		case "server":
+			servers = append(servers, newServer(d))
+		case "http":
+			servers = append(servers, collect(d.block, dir)...)
+		case "include":
+			if dir == "" || len(d.args) == 0 {
+				continue
+			}
+			pattern := d.args[0]
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(dir, pattern)
+			}
+			matches, _ := filepath.Glob(pattern)
+			for _, path := range matches {
+				included, err := ParseFile(path)
+				if err != nil {
+					log.Warnf("nginx importer: include %s: %v", path, err)
+					continue
+				}
+				servers = append(servers, included...)
+			}
+		}
+	}
+	return servers
+}
+
+// newServer converts a server block into a Server
+func newServer(block directive) Server {
+	var s Server
+	var fallbackProxy string
+	for _, d := range block.block {
+		switch d.name {
+		case "server_name":
+			s.Names = append(s.Names, d.args...)
+		case "listen":
+			for _, arg := range d.args[min(1, len(d.args)):] {
+				if arg == "default_server" || arg == "default" {
+					s.Default = true
+				}
+			}
+		case "root":
+			if len(d.args) > 0 {
+				s.Root = d.args[0]
+			}
+		case "proxy_pass":
+			if len(d.args) > 0 {
+				s.ProxyPass = d.args[0]
+			}
+		case "location":
+			proxy := locationProxy(d)
+			if proxy == "" {
+				continue
+			}
+			if len(d.args) > 0 && d.args[len(d.args)-1] == "/" {
+				s.ProxyPass = proxy
+			} else if fallbackProxy == "" {
+				fallbackProxy = proxy
+			}
+		}
+	}
+	if s.ProxyPass == "" {
+		s.ProxyPass = fallbackProxy
+	}
+	return s
+}
+
+// locationProxy returns the proxy_pass target of a location block
+func locationProxy(location directive) string {
+	for _, d := range location.block {
+		if d.name == "proxy_pass" && len(d.args) > 0 {
+			return d.args[0]
+		}
+	}
+	return ""
+}
+
+// Specs converts server blocks into registrations, with the app named by Server.Backend and the proxy_pass target and root kept as metadata. When several servers claim the same hostname, the first one wins, like in nginx.
+func Specs(servers []Server) []fibervhosts.EntrySpec {
+	var specs []fibervhosts.EntrySpec
+	seen := make(map[string]bool)
+	add := func(hostname string, s Server) {
+		if seen[hostname] {
+			return
+		}
+		seen[hostname] = true
+
+		spec := fibervhosts.EntrySpec{Hostname: hostname, App: s.Backend()}
+		values := make(map[string]string)
+		if s.ProxyPass != "" {
+			values[MetadataProxyPass] = s.ProxyPass
+		}
+		if s.Root != "" {
+			values[MetadataRoot] = s.Root
+		}
+		if len(values) > 0 {
+			spec.Metadata = &fibervhosts.Metadata{Values: values}
+		}
+		specs = append(specs, spec)
+	}
+
+	for _, s := range servers {
+		if s.Default {
+			add("", s)
+		}
+		for _, name := range s.Names {
+			for _, hostname := range hostnames(name) {
+				add(hostname, s)
+			}
+		}
+	}
+	return specs
+}
+
+// hostnames converts a server_name value into the hostnames it registers
+func hostnames(name string) []string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "" || name == "_":
+		// Catch-all names, covered by default_server
+		return nil
+	case strings.HasPrefix(name, "~") || strings.Contains(name, "$"):
+		log.Warnf("nginx importer: skipping regular expression server name %q", name)
+		return nil
+	case strings.HasSuffix(name, ".*"):
+		log.Warnf("nginx importer: skipping trailing wildcard server name %q", name)
+		return nil
+	case strings.HasPrefix(name, "."):
+		return []string{name[1:], "*" + name}
+	default:
+		return []string{name}
+	}
+}
+
+// Import parses an nginx configuration and applies its server blocks to the manager under the source "nginx", see VhostsManager.ApplyEntries. Backends are resolved into apps by the resolver; an unknown backend aborts the import with fibervhosts.ErrAppNotFound and leaves the table untouched.
+func Import(manager *fibervhosts.VhostsManager, r io.Reader, resolver fibervhosts.AppResolver) error {
+	servers, err := Parse(r)
+	if err != nil {
+		return err
+	}
+	return manager.ApplyEntries("nginx", fibervhosts.ResolveSpecs(Specs(servers), resolver))
+}
@@ -0,0 +1,114 @@
+package nginx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const config = `
+# Front-end servers
+http {
+    server {
+        listen 80 default_server;
+        server_name _;
+        root /var/www/default;
+    }
+
+    server {
+        listen 80;
+        server_name www.example.com .example.org "~^(?<sub>.+)\.example\.net$";
+
+        location /static/ {
+            root /srv;
+        }
+        location / {
+            proxy_pass http://127.0.0.1:3000;
+        }
+    }
+
+    server {
+        server_name api.example.com www.example.com;
+        location /v1 { proxy_pass http://api:8080; }
+    }
+}
+`
+
+// Test server blocks are parsed with their names, default flag and proxy target.
+func TestParse(t *testing.T) {
+	servers, err := Parse(strings.NewReader(config))
+	assert.NoError(t, err)
+	assert.Len(t, servers, 3)
+
+	assert.True(t, servers[0].Default)
+	assert.Equal(t, "/var/www/default", servers[0].Root)
+	assert.Equal(t, "default", servers[0].Backend())
+
+	assert.Equal(t, []string{"www.example.com", ".example.org", `~^(?<sub>.+)\.example\.net$`}, servers[1].Names)
+	assert.Equal(t, "http://127.0.0.1:3000", servers[1].ProxyPass)
+	assert.Equal(t, "http://api:8080", servers[2].Backend())
+}
+
+// Test invalid configurations are rejected.
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{"server {", "server }", `server_name "open;`, "{ }"} {
+		_, err := Parse(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+}
+
+// Test server names are converted into registrations, the first server winning duplicates.
+func TestSpecs(t *testing.T) {
+	servers, err := Parse(strings.NewReader(config))
+	assert.NoError(t, err)
+
+	specs := Specs(servers)
+	var hostnames []string
+	for _, spec := range specs {
+		hostnames = append(hostnames, spec.Hostname)
+	}
+	assert.Equal(t, []string{"", "www.example.com", "example.org", "*.example.org", "api.example.com"}, hostnames)
+	assert.Equal(t, "http://127.0.0.1:3000", specs[1].App)
+	assert.Equal(t, "http://127.0.0.1:3000", specs[1].Metadata.Get(MetadataProxyPass))
+}
+
+// Test ParseFile follows include directives.
+func TestParseFile_Include(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sites"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte("http { include sites/*.conf; }"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sites", "a.conf"), []byte("server { server_name a.example.com; }"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sites", "b.conf"), []byte("server { server_name b.example.com; }"), 0o644))
+
+	servers, err := ParseFile(filepath.Join(dir, "nginx.conf"))
+	assert.NoError(t, err)
+	assert.Len(t, servers, 2)
+}
+
+// Test importing into a manager.
+func TestImport(t *testing.T) {
+	web := fiber.New()
+	api := fiber.New()
+	fallback := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{
+		"default":               fallback,
+		"http://127.0.0.1:3000": web,
+		"http://api:8080":       api,
+	})
+
+	manager := fibervhosts.NewVhostsManager()
+	assert.NoError(t, Import(manager, strings.NewReader(config), resolver))
+	assert.Len(t, manager.ListEntries(), 5)
+
+	app, _ := manager.GetHostname("api.example.com")
+	assert.Equal(t, api, app)
+
+	// Unknown backends abort the import
+	err := Import(fibervhosts.NewVhostsManager(), strings.NewReader(config), fibervhosts.MapResolver(nil))
+	assert.ErrorIs(t, err, fibervhosts.ErrAppNotFound)
+}
@@ -0,0 +1,177 @@
+package nginx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// directive is a single parsed nginx directive with its arguments and, for block directives, its children
+type directive struct {
+	name  string
+	args  []string
+	block []directive
+}
+
+// parse reads an nginx configuration into its directive tree
+func parse(r io.Reader) ([]directive, error) {
+	t := &tokenizer{r: bufio.NewReader(r), line: 1}
+	directives, err := parseBlock(t, false)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", t.line, err)
+	}
+	return directives, nil
+}
+
+// parseBlock parses directives until the end of the input, or the closing brace when nested
+func parseBlock(t *tokenizer, nested bool) ([]directive, error) {
+	var directives []directive
+	var current *directive
+	for {
+		tok, quoted, err := t.next()
+		if errors.Is(err, io.EOF) {
+			if nested || current != nil {
+				return nil, errors.New("unexpected end of file")
+			}
+			return directives, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case quoted:
+		case tok == ";":
+			if current == nil {
+				return nil, errors.New(`unexpected ";"`)
+			}
+			directives = append(directives, *current)
+			current = nil
+			continue
+		case tok == "{":
+			if current == nil {
+				return nil, errors.New(`unexpected "{"`)
+			}
+			block, err := parseBlock(t, true)
+			if err != nil {
+				return nil, err
+			}
+			current.block = block
+			directives = append(directives, *current)
+			current = nil
+			continue
+		case tok == "}":
+			if !nested || current != nil {
+				return nil, errors.New(`unexpected "}"`)
+			}
+			return directives, nil
+		}
+
+		if current == nil {
+			current = &directive{name: tok}
+		} else {
+			current.args = append(current.args, tok)
+		}
+	}
+}
+
+// tokenizer splits an nginx configuration into words, quoted strings and the special characters ";", "{" and "}"
+type tokenizer struct {
+	r    *bufio.Reader
+	line int
+}
+
+// next returns the next token and whether it was quoted
+func (t *tokenizer) next() (string, bool, error) {
+	// Skip whitespace and comments
+	for {
+		c, err := t.read()
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case c == '#':
+			for c != '\n' {
+				if c, err = t.read(); err != nil {
+					return "", false, err
+				}
+			}
+		case c == ';' || c == '{' || c == '}':
+			return string(c), false, nil
+		case c == '"' || c == '\'':
+			return t.quoted(c)
+		case !isSpace(c):
+			t.r.UnreadByte()
+			return t.word()
+		}
+	}
+}
+
+// word reads an unquoted word
+func (t *tokenizer) word() (string, bool, error) {
+	var b strings.Builder
+	for {
+		c, err := t.read()
+		if errors.Is(err, io.EOF) {
+			return b.String(), false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if isSpace(c) || c == ';' || c == '{' || c == '}' {
+			if c == '\n' {
+				t.line--
+			}
+			t.r.UnreadByte()
+			return b.String(), false, nil
+		}
+		if c == '\\' {
+			if c, err = t.read(); err != nil {
+				return "", false, err
+			}
+			if !isSpace(c) && !strings.ContainsRune(`;{}"'\`, rune(c)) {
+				// Only special characters are escaped, like "\." stays as is
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteByte(c)
+	}
+}
+
+// quoted reads a string up to the closing quote
+func (t *tokenizer) quoted(quote byte) (string, bool, error) {
+	var b strings.Builder
+	for {
+		c, err := t.read()
+		if err != nil {
+			return "", false, errors.New("unterminated string")
+		}
+		switch c {
+		case quote:
+			return b.String(), true, nil
+		case '\\':
+			if c, err = t.read(); err != nil {
+				return "", false, errors.New("unterminated string")
+			}
+			if c != quote && c != '\\' {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteByte(c)
+	}
+}
+
+// read reads a byte, counting lines
+func (t *tokenizer) read() (byte, error) {
+	c, err := t.r.ReadByte()
+	if c == '\n' {
+		t.line++
+	}
+	return c, err
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
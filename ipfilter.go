@@ -0,0 +1,81 @@
+// This file contains the per-vhost IP allow and deny lists, like to limit an internal tool to the office network while public hostnames stay open.
+package fibervhosts
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var ErrInvalidCIDR = errors.New("invalid cidr")
+
+// IPFilter limits the client IPs allowed to access a hostname. Create it with NewIPFilter.
+type IPFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// NewIPFilter creates an IPFilter from lists of CIDRs like "10.0.0.0/8" or single IPs. Requests from IPs in deny are rejected; when allow is not empty, so are requests from IPs not in allow.
+func NewIPFilter(allow, deny []string) (*IPFilter, error) {
+	f := &IPFilter{}
+	var err error
+	if f.allow, err = parsePrefixes(allow); err != nil {
+		return nil, err
+	}
+	if f.deny, err = parsePrefixes(deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parsePrefixes parses a list of CIDRs and single IPs
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidCIDR, cidr)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCIDR, cidr)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// allows reports whether the filter lets addr through
+func (f *IPFilter) allows(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range f.deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, prefix := range f.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns 403 Forbidden for requests from IPs the filter does not let through, including requests without a valid client IP
+func (f *IPFilter) check(c *fiber.Ctx) error {
+	addr, err := netip.ParseAddr(c.IP())
+	if err != nil || !f.allows(addr) {
+		return fiber.ErrForbidden
+	}
+	return nil
+}
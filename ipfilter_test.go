@@ -0,0 +1,57 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test invalid CIDRs are rejected when creating a filter.
+func TestNewIPFilter_Invalid(t *testing.T) {
+	_, err := NewIPFilter([]string{"10.0.0.0/33"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidCIDR)
+	_, err = NewIPFilter(nil, []string{"not-an-ip"})
+	assert.ErrorIs(t, err, ErrInvalidCIDR)
+}
+
+// Test the IP filter of a hostname limits its clients, while other hostnames stay open.
+func TestVhostMiddleware_IPFilter(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	office, err := NewIPFilter([]string{"192.0.2.0/24", "2001:db8::/32"}, []string{"192.0.2.13"})
+	assert.NoError(t, err)
+	blocked, err := NewIPFilter(nil, []string{"198.51.100.0/24"})
+	assert.NoError(t, err)
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("admin.example.com", app, HostConfig{IPFilter: office}))
+	assert.NoError(t, manager.AddHostnameWithConfig("api.example.com", app, HostConfig{IPFilter: blocked}))
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+
+	main := fiber.New(fiber.Config{ProxyHeader: "X-Real-IP"})
+	main.Use(VhostMiddleware(manager))
+
+	for _, tc := range []struct {
+		hostname string
+		ip       string
+		want     int
+	}{
+		{"admin.example.com", "192.0.2.10", fiber.StatusNoContent},
+		{"admin.example.com", "2001:db8::1", fiber.StatusNoContent},
+		{"admin.example.com", "192.0.2.13", fiber.StatusForbidden},
+		{"admin.example.com", "203.0.113.1", fiber.StatusForbidden},
+		{"api.example.com", "203.0.113.1", fiber.StatusNoContent},
+		{"api.example.com", "198.51.100.7", fiber.StatusForbidden},
+		{"www.example.com", "198.51.100.7", fiber.StatusNoContent},
+	} {
+		req := httptest.NewRequest("GET", "http://"+tc.hostname+"/", nil)
+		req.Header.Set("X-Real-IP", tc.ip)
+		resp, err := main.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, resp.StatusCode, "%s from %s", tc.hostname, tc.ip)
+	}
+}
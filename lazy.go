@@ -0,0 +1,94 @@
+// This file contains lazily built sub-apps, registered with a factory that builds the app on the first request for the hostname.
+package fibervhosts
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrInvalidFactory is returned by AddHostnameFactory for a nil factory, and by the middleware when a factory builds no app
+var ErrInvalidFactory = errors.New("invalid app factory")
+
+// AppFactory builds the sub-app of a lazily registered hostname
+type AppFactory func() (*fiber.App, error)
+
+// appFactory builds the app of an entry once, even when the first requests arrive concurrently
+type appFactory struct {
+	mu    sync.Mutex
+	build AppFactory
+}
+
+// AddHostnameFactory registers a hostname whose sub-app is built by factory on the first request and reused afterwards, so thousands of tenants do not all have to be constructed at startup. Until then the registration has no app. Replacing the app, like with SwapApp, drops the factory. A failing factory answers the request with 503 Service Unavailable and is retried on the next request.
+func (m *VhostsManager) AddHostnameFactory(hostname string, factory AppFactory) error {
+	if hostname == "" {
+		return ErrInvalidHostname
+	}
+	if factory == nil {
+		return ErrInvalidFactory
+	}
+
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		if _, exists := table[key]; exists {
+			return nil, ErrHostExists
+		}
+
+		e := newEntry(hostname, nil)
+		e.factory = &appFactory{build: factory}
+		table[key] = e
+		return []ChangeEvent{added(e)}, nil
+	})
+}
+
+// resolveApp returns the app of e, building it with the factory of e on first use. The built app is reported to the OnUpdate hooks; the factory is kept to build the app again after it was evicted, see EvictIdle.
+func (m *VhostsManager) resolveApp(e *entry) (*fiber.App, error) {
+	m.mu.RLock()
+	app, f := e.app, e.factory
+	m.mu.RUnlock()
+	switch {
+	case app != nil:
+		return app, nil
+	case f == nil:
+		return nil, ErrAppNotFound
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Another request may have built it while waiting
+	m.mu.RLock()
+	app = e.app
+	m.mu.RUnlock()
+	if app != nil {
+		return app, nil
+	}
+
+	app, err := f.build()
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, ErrInvalidFactory
+	}
+
+	_ = m.update(func() ([]ChangeEvent, error) {
+		// Skip entries whose app was replaced or removed meanwhile
+		if e.factory != f {
+			return nil, nil
+		}
+		previous := e.toEntry()
+		e.app = app
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+	return app, nil
+}
+
+// builtDispatch returns the dispatch chain of the app resolveApp returned for a request matched to e by hostname before the app was built: the chain of the route published with the app, so it is composed once, or a chain of its own when the route changed meanwhile
+func (m *VhostsManager) builtDispatch(hostname string, e *entry, app *fiber.App, config HostConfig) dispatchFunc {
+	if r := m.lookup.Load().resolve(hostname); r != nil && r.entry == e && r.app == app && r.dispatch != nil && r.evictions == e.evictions.Load() {
+		return r.dispatch
+	}
+	return m.compose(appBackend{app}, config)
+}
@@ -0,0 +1,120 @@
+package fibervhosts
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test a lazily registered app is built once on the first request and then reused.
+func TestVhostsManager_AddHostnameFactory(t *testing.T) {
+	var builds atomic.Int32
+	manager := NewVhostsManager()
+	assert.ErrorIs(t, manager.AddHostnameFactory("nil.example.com", nil), ErrInvalidFactory)
+	assert.NoError(t, manager.AddHostnameFactory("tenant.example.com", func() (*fiber.App, error) {
+		builds.Add(1)
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendString("tenant") })
+		return app, nil
+	}))
+	assert.ErrorIs(t, manager.AddHostnameFactory("tenant.example.com", func() (*fiber.App, error) { return nil, nil }), ErrHostExists)
+
+	var updates atomic.Int32
+	manager.Hooks().OnUpdate(func(previous, current Entry) {
+		assert.Nil(t, previous.App)
+		assert.NotNil(t, current.App)
+		updates.Add(1)
+	})
+
+	app, exists := manager.GetHostname("tenant.example.com")
+	assert.True(t, exists)
+	assert.Nil(t, app)
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := main.Test(httptest.NewRequest("GET", "http://tenant.example.com/", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), builds.Load())
+	assert.Equal(t, int32(1), updates.Load())
+	app, _ = manager.GetHostname("tenant.example.com")
+	assert.NotNil(t, app)
+}
+
+// Test a failing factory answers 503 and is retried on the next request.
+func TestVhostsManager_AddHostnameFactory_Error(t *testing.T) {
+	var builds atomic.Int32
+	manager := NewVhostsManager(Config{Logger: &recordingLogger{}})
+	assert.NoError(t, manager.AddHostnameFactory("tenant.example.com", func() (*fiber.App, error) {
+		if builds.Add(1) == 1 {
+			return nil, errors.New("database unavailable")
+		}
+		return fiber.New(), nil
+	}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	resp, err := main.Test(httptest.NewRequest("GET", "http://tenant.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://tenant.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, int32(2), builds.Load())
+}
+
+// Test requests waiting for a lazily built app share the dispatch chain published with it instead of composing their own.
+func TestVhostsManager_AddHostnameFactoryConcurrent(t *testing.T) {
+	const n = 8
+	var matched atomic.Int32
+	manager := NewVhostsManager(Config{RequestID: &RequestID{Generator: func() string {
+		matched.Add(1)
+		return "id"
+	}}})
+	assert.NoError(t, manager.AddHostnameFactory("tenant.example.com", func() (*fiber.App, error) {
+		for deadline := time.Now().Add(time.Second); matched.Load() < n && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		return fiber.New(), nil
+	}))
+
+	var mu sync.Mutex
+	chains := make(map[*fiber.App]struct{})
+	assert.NoError(t, manager.SetHostConfig("tenant.example.com", HostConfig{Middleware: []fiber.Handler{func(c *fiber.Ctx) error {
+		mu.Lock()
+		chains[c.App()] = struct{}{}
+		mu.Unlock()
+		return c.Next()
+	}}}))
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := manager.Test("tenant.example.com", NewTestRequest("GET", "", "/", nil))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(n), matched.Load())
+	assert.Len(t, chains, 1)
+}
@@ -0,0 +1,61 @@
+// This file contains the per-vhost lifecycle callbacks, which give per-tenant resources like database connections and caches a managed lifecycle: OnStart runs when the registration is added and OnStop when it is removed or the manager shuts down.
+package fibervhosts
+
+import "fmt"
+
+// LifecycleHook is a per-vhost lifecycle callback, see HostConfig.OnStart and HostConfig.OnStop
+type LifecycleHook func(e Entry) error
+
+// startEntry runs the OnStart callback of a newly added entry
+func (m *VhostsManager) startEntry(e *entry) error {
+	m.mu.RLock()
+	start, current := e.config.OnStart, e.toEntry()
+	m.mu.RUnlock()
+
+	if start == nil {
+		return nil
+	}
+	return start(current)
+}
+
+// stopEntry runs the OnStop callback of an entry, at most once per registration
+func (m *VhostsManager) stopEntry(e *entry) error {
+	m.mu.RLock()
+	stop, current := e.config.OnStop, e.toEntry()
+	m.mu.RUnlock()
+
+	if stop == nil || !e.stopped.CompareAndSwap(false, true) {
+		return nil
+	}
+	if err := stop(current); err != nil {
+		return fmt.Errorf("stop %s: %w", current.Pattern, err)
+	}
+	return nil
+}
+
+// stopRemoved runs the OnStop callbacks of the removed entries among the events. Entries still in the table, like renamed ones, keep running. Errors are logged.
+func (m *VhostsManager) stopRemoved(events []ChangeEvent) {
+	for _, ev := range events {
+		if ev.Type != ChangeRemoved || ev.entry == nil {
+			continue
+		}
+		if m.registered(ev.entry) {
+			continue
+		}
+		if err := m.stopEntry(ev.entry); err != nil {
+			m.logger.Warn("Stopping registration failed", "hostname", ev.Entry.Pattern, "error", err)
+		}
+	}
+}
+
+// registered reports whether the entry is still in the table
+func (m *VhostsManager) registered(e *entry) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if e.kind == EntryDefault {
+		return m.defaultApp == e
+	}
+	table, key := m.tableFor(e.pattern)
+	return table[key] == e
+}
@@ -0,0 +1,56 @@
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test OnStart runs on add and OnStop once on removal, but not on rename.
+func TestHostConfig_Lifecycle(t *testing.T) {
+	var calls []string
+	config := HostConfig{
+		OnStart: func(e Entry) error {
+			calls = append(calls, "start "+e.Pattern)
+			return nil
+		},
+		OnStop: func(e Entry) error {
+			calls = append(calls, "stop "+e.Pattern)
+			return nil
+		},
+	}
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("a.example.com", fiber.New(), config))
+	assert.NoError(t, manager.RenameHostname("a.example.com", "b.example.com"))
+	assert.NoError(t, manager.RemoveHostname("b.example.com"))
+	assert.Equal(t, []string{"start a.example.com", "stop b.example.com"}, calls)
+
+	// Shutdown stops the remaining registrations, and a later removal does not stop them again
+	calls = nil
+	assert.NoError(t, manager.AddHostnameWithConfig("c.example.com", fiber.New(), config))
+	assert.NoError(t, manager.Shutdown(context.Background()))
+	manager.Clear()
+	assert.Equal(t, []string{"start c.example.com", "stop c.example.com"}, calls)
+}
+
+// Test a failing OnStart removes the registration without stopping it.
+func TestHostConfig_LifecycleStartError(t *testing.T) {
+	stopped := false
+	manager := NewVhostsManager()
+	err := manager.AddHostnameWithConfig("a.example.com", fiber.New(), HostConfig{
+		OnStart: func(Entry) error { return errors.New("no database") },
+		OnStop: func(Entry) error {
+			stopped = true
+			return nil
+		},
+	})
+	assert.ErrorContains(t, err, "no database")
+	assert.False(t, stopped)
+
+	_, exists := manager.GetHostname("a.example.com")
+	assert.False(t, exists)
+}
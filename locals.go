@@ -0,0 +1,66 @@
+// This file contains the c.Locals keys under which the middleware exposes how a request was routed, so sub-apps and the handlers of the main app can make decisions based on it.
+package fibervhosts
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// LocalsMatchedKey holds a bool reporting whether the request matched a registration, including the default app. It is false for requests answered by Config.NotFoundHandler or passed on with Config.Fallthrough.
+	LocalsMatchedKey = "vhost.matched"
+	// LocalsPatternKey holds the pattern of the matched registration as a string, like "*.example.com", which is empty for the default app
+	LocalsPatternKey = "vhost.pattern"
+	// LocalsHostnameKey holds the hostname the request was matched by as a string, lowercase and without a port, which tells the aliases of a shared registration apart, see AddAliases
+	LocalsHostnameKey = "vhost.hostname"
+	// LocalsMatchTypeKey holds the EntryType of the matched registration, telling exact, wildcard and default app matches apart
+	LocalsMatchTypeKey = "vhost.matchType"
+	// LocalsMetadataKey is the c.Locals key under which the middleware exposes the Metadata of the matched registration to the sub-app
+	LocalsMetadataKey = "vhost.metadata"
+	// LocalsFeaturesKey holds the FeatureFlags of the matched registration, see Features and FeatureEnabled
+	LocalsFeaturesKey = "vhost.features"
+	// LocalsSettingsKey holds the HostConfig.Settings of the matched registration as a map[string]any, see Settings and Setting
+	LocalsSettingsKey = "vhost.settings"
+	// LocalsTenantKey holds the Tenant of the request when tenant resolution is enabled, see TenantResolver and GetTenant
+	LocalsTenantKey = "vhost.tenant"
+	// LocalsRequestIDKey holds the request ID as a string when request IDs are enabled, see RequestID
+	LocalsRequestIDKey = "vhost.requestID"
+)
+
+// matchLocals holds the Locals values of the matches of a route, boxed into interfaces once when the route is built
+type matchLocals struct {
+	pattern   any
+	matchType any
+	metadata  any
+	settings  any
+}
+
+// newMatchLocals boxes the Locals values of the matches of r
+func newMatchLocals(r *route) matchLocals {
+	return matchLocals{
+		pattern:   r.info.Pattern,
+		matchType: r.info.Type,
+		metadata:  r.info.Metadata,
+		settings:  r.config.Settings,
+	}
+}
+
+// setMatchLocals exposes the match of a request to r in its Locals. r is nil for unmatched requests.
+func setMatchLocals(c *fiber.Ctx, r *route) {
+	if r == nil {
+		c.Locals(LocalsMatchedKey, false)
+		return
+	}
+	c.Locals(LocalsMatchedKey, true)
+	c.Locals(LocalsPatternKey, r.locals.pattern)
+	if hostname := normalizeHostname(c.Hostname()); r.info.Type == EntryHost && hostname == r.info.Pattern {
+		c.Locals(LocalsHostnameKey, r.locals.pattern)
+	} else {
+		// The hostname points into the request buffer, which is reused once the request is done
+		c.Locals(LocalsHostnameKey, strings.Clone(hostname))
+	}
+	c.Locals(LocalsMatchTypeKey, r.locals.matchType)
+	c.Locals(LocalsMetadataKey, r.locals.metadata)
+	c.Locals(LocalsSettingsKey, r.locals.settings)
+}
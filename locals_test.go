@@ -0,0 +1,49 @@
+package fibervhosts
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the middleware exposes how a request was routed to the sub-apps and, for unmatched requests, to the main app.
+func TestVhostMiddleware_MatchLocals(t *testing.T) {
+	describe := func(c *fiber.Ctx) error {
+		return c.SendString(fmt.Sprint(c.Locals(LocalsMatchedKey), " ", c.Locals(LocalsMatchTypeKey), " ", c.Locals(LocalsPatternKey)))
+	}
+	newApp := func() *fiber.App {
+		app := fiber.New()
+		app.Get("/", describe)
+		return app
+	}
+
+	manager := NewVhostsManager(Config{Fallthrough: true})
+	assert.NoError(t, manager.AddHostname("www.example.com", newApp()))
+	assert.NoError(t, manager.AddHostname("*.example.org", newApp()))
+
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+	mainApp.Get("/", describe)
+
+	request := func(hostname string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = hostname
+		resp, err := mainApp.Test(req)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return string(body)
+	}
+
+	assert.Equal(t, "true host www.example.com", request("www.example.com"))
+	assert.Equal(t, "true wildcard *.example.org", request("api.example.org"))
+	assert.Equal(t, "false <nil> <nil>", request("nonexistent.com"))
+
+	manager.SetDefaultApp(newApp())
+	assert.Equal(t, "true default ", request("nonexistent.com"))
+}
@@ -0,0 +1,38 @@
+// This file contains the Logger interface the manager and the middleware log through, and the default implementation writing to fiber's log package.
+package fibervhosts
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Logger receives the logs of the manager and the middleware. Fields are alternating keys and values, like "hostname", "api.example.com". A *slog.Logger satisfies it as is; zap's SugaredLogger, zerolog and others need a small adapter.
+type Logger interface {
+	Debug(msg string, fields ...any)
+	Info(msg string, fields ...any)
+	Warn(msg string, fields ...any)
+	Error(msg string, fields ...any)
+}
+
+// fiberLogger is the default Logger, writing to fiber's global log package
+type fiberLogger struct{}
+
+// DefaultLogger returns the Logger used when none is configured, which writes to fiber's log package
+func DefaultLogger() Logger {
+	return fiberLogger{}
+}
+
+func (fiberLogger) Debug(msg string, fields ...any) { log.Debugw(msg, fields...) }
+func (fiberLogger) Info(msg string, fields ...any)  { log.Infow(msg, fields...) }
+func (fiberLogger) Warn(msg string, fields ...any)  { log.Warnw(msg, fields...) }
+func (fiberLogger) Error(msg string, fields ...any) { log.Errorw(msg, fields...) }
+
+// fasthttpLogger adapts a Logger to the fasthttp.Logger of the request contexts the manager creates, logging at the error level like fasthttp does
+type fasthttpLogger struct {
+	logger Logger
+}
+
+func (l fasthttpLogger) Printf(format string, args ...any) {
+	l.logger.Error(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,67 @@
+package fibervhosts
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingLogger records the messages and fields it receives
+type recordingLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+type logRecord struct {
+	level  string
+	msg    string
+	fields []any
+}
+
+func (l *recordingLogger) record(level, msg string, fields []any) {
+	l.mu.Lock()
+	l.records = append(l.records, logRecord{level, msg, fields})
+	l.mu.Unlock()
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...any) { l.record("debug", msg, fields) }
+func (l *recordingLogger) Info(msg string, fields ...any)  { l.record("info", msg, fields) }
+func (l *recordingLogger) Warn(msg string, fields ...any)  { l.record("warn", msg, fields) }
+func (l *recordingLogger) Error(msg string, fields ...any) { l.record("error", msg, fields) }
+
+// Test the middleware logs through the configured logger with the hostname as a field.
+func TestVhostMiddleware_Logger(t *testing.T) {
+	logger := &recordingLogger{}
+	manager := NewVhostsManager(Config{EnableLogging: true, Logger: logger, AccessLog: &AccessLog{Disabled: true}})
+	assert.NoError(t, manager.AddHostname("api.example.com", fiber.New()))
+
+	app := fiber.New()
+	app.Use(VhostMiddleware(manager))
+
+	_, err := app.Test(httptest.NewRequest("GET", "http://api.example.com/", nil))
+	assert.NoError(t, err)
+	_, err = app.Test(httptest.NewRequest("GET", "http://unknown.example.com/", nil))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []logRecord{
+		{"debug", "Dispatching request", []any{"hostname", "api.example.com", "pattern", "api.example.com"}},
+		{"warn", "No application found", []any{"hostname", "unknown.example.com"}},
+	}, logger.records)
+}
+
+// Test a *slog.Logger can be used as the logger.
+func TestConfig_SlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	manager := NewVhostsManager(Config{EnableLogging: true, Logger: slog.New(slog.NewTextHandler(&buf, nil)), AccessLog: &AccessLog{Disabled: true}})
+
+	app := fiber.New()
+	app.Use(VhostMiddleware(manager))
+	_, err := app.Test(httptest.NewRequest("GET", "http://unknown.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `level=WARN msg="No application found" hostname=unknown.example.com`)
+}
@@ -0,0 +1,87 @@
+// This file contains the per-vhost log policy, which sets the log level and the sampling rate of the requests of a hostname, so a noisy high-traffic tenant does not drown the logs of the others.
+package fibervhosts
+
+import "math/rand/v2"
+
+// LogLevel is the minimum level of the logs of a hostname
+type LogLevel int
+
+const (
+	// LogLevelDebug passes all logs
+	LogLevelDebug LogLevel = iota
+	// LogLevelInfo drops debug logs
+	LogLevelInfo
+	// LogLevelWarn drops debug and info logs
+	LogLevelWarn
+	// LogLevelError only passes errors
+	LogLevelError
+	// LogLevelOff drops all logs, including the access log
+	LogLevelOff
+)
+
+// LogPolicy defines how the requests of a hostname are logged. Set it on Config for all hostnames, or on HostConfig to override it for a single registration.
+type LogPolicy struct {
+	// Level is the minimum level of the per-request logs. Defaults to LogLevelDebug, passing everything to the Logger.
+	Level LogLevel
+	// SampleRate is the fraction of requests that are logged, like 0.01 for 1%. The access log entry and the other logs of a request are sampled together. Values outside (0, 1) log every request.
+	SampleRate float64
+}
+
+// sample decides whether a request is logged
+func (p *LogPolicy) sample() bool {
+	if p.Level >= LogLevelOff {
+		return false
+	}
+	if p.SampleRate <= 0 || p.SampleRate >= 1 {
+		return true
+	}
+	return rand.Float64() < p.SampleRate
+}
+
+// requestLogger returns the logger for a request dispatched to r and whether the request is sampled, applying the log policy of r or else the one of the manager
+func (m *VhostsManager) requestLogger(r *route) (Logger, bool) {
+	policy := m.logPolicy
+	if r.config.Logging != nil {
+		policy = r.config.Logging
+	}
+	if policy == nil {
+		return m.logger, true
+	}
+	if !policy.sample() {
+		return levelLogger{m.logger, LogLevelOff}, false
+	}
+	if policy.Level == LogLevelDebug {
+		return m.logger, true
+	}
+	return levelLogger{m.logger, policy.Level}, true
+}
+
+// levelLogger drops the logs below its level
+type levelLogger struct {
+	Logger
+	level LogLevel
+}
+
+func (l levelLogger) Debug(msg string, fields ...any) {
+	if l.level <= LogLevelDebug {
+		l.Logger.Debug(msg, fields...)
+	}
+}
+
+func (l levelLogger) Info(msg string, fields ...any) {
+	if l.level <= LogLevelInfo {
+		l.Logger.Info(msg, fields...)
+	}
+}
+
+func (l levelLogger) Warn(msg string, fields ...any) {
+	if l.level <= LogLevelWarn {
+		l.Logger.Warn(msg, fields...)
+	}
+}
+
+func (l levelLogger) Error(msg string, fields ...any) {
+	if l.level <= LogLevelError {
+		l.Logger.Error(msg, fields...)
+	}
+}
@@ -0,0 +1,53 @@
+package fibervhosts
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the log level and sampling rate of a hostname apply to its request logs and access log.
+func TestVhostMiddleware_LogPolicy(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	logger := &recordingLogger{}
+	var access bytes.Buffer
+	manager := NewVhostsManager(Config{
+		EnableLogging: true,
+		Logger:        logger,
+		AccessLog:     &AccessLog{Output: &access},
+	})
+	assert.NoError(t, manager.AddHostname("chatty.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("warn.example.com", app, HostConfig{Logging: &LogPolicy{Level: LogLevelWarn}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("sampled.example.com", app, HostConfig{Logging: &LogPolicy{SampleRate: 0.1}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("off.example.com", app, HostConfig{Logging: &LogPolicy{Level: LogLevelOff}}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(host string) {
+		_, err := main.Test(httptest.NewRequest("GET", "http://"+host+"/", nil))
+		assert.NoError(t, err)
+	}
+
+	request("chatty.example.com")
+	request("warn.example.com")
+	request("off.example.com")
+	assert.Len(t, logger.records, 1)
+	assert.Equal(t, "chatty.example.com", logger.records[0].fields[1])
+	// The level only filters the logs, the access log is kept unless logging is off
+	assert.Equal(t, 2, strings.Count(access.String(), "\n"))
+
+	access.Reset()
+	for range 1000 {
+		request("sampled.example.com")
+	}
+	lines := strings.Count(access.String(), "\n")
+	assert.Greater(t, lines, 30)
+	assert.Less(t, lines, 200)
+	assert.Len(t, logger.records, 1+lines)
+}
@@ -0,0 +1,213 @@
+// This file contains the lookup table the middleware matches requests against: an immutable copy of the registrations that is updated under the write lock on every change and swapped in atomically, so requests are matched without taking the lock.
+package fibervhosts
+
+import (
+	"hash/maphash"
+	"maps"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// lookupShards is the number of shards the hostnames of the lookup table are spread over. A change copies only the shards of the hostnames it touches, so a single change in a table of 100k hostnames copies about 1.5k of them.
+const lookupShards = 64
+
+// lookupTable is an immutable view of the registrations. It is never modified once published; unchanged shards and the wildcard tree are shared with the tables published after it.
+type lookupTable struct {
+	seed      maphash.Seed
+	hosts     [lookupShards]map[string]*route
+	wildcards *wildcardNode
+	fallback  *route
+	cache     *lookupCache
+}
+
+// route is an entry as seen by the middleware. It holds the settings of the entry at the time the table was built, as the entry itself may change under the write lock meanwhile; its atomic state like the suspended flag is read from the entry.
+type route struct {
+	entry *entry
+	app   *fiber.App
+	// dispatch is the dispatch chain of the backend of the entry, nil while a lazily built app was not built yet
+	dispatch dispatchFunc
+	config   HostConfig
+	info     Entry
+	// evictions is the eviction count of the entry the dispatch chain was composed at
+	evictions uint64
+	// locals holds the values of the match Locals, boxed once instead of on every request, see setMatchLocals
+	locals matchLocals
+}
+
+// newRoute captures the current settings of e and composes its dispatch chain. The caller must hold the lock.
+func (m *VhostsManager) newRoute(e *entry) *route {
+	r := &route{entry: e, app: e.app, config: e.config, info: e.toEntry(), evictions: e.evictions.Load()}
+	r.locals = newMatchLocals(r)
+	if b := e.backend(); b != nil {
+		r.dispatch = m.compose(b, r.config)
+	}
+	return r
+}
+
+// buildLookup builds the lookup table from all registrations and swaps it in. The caller must hold the write lock.
+func (m *VhostsManager) buildLookup() {
+	t := &lookupTable{seed: maphash.MakeSeed(), cache: newLookupCache(m.lookupCacheSize)}
+	for i := range t.hosts {
+		t.hosts[i] = make(map[string]*route, len(m.hosts)/lookupShards)
+	}
+	for key, e := range m.hosts {
+		t.hosts[t.shard(key)][key] = m.newRoute(e)
+	}
+	for alias, e := range m.aliases {
+		if _, registered := m.hosts[alias]; !registered {
+			t.hosts[t.shard(alias)][alias] = t.hosts[t.shard(e.pattern)][e.pattern]
+		}
+	}
+	t.wildcards = m.buildWildcards()
+	if m.defaultApp != nil {
+		t.fallback = m.newRoute(m.defaultApp)
+	}
+	m.lookup.Store(t)
+}
+
+// publishLookup swaps in a lookup table with the registrations of the given patterns updated, copying only the shards holding them. The empty pattern stands for the default app, and aliases are patterns as well. The aliases of updated registrations follow their new route; those of removed registrations are dropped. The caller must hold the write lock.
+func (m *VhostsManager) publishLookup(patterns ...string) {
+	prev := m.lookup.Load()
+	t := &lookupTable{seed: prev.seed, hosts: prev.hosts, wildcards: prev.wildcards, fallback: prev.fallback, cache: newLookupCache(m.lookupCacheSize)}
+
+	var copied [lookupShards]bool
+	copyShard := func(i int) {
+		if !copied[i] {
+			t.hosts[i] = maps.Clone(prev.hosts[i])
+			copied[i] = true
+		}
+	}
+	wildcards := false
+	var aliases []string
+	for _, pattern := range patterns {
+		switch {
+		case pattern == "":
+			t.fallback = nil
+			if m.defaultApp != nil {
+				t.fallback = m.newRoute(m.defaultApp)
+			}
+		case isWildcard(pattern):
+			wildcards = true
+		default:
+			i := t.shard(pattern)
+			copyShard(i)
+			if r, exists := prev.hosts[i][pattern]; exists && len(r.entry.aliases) > 0 && m.hosts[r.entry.pattern] != r.entry {
+				// The registration is gone, and so are its aliases
+				for _, alias := range r.entry.aliases {
+					if m.aliases[alias] == r.entry {
+						delete(m.aliases, alias)
+					}
+				}
+				aliases = append(aliases, r.entry.aliases...)
+				r.entry.aliases = nil
+			}
+			if e, exists := m.hosts[pattern]; exists {
+				t.hosts[i][pattern] = m.newRoute(e)
+				aliases = append(aliases, e.aliases...)
+			} else if _, aliased := m.aliases[pattern]; aliased {
+				aliases = append(aliases, pattern)
+			} else {
+				delete(t.hosts[i], pattern)
+			}
+		}
+	}
+	// Aliases share the route of their registration, once it is updated
+	for _, alias := range aliases {
+		if _, registered := m.hosts[alias]; registered {
+			continue
+		}
+		i := t.shard(alias)
+		copyShard(i)
+		if e, aliased := m.aliases[alias]; aliased {
+			t.hosts[i][alias] = t.hosts[t.shard(e.pattern)][e.pattern]
+		} else {
+			delete(t.hosts[i], alias)
+		}
+	}
+	// Wildcards are few compared to hostnames, so their tree is rebuilt as a whole
+	if wildcards {
+		t.wildcards = m.buildWildcards()
+	}
+	m.lookup.Store(t)
+}
+
+// buildWildcards builds the wildcard tree from the registrations. The caller must hold the lock.
+func (m *VhostsManager) buildWildcards() *wildcardNode {
+	root := &wildcardNode{}
+	for key, e := range m.wildcards {
+		root.insert(key, m.newRoute(e))
+	}
+	return root
+}
+
+// shard returns the shard a hostname is stored in
+func (t *lookupTable) shard(hostname string) int {
+	return int(maphash.String(t.seed, hostname) % lookupShards)
+}
+
+// lookupHost returns the route of an exact hostname
+func (t *lookupTable) lookupHost(hostname string) (*route, bool) {
+	r, exists := t.hosts[t.shard(hostname)][hostname]
+	return r, exists
+}
+
+// match finds the route for a given hostname, trying exact match first, then wildcard match, and finally returning the default app route if no match is found. Apart from an exact match of the hostname as requested, like a registration including the port, the hostname is normalized first, see normalizeHostname. Matching does not allocate.
+func (t *lookupTable) match(hostname string) *route {
+	if r, exists := t.lookupHost(hostname); exists {
+		return r
+	}
+	hostname = normalizeHostname(hostname)
+	if r, exists := t.lookupHost(hostname); exists {
+		return r
+	}
+	if r := t.wildcards.match(hostname); r != nil {
+		return r
+	}
+	return t.fallback
+}
+
+// toEntry returns the public description of the route
+func (r *route) toEntry() Entry {
+	info := r.info
+	info.Suspended = r.entry.suspended.Load()
+	info.Pending = r.entry.pending.Load()
+	info.Metadata = info.Metadata.clone()
+	return info
+}
+
+// NormalizeHostname returns hostname the way requests are matched against the registrations: without the port and a trailing dot, and lowercased, see VhostMiddleware
+func NormalizeHostname(hostname string) string {
+	return normalizeHostname(hostname)
+}
+
+// normalizeHostname strips the port and a trailing dot from a request hostname and lowercases it, so "WWW.Example.com.:8080" matches "www.example.com". It returns a substring of hostname and only allocates for hostnames with uppercase letters, which fasthttp already lowercases in the Host header.
+func normalizeHostname(hostname string) string {
+	// IPv6 literals like "[::1]" keep their colons
+	if i := strings.LastIndexByte(hostname, ':'); i >= 0 && strings.IndexByte(hostname[i:], ']') < 0 {
+		hostname = hostname[:i]
+	}
+	hostname = strings.TrimSuffix(hostname, ".")
+	for i := 0; i < len(hostname); i++ {
+		if 'A' <= hostname[i] && hostname[i] <= 'Z' {
+			return strings.ToLower(hostname)
+		}
+	}
+	return hostname
+}
+
+// changedPatterns returns the patterns of the registrations changed by the events, for publishLookup
+func changedPatterns(events []ChangeEvent) []string {
+	patterns := make([]string, 0, len(events))
+	for _, ev := range events {
+		patterns = append(patterns, ev.Entry.Pattern)
+		if ev.Type == ChangeUpdated && ev.Previous.Pattern != ev.Entry.Pattern {
+			patterns = append(patterns, ev.Previous.Pattern)
+		}
+		if ev.Type == ChangeUpdated {
+			// Dropped aliases are no longer refreshed with the registration
+			patterns = append(patterns, ev.Previous.Aliases...)
+		}
+	}
+	return patterns
+}
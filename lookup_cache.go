@@ -0,0 +1,71 @@
+// This file contains the optional LRU cache of the lookup table, which remembers the route of recently requested hostnames so repeat requests skip matching entirely.
+package fibervhosts
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// lookupCache is a bounded LRU cache of hostname lookups. It belongs to a single lookup table, so publishing a new table after a change starts with an empty cache.
+type lookupCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+// lookupCacheItem is a cached lookup. A nil route caches a hostname without a match.
+type lookupCacheItem struct {
+	hostname string
+	route    *route
+}
+
+// newLookupCache creates a cache holding up to size lookups, or returns nil if size is not positive
+func newLookupCache(size int) *lookupCache {
+	if size <= 0 {
+		return nil
+	}
+	return &lookupCache{size: size, order: list.New(), items: make(map[string]*list.Element, size)}
+}
+
+// get returns the cached route of a hostname and whether it was cached
+func (c *lookupCache) get(hostname string) (*route, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[hostname]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lookupCacheItem).route, true
+}
+
+// add caches the route of a hostname, evicting the least recently used lookup when full. The hostname is copied, as it may point into a request buffer.
+func (c *lookupCache) add(hostname string, r *route) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[hostname]; ok {
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lookupCacheItem).hostname)
+	}
+	item := &lookupCacheItem{hostname: strings.Clone(hostname), route: r}
+	c.items[item.hostname] = c.order.PushFront(item)
+}
+
+// resolve returns the route for a request hostname like match, going through the cache when it is enabled. The cache is keyed by the hostname as requested, so hits skip normalization too.
+func (t *lookupTable) resolve(hostname string) *route {
+	if t.cache == nil {
+		return t.match(hostname)
+	}
+	if r, ok := t.cache.get(hostname); ok {
+		return r
+	}
+	r := t.match(hostname)
+	t.cache.add(hostname, r)
+	return r
+}
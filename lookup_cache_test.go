@@ -0,0 +1,58 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the lookup cache evicts the least recently used hostname.
+func TestLookupCache_Evict(t *testing.T) {
+	cache := newLookupCache(2)
+	a, b := &route{}, &route{}
+	cache.add("a.example.com", a)
+	cache.add("b.example.com", b)
+
+	r, ok := cache.get("a.example.com")
+	assert.True(t, ok)
+	assert.Same(t, a, r)
+
+	// Misses are cached too
+	cache.add("c.example.com", nil)
+	_, ok = cache.get("b.example.com")
+	assert.False(t, ok)
+	r, ok = cache.get("c.example.com")
+	assert.True(t, ok)
+	assert.Nil(t, r)
+	_, ok = cache.get("a.example.com")
+	assert.True(t, ok)
+
+	assert.Nil(t, newLookupCache(0))
+}
+
+// Test cached lookups are dropped when the table changes.
+func TestVhostsManager_LookupCache(t *testing.T) {
+	manager := NewVhostsManager(Config{LookupCacheSize: 10})
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	assert.NoError(t, manager.AddHostname("*.example.com", app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	status := func() int {
+		resp, err := main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+	assert.Equal(t, fiber.StatusOK, status())
+	r, ok := manager.lookup.Load().cache.get("www.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "*.example.com", r.info.Pattern)
+
+	assert.NoError(t, manager.RemoveHostname("*.example.com"))
+	_, ok = manager.lookup.Load().cache.get("www.example.com")
+	assert.False(t, ok)
+	assert.Equal(t, fiber.StatusNotFound, status())
+}
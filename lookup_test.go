@@ -0,0 +1,110 @@
+package fibervhosts
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test changes publish a new lookup table while published tables stay unchanged.
+func TestVhostsManager_PublishLookup(t *testing.T) {
+	defaultApp := fiber.New()
+	manager := NewVhostsManager(Config{DefaultApp: defaultApp})
+	initial := manager.lookup.Load()
+	assert.Equal(t, defaultApp, initial.match("www.example.com").app)
+
+	app := fiber.New()
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostname("*.example.com", fiber.New()))
+	table := manager.lookup.Load()
+	assert.Equal(t, app, table.match("www.example.com").app)
+	assert.Equal(t, "*.example.com", table.match("api.example.com").info.Pattern)
+	assert.Equal(t, defaultApp, initial.match("www.example.com").app)
+
+	// Settings changed without a change event are published too
+	assert.NoError(t, manager.SetHostConfig("www.example.com", HostConfig{SlowRequestThreshold: 1}))
+	assert.Equal(t, HostConfig{SlowRequestThreshold: 1}, manager.lookup.Load().match("www.example.com").config)
+	assert.Zero(t, table.match("www.example.com").config.SlowRequestThreshold)
+
+	// Failed changes keep the table
+	table = manager.lookup.Load()
+	assert.ErrorIs(t, manager.AddHostname("www.example.com", fiber.New()), ErrHostExists)
+	assert.Same(t, table, manager.lookup.Load())
+}
+
+// Test request hostnames are normalized before matching.
+func TestNormalizeHostname(t *testing.T) {
+	for hostname, normalized := range map[string]string{
+		"www.example.com":      "www.example.com",
+		"www.example.com:8080": "www.example.com",
+		"www.example.com.":     "www.example.com",
+		"WWW.Example.COM.:443": "www.example.com",
+		"[::1]":                "[::1]",
+		"[::1]:8080":           "[::1]",
+		"":                     "",
+	} {
+		assert.Equal(t, normalized, normalizeHostname(hostname), hostname)
+	}
+}
+
+// Test matching exact and wildcard hostnames does not allocate.
+func TestLookupTable_MatchAllocs(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("www.example.com", fiber.New()))
+	assert.NoError(t, manager.AddHostname("*.example.org", fiber.New()))
+	assert.NoError(t, manager.AddHostname("127.0.0.1:3000", fiber.New()))
+	table := manager.lookup.Load()
+	assert.Equal(t, "127.0.0.1:3000", table.match("127.0.0.1:3000").info.Pattern)
+	assert.Equal(t, "www.example.com", table.match("WWW.example.com.").info.Pattern)
+
+	for _, hostname := range []string{"www.example.com", "www.example.com:8080", "127.0.0.1:3000", "a.b.example.org", "missing.example.net"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			table.match(hostname)
+		})
+		assert.Zero(t, allocs, hostname)
+	}
+}
+
+// Test a change copies only the shards it touches and keeps the table in line with the registrations.
+func TestVhostsManager_PublishLookupShards(t *testing.T) {
+	manager := NewVhostsManager()
+	apps := make(map[string]*fiber.App)
+	for i := 0; i < 1000; i++ {
+		apps[fmt.Sprintf("host%d.example.com", i)] = fiber.New()
+	}
+	assert.NoError(t, manager.AddHostnames(apps))
+
+	before := manager.lookup.Load()
+	assert.NoError(t, manager.AddHostname("new.example.com", fiber.New()))
+	after := manager.lookup.Load()
+	changed := 0
+	for i := range after.hosts {
+		if reflect.ValueOf(after.hosts[i]).Pointer() != reflect.ValueOf(before.hosts[i]).Pointer() {
+			changed++
+		}
+	}
+	assert.Equal(t, 1, changed)
+	assert.Same(t, before.wildcards, after.wildcards)
+
+	assert.NoError(t, manager.RemoveHostname("host1.example.com"))
+	assert.NoError(t, manager.RenameHostname("host2.example.com", "renamed.example.com"))
+	assert.NoError(t, manager.UpdateHostname("host3.example.com", fiber.New()))
+	assert.NoError(t, manager.AddHostname("*.example.org", fiber.New()))
+
+	table := manager.lookup.Load()
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	published := 0
+	for _, shard := range table.hosts {
+		for key, r := range shard {
+			assert.Same(t, manager.hosts[key], r.entry, key)
+			assert.Same(t, manager.hosts[key].app, r.app, key)
+			published++
+		}
+	}
+	assert.Equal(t, len(manager.hosts), published)
+	assert.Equal(t, "*.example.org", table.match("www.example.org").info.Pattern)
+}
@@ -0,0 +1,107 @@
+// This file contains the per-vhost maintenance mode, which answers the requests for a hostname with a 503 maintenance page while the other hostnames stay live.
+package fibervhosts
+
+import (
+	"bytes"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MaintenanceConfig defines the maintenance response of a hostname, see SetMaintenance
+type MaintenanceConfig struct {
+	// Template is an html/template rendered as the response body with MaintenanceData. Defaults to a plain maintenance page.
+	Template string
+	// Message is passed to the template, like the reason or the expected end of the maintenance
+	Message string
+	// RetryAfter is sent in the Retry-After header, in whole seconds. Defaults to 0, which sends no header.
+	RetryAfter time.Duration
+}
+
+// MaintenanceData is the data the maintenance template is rendered with
+type MaintenanceData struct {
+	Hostname   string
+	Message    string
+	RetryAfter time.Duration
+}
+
+// defaultMaintenanceTemplate is the maintenance page used when MaintenanceConfig.Template is empty
+const defaultMaintenanceTemplate = `<!DOCTYPE html>
+<html>
+<head><title>Under maintenance</title></head>
+<body>
+<h1>Under maintenance</h1>
+<p>{{if .Message}}{{.Message}}{{else}}{{.Hostname}} is down for maintenance. Please try again later.{{end}}</p>
+</body>
+</html>
+`
+
+// maintenancePage is a parsed maintenance config
+type maintenancePage struct {
+	template   *template.Template
+	message    string
+	retryAfter time.Duration
+}
+
+// SetMaintenance puts a hostname into maintenance mode: the middleware answers its requests with 503 Service Unavailable and the rendered maintenance page instead of dispatching them. Setting it again replaces the page. It returns the template parse error for invalid templates.
+func (m *VhostsManager) SetMaintenance(hostname string, page MaintenanceConfig) error {
+	text := page.Template
+	if text == "" {
+		text = defaultMaintenanceTemplate
+	}
+	tmpl, err := template.New("maintenance").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists {
+		return ErrHostNotFound
+	}
+	e.maintenance.Store(&maintenancePage{tmpl, page.Message, page.RetryAfter})
+	return nil
+}
+
+// ClearMaintenance takes a hostname out of maintenance mode
+func (m *VhostsManager) ClearMaintenance(hostname string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists {
+		return ErrHostNotFound
+	}
+	e.maintenance.Store(nil)
+	return nil
+}
+
+// IsInMaintenance reports whether a hostname is registered and in maintenance mode
+func (m *VhostsManager) IsInMaintenance(hostname string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	return exists && e.maintenance.Load() != nil
+}
+
+// respond answers a request with the maintenance page
+func (p *maintenancePage) respond(c *fiber.Ctx, logger Logger) error {
+	if p.retryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(p.retryAfter/time.Second)))
+	}
+
+	var body bytes.Buffer
+	data := MaintenanceData{Hostname: strings.Clone(c.Hostname()), Message: p.message, RetryAfter: p.retryAfter}
+	if err := p.template.Execute(&body, data); err != nil {
+		logger.Error("Rendering maintenance page failed", "hostname", data.Hostname, "error", err)
+		return fiber.ErrServiceUnavailable
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(fiber.StatusServiceUnavailable).Send(body.Bytes())
+}
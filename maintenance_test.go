@@ -0,0 +1,60 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test a hostname in maintenance mode serves the maintenance page while other hostnames stay live.
+func TestVhostsManager_SetMaintenance(t *testing.T) {
+	manager := NewVhostsManager()
+	for _, host := range []string{"www.example.com", "api.example.com"} {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendString("live") })
+		assert.NoError(t, manager.AddHostname(host, app))
+	}
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(host string) (int, string, string) {
+		resp, err := main.Test(httptest.NewRequest("GET", "http://"+host+"/", nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderRetryAfter), string(body)
+	}
+
+	assert.ErrorIs(t, manager.SetMaintenance("missing.example.com", MaintenanceConfig{}), ErrHostNotFound)
+	assert.Error(t, manager.SetMaintenance("www.example.com", MaintenanceConfig{Template: "{{"}))
+	assert.NoError(t, manager.SetMaintenance("www.example.com", MaintenanceConfig{
+		Template:   "<p>{{.Hostname}}: {{.Message}}</p>",
+		Message:    "Back at <noon>",
+		RetryAfter: 2 * time.Minute,
+	}))
+	assert.True(t, manager.IsInMaintenance("www.example.com"))
+
+	status, retryAfter, body := request("www.example.com")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, "120", retryAfter)
+	assert.Equal(t, "<p>www.example.com: Back at &lt;noon&gt;</p>", body)
+
+	status, _, body = request("api.example.com")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "live", body)
+
+	// The default page is used without a template
+	assert.NoError(t, manager.SetMaintenance("www.example.com", MaintenanceConfig{}))
+	status, retryAfter, body = request("www.example.com")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Empty(t, retryAfter)
+	assert.Contains(t, body, "www.example.com is down for maintenance")
+
+	assert.NoError(t, manager.ClearMaintenance("www.example.com"))
+	assert.False(t, manager.IsInMaintenance("www.example.com"))
+	status, _, _ = request("www.example.com")
+	assert.Equal(t, fiber.StatusOK, status)
+}
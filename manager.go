@@ -0,0 +1,27 @@
+// This file contains the HostManager interface, the public behavior of a manager consumed by VhostMiddleware, so applications embedding the package can test their code against a fake, see the vhoststest package.
+package fibervhosts
+
+import "github.com/gofiber/fiber/v2"
+
+// HostManager registers the sub-apps of hostnames and serves their requests. VhostsManager is the implementation of the package; vhoststest.Manager is an in-memory fake for tests.
+type HostManager interface {
+	// Handler returns the handler matching requests to the registered sub-apps, see VhostMiddleware
+	Handler() fiber.Handler
+
+	AddHostname(hostname string, app *fiber.App) error
+	AddHostnameWithConfig(hostname string, app *fiber.App, config HostConfig) error
+	UpdateHostname(hostname string, app *fiber.App) error
+	RemoveHostname(hostname string) error
+	GetHostname(hostname string) (*fiber.App, bool)
+	GetHostnames() []string
+	SetDefaultApp(app *fiber.App)
+
+	SuspendHostname(hostname string) error
+	ResumeHostname(hostname string) error
+	IsSuspended(hostname string) bool
+
+	SetMetadata(hostname string, md Metadata) error
+	GetMetadata(hostname string) (Metadata, bool)
+}
+
+var _ HostManager = (*VhostsManager)(nil)
@@ -0,0 +1,81 @@
+// This file contains the per-vhost metadata and tags that can be attached to registrations.
+package fibervhosts
+
+import "slices"
+
+// Metadata holds arbitrary key/value data (owner, environment, tenant ID, ...) and tags attached to a registration
+type Metadata struct {
+	Values map[string]string `json:"values,omitempty" yaml:"values,omitempty" toml:"values,omitempty"`
+	Tags   []string          `json:"tags,omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
+}
+
+// Get returns the value stored under key, or an empty string if it is not set
+func (md Metadata) Get(key string) string {
+	return md.Values[key]
+}
+
+// HasTag reports whether the metadata carries the given tag
+func (md Metadata) HasTag(tag string) bool {
+	return slices.Contains(md.Tags, tag)
+}
+
+// IsEmpty reports whether the metadata holds no values and no tags
+func (md Metadata) IsEmpty() bool {
+	return len(md.Values) == 0 && len(md.Tags) == 0
+}
+
+// equal reports whether both metadata hold the same values and tags
+func (md Metadata) equal(other Metadata) bool {
+	if len(md.Values) != len(other.Values) || !slices.Equal(md.Tags, other.Tags) {
+		return false
+	}
+	for k, v := range md.Values {
+		if ov, ok := other.Values[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// clone returns a deep copy so callers can't modify the stored metadata
+func (md Metadata) clone() Metadata {
+	var c Metadata
+	if len(md.Values) > 0 {
+		c.Values = make(map[string]string, len(md.Values))
+		for k, v := range md.Values {
+			c.Values[k] = v
+		}
+	}
+	if len(md.Tags) > 0 {
+		c.Tags = slices.Clone(md.Tags)
+	}
+	return c
+}
+
+// SetMetadata replaces the metadata attached to a registered hostname or wildcard pattern
+func (m *VhostsManager) SetMetadata(hostname string, md Metadata) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+
+		previous := e.toEntry()
+		e.metadata = md.clone()
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
+
+// GetMetadata returns the metadata attached to a registered hostname or wildcard pattern
+func (m *VhostsManager) GetMetadata(hostname string) (Metadata, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists {
+		return Metadata{}, false
+	}
+	return e.metadata.clone(), true
+}
@@ -0,0 +1,89 @@
+package fibervhosts
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test setting and reading metadata of a registration.
+func TestVhostsManager_Metadata(t *testing.T) {
+	manager := NewVhostsManager()
+	app := fiber.New()
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.NoError(t, manager.AddHostname("other.com", app))
+
+	md := Metadata{
+		Values: map[string]string{"owner": "team-a", "tenant": "42"},
+		Tags:   []string{"production"},
+	}
+	assert.Equal(t, ErrHostNotFound, manager.SetMetadata("unknown.com", md))
+	assert.NoError(t, manager.SetMetadata("example.com", md))
+
+	// Modifying the original must not change the stored copy
+	md.Values["owner"] = "team-b"
+
+	stored, exists := manager.GetMetadata("example.com")
+	assert.True(t, exists)
+	assert.Equal(t, "team-a", stored.Get("owner"))
+	assert.True(t, stored.HasTag("production"))
+
+	// Filter listings by tag and value
+	tagged := manager.FilterEntries(WithTag("production"))
+	assert.Len(t, tagged, 1)
+	assert.Equal(t, "example.com", tagged[0].Pattern)
+	assert.Len(t, manager.FilterEntries(WithMetadata("tenant", "42")), 1)
+	assert.Empty(t, manager.FilterEntries(WithMetadata("tenant", "43")))
+
+	// Metadata follows a rename
+	assert.NoError(t, manager.RenameHostname("example.com", "example.net"))
+	stored, exists = manager.GetMetadata("example.net")
+	assert.True(t, exists)
+	assert.Equal(t, "42", stored.Get("tenant"))
+}
+
+// Test the middleware exposes metadata to the sub-app.
+func TestVhostMiddleware_MetadataLocals(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		md, _ := c.Locals(LocalsMetadataKey).(Metadata)
+		return c.SendString(md.Get("tenant"))
+	})
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.NoError(t, manager.SetMetadata("example.com", Metadata{Values: map[string]string{"tenant": "acme"}}))
+
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "example.com"
+	resp, err := mainApp.Test(req)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "acme", string(body))
+}
+
+// Test metadata survives an export and import.
+func TestVhostsManager_MetadataExport(t *testing.T) {
+	app := fiber.New(fiber.Config{AppName: "app"})
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.NoError(t, manager.SetMetadata("example.com", Metadata{Tags: []string{"staging"}}))
+
+	var buf bytes.Buffer
+	assert.NoError(t, manager.ExportJSON(&buf))
+
+	restored := NewVhostsManager()
+	assert.NoError(t, restored.ImportJSON(&buf, func(name string) *fiber.App { return app }))
+	stored, exists := restored.GetMetadata("example.com")
+	assert.True(t, exists)
+	assert.True(t, stored.HasTag("staging"))
+}
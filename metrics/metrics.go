@@ -0,0 +1,230 @@
+// Package metrics exposes Prometheus metrics for the registrations of a fibervhosts.VhostsManager: request counts by status class and latency histograms labeled by the matched hostname pattern, plus gauges of the registered hostnames and of the health of proxy upstreams.
+//
+// Mount Collector.Middleware on the main app in front of fibervhosts.VhostMiddleware, and either register the collector with an existing registry or serve Collector.Handler on a /metrics route.
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Host label values that are not hostname patterns
+const (
+	// DefaultLabel labels requests served by the default app
+	DefaultLabel = "_default"
+	// UnmatchedLabel labels requests no registration matched
+	UnmatchedLabel = "_unmatched"
+	// OtherLabel labels requests of hostnames beyond Config.MaxHosts
+	OtherLabel = "_other"
+)
+
+// localsHostKey is the c.Locals key under which the matched hostname pattern is passed from the OnMatch hook to the middleware
+const localsHostKey = "vhost.metrics.host"
+
+// Config defines the config for the metrics collector
+type Config struct {
+	// Namespace prefixes the metric names. Defaults to "vhosts".
+	Namespace string
+	// MaxHosts caps the number of distinct host label values. Requests of further hostnames are labeled OtherLabel. Defaults to 100.
+	MaxHosts int
+	// Buckets are the latency histogram buckets in seconds. Defaults to prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Namespace: "vhosts",
+	MaxHosts:  100,
+	Buckets:   prometheus.DefBuckets,
+}
+
+// Collector collects the request metrics of a VhostsManager. It implements prometheus.Collector.
+type Collector struct {
+	vhosts *fibervhosts.VhostsManager
+	config Config
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	hosts    *prometheus.Desc
+
+	upstreamHealthy *prometheus.Desc
+	upstreamActive  *prometheus.Desc
+	upstreamChanges *prometheus.Desc
+
+	mu     sync.Mutex
+	labels map[string]struct{}
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// New creates a metrics collector for the manager. It registers an OnMatch hook to learn which registration served each request.
+func New(vhosts *fibervhosts.VhostsManager, config ...Config) *Collector {
+	cfg := ConfigDefault
+	if len(config) > 0 {
+		cfg = config[0]
+		if cfg.Namespace == "" {
+			cfg.Namespace = ConfigDefault.Namespace
+		}
+		if cfg.MaxHosts <= 0 {
+			cfg.MaxHosts = ConfigDefault.MaxHosts
+		}
+		if len(cfg.Buckets) == 0 {
+			cfg.Buckets = ConfigDefault.Buckets
+		}
+	}
+
+	c := &Collector{
+		vhosts: vhosts,
+		config: cfg,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Name:      "requests_total",
+			Help:      "Number of requests by matched hostname and status class.",
+		}, []string{"host", "class"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Request latency by matched hostname.",
+			Buckets:   cfg.Buckets,
+		}, []string{"host"}),
+		hosts: prometheus.NewDesc(
+			prometheus.BuildFQName(cfg.Namespace, "", "registered_hosts"),
+			"Number of registrations by type.",
+			[]string{"type"}, nil,
+		),
+		upstreamHealthy: prometheus.NewDesc(
+			prometheus.BuildFQName(cfg.Namespace, "", "upstream_healthy"),
+			"Whether an upstream of a proxy host is in rotation.",
+			[]string{"host", "upstream"}, nil,
+		),
+		upstreamActive: prometheus.NewDesc(
+			prometheus.BuildFQName(cfg.Namespace, "", "upstream_active_requests"),
+			"Number of requests in flight to an upstream of a proxy host.",
+			[]string{"host", "upstream"}, nil,
+		),
+		upstreamChanges: prometheus.NewDesc(
+			prometheus.BuildFQName(cfg.Namespace, "", "upstream_health_changes_total"),
+			"Number of times an upstream of a proxy host went out of or back into rotation.",
+			[]string{"host", "upstream"}, nil,
+		),
+		labels: make(map[string]struct{}),
+	}
+
+	vhosts.Hooks().OnMatch(func(ctx *fiber.Ctx, e fibervhosts.Entry) {
+		ctx.Locals(localsHostKey, hostLabel(e))
+	})
+	return c
+}
+
+// hostLabel returns the host label value of a registration
+func hostLabel(e fibervhosts.Entry) string {
+	if e.Type == fibervhosts.EntryDefault {
+		return DefaultLabel
+	}
+	return e.Pattern
+}
+
+// Middleware returns a handler recording the count, status class and latency of every request. Mount it in front of fibervhosts.VhostMiddleware.
+func (c *Collector) Middleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		err := ctx.Next()
+		elapsed := time.Since(start)
+
+		// Errors are turned into responses by the error handler later on
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+
+		host, _ := ctx.Locals(localsHostKey).(string)
+		host = c.label(host)
+		c.requests.WithLabelValues(host, statusClass(status)).Inc()
+		c.duration.WithLabelValues(host).Observe(elapsed.Seconds())
+		return err
+	}
+}
+
+// label caps the number of distinct host label values
+func (c *Collector) label(host string) string {
+	if host == "" {
+		return UnmatchedLabel
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, seen := c.labels[host]; seen {
+		return host
+	}
+	if len(c.labels) >= c.config.MaxHosts {
+		return OtherLabel
+	}
+	c.labels[host] = struct{}{}
+	return host
+}
+
+// statusClass returns the class of a status code, like "2xx"
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	ch <- c.hosts
+	ch <- c.upstreamHealthy
+	ch <- c.upstreamActive
+	ch <- c.upstreamChanges
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+
+	counts := map[fibervhosts.EntryType]int{
+		fibervhosts.EntryHost:     0,
+		fibervhosts.EntryWildcard: 0,
+		fibervhosts.EntryDefault:  0,
+	}
+	for _, e := range c.vhosts.ListEntries() {
+		counts[e.Type]++
+	}
+	for kind, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.hosts, prometheus.GaugeValue, float64(count), string(kind))
+	}
+
+	for _, u := range c.vhosts.Upstreams() {
+		healthy := 0.0
+		if u.Healthy {
+			healthy = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.upstreamHealthy, prometheus.GaugeValue, healthy, u.Pattern, u.URL)
+		ch <- prometheus.MustNewConstMetric(c.upstreamActive, prometheus.GaugeValue, float64(u.Active), u.Pattern, u.URL)
+		ch <- prometheus.MustNewConstMetric(c.upstreamChanges, prometheus.CounterValue, float64(u.Changes), u.Pattern, u.URL)
+	}
+}
+
+// Handler returns a handler serving the metrics of the collector together with the Go runtime and process metrics, ready to be mounted on a /metrics route
+func (c *Collector) Handler() fiber.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c, collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return adaptor.HTTPHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test requests are counted by matched pattern and status class.
+func TestCollector_Middleware(t *testing.T) {
+	api := fiber.New()
+	api.Get("/", func(c *fiber.Ctx) error { return c.SendString("api") })
+	api.Get("/fail", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusBadGateway) })
+
+	manager := fibervhosts.NewVhostsManager(fibervhosts.Config{DefaultApp: fiber.New()})
+	assert.NoError(t, manager.AddHostname("api.example.com", api))
+	assert.NoError(t, manager.AddHostname("*.example.org", api))
+	assert.NoError(t, manager.AddProxyHost("proxy.example.com", "http://127.0.0.1:1"))
+
+	collector := New(manager, Config{MaxHosts: 2})
+	app := fiber.New()
+	app.Get("/metrics", collector.Handler())
+	app.Use(collector.Middleware())
+	app.Use(fibervhosts.VhostMiddleware(manager))
+
+	for _, target := range []string{
+		"http://api.example.com/",
+		"http://api.example.com/fail",
+		"http://a.example.org/",
+		"http://b.example.org/",
+		"http://unknown.example.net/",
+	} {
+		_, err := app.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues("api.example.com", "2xx")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues("api.example.com", "5xx")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(collector.requests.WithLabelValues("*.example.org", "2xx")))
+	// The default app is beyond the cap of two host labels
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues(OtherLabel, "4xx")))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "http://metrics.local/metrics", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `vhosts_registered_hosts{type="wildcard"} 1`)
+	assert.Contains(t, string(body), `vhosts_request_duration_seconds_count{host="api.example.com"} 2`)
+	assert.Contains(t, string(body), `vhosts_upstream_healthy{host="proxy.example.com",upstream="http://127.0.0.1:1"} 1`)
+	assert.True(t, strings.Contains(string(body), "go_goroutines"))
+}
+
+// Test requests without a registration are labeled as unmatched.
+func TestCollector_Unmatched(t *testing.T) {
+	manager := fibervhosts.NewVhostsManager()
+	collector := New(manager)
+	app := fiber.New()
+	app.Use(collector.Middleware())
+	app.Use(fibervhosts.VhostMiddleware(manager))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "http://nobody.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues(UnmatchedLabel, "4xx")))
+}
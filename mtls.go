@@ -0,0 +1,98 @@
+// This file contains the per-vhost mutual TLS support: requesting client certificates only for hostnames that require them and verifying them against the CA pool of the hostname.
+package fibervhosts
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Locals keys set by the middleware for hostnames with client authentication
+const (
+	// LocalsClientCertKey holds the verified client *x509.Certificate
+	LocalsClientCertKey = "vhost.clientCert"
+	// LocalsClientCertErrorKey holds the verification error in ClientAuthFlag mode
+	LocalsClientCertErrorKey = "vhost.clientCertError"
+)
+
+// ErrNoClientCertificate is the verification error of requests without a client certificate
+var ErrNoClientCertificate = errors.New("no client certificate")
+
+// ClientAuthMode selects what happens to requests whose client certificate doesn't validate
+type ClientAuthMode int
+
+const (
+	// ClientAuthReject answers such requests with 403 Forbidden
+	ClientAuthReject ClientAuthMode = iota
+	// ClientAuthFlag dispatches such requests anyway, with the error stored under LocalsClientCertErrorKey
+	ClientAuthFlag
+)
+
+// ClientAuth defines the client certificate requirements of a hostname
+type ClientAuth struct {
+	// CAs verifies the client certificates. Required.
+	CAs *x509.CertPool
+	// Mode selects whether invalid requests are rejected or flagged. Defaults to ClientAuthReject.
+	Mode ClientAuthMode
+}
+
+// verify checks the client certificate of a connection against the CA pool and returns the verified leaf certificate
+func (a *ClientAuth) verify(state *tls.ConnectionState) (*x509.Certificate, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil, ErrNoClientCertificate
+	}
+
+	leaf := state.PeerCertificates[0]
+	opts := x509.VerifyOptions{
+		Roots:         a.CAs,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	return leaf, nil
+}
+
+// check enforces the client authentication of the hostname. It returns an error when the request must be rejected.
+func (a *ClientAuth) check(c *fiber.Ctx) error {
+	leaf, err := a.verify(c.Context().TLSConnectionState())
+	if err == nil {
+		c.Locals(LocalsClientCertKey, leaf)
+		return nil
+	}
+	if a.Mode == ClientAuthFlag {
+		c.Locals(LocalsClientCertErrorKey, err)
+		return nil
+	}
+	return fiber.ErrForbidden
+}
+
+// EnableClientAuth makes a TLS config request client certificates during the handshake, but only for server names whose registration has HostConfig.ClientAuth set, so public hostnames never prompt for a certificate. The certificates are verified per hostname by the middleware. TLSConfig already includes this.
+func (m *VhostsManager) EnableClientAuth(config *tls.Config) {
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if !m.requiresClientAuth(hello.ServerName) {
+			return nil, nil
+		}
+		c := config.Clone()
+		c.GetConfigForClient = nil
+		c.ClientAuth = tls.RequestClientCert
+		return c, nil
+	}
+}
+
+// requiresClientAuth reports whether the registration matching a server name requires client certificates
+func (m *VhostsManager) requiresClientAuth(serverName string) bool {
+	name := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e := m.findMatchingEntry(name)
+	return e != nil && e.config.ClientAuth != nil
+}
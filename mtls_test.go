@@ -0,0 +1,153 @@
+package fibervhosts
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCA issues client certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// pool returns a pool trusting the CA
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// issue creates a client certificate signed by the CA
+func (ca *testCA) issue(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// Test client certificates are required per hostname over a real TLS listener.
+func TestVhostsManager_ClientAuth(t *testing.T) {
+	ca := newTestCA(t)
+	other := newTestCA(t)
+
+	internal := fiber.New()
+	internal.Get("/", func(c *fiber.Ctx) error {
+		cert := c.Locals(LocalsClientCertKey).(*x509.Certificate)
+		return c.SendString("hello " + cert.Subject.CommonName)
+	})
+	public := fiber.New()
+	public.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("public")
+	})
+	flagged := fiber.New()
+	flagged.Get("/", func(c *fiber.Ctx) error {
+		if err, ok := c.Locals(LocalsClientCertErrorKey).(error); ok {
+			return c.SendString("flagged: " + err.Error())
+		}
+		return c.SendString("verified")
+	})
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("internal.example.com", internal, HostConfig{ClientAuth: &ClientAuth{CAs: ca.pool()}}))
+	assert.NoError(t, manager.AddHostname("public.example.com", public))
+	assert.NoError(t, manager.AddHostname("flagged.example.com", flagged))
+	assert.NoError(t, manager.SetHostConfig("flagged.example.com", HostConfig{ClientAuth: &ClientAuth{CAs: ca.pool(), Mode: ClientAuthFlag}}))
+
+	serverCert, _, _ := testCertificate(t, "internal.example.com", "public.example.com", "flagged.example.com")
+	manager.SetDefaultCertificate(serverCert)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(VhostMiddleware(manager))
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", manager.TLSConfig())
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	get := func(host string, certs ...tls.Certificate) (int, string) {
+		var requested bool
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				ServerName:         host,
+				InsecureSkipVerify: true,
+				GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+					requested = true
+					if len(certs) == 0 {
+						return &tls.Certificate{}, nil
+					}
+					return &certs[0], nil
+				},
+			},
+		}}
+		req, err := http.NewRequest(http.MethodGet, "https://"+ln.Addr().String()+"/", nil)
+		assert.NoError(t, err)
+		req.Host = host
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		// Only hostnames requiring client certificates ask for one
+		assert.Equal(t, host != "public.example.com", requested, host)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("internal.example.com", ca.issue(t, "alice"))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello alice", body)
+
+	status, _ = get("internal.example.com")
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = get("internal.example.com", other.issue(t, "mallory"))
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, body = get("public.example.com")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "public", body)
+
+	status, body = get("flagged.example.com")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "flagged: no client certificate", body)
+}
@@ -0,0 +1,102 @@
+// This file contains the Provider interface through which external sources of truth, like files, databases or APIs, drive the vhost table.
+package fibervhosts
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultProviderSource is the source of registrations made by providers without a Name method
+const defaultProviderSource = "provider"
+
+// Provider is an external source of truth for the vhost table. Both methods return the complete desired set of registrations owned by the provider, not a diff; entries with a nil App (an app name the provider could not resolve) are skipped with a warning. A provider may implement Name() string to set the Source of its registrations, which defaults to "provider", and SetLogger(Logger) to log through the Logger of the manager it is attached to, like by embedding ProviderLogger.
+type Provider interface {
+	// Load returns the current desired registrations
+	Load() ([]Entry, error)
+	// Watch emits the desired registrations every time they change until ctx is done, then closes the channel. Connection errors should be handled by the provider itself, for example by retrying.
+	Watch(ctx context.Context) <-chan []Entry
+}
+
+// ProviderLogger gives a provider the Logger to log through: the one configured for the provider, or else the one of the manager it is attached to, which AttachProvider sets with SetLogger, or else DefaultLogger. Providers embed it, see NewProviderLogger.
+type ProviderLogger struct {
+	configured Logger
+	attached   Logger
+}
+
+// NewProviderLogger returns a ProviderLogger logging through configured, unless it is nil
+func NewProviderLogger(configured Logger) ProviderLogger {
+	return ProviderLogger{configured: configured}
+}
+
+// SetLogger makes the provider log through the Logger of the manager it is attached to, unless it has a Logger configured
+func (l *ProviderLogger) SetLogger(logger Logger) {
+	l.attached = logger
+}
+
+// Logger returns the Logger the provider logs through
+func (l *ProviderLogger) Logger() Logger {
+	switch {
+	case l.configured != nil:
+		return l.configured
+	case l.attached != nil:
+		return l.attached
+	}
+	return DefaultLogger()
+}
+
+// AttachProvider loads the registrations of the provider into the manager and keeps them in sync with its Watch channel, see ApplyEntries. Registrations made through other means are never touched by the provider. It returns an error and attaches nothing if the initial load fails. The detach function stops watching and waits for the watch to end; the registrations made by the provider are kept.
+func (m *VhostsManager) AttachProvider(p Provider) (func(), error) {
+	source := providerSource(p)
+	if logged, ok := p.(interface{ SetLogger(Logger) }); ok {
+		logged.SetLogger(m.logger)
+	}
+
+	entries, err := p.Load()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.applyProvider(source, entries); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for entries := range p.Watch(ctx) {
+			if _, err := m.applyProvider(source, entries); err != nil {
+				m.logger.Warn("Applying provider entries failed", "provider", source, "error", err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	detach := func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+	return detach, nil
+}
+
+// applyProvider applies the desired registrations of a provider, skipping entries without an app, and returns the changes it made
+func (m *VhostsManager) applyProvider(source string, entries []Entry) ([]ChangeEvent, error) {
+	valid := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.App == nil {
+			m.logger.Warn("Skipping provider entry with unknown app", "provider", source, "app", e.AppName, "hostname", e.Pattern)
+			continue
+		}
+		valid = append(valid, e)
+	}
+	return m.applyEntries(source, valid)
+}
+
+// providerSource returns the source name of the provider's registrations
+func providerSource(p Provider) string {
+	if named, ok := p.(interface{ Name() string }); ok && named.Name() != "" {
+		return named.Name()
+	}
+	return defaultProviderSource
+}
@@ -0,0 +1,92 @@
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProvider returns fixed registrations from Load and forwards updates to Watch
+type fakeProvider struct {
+	entries []Entry
+	err     error
+	updates chan []Entry
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Load() ([]Entry, error) { return p.entries, p.err }
+
+func (p *fakeProvider) Watch(ctx context.Context) <-chan []Entry {
+	out := make(chan []Entry)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case entries := <-p.updates:
+				out <- entries
+			}
+		}
+	}()
+	return out
+}
+
+// Test that an attached provider drives its own registrations only.
+func TestVhostsManager_AttachProvider(t *testing.T) {
+	app := fiber.New()
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("manual.example.com", app))
+
+	p := &fakeProvider{
+		entries: []Entry{
+			{Pattern: "a.example.com", App: app},
+			{Pattern: "missing.example.com", AppName: "missing"},
+		},
+		updates: make(chan []Entry),
+	}
+	detach, err := manager.AttachProvider(p)
+	assert.NoError(t, err)
+
+	entries := manager.FilterEntries(func(e Entry) bool { return e.Source == "fake" })
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "a.example.com", entries[0].Pattern)
+
+	p.updates <- []Entry{{Pattern: "b.example.com", App: app}}
+	assert.Eventually(t, func() bool {
+		_, added := manager.GetHostname("b.example.com")
+		_, kept := manager.GetHostname("a.example.com")
+		return added && !kept
+	}, time.Second, 5*time.Millisecond)
+
+	// Registrations are kept after detaching
+	detach()
+	detach()
+	assert.ElementsMatch(t, []string{"manual.example.com", "b.example.com"}, manager.GetHostnames())
+}
+
+// Test that a failing initial load attaches nothing.
+func TestVhostsManager_AttachProvider_LoadError(t *testing.T) {
+	manager := NewVhostsManager()
+	detach, err := manager.AttachProvider(&fakeProvider{err: errors.New("unreachable")})
+	assert.Error(t, err)
+	assert.Nil(t, detach)
+}
+
+// Test providers log through their configured Logger, or else the Logger of the manager they are attached to.
+func TestProviderLogger(t *testing.T) {
+	configured, attached := &recordingLogger{}, &recordingLogger{}
+	l := NewProviderLogger(nil)
+	assert.Equal(t, DefaultLogger(), l.Logger())
+	l.SetLogger(attached)
+	assert.Equal(t, attached, l.Logger())
+
+	l = NewProviderLogger(configured)
+	l.SetLogger(attached)
+	assert.Equal(t, configured, l.Logger())
+}
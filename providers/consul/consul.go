@@ -0,0 +1,257 @@
+// Package consul provides a fibervhosts.Provider that keeps a VhostsManager in sync with Consul, reading vhost definitions from the KV store or from service catalog tags and following changes with blocking queries.
+//
+// In KV mode every key below the prefix registers the hostname named by the rest of the key. The value is either a plain app name or a JSON object like {"app":"web","suspended":true}. The key "<prefix>_default" sets the default app.
+//
+// In catalog mode every service tag of the form "vhost=<hostname>" registers the hostname for the app named after the service.
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+)
+
+// DefaultKey is the key, relative to the prefix, holding the default app
+const DefaultKey = "_default"
+
+// Mode selects where vhost definitions are read from
+type Mode int
+
+const (
+	// ModeKV reads vhost definitions from the KV store
+	ModeKV Mode = iota
+	// ModeCatalog reads vhost definitions from service catalog tags
+	ModeCatalog
+)
+
+// Config defines the config for the Consul provider
+type Config struct {
+	// Address is the base URL of the Consul agent. Defaults to "http://127.0.0.1:8500".
+	Address string
+	// Token is sent as ACL token when set
+	Token string
+	// Mode selects KV or catalog mode. Defaults to ModeKV.
+	Mode Mode
+	// Prefix is the KV prefix holding the vhost table in KV mode. Defaults to "vhosts/".
+	Prefix string
+	// TagPrefix marks the service tags holding hostnames in catalog mode. Defaults to "vhost=".
+	TagPrefix string
+	// Resolver maps app names to apps. Required.
+	Resolver fibervhosts.AppResolver
+	// Source identifies the registrations managed by this provider. Defaults to "consul".
+	Source string
+	// WaitTime is the maximum duration of a blocking query. Defaults to 5 minutes.
+	WaitTime time.Duration
+	// Client is used for all requests. Its timeout must exceed WaitTime. Defaults to http.DefaultClient.
+	Client *http.Client
+	// RetryInterval is the delay before retrying after an error. Defaults to 5 seconds.
+	RetryInterval time.Duration
+	// Logger receives the warnings of the provider, like connection errors. Defaults to the Logger of the manager the provider is attached to, see fibervhosts.ProviderLogger.
+	Logger fibervhosts.Logger
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Address:       "http://127.0.0.1:8500",
+	Mode:          ModeKV,
+	Prefix:        "vhosts/",
+	TagPrefix:     "vhost=",
+	Source:        "consul",
+	WaitTime:      5 * time.Minute,
+	RetryInterval: 5 * time.Second,
+}
+
+// Provider is a fibervhosts.Provider reading the vhost table from Consul
+type Provider struct {
+	config Config
+	fibervhosts.ProviderLogger
+
+	// index is the Consul index of the last Load, where Watch starts
+	index atomic.Uint64
+}
+
+// New creates a new Consul provider. Attach it with VhostsManager.AttachProvider.
+func New(config Config) *Provider {
+	if config.Address == "" {
+		config.Address = ConfigDefault.Address
+	}
+	if config.Prefix == "" {
+		config.Prefix = ConfigDefault.Prefix
+	}
+	if config.TagPrefix == "" {
+		config.TagPrefix = ConfigDefault.TagPrefix
+	}
+	if config.Source == "" {
+		config.Source = ConfigDefault.Source
+	}
+	if config.WaitTime <= 0 {
+		config.WaitTime = ConfigDefault.WaitTime
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = ConfigDefault.RetryInterval
+	}
+	config.Address = strings.TrimRight(config.Address, "/")
+
+	return &Provider{config: config, ProviderLogger: fibervhosts.NewProviderLogger(config.Logger)}
+}
+
+// Name returns the source of the registrations made by the provider
+func (p *Provider) Name() string {
+	return p.config.Source
+}
+
+// Load reads the vhost definitions once and returns their registrations
+func (p *Provider) Load() ([]fibervhosts.Entry, error) {
+	index, entries, err := p.poll(context.Background(), 0)
+	if err != nil {
+		return nil, err
+	}
+	p.index.Store(index)
+	return entries, nil
+}
+
+// Watch follows the vhost definitions with blocking queries, starting where the last Load left off, and emits the registrations every time Consul answers a query, so changes are picked up as soon as Consul reports them. Errors are logged and retried after Config.RetryInterval. The channel is closed once ctx is done.
+func (p *Provider) Watch(ctx context.Context) <-chan []fibervhosts.Entry {
+	out := make(chan []fibervhosts.Entry)
+	go func() {
+		defer close(out)
+		index := p.index.Load()
+		for {
+			next, entries, err := p.poll(ctx, index)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				// Reset the index if it goes backwards, as documented for blocking queries
+				if next < index {
+					next = 0
+				}
+				index = next
+				select {
+				case out <- entries:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			p.Logger().Warn("Consul provider failed, retrying", "error", err, "retry", p.config.RetryInterval)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.config.RetryInterval):
+			}
+		}
+	}()
+	return out
+}
+
+// poll runs one (blocking, if index is set) query and returns its registrations and the index for the next query
+func (p *Provider) poll(ctx context.Context, index uint64) (uint64, []fibervhosts.Entry, error) {
+	var path string
+	if p.config.Mode == ModeCatalog {
+		path = "/v1/catalog/services"
+	} else {
+		path = "/v1/kv/" + strings.TrimLeft(p.config.Prefix, "/")
+	}
+
+	query := url.Values{}
+	if p.config.Mode == ModeKV {
+		query.Set("recurse", "true")
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", p.config.WaitTime.String())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Address+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if p.config.Token != "" {
+		req.Header.Set("X-Consul-Token", p.config.Token)
+	}
+
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	var specs []fibervhosts.EntrySpec
+	switch {
+	case resp.StatusCode == http.StatusNotFound && p.config.Mode == ModeKV:
+		// An empty prefix: nothing registered
+	case resp.StatusCode != http.StatusOK:
+		return 0, nil, fmt.Errorf("%s: unexpected status %s", path, resp.Status)
+	case p.config.Mode == ModeCatalog:
+		var services map[string][]string
+		if err := json.NewDecoder(resp.Body).Decode(&services); err != nil {
+			return 0, nil, fmt.Errorf("decode catalog: %w", err)
+		}
+		specs = p.catalogSpecs(services)
+	default:
+		var pairs []kvPair
+		if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+			return 0, nil, fmt.Errorf("decode kv: %w", err)
+		}
+		specs = p.kvSpecs(pairs)
+	}
+
+	return next, fibervhosts.ResolveSpecs(specs, p.config.Resolver), nil
+}
+
+// kvPair is a single entry of a recursive KV read. Value is base64 encoded, which encoding/json decodes into the byte slice.
+type kvPair struct {
+	Key   string
+	Value []byte
+}
+
+// kvSpecs converts KV pairs into specs, logging invalid values
+func (p *Provider) kvSpecs(pairs []kvPair) []fibervhosts.EntrySpec {
+	prefix := strings.TrimLeft(p.config.Prefix, "/")
+	specs := make([]fibervhosts.EntrySpec, 0, len(pairs))
+	for _, pair := range pairs {
+		hostname := strings.TrimPrefix(pair.Key, prefix)
+		if hostname == "" || strings.HasSuffix(hostname, "/") {
+			// Folder keys
+			continue
+		}
+		if hostname == DefaultKey {
+			hostname = ""
+		}
+		spec, err := fibervhosts.ParseEntrySpec(hostname, string(pair.Value))
+		if err != nil {
+			p.Logger().Warn("Consul provider skipping invalid value", "key", pair.Key, "error", err)
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// catalogSpecs converts service tags into specs
+func (p *Provider) catalogSpecs(services map[string][]string) []fibervhosts.EntrySpec {
+	var specs []fibervhosts.EntrySpec
+	for service, tags := range services {
+		for _, tag := range tags {
+			if hostname, ok := strings.CutPrefix(tag, p.config.TagPrefix); ok && hostname != "" {
+				specs = append(specs, fibervhosts.EntrySpec{Hostname: hostname, App: service})
+			}
+		}
+	}
+	return specs
+}
@@ -0,0 +1,88 @@
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test KV mode follows changes through blocking queries.
+func TestProvider_KV(t *testing.T) {
+	api := fiber.New()
+	web := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"api": api, "web": web})
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/kv/vhosts/", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+
+		switch calls.Add(1) {
+		case 1:
+			assert.Empty(t, r.URL.Query().Get("index"))
+			w.Header().Set("X-Consul-Index", "10")
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"Key": "vhosts/", "Value": nil},
+				{"Key": "vhosts/api.example.com", "Value": []byte("api")},
+				{"Key": "vhosts/_default", "Value": []byte("web")},
+			})
+		case 2:
+			assert.Equal(t, "10", r.URL.Query().Get("index"))
+			w.Header().Set("X-Consul-Index", "11")
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"Key": "vhosts/*.example.org", "Value": []byte(`{"app":"web","suspended":true}`)},
+			})
+		default:
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	manager := fibervhosts.NewVhostsManager()
+	detach, err := manager.AttachProvider(New(Config{Address: server.URL, Token: "secret", Resolver: resolver}))
+	assert.NoError(t, err)
+	defer detach()
+
+	assert.Eventually(t, func() bool {
+		return manager.IsSuspended("*.example.org")
+	}, time.Second, 5*time.Millisecond)
+
+	// The second query replaced the whole table of the provider
+	_, exists := manager.GetHostname("api.example.com")
+	assert.False(t, exists)
+	assert.Len(t, manager.ListEntries(), 1)
+}
+
+// Test catalog mode registers hostnames from service tags.
+func TestProvider_Catalog(t *testing.T) {
+	api := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"api": api})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/catalog/services", r.URL.Path)
+		w.Header().Set("X-Consul-Index", "3")
+		json.NewEncoder(w).Encode(map[string][]string{
+			"api":     {"http", "vhost=api.example.com", "vhost=*.api.example.com"},
+			"unknown": {"vhost=unknown.example.com"},
+			"consul":  {},
+		})
+	}))
+	defer server.Close()
+
+	manager := fibervhosts.NewVhostsManager()
+	detach, err := manager.AttachProvider(New(Config{Address: server.URL, Mode: ModeCatalog, Resolver: resolver}))
+	assert.NoError(t, err)
+	detach()
+
+	assert.Len(t, manager.ListEntries(), 2)
+	app, exists := manager.GetHostname("api.example.com")
+	assert.True(t, exists)
+	assert.Equal(t, api, app)
+}
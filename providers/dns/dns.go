@@ -0,0 +1,288 @@
+// Package dns provides a fibervhosts.Provider that discovers vhosts from DNS records under a configured zone, so edge nodes pick up new tenant domains without a control-plane push. The records are refreshed when their TTL expires.
+//
+// In TXT mode every TXT record of the zone (like "_vhosts.example.com") registers a hostname as "<hostname>=<app>", where the value may also be a JSON object like {"app":"web","suspended":true}. The hostname "_default" sets the default app.
+//
+// In SRV mode every SRV record of the zone registers its target as hostname, with "<target>:<port>" as the backend identifier passed to the resolver.
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DefaultHostname is the hostname of a TXT record setting the default app
+const DefaultHostname = "_default"
+
+// Mode selects the record type the vhosts are read from
+type Mode int
+
+const (
+	// ModeTXT reads "<hostname>=<app>" TXT records
+	ModeTXT Mode = iota
+	// ModeSRV reads SRV records, registering their targets
+	ModeSRV
+)
+
+// Config defines the config for the DNS provider
+type Config struct {
+	// Zone is the name holding the records, like "_vhosts.example.com". Required.
+	Zone string
+	// Mode selects TXT or SRV records. Defaults to ModeTXT.
+	Mode Mode
+	// Server is the address of the DNS server. Defaults to the first nameserver of /etc/resolv.conf.
+	Server string
+	// Resolver maps app names to apps. Required.
+	Resolver fibervhosts.AppResolver
+	// Source identifies the registrations managed by this provider. Defaults to "dns".
+	Source string
+	// Timeout bounds a single query. Defaults to 5 seconds.
+	Timeout time.Duration
+	// MinInterval and MaxInterval bound the refresh interval derived from the record TTL. Default to 10 seconds and 1 hour.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// Logger receives the warnings of the provider, like connection errors. Defaults to the Logger of the manager the provider is attached to, see fibervhosts.ProviderLogger.
+	Logger fibervhosts.Logger
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Mode:        ModeTXT,
+	Server:      "127.0.0.1:53",
+	Source:      "dns",
+	Timeout:     5 * time.Second,
+	MinInterval: 10 * time.Second,
+	MaxInterval: time.Hour,
+}
+
+// Provider is a fibervhosts.Provider reading the vhost table from DNS records
+type Provider struct {
+	config Config
+	fibervhosts.ProviderLogger
+}
+
+// New creates a new DNS provider. Attach it with VhostsManager.AttachProvider.
+func New(config Config) *Provider {
+	if config.Server == "" {
+		config.Server = systemNameserver()
+	}
+	if _, _, err := net.SplitHostPort(config.Server); err != nil {
+		config.Server = net.JoinHostPort(config.Server, "53")
+	}
+	if config.Source == "" {
+		config.Source = ConfigDefault.Source
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = ConfigDefault.Timeout
+	}
+	if config.MinInterval <= 0 {
+		config.MinInterval = ConfigDefault.MinInterval
+	}
+	if config.MaxInterval <= 0 {
+		config.MaxInterval = ConfigDefault.MaxInterval
+	}
+	if !strings.HasSuffix(config.Zone, ".") {
+		config.Zone += "."
+	}
+	return &Provider{config: config, ProviderLogger: fibervhosts.NewProviderLogger(config.Logger)}
+}
+
+// Name returns the source of the registrations made by the provider
+func (p *Provider) Name() string {
+	return p.config.Source
+}
+
+// Load resolves the zone once and returns its registrations
+func (p *Provider) Load() ([]fibervhosts.Entry, error) {
+	entries, _, err := p.lookup(context.Background())
+	return entries, err
+}
+
+// Watch resolves the zone and resolves it again when the records expire, emitting the registrations after every lookup. The refresh interval is the lowest record TTL, bounded by Config.MinInterval and Config.MaxInterval. Failed lookups are logged and retried after Config.MinInterval. The channel is closed once ctx is done.
+func (p *Provider) Watch(ctx context.Context) <-chan []fibervhosts.Entry {
+	out := make(chan []fibervhosts.Entry)
+	go func() {
+		defer close(out)
+		for {
+			entries, ttl, err := p.lookup(ctx)
+			wait := p.config.MinInterval
+			if err == nil {
+				wait = min(max(ttl, p.config.MinInterval), p.config.MaxInterval)
+				select {
+				case out <- entries:
+				case <-ctx.Done():
+					return
+				}
+			} else if ctx.Err() == nil {
+				p.Logger().Warn("DNS provider failed, retrying", "error", err, "retry", wait)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+	return out
+}
+
+// lookup queries the zone and returns its registrations together with the lowest TTL of the records. A zone without records has no registrations and uses Config.MaxInterval as TTL.
+func (p *Provider) lookup(ctx context.Context) ([]fibervhosts.Entry, time.Duration, error) {
+	qtype := dnsmessage.TypeTXT
+	if p.config.Mode == ModeSRV {
+		qtype = dnsmessage.TypeSRV
+	}
+	answers, err := p.query(ctx, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ttl := p.config.MaxInterval
+	var specs []fibervhosts.EntrySpec
+	for _, rr := range answers {
+		if rr.Header.Type != qtype {
+			// Like the CNAME leading to the records
+			continue
+		}
+		ttl = min(ttl, time.Duration(rr.Header.TTL)*time.Second)
+
+		switch body := rr.Body.(type) {
+		case *dnsmessage.TXTResource:
+			record := strings.Join(body.TXT, "")
+			hostname, value, ok := strings.Cut(record, "=")
+			if !ok || hostname == "" {
+				p.Logger().Warn("DNS provider skipping invalid TXT record", "record", record)
+				continue
+			}
+			if hostname == DefaultHostname {
+				hostname = ""
+			}
+			spec, err := fibervhosts.ParseEntrySpec(hostname, value)
+			if err != nil {
+				p.Logger().Warn("DNS provider skipping invalid TXT record", "record", record, "error", err)
+				continue
+			}
+			specs = append(specs, spec)
+		case *dnsmessage.SRVResource:
+			target := strings.TrimSuffix(body.Target.String(), ".")
+			if target == "" {
+				continue
+			}
+			specs = append(specs, fibervhosts.EntrySpec{
+				Hostname: target,
+				App:      net.JoinHostPort(target, strconv.Itoa(int(body.Port))),
+			})
+		}
+	}
+	return fibervhosts.ResolveSpecs(specs, p.config.Resolver), ttl, nil
+}
+
+// query sends a single question for the zone and returns the answers. Truncated UDP responses are retried over TCP. A name without records yields no answers.
+func (p *Provider) query(ctx context.Context, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	name, err := dnsmessage.NewName(p.config.Zone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zone %q: %w", p.config.Zone, err)
+	}
+	id := uint16(time.Now().UnixNano())
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	resp, err := exchange(ctx, "udp", p.config.Server, packet)
+	if err == nil && resp.Truncated {
+		resp, err = exchange(ctx, "tcp", p.config.Server, packet)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.ID != id {
+		return nil, errors.New("response id mismatch")
+	}
+
+	switch resp.RCode {
+	case dnsmessage.RCodeSuccess:
+		return resp.Answers, nil
+	case dnsmessage.RCodeNameError:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("query %s: %s", p.config.Zone, resp.RCode)
+	}
+}
+
+// exchange sends a packed query over the network and parses the response. TCP messages are prefixed with their length.
+func exchange(ctx context.Context, network, server string, packet []byte) (*dnsmessage.Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		packet = append([]byte{byte(len(packet) >> 8), byte(len(packet))}, packet...)
+	}
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	if network == "tcp" {
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, int(size[0])<<8|int(size[1]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+	} else {
+		buf = make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(buf); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	return &resp, nil
+}
+
+// systemNameserver returns the first nameserver of /etc/resolv.conf, or the default server
+func systemNameserver() string {
+	data, err := os.ReadFile("/etc/resolv.conf")
+	if err != nil {
+		return ConfigDefault.Server
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+	return ConfigDefault.Server
+}
@@ -0,0 +1,123 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNS answers queries over UDP with the configured records
+type fakeDNS struct {
+	conn net.PacketConn
+
+	mu      sync.Mutex
+	answers []dnsmessage.Resource
+}
+
+func newFakeDNS(t *testing.T) *fakeDNS {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	s := &fakeDNS{conn: conn}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil {
+				continue
+			}
+
+			s.mu.Lock()
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true},
+				Questions: query.Questions,
+				Answers:   s.answers,
+			}
+			s.mu.Unlock()
+			packet, _ := resp.Pack()
+			conn.WriteTo(packet, addr)
+		}
+	}()
+	return s
+}
+
+// set replaces the records served by the fake server
+func (s *fakeDNS) set(answers ...dnsmessage.Resource) {
+	s.mu.Lock()
+	s.answers = answers
+	s.mu.Unlock()
+}
+
+func txt(ttl uint32, value string) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("_vhosts.example.com."), Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.TXTResource{TXT: []string{value}},
+	}
+}
+
+// Test TXT records are loaded and refreshed when their TTL expires.
+func TestProvider_TXT(t *testing.T) {
+	api := fiber.New()
+	web := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"api": api, "web": web})
+
+	server := newFakeDNS(t)
+	server.set(
+		txt(1, "api.example.com=api"),
+		txt(60, `*.example.org={"app":"web","suspended":true}`),
+		txt(60, "_default=web"),
+		txt(60, "no separator"),
+	)
+
+	manager := fibervhosts.NewVhostsManager()
+	provider := New(Config{Zone: "_vhosts.example.com", Server: server.conn.LocalAddr().String(), Resolver: resolver, MinInterval: 10 * time.Millisecond})
+
+	entries, ttl, err := provider.lookup(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, time.Second, ttl)
+
+	detach, err := manager.AttachProvider(provider)
+	assert.NoError(t, err)
+	defer detach()
+	assert.True(t, manager.IsSuspended("*.example.org"))
+
+	// The change is picked up once the shortest TTL expires
+	server.set(txt(1, "new.example.com=api"))
+	assert.Eventually(t, func() bool {
+		_, exists := manager.GetHostname("new.example.com")
+		return exists && len(manager.ListEntries()) == 1
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+// Test SRV targets are registered with their address as backend.
+func TestProvider_SRV(t *testing.T) {
+	app := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"shop.example.com:8080": app})
+
+	server := newFakeDNS(t)
+	server.set(dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("_vhosts._tcp.example.com."), Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: 60},
+		Body:   &dnsmessage.SRVResource{Port: 8080, Target: dnsmessage.MustNewName("shop.example.com.")},
+	})
+
+	provider := New(Config{Zone: "_vhosts._tcp.example.com.", Mode: ModeSRV, Server: server.conn.LocalAddr().String(), Resolver: resolver})
+	entries, err := provider.Load()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "shop.example.com", entries[0].Pattern)
+	assert.Equal(t, app, entries[0].App)
+}
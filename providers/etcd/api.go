@@ -0,0 +1,70 @@
+package etcd
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// The request and response types of the etcd v3 JSON gateway. Keys and values are base64 encoded and 64-bit integers are encoded as strings.
+
+type rangeRequest struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"range_end"`
+}
+
+type rangeResponse struct {
+	Header responseHeader `json:"header"`
+	Kvs    []keyValue     `json:"kvs"`
+}
+
+type watchRequest struct {
+	CreateRequest watchCreateRequest `json:"create_request"`
+}
+
+type watchCreateRequest struct {
+	Key           string `json:"key"`
+	RangeEnd      string `json:"range_end"`
+	StartRevision string `json:"start_revision"`
+}
+
+type watchResponse struct {
+	Result struct {
+		Header   responseHeader `json:"header"`
+		Canceled bool           `json:"canceled"`
+		Events   []event        `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type responseHeader struct {
+	Revision int64String `json:"revision"`
+}
+
+type event struct {
+	// Type is empty for PUT events, as the gateway omits zero enum values
+	Type string   `json:"type"`
+	Kv   keyValue `json:"kv"`
+}
+
+type keyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// int64String decodes a 64-bit integer sent either as a JSON string or number
+type int64String int64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (i *int64String) UnmarshalJSON(data []byte) error {
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		data = []byte(s)
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*i = int64String(n)
+	return nil
+}
@@ -0,0 +1,289 @@
+// Package etcd provides a fibervhosts.Provider that keeps a VhostsManager in sync with an etcd key prefix, so multi-instance deployments share one source of truth for which domains map to which app.
+//
+// Every key below the prefix registers the hostname named by the rest of the key. The value is either a plain app name or a JSON object like {"app":"web","suspended":true,"metadata":{"tags":["gold"]}}. The key "<prefix>_default" sets the default app. The provider talks to the etcd v3 JSON gateway, which is served on the regular client port, so no etcd client library is needed.
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+)
+
+// DefaultKey is the key, relative to the prefix, holding the default app
+const DefaultKey = "_default"
+
+// Config defines the config for the etcd provider
+type Config struct {
+	// Endpoint is the base URL of an etcd member. Defaults to "http://127.0.0.1:2379".
+	Endpoint string
+	// Prefix is the key prefix holding the vhost table. Defaults to "/vhosts/".
+	Prefix string
+	// Resolver maps app names to apps. Required.
+	Resolver fibervhosts.AppResolver
+	// Source identifies the registrations managed by this provider. Defaults to "etcd".
+	Source string
+	// Client is used for all requests. It must not have a timeout, as watches are long-lived. Defaults to http.DefaultClient.
+	Client *http.Client
+	// RetryInterval is the delay before reconnecting after an error. Defaults to 5 seconds.
+	RetryInterval time.Duration
+	// Logger receives the warnings of the provider, like connection errors. Defaults to the Logger of the manager the provider is attached to, see fibervhosts.ProviderLogger.
+	Logger fibervhosts.Logger
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Endpoint:      "http://127.0.0.1:2379",
+	Prefix:        "/vhosts/",
+	Source:        "etcd",
+	RetryInterval: 5 * time.Second,
+}
+
+// Provider is a fibervhosts.Provider reading the vhost table from an etcd prefix
+type Provider struct {
+	config Config
+	fibervhosts.ProviderLogger
+
+	mu    sync.Mutex
+	specs map[string]fibervhosts.EntrySpec
+}
+
+// New creates a new etcd provider. Attach it with VhostsManager.AttachProvider.
+func New(config Config) *Provider {
+	if config.Endpoint == "" {
+		config.Endpoint = ConfigDefault.Endpoint
+	}
+	if config.Prefix == "" {
+		config.Prefix = ConfigDefault.Prefix
+	}
+	if config.Source == "" {
+		config.Source = ConfigDefault.Source
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = ConfigDefault.RetryInterval
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+
+	return &Provider{config: config, ProviderLogger: fibervhosts.NewProviderLogger(config.Logger)}
+}
+
+// Name returns the source of the registrations made by the provider
+func (p *Provider) Name() string {
+	return p.config.Source
+}
+
+// Load reads the prefix once and returns its registrations
+func (p *Provider) Load() ([]fibervhosts.Entry, error) {
+	if _, err := p.load(context.Background()); err != nil {
+		return nil, err
+	}
+	return p.entries(), nil
+}
+
+// Watch reads the prefix and then watches it for changes, emitting the registrations after every change. Connection errors are logged and retried after Config.RetryInterval. The channel is closed once ctx is done.
+func (p *Provider) Watch(ctx context.Context) <-chan []fibervhosts.Entry {
+	out := make(chan []fibervhosts.Entry)
+	go func() {
+		defer close(out)
+		for {
+			revision, err := p.load(ctx)
+			if err == nil && p.emit(ctx, out) {
+				err = p.watch(ctx, revision+1, out)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			p.Logger().Warn("etcd provider failed, retrying", "error", err, "retry", p.config.RetryInterval)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.config.RetryInterval):
+			}
+		}
+	}()
+	return out
+}
+
+// load reads all keys below the prefix and replaces the known specs with them. It returns the store revision of the read.
+func (p *Provider) load(ctx context.Context) (int64, error) {
+	req := rangeRequest{Key: encode(p.config.Prefix), RangeEnd: encode(prefixEnd(p.config.Prefix))}
+	resp, err := p.post(ctx, "/v3/kv/range", req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result rangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decode range response: %w", err)
+	}
+
+	specs := make(map[string]fibervhosts.EntrySpec, len(result.Kvs))
+	for _, kv := range result.Kvs {
+		if hostname, spec, ok := p.parse(kv); ok {
+			specs[hostname] = spec
+		}
+	}
+
+	p.mu.Lock()
+	p.specs = specs
+	p.mu.Unlock()
+	return int64(result.Header.Revision), nil
+}
+
+// watch streams changes below the prefix starting at revision and emits the registrations after every change until the stream ends
+func (p *Provider) watch(ctx context.Context, revision int64, out chan<- []fibervhosts.Entry) error {
+	req := watchRequest{CreateRequest: watchCreateRequest{
+		Key:           encode(p.config.Prefix),
+		RangeEnd:      encode(prefixEnd(p.config.Prefix)),
+		StartRevision: strconv.FormatInt(revision, 10),
+	}}
+	resp, err := p.post(ctx, "/v3/watch", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg watchResponse
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("watch stream closed")
+			}
+			return fmt.Errorf("decode watch response: %w", err)
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("watch: %s", msg.Error.Message)
+		}
+		if msg.Result.Canceled {
+			return errors.New("watch canceled")
+		}
+		if len(msg.Result.Events) == 0 {
+			continue
+		}
+
+		p.mu.Lock()
+		for _, ev := range msg.Result.Events {
+			hostname := p.hostname(ev.Kv)
+			if ev.Type == "DELETE" {
+				delete(p.specs, hostname)
+				continue
+			}
+			if hostname, spec, ok := p.parse(ev.Kv); ok {
+				p.specs[hostname] = spec
+			}
+		}
+		p.mu.Unlock()
+
+		if !p.emit(ctx, out) {
+			return ctx.Err()
+		}
+	}
+}
+
+// emit sends the current registrations on out. It returns false if ctx is done first.
+func (p *Provider) emit(ctx context.Context, out chan<- []fibervhosts.Entry) bool {
+	select {
+	case out <- p.entries():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// entries resolves the known specs into registrations
+func (p *Provider) entries() []fibervhosts.Entry {
+	p.mu.Lock()
+	specs := make([]fibervhosts.EntrySpec, 0, len(p.specs))
+	for _, spec := range p.specs {
+		specs = append(specs, spec)
+	}
+	p.mu.Unlock()
+
+	return fibervhosts.ResolveSpecs(specs, p.config.Resolver)
+}
+
+// hostname returns the hostname a key refers to, or an empty string for the default app key
+func (p *Provider) hostname(kv keyValue) string {
+	hostname := strings.TrimPrefix(decode(kv.Key), p.config.Prefix)
+	if hostname == DefaultKey {
+		return ""
+	}
+	return hostname
+}
+
+// parse parses a key/value pair into a spec, logging invalid values
+func (p *Provider) parse(kv keyValue) (string, fibervhosts.EntrySpec, bool) {
+	hostname := p.hostname(kv)
+	spec, err := fibervhosts.ParseEntrySpec(hostname, decode(kv.Value))
+	if err != nil {
+		p.Logger().Warn("etcd provider skipping invalid value", "key", decode(kv.Key), "error", err)
+		return "", spec, false
+	}
+	return hostname, spec, true
+}
+
+// post sends a JSON request to the etcd gateway
+func (p *Provider) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected status %s", path, resp.Status)
+	}
+	return resp, nil
+}
+
+// prefixEnd returns the range end covering every key starting with prefix
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	// The prefix is all 0xff bytes, so range to the end of the keyspace
+	return "\x00"
+}
+
+// encode base64-encodes a key for the JSON gateway
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// decode decodes a base64 key or value from the JSON gateway
+func decode(s string) string {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
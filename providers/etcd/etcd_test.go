@@ -0,0 +1,137 @@
+package etcd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEtcd serves the range and watch endpoints of the etcd JSON gateway
+func fakeEtcd(t *testing.T, kvs map[string]string, events <-chan string) *httptest.Server {
+	t.Helper()
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			resp := map[string]any{"header": map[string]any{"revision": "7"}}
+			var list []map[string]string
+			for k, v := range kvs {
+				list = append(list, map[string]string{"key": b64(k), "value": b64(v)})
+			}
+			resp["kvs"] = list
+			assert.NoError(t, json.NewEncoder(w).Encode(resp))
+		case "/v3/watch":
+			var req watchRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "8", req.CreateRequest.StartRevision)
+
+			fmt.Fprint(w, `{"result":{"created":true}}`)
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case <-r.Context().Done():
+					return
+				case ev := <-events:
+					fmt.Fprint(w, ev)
+					w.(http.Flusher).Flush()
+				}
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// Test the provider loads the prefix and applies watched changes.
+func TestProvider_Watch(t *testing.T) {
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	api := fiber.New()
+	web := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"api": api, "web": web})
+
+	events := make(chan string, 1)
+	server := fakeEtcd(t, map[string]string{
+		"/vhosts/api.example.com": "api",
+		"/vhosts/*.example.org":   `{"app":"web","metadata":{"tags":["gold"]}}`,
+		"/vhosts/_default":        "web",
+		"/vhosts/bad.example.com": "unknown",
+	}, events)
+	defer server.Close()
+
+	manager := fibervhosts.NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("manual.example.com", api))
+
+	detach, err := manager.AttachProvider(New(Config{Endpoint: server.URL, Resolver: resolver}))
+	assert.NoError(t, err)
+	defer detach()
+
+	_, exists := manager.GetHostname("api.example.com")
+	assert.True(t, exists)
+
+	md, _ := manager.GetMetadata("*.example.org")
+	assert.True(t, md.HasTag("gold"))
+	assert.Len(t, manager.FilterEntries(func(e fibervhosts.Entry) bool { return e.Source == "etcd" }), 3)
+
+	// A put and a delete arrive through the watch
+	events <- fmt.Sprintf(`{"result":{"events":[{"kv":{"key":%q,"value":%q}},{"type":"DELETE","kv":{"key":%q}}]}}`,
+		b64("/vhosts/new.example.com"), b64("web"), b64("/vhosts/api.example.com"))
+
+	assert.Eventually(t, func() bool {
+		_, added := manager.GetHostname("new.example.com")
+		_, kept := manager.GetHostname("api.example.com")
+		return added && !kept
+	}, time.Second, 5*time.Millisecond)
+
+	// Manual registrations are never touched
+	_, exists = manager.GetHostname("manual.example.com")
+	assert.True(t, exists)
+}
+
+// Test the range end covers every key with the prefix.
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, "/vhosts0", prefixEnd("/vhosts/"))
+	assert.Equal(t, "b", prefixEnd("a\xff"))
+	assert.Equal(t, "\x00", prefixEnd("\xff"))
+}
+
+// warnings records the warnings logged through it
+type warnings struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (w *warnings) Debug(string, ...any) {}
+func (w *warnings) Info(string, ...any)  {}
+func (w *warnings) Error(string, ...any) {}
+func (w *warnings) Warn(msg string, fields ...any) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.msgs = append(w.msgs, fmt.Sprint(msg, fields))
+}
+
+// Test the provider logs through the Logger of the manager it is attached to.
+func TestProvider_Logger(t *testing.T) {
+	server := fakeEtcd(t, map[string]string{"/vhosts/bad.example.com": `{"app":`}, make(chan string))
+	defer server.Close()
+
+	logger := &warnings{}
+	manager := fibervhosts.NewVhostsManager(fibervhosts.Config{Logger: logger})
+	detach, err := manager.AttachProvider(New(Config{Endpoint: server.URL, Resolver: fibervhosts.MapResolver(nil)}))
+	assert.NoError(t, err)
+	detach()
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	assert.NotEmpty(t, logger.msgs)
+	assert.Contains(t, logger.msgs[0], "bad.example.com")
+}
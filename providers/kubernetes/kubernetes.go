@@ -0,0 +1,309 @@
+// Package kubernetes provides a fibervhosts.Provider that lets a VhostsManager act as an in-cluster ingress data plane: it watches Ingress (or Gateway API HTTPRoute) resources and registers their hostnames in the manager, mapping each backend service to a locally registered app.
+//
+// A backend service is identified as "<namespace>/<service>" unless Config.BackendName is set. The provider talks to the API server REST endpoints directly, using the in-cluster service account by default, so no Kubernetes client library is needed.
+package kubernetes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+)
+
+// Kind selects the watched resource
+type Kind int
+
+const (
+	// KindIngress watches networking.k8s.io/v1 Ingress resources
+	KindIngress Kind = iota
+	// KindHTTPRoute watches gateway.networking.k8s.io/v1 HTTPRoute resources
+	KindHTTPRoute
+)
+
+// Paths of the in-cluster service account credentials
+const (
+	serviceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCA    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// Config defines the config for the Kubernetes provider
+type Config struct {
+	// Host is the API server URL. Defaults to the in-cluster address from KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT.
+	Host string
+	// Token is the bearer token. Defaults to the in-cluster service account token.
+	Token string
+	// Client is used for all requests. Defaults to a client trusting the in-cluster service account CA.
+	Client *http.Client
+	// Kind selects Ingress or HTTPRoute resources. Defaults to KindIngress.
+	Kind Kind
+	// Namespace limits the watch to one namespace. Defaults to all namespaces.
+	Namespace string
+	// IngressClass only accepts Ingresses of this class, by spec.ingressClassName or the kubernetes.io/ingress.class annotation. Defaults to accepting all.
+	IngressClass string
+	// BackendName builds the app name of a backend service. Defaults to "<namespace>/<service>".
+	BackendName func(namespace, service string) string
+	// Resolver maps backend names to apps. Required.
+	Resolver fibervhosts.AppResolver
+	// Source identifies the registrations managed by this provider. Defaults to "kubernetes".
+	Source string
+	// RetryInterval is the delay before reconnecting after an error. Defaults to 5 seconds.
+	RetryInterval time.Duration
+	// Logger receives the warnings of the provider, like connection errors. Defaults to the Logger of the manager the provider is attached to, see fibervhosts.ProviderLogger.
+	Logger fibervhosts.Logger
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Kind:          KindIngress,
+	Source:        "kubernetes",
+	RetryInterval: 5 * time.Second,
+	BackendName: func(namespace, service string) string {
+		return namespace + "/" + service
+	},
+}
+
+// Provider is a fibervhosts.Provider reading the vhost table from Kubernetes resources
+type Provider struct {
+	config Config
+	fibervhosts.ProviderLogger
+
+	mu      sync.Mutex
+	objects map[string][]fibervhosts.EntrySpec
+}
+
+// New creates a new Kubernetes provider. Attach it with VhostsManager.AttachProvider. Without Host, the in-cluster configuration is loaded, which fails outside a pod.
+func New(config Config) (*Provider, error) {
+	if config.Host == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("kubernetes provider: not running in a cluster and no Host configured")
+		}
+		config.Host = "https://" + net.JoinHostPort(host, port)
+
+		if config.Token == "" {
+			token, err := os.ReadFile(serviceAccountToken)
+			if err != nil {
+				return nil, fmt.Errorf("kubernetes provider: %w", err)
+			}
+			config.Token = strings.TrimSpace(string(token))
+		}
+		if config.Client == nil {
+			ca, err := os.ReadFile(serviceAccountCA)
+			if err != nil {
+				return nil, fmt.Errorf("kubernetes provider: %w", err)
+			}
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(ca)
+			config.Client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+		}
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.BackendName == nil {
+		config.BackendName = ConfigDefault.BackendName
+	}
+	if config.Source == "" {
+		config.Source = ConfigDefault.Source
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = ConfigDefault.RetryInterval
+	}
+	config.Host = strings.TrimRight(config.Host, "/")
+
+	return &Provider{config: config, ProviderLogger: fibervhosts.NewProviderLogger(config.Logger)}, nil
+}
+
+// Name returns the source of the registrations made by the provider
+func (p *Provider) Name() string {
+	return p.config.Source
+}
+
+// Load lists the resources once and returns their registrations
+func (p *Provider) Load() ([]fibervhosts.Entry, error) {
+	if _, err := p.list(context.Background()); err != nil {
+		return nil, err
+	}
+	return p.entries(), nil
+}
+
+// Watch lists the resources and then watches them, emitting the registrations after every change. Errors are logged and the list and watch are restarted after Config.RetryInterval. The channel is closed once ctx is done.
+func (p *Provider) Watch(ctx context.Context) <-chan []fibervhosts.Entry {
+	out := make(chan []fibervhosts.Entry)
+	go func() {
+		defer close(out)
+		for {
+			version, err := p.list(ctx)
+			if err == nil && p.emit(ctx, out) {
+				err = p.watch(ctx, version, out)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			p.Logger().Warn("Kubernetes provider failed, retrying", "error", err, "retry", p.config.RetryInterval)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.config.RetryInterval):
+			}
+		}
+	}()
+	return out
+}
+
+// list reads all resources and replaces the known objects with them. It returns the resource version to watch from.
+func (p *Provider) list(ctx context.Context) (string, error) {
+	resp, err := p.get(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Items []object `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return "", fmt.Errorf("decode list: %w", err)
+	}
+
+	objects := make(map[string][]fibervhosts.EntrySpec, len(list.Items))
+	for _, obj := range list.Items {
+		objects[obj.key()] = p.specs(obj)
+	}
+
+	p.mu.Lock()
+	p.objects = objects
+	p.mu.Unlock()
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch streams changes starting at version and emits the registrations after every change until the stream ends
+func (p *Provider) watch(ctx context.Context, version string, out chan<- []fibervhosts.Entry) error {
+	resp, err := p.get(ctx, url.Values{"watch": {"1"}, "resourceVersion": {version}, "allowWatchBookmarks": {"true"}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev struct {
+			Type   string          `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+		if err := dec.Decode(&ev); err != nil {
+			return fmt.Errorf("watch: %w", err)
+		}
+
+		var obj object
+		if err := json.Unmarshal(ev.Object, &obj); err != nil {
+			return fmt.Errorf("decode watch event: %w", err)
+		}
+
+		p.mu.Lock()
+		switch ev.Type {
+		case "ADDED", "MODIFIED":
+			p.objects[obj.key()] = p.specs(obj)
+		case "DELETED":
+			delete(p.objects, obj.key())
+		case "ERROR":
+			// Usually an expired resource version, which requires a new list
+			p.mu.Unlock()
+			return fmt.Errorf("watch error: %s", ev.Object)
+		default:
+			p.mu.Unlock()
+			continue
+		}
+		p.mu.Unlock()
+
+		if !p.emit(ctx, out) {
+			return ctx.Err()
+		}
+	}
+}
+
+// emit sends the current registrations on out. It returns false if ctx is done first.
+func (p *Provider) emit(ctx context.Context, out chan<- []fibervhosts.Entry) bool {
+	select {
+	case out <- p.entries():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// entries resolves the specs of all known objects into registrations. When several objects claim the same hostname, the first object by namespace/name wins.
+func (p *Provider) entries() []fibervhosts.Entry {
+	p.mu.Lock()
+	keys := make([]string, 0, len(p.objects))
+	for key := range p.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var specs []fibervhosts.EntrySpec
+	claimed := make(map[string]string)
+	for _, key := range keys {
+		for _, spec := range p.objects[key] {
+			if owner, taken := claimed[spec.Hostname]; taken {
+				p.Logger().Warn("Kubernetes provider skipping hostname claimed by another resource", "hostname", spec.Hostname, "resource", key, "owner", owner)
+				continue
+			}
+			claimed[spec.Hostname] = key
+			specs = append(specs, spec)
+		}
+	}
+	p.mu.Unlock()
+
+	return fibervhosts.ResolveSpecs(specs, p.config.Resolver)
+}
+
+// get requests the resource collection with the given query
+func (p *Provider) get(ctx context.Context, query url.Values) (*http.Response, error) {
+	path := "/apis/networking.k8s.io/v1"
+	resource := "ingresses"
+	if p.config.Kind == KindHTTPRoute {
+		path, resource = "/apis/gateway.networking.k8s.io/v1", "httproutes"
+	}
+	if p.config.Namespace != "" {
+		path += "/namespaces/" + url.PathEscape(p.config.Namespace)
+	}
+	target := p.config.Host + path + "/" + resource
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.config.Token)
+	}
+
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected status %s", resource, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,120 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPIServer serves the list and watch requests for a resource collection
+func fakeAPIServer(t *testing.T, path, list string, events <-chan string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		if r.URL.Query().Get("watch") == "" {
+			fmt.Fprint(w, list)
+			return
+		}
+		assert.Equal(t, "42", r.URL.Query().Get("resourceVersion"))
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case ev := <-events:
+				fmt.Fprintln(w, ev)
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+}
+
+// Test the provider lists Ingresses and applies watched changes.
+func TestProvider_Watch_Ingress(t *testing.T) {
+	web := fiber.New()
+	api := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"shop/web": web, "shop/api": api})
+
+	list := `{"metadata":{"resourceVersion":"42"},"items":[
+		{"metadata":{"name":"front","namespace":"shop"},"spec":{"ingressClassName":"vhosts","rules":[
+			{"host":"www.example.com","http":{"paths":[{"backend":{"service":{"name":"web"}}}]}},
+			{"host":"*.example.org"}
+		],"defaultBackend":{"service":{"name":"web"}}}},
+		{"metadata":{"name":"other","namespace":"shop"},"spec":{"ingressClassName":"nginx","rules":[
+			{"host":"nginx.example.com","http":{"paths":[{"backend":{"service":{"name":"web"}}}]}}
+		]}},
+		{"metadata":{"name":"broken","namespace":"shop"},"spec":{"ingressClassName":"vhosts","rules":[
+			{"host":"bad.example.com","http":{"paths":[{"backend":{"service":{"name":"unknown"}}}]}}
+		]}}
+	]}`
+	events := make(chan string, 1)
+	server := fakeAPIServer(t, "/apis/networking.k8s.io/v1/ingresses", list, events)
+	defer server.Close()
+
+	manager := fibervhosts.NewVhostsManager()
+	provider, err := New(Config{Host: server.URL, Token: "secret", IngressClass: "vhosts", Resolver: resolver})
+	assert.NoError(t, err)
+	detach, err := manager.AttachProvider(provider)
+	assert.NoError(t, err)
+	defer detach()
+
+	entries := manager.ListEntries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "*.example.org", entries[1].Pattern)
+	assert.Equal(t, web, entries[1].App)
+	assert.Equal(t, "kubernetes", entries[1].Source)
+	assert.Equal(t, "front", entries[0].Metadata.Get("name"))
+
+	events <- `{"type":"ADDED","object":{"metadata":{"name":"api","namespace":"shop"},"spec":{"ingressClassName":"vhosts","rules":[
+		{"host":"api.example.com","http":{"paths":[{"backend":{"service":{"name":"api"}}}]}}]}}}`
+	assert.Eventually(t, func() bool {
+		app, _ := manager.GetHostname("api.example.com")
+		return app == api
+	}, time.Second, 10*time.Millisecond)
+
+	events <- `{"type":"DELETED","object":{"metadata":{"name":"front","namespace":"shop"}}}`
+	assert.Eventually(t, func() bool {
+		return len(manager.ListEntries()) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+// Test that HTTPRoute hostnames map to the first backend reference.
+func TestProvider_Load_HTTPRoute(t *testing.T) {
+	app := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"backends/site": app})
+
+	list := `{"metadata":{"resourceVersion":"42"},"items":[
+		{"metadata":{"name":"site","namespace":"routes"},"spec":{
+			"hostnames":["a.example.com","b.example.com"],
+			"rules":[{"backendRefs":[{"name":"site","namespace":"backends"}]}]
+		}}
+	]}`
+	server := fakeAPIServer(t, "/apis/gateway.networking.k8s.io/v1/namespaces/routes/httproutes", list, nil)
+	defer server.Close()
+
+	manager := fibervhosts.NewVhostsManager()
+	provider, err := New(Config{Host: server.URL, Token: "secret", Kind: KindHTTPRoute, Namespace: "routes", Resolver: resolver})
+	assert.NoError(t, err)
+	entries, err := provider.Load()
+	assert.NoError(t, err)
+	assert.NoError(t, manager.ApplyEntries(provider.Name(), entries))
+	assert.ElementsMatch(t, []string{"a.example.com", "b.example.com"}, manager.GetHostnames())
+}
+
+// Test that New fails outside a cluster without a Host.
+func TestNew_NotInCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, err := New(Config{})
+	assert.Error(t, err)
+}
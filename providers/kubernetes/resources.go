@@ -0,0 +1,106 @@
+package kubernetes
+
+import (
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+)
+
+// object holds the fields of Ingress and HTTPRoute resources used by the provider
+type object struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		// Ingress
+		IngressClassName string          `json:"ingressClassName"`
+		DefaultBackend   *ingressBackend `json:"defaultBackend"`
+		Rules            []struct {
+			Host string `json:"host"`
+			HTTP *struct {
+				Paths []struct {
+					Backend ingressBackend `json:"backend"`
+				} `json:"paths"`
+			} `json:"http"`
+			// HTTPRoute
+			BackendRefs []struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"backendRefs"`
+		} `json:"rules"`
+		// HTTPRoute
+		Hostnames []string `json:"hostnames"`
+	} `json:"spec"`
+}
+
+type ingressBackend struct {
+	Service *struct {
+		Name string `json:"name"`
+	} `json:"service"`
+}
+
+// key returns the namespace/name of the object
+func (o object) key() string {
+	return o.Metadata.Namespace + "/" + o.Metadata.Name
+}
+
+// specs returns the registrations described by the object
+func (p *Provider) specs(o object) []fibervhosts.EntrySpec {
+	metadata := &fibervhosts.Metadata{Values: map[string]string{
+		"namespace": o.Metadata.Namespace,
+		"name":      o.Metadata.Name,
+	}}
+	spec := func(hostname, namespace, service string) fibervhosts.EntrySpec {
+		return fibervhosts.EntrySpec{
+			Hostname: hostname,
+			App:      p.config.BackendName(namespace, service),
+			Metadata: metadata,
+		}
+	}
+
+	var specs []fibervhosts.EntrySpec
+	if p.config.Kind == KindHTTPRoute {
+		// All hostnames are served by the first backend of the first rule
+		for _, rule := range o.Spec.Rules {
+			if len(rule.BackendRefs) == 0 {
+				continue
+			}
+			ref := rule.BackendRefs[0]
+			namespace := o.Metadata.Namespace
+			if ref.Namespace != "" {
+				namespace = ref.Namespace
+			}
+			for _, hostname := range o.Spec.Hostnames {
+				specs = append(specs, spec(hostname, namespace, ref.Name))
+			}
+			break
+		}
+		return specs
+	}
+
+	if p.config.IngressClass != "" {
+		class := o.Spec.IngressClassName
+		if class == "" {
+			class = o.Metadata.Annotations["kubernetes.io/ingress.class"]
+		}
+		if class != p.config.IngressClass {
+			return nil
+		}
+	}
+
+	// Each rule host is served by the backend of its first path, falling back to the default backend
+	for _, rule := range o.Spec.Rules {
+		if rule.Host == "" {
+			continue
+		}
+		backend := o.Spec.DefaultBackend
+		if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+			backend = &rule.HTTP.Paths[0].Backend
+		}
+		if backend == nil || backend.Service == nil {
+			continue
+		}
+		specs = append(specs, spec(rule.Host, o.Metadata.Namespace, backend.Service.Name))
+	}
+	return specs
+}
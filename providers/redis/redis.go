@@ -0,0 +1,221 @@
+// Package redis provides a fibervhosts.Provider that keeps a VhostsManager in sync with a Redis hash, so a fleet of Fiber nodes shares one dynamic routing table.
+//
+// Every field of the hash registers the hostname named by the field. The value is either a plain app name or a JSON object like {"app":"web","suspended":true}. The field "_default" sets the default app. Changes are picked up through a pub/sub channel, on which writers publish after updating the hash, or through keyspace notifications for the hash when enabled on the server (notify-keyspace-events "Kh").
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+)
+
+// DefaultField is the hash field holding the default app
+const DefaultField = "_default"
+
+// Config defines the config for the Redis provider
+type Config struct {
+	// Addr is the host:port of the Redis server. Defaults to "127.0.0.1:6379".
+	Addr string
+	// Username and Password are used to authenticate when Password is set
+	Username string
+	Password string
+	// DB is the database holding the hash
+	DB int
+	// Key is the hash holding the vhost table. Defaults to "vhosts".
+	Key string
+	// Channel is the pub/sub channel announcing changes. Defaults to "vhosts:changes".
+	Channel string
+	// KeyspaceNotifications subscribes to the keyspace notifications of Key instead of Channel
+	KeyspaceNotifications bool
+	// Resolver maps app names to apps. Required.
+	Resolver fibervhosts.AppResolver
+	// Source identifies the registrations managed by this provider. Defaults to "redis".
+	Source string
+	// DialTimeout limits connecting to the server. Defaults to 5 seconds.
+	DialTimeout time.Duration
+	// PingInterval is how often an idle subscription is checked. Defaults to 30 seconds.
+	PingInterval time.Duration
+	// RetryInterval is the delay before reconnecting after an error. Defaults to 5 seconds.
+	RetryInterval time.Duration
+	// Logger receives the warnings of the provider, like connection errors. Defaults to the Logger of the manager the provider is attached to, see fibervhosts.ProviderLogger.
+	Logger fibervhosts.Logger
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Addr:          "127.0.0.1:6379",
+	Key:           "vhosts",
+	Channel:       "vhosts:changes",
+	Source:        "redis",
+	DialTimeout:   5 * time.Second,
+	PingInterval:  30 * time.Second,
+	RetryInterval: 5 * time.Second,
+}
+
+// Provider is a fibervhosts.Provider reading the vhost table from a Redis hash
+type Provider struct {
+	config Config
+	fibervhosts.ProviderLogger
+}
+
+// New creates a new Redis provider. Attach it with VhostsManager.AttachProvider.
+func New(config Config) *Provider {
+	if config.Addr == "" {
+		config.Addr = ConfigDefault.Addr
+	}
+	if config.Key == "" {
+		config.Key = ConfigDefault.Key
+	}
+	if config.Channel == "" {
+		config.Channel = ConfigDefault.Channel
+	}
+	if config.Source == "" {
+		config.Source = ConfigDefault.Source
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = ConfigDefault.DialTimeout
+	}
+	if config.PingInterval <= 0 {
+		config.PingInterval = ConfigDefault.PingInterval
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = ConfigDefault.RetryInterval
+	}
+	return &Provider{config: config, ProviderLogger: fibervhosts.NewProviderLogger(config.Logger)}
+}
+
+// Name returns the source of the registrations made by the provider
+func (p *Provider) Name() string {
+	return p.config.Source
+}
+
+// Load reads the hash once and returns its registrations
+func (p *Provider) Load() ([]fibervhosts.Entry, error) {
+	return p.read(context.Background())
+}
+
+// Watch subscribes to changes, reads the hash, and re-reads it on every announced change, emitting the registrations after every read. Connection errors are logged and retried after Config.RetryInterval. The channel is closed once ctx is done.
+func (p *Provider) Watch(ctx context.Context) <-chan []fibervhosts.Entry {
+	out := make(chan []fibervhosts.Entry)
+	go func() {
+		defer close(out)
+		for {
+			err := p.follow(ctx, out)
+			if ctx.Err() != nil {
+				return
+			}
+			p.Logger().Warn("Redis provider failed, retrying", "error", err, "retry", p.config.RetryInterval)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.config.RetryInterval):
+			}
+		}
+	}()
+	return out
+}
+
+// read reads the hash on a new connection and returns its registrations
+func (p *Provider) read(ctx context.Context) ([]fibervhosts.Entry, error) {
+	cn, err := dial(ctx, p.config)
+	if err != nil {
+		return nil, err
+	}
+	defer cn.Close()
+
+	fields, err := cn.hgetall(p.config.Key)
+	if err != nil {
+		return nil, err
+	}
+	return p.entries(fields), nil
+}
+
+// follow subscribes, reads the hash and then reads it again for every message, emitting the registrations after every read until the subscription fails
+func (p *Provider) follow(ctx context.Context, out chan<- []fibervhosts.Entry) error {
+	sub, err := dial(ctx, p.config)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	// Unblock the subscription when the context is done
+	stop := context.AfterFunc(ctx, func() { sub.Close() })
+	defer stop()
+
+	// Subscribe before the initial read so no change can slip in between
+	if err := sub.subscribe(p.channel()); err != nil {
+		return err
+	}
+	refresh := func() error {
+		entries, err := p.read(ctx)
+		if err != nil {
+			return err
+		}
+		select {
+		case out <- entries:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := refresh(); err != nil {
+		return err
+	}
+
+	// Ping the idle subscription so a dead connection is noticed
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(p.config.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if sub.send("PING") != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		if _, err := sub.receive(2 * p.config.PingInterval); err != nil {
+			return err
+		}
+		if err := refresh(); err != nil {
+			return err
+		}
+	}
+}
+
+// channel returns the channel announcing changes of the hash
+func (p *Provider) channel() string {
+	if p.config.KeyspaceNotifications {
+		return "__keyspace@" + strconv.Itoa(p.config.DB) + "__:" + p.config.Key
+	}
+	return p.config.Channel
+}
+
+// entries resolves the hash fields into registrations. Invalid values are logged and skipped so they don't block the rest of the table.
+func (p *Provider) entries(fields map[string]string) []fibervhosts.Entry {
+	specs := make([]fibervhosts.EntrySpec, 0, len(fields))
+	for field, value := range fields {
+		hostname := field
+		if hostname == DefaultField {
+			hostname = ""
+		}
+		spec, err := fibervhosts.ParseEntrySpec(hostname, value)
+		if err != nil {
+			p.Logger().Warn("Redis provider skipping invalid value", "field", field, "error", err)
+			continue
+		}
+		specs = append(specs, spec)
+	}
+
+	return fibervhosts.ResolveSpecs(specs, p.config.Resolver)
+}
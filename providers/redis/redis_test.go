@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is a tiny RESP server supporting AUTH, HGETALL, SUBSCRIBE and PING
+type fakeRedis struct {
+	ln net.Listener
+
+	mu          sync.Mutex
+	hash        map[string]string
+	subscribers []*conn
+	commands    []string
+}
+
+// newFakeRedis starts a fake server on a random port
+func newFakeRedis(t *testing.T, hash map[string]string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	f := &fakeRedis{ln: ln, hash: hash}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		c, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(&conn{c: c, r: bufio.NewReader(c)})
+	}
+}
+
+func (f *fakeRedis) handle(cn *conn) {
+	defer cn.Close()
+	for {
+		cmd, err := cn.read()
+		if err != nil {
+			return
+		}
+		args := cmd.([]any)
+		name := strings.ToUpper(args[0].(string))
+
+		f.mu.Lock()
+		f.commands = append(f.commands, name)
+		switch name {
+		case "AUTH":
+			cn.c.Write([]byte("+OK\r\n"))
+		case "HGETALL":
+			var reply []string
+			for k, v := range f.hash {
+				reply = append(reply, k, v)
+			}
+			writeArray(cn, reply...)
+		case "SUBSCRIBE":
+			writeArray(cn, "subscribe", args[1].(string))
+			f.subscribers = append(f.subscribers, cn)
+		case "PING":
+			writeArray(cn, "pong", "")
+		}
+		f.mu.Unlock()
+	}
+}
+
+// set updates a hash field and announces the change
+func (f *fakeRedis) set(field, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hash[field] = value
+	for _, sub := range f.subscribers {
+		writeArray(sub, "message", "vhosts:changes", "changed")
+	}
+}
+
+func writeArray(cn *conn, items ...string) {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(items)) + "\r\n")
+	for _, item := range items {
+		b.WriteString("$" + strconv.Itoa(len(item)) + "\r\n" + item + "\r\n")
+	}
+	cn.c.Write([]byte(b.String()))
+}
+
+// Test the provider loads the hash and follows announced changes.
+func TestProvider_Watch(t *testing.T) {
+	api := fiber.New()
+	web := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"api": api, "web": web})
+
+	server := newFakeRedis(t, map[string]string{
+		"api.example.com": "api",
+		"_default":        "web",
+	})
+
+	manager := fibervhosts.NewVhostsManager()
+	detach, err := manager.AttachProvider(New(Config{Addr: server.ln.Addr().String(), Password: "secret", Resolver: resolver}))
+	assert.NoError(t, err)
+	defer detach()
+
+	_, exists := manager.GetHostname("api.example.com")
+	assert.True(t, exists)
+	assert.Len(t, manager.ListEntries(), 2)
+
+	server.set("*.example.org", `{"app":"web","suspended":true}`)
+	assert.Eventually(t, func() bool {
+		return manager.IsSuspended("*.example.org")
+	}, time.Second, 5*time.Millisecond)
+
+	server.mu.Lock()
+	assert.Contains(t, server.commands, "AUTH")
+	server.mu.Unlock()
+}
+
+// Test the keyspace notification channel name.
+func TestProvider_Channel(t *testing.T) {
+	p := New(Config{DB: 2, KeyspaceNotifications: true})
+	assert.Equal(t, "__keyspace@2__:vhosts", p.channel())
+	assert.Equal(t, "vhosts:changes", New(Config{}).channel())
+}
@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// conn is a minimal RESP2 connection, supporting exactly what the provider needs: AUTH, SELECT, HGETALL and SUBSCRIBE
+type conn struct {
+	c net.Conn
+	r *bufio.Reader
+}
+
+// dial opens a connection and authenticates it
+func dial(ctx context.Context, config Config) (*conn, error) {
+	d := net.Dialer{Timeout: config.DialTimeout}
+	c, err := d.DialContext(ctx, "tcp", config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{c: c, r: bufio.NewReader(c)}
+
+	if config.Password != "" {
+		args := []string{"AUTH", config.Password}
+		if config.Username != "" {
+			args = []string{"AUTH", config.Username, config.Password}
+		}
+		if _, err := cn.do(args...); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if config.DB != 0 {
+		if _, err := cn.do("SELECT", strconv.Itoa(config.DB)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// Close closes the connection
+func (cn *conn) Close() error {
+	return cn.c.Close()
+}
+
+// do sends a command and reads its reply
+func (cn *conn) do(args ...string) (any, error) {
+	if err := cn.send(args...); err != nil {
+		return nil, err
+	}
+	return cn.read()
+}
+
+// send writes a command as an array of bulk strings
+func (cn *conn) send(args ...string) error {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	_, err := cn.c.Write(buf)
+	return err
+}
+
+// read reads a single reply. Bulk strings are returned as string, arrays as []any, integers as int64 and nil replies as nil.
+func (cn *conn) read() (any, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("malformed reply")
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = cn.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", kind)
+	}
+}
+
+// hgetall reads all fields of a hash
+func (cn *conn) hgetall(key string) (map[string]string, error) {
+	reply, err := cn.do("HGETALL", key)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items)%2 != 0 {
+		return nil, errors.New("unexpected HGETALL reply")
+	}
+	fields := make(map[string]string, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		k, _ := items[i].(string)
+		v, _ := items[i+1].(string)
+		fields[k] = v
+	}
+	return fields, nil
+}
+
+// subscribe subscribes to a channel and waits for the confirmation
+func (cn *conn) subscribe(channel string) error {
+	reply, err := cn.do("SUBSCRIBE", channel)
+	if err != nil {
+		return err
+	}
+	if items, ok := reply.([]any); !ok || len(items) < 1 || items[0] != "subscribe" {
+		return errors.New("unexpected SUBSCRIBE reply")
+	}
+	return nil
+}
+
+// receive waits for the next published message. It fails when nothing, not even a pong, is received within timeout.
+func (cn *conn) receive(timeout time.Duration) (string, error) {
+	for {
+		if err := cn.c.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return "", err
+		}
+		reply, err := cn.read()
+		if err != nil {
+			return "", err
+		}
+
+		items, ok := reply.([]any)
+		if !ok || len(items) < 3 || items[0] != "message" {
+			// Pong replies and other subscription confirmations
+			continue
+		}
+		payload, _ := items[2].(string)
+		return payload, nil
+	}
+}
@@ -0,0 +1,167 @@
+// Package sqldb provides a fibervhosts.Provider that loads the vhost table of a VhostsManager from a SQL table and refreshes it periodically or on demand. It works with any database/sql driver.
+//
+// The default query expects a table like:
+//
+//	CREATE TABLE vhosts (
+//	    hostname TEXT PRIMARY KEY,  -- hostname, "*.example.com" or "" for the default app
+//	    app_name TEXT NOT NULL,
+//	    enabled  BOOLEAN NOT NULL DEFAULT TRUE,
+//	    config   TEXT              -- optional JSON, like {"metadata":{"tags":["gold"]}}
+//	);
+//
+// Disabled rows stay registered but are suspended.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+)
+
+// Config defines the config for the SQL provider
+type Config struct {
+	// DB is the database holding the vhost table. Required.
+	DB *sql.DB
+	// Table is the name of the vhost table. Defaults to "vhosts". Ignored when Query is set.
+	Table string
+	// Query selects the hostname, app name, enabled flag and config JSON columns, in that order. Defaults to "SELECT hostname, app_name, enabled, config FROM <Table>".
+	Query string
+	// Resolver maps app names to apps. Required.
+	Resolver fibervhosts.AppResolver
+	// Source identifies the registrations managed by this provider. Defaults to "sql".
+	Source string
+	// Interval is the time between refreshes in Run. Defaults to 30 seconds.
+	Interval time.Duration
+	// Logger receives the warnings of the provider, like connection errors. Defaults to the Logger of the manager the provider is attached to, see fibervhosts.ProviderLogger.
+	Logger fibervhosts.Logger
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Table:    "vhosts",
+	Source:   "sql",
+	Interval: 30 * time.Second,
+}
+
+// Provider is a fibervhosts.Provider reading the vhost table from a SQL table
+type Provider struct {
+	config Config
+	fibervhosts.ProviderLogger
+	refresh chan struct{}
+}
+
+// New creates a new SQL provider. Attach it with VhostsManager.AttachProvider.
+func New(config Config) *Provider {
+	if config.Table == "" {
+		config.Table = ConfigDefault.Table
+	}
+	if config.Query == "" {
+		config.Query = "SELECT hostname, app_name, enabled, config FROM " + config.Table
+	}
+	if config.Source == "" {
+		config.Source = ConfigDefault.Source
+	}
+	if config.Interval <= 0 {
+		config.Interval = ConfigDefault.Interval
+	}
+	return &Provider{config: config, ProviderLogger: fibervhosts.NewProviderLogger(config.Logger), refresh: make(chan struct{}, 1)}
+}
+
+// rowConfig is the JSON stored in the config column
+type rowConfig struct {
+	Suspended bool                  `json:"suspended"`
+	Metadata  *fibervhosts.Metadata `json:"metadata"`
+}
+
+// Name returns the source of the registrations made by the provider
+func (p *Provider) Name() string {
+	return p.config.Source
+}
+
+// Load reads the table once and returns its registrations
+func (p *Provider) Load() ([]fibervhosts.Entry, error) {
+	return p.query(context.Background())
+}
+
+// query reads the table and returns its registrations. Rows with an invalid config are logged and skipped.
+func (p *Provider) query(ctx context.Context) ([]fibervhosts.Entry, error) {
+	rows, err := p.config.DB.QueryContext(ctx, p.config.Query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var specs []fibervhosts.EntrySpec
+	for rows.Next() {
+		var (
+			hostname, app string
+			enabled       bool
+			config        sql.NullString
+		)
+		if err := rows.Scan(&hostname, &app, &enabled, &config); err != nil {
+			return nil, err
+		}
+
+		spec := fibervhosts.EntrySpec{Hostname: hostname, App: app}
+		if config.Valid && config.String != "" {
+			var rc rowConfig
+			if err := json.Unmarshal([]byte(config.String), &rc); err != nil {
+				p.Logger().Warn("SQL provider skipping invalid config", "hostname", hostname, "error", err)
+				continue
+			}
+			spec.Suspended, spec.Metadata = rc.Suspended, rc.Metadata
+		}
+		if !enabled {
+			spec.Suspended = true
+		}
+		specs = append(specs, spec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return fibervhosts.ResolveSpecs(specs, p.config.Resolver), nil
+}
+
+// Refresh asks a watching provider to read the table right away instead of waiting for the next interval
+func (p *Provider) Refresh() {
+	select {
+	case p.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Watch reads the table immediately and then every Config.Interval, or earlier when Refresh is called, emitting the registrations after every read. Errors are logged and retried on the next refresh. The channel is closed once ctx is done.
+func (p *Provider) Watch(ctx context.Context) <-chan []fibervhosts.Entry {
+	out := make(chan []fibervhosts.Entry)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(p.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			entries, err := p.query(ctx)
+			switch {
+			case err == nil:
+				select {
+				case out <- entries:
+				case <-ctx.Done():
+					return
+				}
+			case !errors.Is(err, context.Canceled):
+				p.Logger().Warn("SQL provider query failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-p.refresh:
+			}
+		}
+	}()
+	return out
+}
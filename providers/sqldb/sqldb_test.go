@@ -0,0 +1,104 @@
+package sqldb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver serves the rows of a single in-memory table for any query
+type fakeDriver struct {
+	mu   sync.Mutex
+	rows [][]driver.Value
+}
+
+func (d *fakeDriver) set(rows ...[]driver.Value) {
+	d.mu.Lock()
+	d.rows = rows
+	d.mu.Unlock()
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return &fakeStmt{d: c.d}, nil }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type fakeStmt struct{ d *fakeDriver }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return 0 }
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return &fakeRows{rows: append([][]driver.Value(nil), s.d.rows...)}, nil
+}
+
+type fakeRows struct{ rows [][]driver.Value }
+
+func (r *fakeRows) Columns() []string { return []string{"hostname", "app_name", "enabled", "config"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register("fakevhosts", fake)
+}
+
+// Test loading the table and refreshing on demand.
+func TestProvider_Watch(t *testing.T) {
+	api := fiber.New()
+	web := fiber.New()
+	resolver := fibervhosts.MapResolver(map[string]*fiber.App{"api": api, "web": web})
+
+	db, err := sql.Open("fakevhosts", "")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	fake.set(
+		[]driver.Value{"api.example.com", "api", true, nil},
+		[]driver.Value{"*.example.org", "web", true, `{"metadata":{"tags":["gold"]}}`},
+		[]driver.Value{"off.example.com", "web", false, nil},
+		[]driver.Value{"", "web", true, nil},
+	)
+
+	manager := fibervhosts.NewVhostsManager()
+	provider := New(Config{DB: db, Resolver: resolver, Interval: time.Hour})
+	detach, err := manager.AttachProvider(provider)
+	assert.NoError(t, err)
+	defer detach()
+
+	assert.Len(t, manager.ListEntries(), 4)
+	assert.True(t, manager.IsSuspended("off.example.com"))
+	md, _ := manager.GetMetadata("*.example.org")
+	assert.True(t, md.HasTag("gold"))
+
+	// Watch picks up changes when asked to refresh
+	fake.set([]driver.Value{"api.example.com", "web", true, nil})
+	provider.Refresh()
+	assert.Eventually(t, func() bool {
+		app, _ := manager.GetHostname("api.example.com")
+		return app == web && len(manager.ListEntries()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
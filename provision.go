@@ -0,0 +1,84 @@
+// This file contains tenant provisioning, which sets up the registration of a new customer domain from an app template in one call: its sub-app, settings, metadata and certificate.
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var ErrInvalidTemplate = errors.New("invalid app template")
+
+// MetadataTemplateKey is the metadata key Provision records the name of the template under
+const MetadataTemplateKey = "template"
+
+// AppTemplate builds the registrations of new tenants, see Provision
+type AppTemplate struct {
+	// Name identifies the template, recorded in the metadata of provisioned registrations under MetadataTemplateKey
+	Name string
+	// Build returns the sub-app of a tenant, with its routes and middleware, like a fresh instance of a shared app using the storage prefix of the tenant
+	Build func(p Provisioning) (*fiber.App, error)
+	// Config is the HostConfig of provisioned registrations
+	Config HostConfig
+	// Metadata is attached to provisioned registrations, like the plan of the tenants
+	Metadata Metadata
+	// StoragePrefix is prepended to the storage prefix of every tenant, like "tenants:" giving "tenants:shop.example.com:"
+	StoragePrefix string
+	// Certificates issues the certificates of provisioned hostnames, like a secrets manager or an ACME client, see SetCertificateSource. Nil provisions hostnames without a certificate of their own.
+	Certificates CertificateSource
+}
+
+// Provisioning describes the tenant an AppTemplate builds a sub-app for
+type Provisioning struct {
+	Hostname string
+	// StoragePrefix namespaces the storage keys of the tenant, like its sessions and cache entries, so tenants sharing a store do not see each other's data
+	StoragePrefix string
+	// Metadata is the metadata the registration is provisioned with
+	Metadata Metadata
+}
+
+// Provision sets up the registration of a new tenant from template in one call: the template builds the sub-app, which is registered with the config and the metadata of the template and, if it has Certificates, with the certificate of the hostname. Nothing is registered when a step fails; the registration is published with its certificate, so it is never served without it. The certificate is fetched without a deadline, so the source should bound its requests.
+func (m *VhostsManager) Provision(hostname string, template AppTemplate) error {
+	if hostname == "" {
+		return ErrInvalidHostname
+	}
+	if template.Build == nil {
+		return fmt.Errorf("%w: %q has no Build", ErrInvalidTemplate, template.Name)
+	}
+	m.mu.RLock()
+	table, key := m.tableFor(hostname)
+	_, exists := table[key]
+	m.mu.RUnlock()
+	if exists {
+		return ErrHostExists
+	}
+
+	md := template.Metadata.clone()
+	if template.Name != "" {
+		if md.Values == nil {
+			md.Values = make(map[string]string)
+		}
+		md.Values[MetadataTemplateKey] = template.Name
+	}
+	app, err := template.Build(Provisioning{Hostname: hostname, StoragePrefix: template.StoragePrefix + hostname + ":", Metadata: md.clone()})
+	if err != nil {
+		return fmt.Errorf("build %s: %w", hostname, err)
+	}
+
+	prepare := func(e *entry) {
+		e.metadata = md
+	}
+	if template.Certificates != nil {
+		src := &certSource{source: template.Certificates}
+		cert, err := src.fetch(context.Background(), hostname)
+		if err != nil {
+			return fmt.Errorf("fetch certificate for %s: %w", hostname, err)
+		}
+		prepare = func(e *entry) {
+			e.metadata, e.cert, e.certSource = md, cert, src
+		}
+	}
+	return m.addStarted(hostname, app, template.Config, prepare)
+}
@@ -0,0 +1,62 @@
+package fibervhosts
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test tenants are provisioned from a template with their sub-app, metadata and certificate, and nothing is registered when a step fails.
+func TestVhostsManager_Provision(t *testing.T) {
+	source := &fakeCertSource{t: t, lease: time.Hour}
+	template := AppTemplate{
+		Name: "shop",
+		Build: func(p Provisioning) (*fiber.App, error) {
+			if p.Hostname == "broken.example.com" {
+				return nil, errors.New("no storage")
+			}
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				return c.SendString(p.StoragePrefix + " " + p.Metadata.Get("plan"))
+			})
+			return app, nil
+		},
+		Config:        HostConfig{Settings: map[string]any{"theme": "shop"}},
+		Metadata:      Metadata{Values: map[string]string{"plan": "pro"}, Tags: []string{"customer"}},
+		StoragePrefix: "tenants:",
+		Certificates:  source,
+	}
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.Provision("shop.acme.com", template))
+	assert.ErrorIs(t, manager.Provision("shop.acme.com", template), ErrHostExists)
+	assert.ErrorIs(t, manager.Provision("", template), ErrInvalidHostname)
+	assert.ErrorIs(t, manager.Provision("new.example.com", AppTemplate{Name: "empty"}), ErrInvalidTemplate)
+	assert.ErrorContains(t, manager.Provision("broken.example.com", template), "no storage")
+	source.err = errors.New("issuer down")
+	assert.ErrorContains(t, manager.Provision("noca.example.com", template), "issuer down")
+	assert.Equal(t, []string{"shop.acme.com"}, manager.GetHostnames())
+
+	md, _ := manager.GetMetadata("shop.acme.com")
+	assert.Equal(t, "shop", md.Get(MetadataTemplateKey))
+	assert.Equal(t, "pro", md.Get("plan"))
+	assert.True(t, md.HasTag("customer"))
+	_, ok := template.Metadata.Values[MetadataTemplateKey]
+	assert.False(t, ok)
+	cert, ok := manager.HostCertificate("shop.acme.com")
+	if assert.True(t, ok) {
+		assert.Equal(t, "shop.acme.com", cert.Leaf.DNSNames[0])
+	}
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	resp, err := main.Test(httptest.NewRequest("GET", "http://shop.acme.com/", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "tenants:shop.acme.com: pro", string(body))
+}
@@ -0,0 +1,283 @@
+// This file contains proxy hosts, hostnames forwarded to an upstream HTTP server instead of a local sub-app, which turns the manager into a hostname based gateway.
+package fibervhosts
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+var ErrInvalidUpstream = errors.New("invalid upstream")
+
+// mimeEventStream is the content type of server-sent events
+const mimeEventStream = "text/event-stream"
+
+// hopHeaders are the hop-by-hop headers, which apply to a single connection and are not forwarded
+var hopHeaders = []string{
+	fiber.HeaderConnection,
+	fiber.HeaderKeepAlive,
+	fiber.HeaderProxyAuthenticate,
+	fiber.HeaderProxyAuthorization,
+	fiber.HeaderTE,
+	fiber.HeaderTrailer,
+	fiber.HeaderTransferEncoding,
+	fiber.HeaderUpgrade,
+}
+
+// ProxyConfig defines how AddProxyHost forwards requests to the upstream
+type ProxyConfig struct {
+	// Timeout limits sending a request to the upstream and reading its response. Defaults to 30 seconds. Requests for server-sent events, accepting text/event-stream, are exempt from the limit on reading, which would cut their stream.
+	Timeout time.Duration
+	// FlushInterval batches the writes of streamed responses, like server-sent events, and flushes them to the client at this interval. Zero flushes every write of the upstream right away.
+	FlushInterval time.Duration
+	// PreserveHost forwards the Host header of the request instead of the host of the upstream
+	PreserveHost bool
+	// Headers are set on every request forwarded to the upstream, like a shared secret the upstream checks
+	Headers map[string]string
+	// Balance selects the upstream of each request of a proxy pool, see AddProxyPool. Defaults to BalanceRoundRobin.
+	Balance BalanceStrategy
+	// HealthCheck probes the upstreams with CheckUpstreams and takes failing ones out of rotation until they recover. Nil keeps every upstream in rotation.
+	HealthCheck *UpstreamCheck
+	// ErrorHandler answers requests the upstream failed, with fiber.ErrBadGateway or, when it timed out, fiber.ErrGatewayTimeout. Defaults to the error handler of fiber.
+	ErrorHandler fiber.ErrorHandler
+}
+
+// AddProxyHost adds a hostname whose requests are forwarded to upstream, an http or https URL like "http://10.0.0.5:8080" or "https://api.internal/v2" whose path prefixes the request path, or a unix socket like "unix:///var/run/app.sock" of a co-located service, which gets the requests for localhost. The response of the upstream is streamed back to the client. The request gets the X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto headers. Upgrade requests like WebSocket handshakes are tunneled to the upstream on a connection of their own.
+func (m *VhostsManager) AddProxyHost(hostname, upstream string, config ...ProxyConfig) error {
+	return m.AddProxyPool(hostname, []string{upstream}, config...)
+}
+
+// upstream is a server a proxy host forwards requests to
+type upstream struct {
+	url string
+	// origin is the scheme and host of the forwarded requests, host their Host header and base the prefix of their path
+	origin, host, base string
+	client             *fasthttp.HostClient
+	// streamClient forwards requests for server-sent events, without a read timeout
+	streamClient *fasthttp.HostClient
+	dial         func() (net.Conn, error)
+	// active counts the requests in flight to the upstream, including streamed responses and tunnels
+	active atomic.Int64
+	// down takes the upstream out of rotation after failed health checks, see UpstreamCheck
+	down   atomic.Bool
+	health upstreamHealth
+}
+
+// newUpstream parses the URL of an upstream, see AddProxyHost
+func newUpstream(raw string, timeout time.Duration) (*upstream, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidUpstream, raw)
+	}
+	var network, addr string
+	up := &upstream{url: raw}
+	isTLS := u.Scheme == "https"
+	switch {
+	case (u.Scheme == "http" || isTLS) && u.Host != "":
+		network, addr, up.host = "tcp", fasthttp.AddMissingPort(u.Host, isTLS), u.Host
+		up.base = strings.TrimSuffix(u.Path, "/")
+	case u.Scheme == "unix" && u.Host == "" && u.Path != "":
+		// Like nginx, requests to a unix socket are sent for localhost
+		network, addr, up.host = "unix", u.Path, "localhost"
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidUpstream, raw)
+	}
+	up.origin = "http://" + up.host
+	if isTLS {
+		up.origin = "https://" + up.host
+	}
+
+	up.dial = func() (net.Conn, error) {
+		dialer := &net.Dialer{Timeout: timeout}
+		if isTLS {
+			return tls.DialWithDialer(dialer, network, addr, &tls.Config{ServerName: u.Hostname()})
+		}
+		return dialer.Dial(network, addr)
+	}
+	newClient := func(readTimeout time.Duration) *fasthttp.HostClient {
+		client := &fasthttp.HostClient{
+			Addr:                     addr,
+			IsTLS:                    isTLS,
+			ReadTimeout:              readTimeout,
+			WriteTimeout:             timeout,
+			StreamResponseBody:       true,
+			DisablePathNormalizing:   true,
+			NoDefaultUserAgentHeader: true,
+		}
+		if network == "unix" {
+			client.Dial = func(string) (net.Conn, error) {
+				return up.dial()
+			}
+		}
+		return client
+	}
+	// The read timeout of fasthttp covers the streamed body as well
+	up.client, up.streamClient = newClient(timeout), newClient(0)
+	return up, nil
+}
+
+// newProxy builds the pool of a proxy host and the sub-app forwarding to it
+func (m *VhostsManager) newProxy(upstreams []string, config ...ProxyConfig) (*proxyPool, error) {
+	var cfg ProxyConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	pool, err := newProxyPool(upstreams, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.app = fiber.New(fiber.Config{AppName: "proxy:" + strings.Join(upstreams, ","), DisableStartupMessage: true, ErrorHandler: cfg.ErrorHandler})
+	pool.app.Use(func(c *fiber.Ctx) error {
+		up := pool.pick()
+		up.active.Add(1)
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		c.Request().CopyTo(req)
+		for _, header := range hopHeaders {
+			req.Header.Del(header)
+		}
+		req.SetRequestURI(up.origin + up.base + string(c.Request().URI().RequestURI()))
+		if cfg.PreserveHost {
+			req.Header.SetHost(c.Hostname())
+			req.UseHostHeader = true
+		} else {
+			req.Header.SetHost(up.host)
+		}
+		if forwarded := c.Get(fiber.HeaderXForwardedFor); forwarded != "" {
+			req.Header.Set(fiber.HeaderXForwardedFor, forwarded+", "+c.IP())
+		} else {
+			req.Header.Set(fiber.HeaderXForwardedFor, c.IP())
+		}
+		req.Header.Set(fiber.HeaderXForwardedHost, c.Hostname())
+		req.Header.Set(fiber.HeaderXForwardedProto, c.Protocol())
+		for name, value := range cfg.Headers {
+			req.Header.Set(name, value)
+		}
+		if isUpgrade(c) {
+			return m.tunnelUpgrade(c, req, up, cfg.Timeout)
+		}
+
+		client := up.client
+		if isEventStream(c) {
+			client = up.streamClient
+		}
+		resp := fasthttp.AcquireResponse()
+		if err := client.Do(req, resp); err != nil {
+			fasthttp.ReleaseResponse(resp)
+			up.active.Add(-1)
+			m.logger.Warn("Proxying request failed", "hostname", strings.Clone(c.Hostname()), "upstream", up.url, "error", err)
+			if errors.Is(err, fasthttp.ErrTimeout) {
+				return fiber.ErrGatewayTimeout
+			}
+			return fiber.ErrBadGateway
+		}
+
+		resp.Header.CopyTo(&c.Response().Header)
+		for _, header := range hopHeaders {
+			c.Response().Header.Del(header)
+		}
+		stream := resp.BodyStream()
+		if stream == nil {
+			c.Response().SetBody(resp.Body())
+			fasthttp.ReleaseResponse(resp)
+			up.active.Add(-1)
+			return nil
+		}
+		// The server closes the stream once it was sent, which returns the upstream connection
+		body := &upstreamBody{Reader: stream, resp: resp, upstream: up}
+		if cfg.FlushInterval <= 0 {
+			c.Response().SetBodyStream(body, resp.Header.ContentLength())
+			return nil
+		}
+		c.Response().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer body.Close()
+			if err := flushEvery(w, body, cfg.FlushInterval); err != nil {
+				m.logger.Debug("Streaming response failed", "upstream", up.url, "error", err)
+			}
+		})
+		return nil
+	})
+	return pool, nil
+}
+
+// isEventStream reports whether a request asks for server-sent events
+func isEventStream(c *fiber.Ctx) bool {
+	return strings.Contains(c.Get(fiber.HeaderAccept), mimeEventStream)
+}
+
+// flushEvery copies src to w and flushes what was written every interval, so the writes of a busy stream go out in batches and none of them waits longer than interval
+func flushEvery(w *bufio.Writer, src io.Reader, interval time.Duration) error {
+	var mu sync.Mutex
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			// A failed flush fails the next write as well
+			mu.Lock()
+			_ = w.Flush()
+			mu.Unlock()
+		}
+	}()
+	// w belongs to the server again once this returns
+	defer wg.Wait()
+	defer close(done)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			mu.Lock()
+			_, werr := w.Write(buf[:n])
+			mu.Unlock()
+			if werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// upstreamBody streams the body of an upstream response and releases the response when closed
+type upstreamBody struct {
+	io.Reader
+	resp     *fasthttp.Response
+	upstream *upstream
+}
+
+// Close closes the body stream and releases the response
+func (b *upstreamBody) Close() error {
+	err := b.resp.CloseBodyStream()
+	fasthttp.ReleaseResponse(b.resp)
+	b.upstream.active.Add(-1)
+	return err
+}
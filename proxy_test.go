@@ -0,0 +1,165 @@
+package fibervhosts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test proxy hosts forward requests with the forwarding headers to the upstream and stream its response back.
+func TestVhostsManager_AddProxyHost(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/stream":
+			for i := 0; i < 3; i++ {
+				fmt.Fprintf(w, "chunk %d\n", i)
+				w.(http.Flusher).Flush()
+			}
+		case "/v2/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.Header().Set("X-Upstream", "yes")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, "%s %s host=%s xff=%s xfh=%s xfp=%s secret=%s", r.Method, r.URL.RequestURI(), r.Host,
+				r.Header.Get("X-Forwarded-For"), r.Header.Get("X-Forwarded-Host"), r.Header.Get("X-Forwarded-Proto"), r.Header.Get("X-Secret"))
+		}
+	}))
+	defer upstream.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	manager := NewVhostsManager(Config{Logger: &recordingLogger{}})
+	assert.NoError(t, manager.AddProxyHost("api.example.com", upstream.URL+"/v2", ProxyConfig{Headers: map[string]string{"X-Secret": "s3cret"}, Timeout: 50 * time.Millisecond}))
+	assert.NoError(t, manager.AddProxyHost("www.example.com", upstream.URL, ProxyConfig{PreserveHost: true}))
+	assert.NoError(t, manager.AddProxyHost("down.example.com", closed.URL))
+	assert.ErrorIs(t, manager.AddProxyHost("bad.example.com", "ftp://example.com"), ErrInvalidUpstream)
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(method, target string) (*http.Response, string) {
+		resp, err := main.Test(httptest.NewRequest(method, target, nil), -1)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := request("POST", "http://api.example.com/users?page=2")
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "yes", resp.Header.Get("X-Upstream"))
+	assert.Equal(t, "POST /v2/users?page=2 host="+upstream.Listener.Addr().String()+" xff=0.0.0.0 xfh=api.example.com xfp=http secret=s3cret", body)
+
+	_, body = request("GET", "http://www.example.com/")
+	assert.Contains(t, body, "GET / host=www.example.com ")
+
+	resp, body = request("GET", "http://api.example.com/stream")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "chunk 0\nchunk 1\nchunk 2\n", body)
+
+	resp, _ = request("GET", "http://api.example.com/slow")
+	assert.Equal(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+
+	resp, _ = request("GET", "http://down.example.com/")
+	assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+}
+
+// Test proxy hosts forward requests to a unix socket upstream.
+func TestVhostsManager_AddProxyHostUnix(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+	upstream := &httptest.Server{Listener: ln, Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s host=%s xfh=%s", r.Method, r.URL.RequestURI(), r.Host, r.Header.Get("X-Forwarded-Host"))
+	})}}
+	upstream.Start()
+	defer upstream.Close()
+
+	manager := NewVhostsManager(Config{Logger: &recordingLogger{}})
+	assert.NoError(t, manager.AddProxyHost("php.example.com", "unix://"+socket))
+	assert.NoError(t, manager.AddProxyHost("www.example.com", "unix://"+socket, ProxyConfig{PreserveHost: true}))
+	assert.NoError(t, manager.AddProxyHost("gone.example.com", "unix://"+socket+".missing"))
+	assert.ErrorIs(t, manager.AddProxyHost("bad.example.com", "unix://host/app.sock"), ErrInvalidUpstream)
+	assert.ErrorIs(t, manager.AddProxyHost("bad.example.com", "unix://"), ErrInvalidUpstream)
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(target string) (int, string) {
+		resp, err := main.Test(httptest.NewRequest("GET", target, nil), -1)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := request("http://php.example.com/index.php?page=2")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "GET /index.php?page=2 host=localhost xfh=php.example.com", body)
+
+	_, body = request("http://www.example.com/")
+	assert.Equal(t, "GET / host=www.example.com xfh=www.example.com", body)
+
+	status, _ = request("http://gone.example.com/")
+	assert.Equal(t, fiber.StatusBadGateway, status)
+}
+
+// Test server-sent events stream through proxy hosts past their timeout, with and without a flush interval.
+func TestVhostsManager_AddProxyHostEventStream(t *testing.T) {
+	next := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-next:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, "data: second\n\n")
+	}))
+	defer upstream.Close()
+
+	manager := NewVhostsManager(Config{Compression: &Compression{MinSize: 1}, Cache: &Cache{}})
+	assert.NoError(t, manager.AddProxyHost("events.example.com", upstream.URL, ProxyConfig{Timeout: 20 * time.Millisecond}))
+	assert.NoError(t, manager.AddProxyHost("batched.example.com", upstream.URL, ProxyConfig{Timeout: 20 * time.Millisecond, FlushInterval: 10 * time.Millisecond}))
+
+	main := fiber.New(fiber.Config{DisableStartupMessage: true})
+	main.Use(VhostMiddleware(manager))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go main.Listener(ln)
+	defer main.Shutdown()
+
+	for _, host := range []string{"events.example.com", "batched.example.com"} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/events", nil)
+		assert.NoError(t, err)
+		req.Host = host
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		assert.Empty(t, resp.Header.Get(HeaderXCache))
+		br := bufio.NewReader(resp.Body)
+		event, err := br.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "data: first\n", event)
+
+		// The stream outlives the timeout of the proxy host
+		time.Sleep(50 * time.Millisecond)
+		next <- struct{}{}
+		rest, err := io.ReadAll(br)
+		assert.NoError(t, err)
+		assert.Equal(t, "\ndata: second\n\n", string(rest))
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,172 @@
+// This file contains the per-vhost quotas of the middleware, which cap the requests and the bytes transferred per window of a hostname or tenant, like the monthly allowance of a plan, with counters in a pluggable store so several instances share them.
+package fibervhosts
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Quota caps the requests and the bytes transferred per window of a hostname. Set it on Config for all hostnames, or on HostConfig to override it for a single registration.
+//
+// Unlike RateLimit, which smooths bursts, a quota counts over fixed windows aligned to the Unix epoch, like a day. Requests over the quota are answered with StatusCode and a Retry-After header until the window ends. The bytes transferred are the request and the response bodies; streamed responses count with their Content-Length, if any. They are counted once a request is done, so the request crossing the byte quota still completes.
+type Quota struct {
+	// Requests is the number of requests allowed per window. Zero does not limit the requests.
+	Requests int64
+	// Bytes is the number of bytes allowed to be transferred per window. Zero does not limit the bytes.
+	Bytes int64
+	// Window is the duration of the window. Defaults to 24 hours.
+	Window time.Duration
+	// PerTenant counts the requests of every tenant on its own, see TenantResolver, so a tenant spread over several hostnames shares one quota. Requests without a tenant are counted by hostname.
+	PerTenant bool
+	// StatusCode answers the requests over the quota. Defaults to 429 Too Many Requests; 503 Service Unavailable tells clients the hostname is out of service instead.
+	StatusCode int
+	// Store keeps the counters, like in Redis to share them between instances. Defaults to an in-memory store.
+	Store QuotaStore
+	// Disabled turns off the quota, like for a single internal hostname
+	Disabled bool
+
+	once   sync.Once
+	memory *memoryRateLimitStore
+}
+
+// QuotaStore keeps the counters of the quotas. Implementations must be safe for concurrent use.
+type QuotaStore interface {
+	// Add adds n to the counter of key and returns the new count. A new counter expires after expiration.
+	Add(ctx context.Context, key string, n int64, expiration time.Duration) (int64, error)
+	// Get returns the count of key, or zero if it does not exist or expired
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+// QuotaUsage is the usage of a quota in its current window, see Quota.Usage
+type QuotaUsage struct {
+	Requests int64 `json:"requests"`
+	Bytes    int64 `json:"bytes"`
+	// Resets is when the current window ends
+	Resets time.Time `json:"resets"`
+}
+
+// window returns the window of the quota
+func (q *Quota) window() time.Duration {
+	if q.Window <= 0 {
+		return 24 * time.Hour
+	}
+	return q.Window
+}
+
+// store returns the store of the quota, creating the in-memory store on first use
+func (q *Quota) store() QuotaStore {
+	if q.Store != nil {
+		return q.Store
+	}
+	q.once.Do(func() {
+		q.memory = newMemoryRateLimitStore()
+	})
+	return q.memory
+}
+
+// keys returns the keys of the request and byte counters of subject in the window of now, and when the window ends
+func (q *Quota) keys(subject string, now time.Time) (requests, bytes string, resets time.Time) {
+	window := q.window()
+	index := now.UnixNano() / int64(window)
+	suffix := ":" + strconv.FormatInt(index, 10)
+	return "requests|" + subject + suffix, "bytes|" + subject + suffix, time.Unix(0, (index+1)*int64(window))
+}
+
+// Usage returns the usage of the quota in the current window by subject, the pattern of a registration like "*.example.com" or, with PerTenant, the ID of a tenant
+func (q *Quota) Usage(ctx context.Context, subject string) (QuotaUsage, error) {
+	requestsKey, bytesKey, resets := q.keys(subject, time.Now())
+	usage := QuotaUsage{Resets: resets}
+	var err error
+	if usage.Requests, err = q.store().Get(ctx, requestsKey); err != nil {
+		return QuotaUsage{}, err
+	}
+	if usage.Bytes, err = q.store().Get(ctx, bytesKey); err != nil {
+		return QuotaUsage{}, err
+	}
+	return usage, nil
+}
+
+// quotaFor returns the quota of r, or nil when its requests are not counted
+func (m *VhostsManager) quotaFor(r *route) *Quota {
+	q := m.quota
+	if r.config.Quota != nil {
+		q = r.config.Quota
+	}
+	if q == nil || q.Disabled {
+		return nil
+	}
+	return q
+}
+
+// quotaSubject returns what a request to r is counted for
+func quotaSubject(c *fiber.Ctx, r *route, q *Quota) string {
+	if q.PerTenant {
+		if tenant, ok := GetTenant(c); ok {
+			return tenant.ID
+		}
+	}
+	if r.info.Type == EntryDefault {
+		return defaultAppName
+	}
+	return r.info.Pattern
+}
+
+// checkQuota counts a request to r and answers it with the status of its quota when the requests or the bytes of the window are used up. Requests are let through when the store fails, so an outage of a shared store does not take down the hostnames.
+func (m *VhostsManager) checkQuota(c *fiber.Ctx, r *route) error {
+	q := m.quotaFor(r)
+	if q == nil {
+		return nil
+	}
+
+	now := time.Now()
+	requestsKey, bytesKey, resets := q.keys(quotaSubject(c, r, q), now)
+	store := q.store()
+	exceeded := false
+	if q.Requests > 0 {
+		count, err := store.Add(c.UserContext(), requestsKey, 1, 2*q.window())
+		if err != nil {
+			m.logger.Warn("Quota store failed", "pattern", r.info.Pattern, "error", err)
+			return nil
+		}
+		exceeded = count > q.Requests
+	}
+	if q.Bytes > 0 && !exceeded {
+		count, err := store.Get(c.UserContext(), bytesKey)
+		if err != nil {
+			m.logger.Warn("Quota store failed", "pattern", r.info.Pattern, "error", err)
+			return nil
+		}
+		exceeded = count >= q.Bytes
+	}
+	if !exceeded {
+		return nil
+	}
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(resets.Sub(now).Seconds()))))
+	if q.StatusCode != 0 {
+		return fiber.NewError(q.StatusCode)
+	}
+	return fiber.ErrTooManyRequests
+}
+
+// countQuotaBytes counts the bytes transferred by a request to r once it is done
+func (m *VhostsManager) countQuotaBytes(c *fiber.Ctx, r *route) {
+	q := m.quotaFor(r)
+	if q == nil || q.Bytes <= 0 {
+		return
+	}
+	n := int64(len(c.Request().Body()) + max(responseSize(c.Response()), 0))
+	if n == 0 {
+		return
+	}
+	_, bytesKey, _ := q.keys(quotaSubject(c, r, q), time.Now())
+	// The context of the request may be canceled by now, like by its timeout
+	if _, err := q.store().Add(context.WithoutCancel(c.UserContext()), bytesKey, n, 2*q.window()); err != nil {
+		m.logger.Warn("Quota store failed", "pattern", r.info.Pattern, "error", err)
+	}
+}
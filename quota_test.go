@@ -0,0 +1,71 @@
+package fibervhosts
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test quotas cap the requests and the bytes per window of a hostname or tenant.
+func TestVhostMiddleware_Quota(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	app.Get("/large", func(c *fiber.Ctx) error {
+		return c.SendString(strings.Repeat("x", 600))
+	})
+
+	requests := &Quota{Requests: 2, Window: time.Hour}
+	bytes := &Quota{Bytes: 1000, StatusCode: fiber.StatusServiceUnavailable}
+	tenants := &Quota{Requests: 3, PerTenant: true}
+	manager := NewVhostsManager(Config{Quota: requests, Tenants: &TenantResolver{MetadataKey: "tenant"}})
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("media.example.com", app, HostConfig{Quota: bytes}))
+	assert.NoError(t, manager.AddHostnameWithConfig("unlimited.example.com", app, HostConfig{Quota: &Quota{Disabled: true}}))
+	for _, hostname := range []string{"a.acme.com", "b.acme.com"} {
+		assert.NoError(t, manager.AddHostnameWithConfig(hostname, app, HostConfig{Quota: tenants}))
+		assert.NoError(t, manager.SetMetadata(hostname, Metadata{Values: map[string]string{"tenant": "acme"}}))
+	}
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	retryAfter := ""
+	status := func(target string) int {
+		resp, err := main.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+		retryAfter = resp.Header.Get(fiber.HeaderRetryAfter)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, status("http://www.example.com/"))
+	assert.Equal(t, fiber.StatusOK, status("http://www.example.com/"))
+	assert.Equal(t, fiber.StatusTooManyRequests, status("http://www.example.com/"))
+	assert.NotEmpty(t, retryAfter)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, fiber.StatusOK, status("http://unlimited.example.com/"))
+	}
+
+	// The request crossing the byte quota completes, the next one is rejected
+	assert.Equal(t, fiber.StatusOK, status("http://media.example.com/large"))
+	assert.Equal(t, fiber.StatusOK, status("http://media.example.com/large"))
+	assert.Equal(t, fiber.StatusServiceUnavailable, status("http://media.example.com/"))
+	usage, err := bytes.Usage(context.Background(), "media.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1200), usage.Bytes)
+	assert.True(t, usage.Resets.After(time.Now()))
+
+	// Both hostnames of the tenant share its quota
+	assert.Equal(t, fiber.StatusOK, status("http://a.acme.com/"))
+	assert.Equal(t, fiber.StatusOK, status("http://b.acme.com/"))
+	assert.Equal(t, fiber.StatusOK, status("http://a.acme.com/"))
+	assert.Equal(t, fiber.StatusTooManyRequests, status("http://b.acme.com/"))
+	usage, err = tenants.Usage(context.Background(), "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), usage.Requests)
+}
@@ -0,0 +1,87 @@
+package fibervhosts
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// Test requests are served consistently while the table changes concurrently. Run with -race to check the middleware reads nothing unsynchronized.
+func TestVhostMiddleware_ConcurrentChanges(t *testing.T) {
+	manager := NewVhostsManager(Config{LookupCacheSize: 16, RecoverFromPanic: true})
+	newApp := func(body string) *fiber.App {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendString(body) })
+		return app
+	}
+	assert.NoError(t, manager.AddHostname("stable.example.com", newApp("stable")))
+	assert.NoError(t, manager.AddHostname("*.example.org", newApp("wildcard")))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	handler := main.Handler()
+
+	const rounds = 200
+	var wg sync.WaitGroup
+	writer := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				fn(i)
+			}
+		}()
+	}
+	writer(func(i int) {
+		host := fmt.Sprintf("churn%d.example.com", i%4)
+		if manager.AddHostname(host, newApp("churn")) != nil {
+			_ = manager.RemoveHostname(host)
+		}
+	})
+	writer(func(i int) { _, _ = manager.SwapApp("stable.example.com", newApp("stable")) })
+	writer(func(i int) {
+		_ = manager.SetHostConfig("stable.example.com", HostConfig{SlowRequestThreshold: -1})
+		_ = manager.SetMetadata("*.example.org", Metadata{Tags: []string{fmt.Sprint(i)}})
+	})
+	writer(func(i int) {
+		if i%2 == 0 {
+			_ = manager.SuspendHostname("*.example.org")
+		} else {
+			_ = manager.ResumeHostname("*.example.org")
+		}
+	})
+	writer(func(i int) {
+		tx := manager.Begin()
+		tx.AddHostname("tx.example.com", newApp("tx"))
+		tx.RemoveHostname("tx.example.com")
+		_ = tx.Commit()
+	})
+
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fctx := &fasthttp.RequestCtx{}
+			for i := 0; i < rounds; i++ {
+				for _, host := range []string{"stable.example.com", "www.example.org", fmt.Sprintf("churn%d.example.com", i%4), "missing.example.net"} {
+					benchRequest(fctx, handler, host)
+					status := fctx.Response.StatusCode()
+					switch host {
+					case "stable.example.com":
+						assert.Equal(t, fiber.StatusOK, status)
+						assert.Equal(t, "stable", string(fctx.Response.Body()))
+					case "missing.example.net":
+						assert.Equal(t, fiber.StatusNotFound, status)
+					default:
+						assert.Contains(t, []int{fiber.StatusOK, fiber.StatusNotFound, fiber.StatusServiceUnavailable}, status, host)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
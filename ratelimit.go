@@ -0,0 +1,180 @@
+// This file contains the per-vhost rate limit of the middleware, a sliding window limiter keyed by registration and optionally by client IP, whose counters live in a pluggable store so several instances can share them.
+package fibervhosts
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimit limits the number of requests per window of a hostname. Set it on Config for all hostnames, or on HostConfig to override it for a single registration.
+//
+// The rate is estimated over a sliding window, weighting the count of the previous fixed window by how much of it still overlaps. Requests over the limit are answered with 429 Too Many Requests and a Retry-After header; they count towards the rate as well.
+type RateLimit struct {
+	// Max is the number of requests allowed per window
+	Max int
+	// Window is the duration of the window. Defaults to one second.
+	Window time.Duration
+	// PerIP limits every client IP of a hostname on its own, instead of all clients of the hostname together
+	PerIP bool
+	// Store keeps the counters, like in Redis to share them between instances. Defaults to an in-memory store.
+	Store RateLimitStore
+	// Disabled turns off rate limiting, like for a single internal hostname
+	Disabled bool
+
+	once   sync.Once
+	memory *memoryRateLimitStore
+}
+
+// RateLimitStore keeps the request counters of the rate limits. Implementations must be safe for concurrent use.
+type RateLimitStore interface {
+	// Increment adds one to the counter of key and returns the new count. A new counter expires after expiration.
+	Increment(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	// Get returns the count of key, or zero if it does not exist or expired
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+// window returns the window of the limit
+func (l *RateLimit) window() time.Duration {
+	if l.Window <= 0 {
+		return time.Second
+	}
+	return l.Window
+}
+
+// store returns the store of the limit, creating the in-memory store on first use
+func (l *RateLimit) store() RateLimitStore {
+	if l.Store != nil {
+		return l.Store
+	}
+	l.once.Do(func() {
+		l.memory = newMemoryRateLimitStore()
+	})
+	return l.memory
+}
+
+// take counts a request for key at now and reports whether it is within the limit, and otherwise how long to wait until the current window ends
+func (l *RateLimit) take(ctx context.Context, key string, now time.Time) (bool, time.Duration, error) {
+	window := l.window()
+	index := now.UnixNano() / int64(window)
+	remaining := time.Duration((index+1)*int64(window) - now.UnixNano())
+
+	store := l.store()
+	current, err := store.Increment(ctx, key+":"+strconv.FormatInt(index, 10), 2*window)
+	if err != nil {
+		return true, 0, err
+	}
+	previous, err := store.Get(ctx, key+":"+strconv.FormatInt(index-1, 10))
+	if err != nil {
+		return true, 0, err
+	}
+
+	rate := float64(previous)*float64(remaining)/float64(window) + float64(current)
+	if rate <= float64(l.Max) {
+		return true, 0, nil
+	}
+	return false, remaining, nil
+}
+
+// rateLimitFor returns the rate limit of r, or nil when its requests are not limited
+func (m *VhostsManager) rateLimitFor(r *route) *RateLimit {
+	l := m.rateLimit
+	if r.config.RateLimit != nil {
+		l = r.config.RateLimit
+	}
+	if l == nil || l.Disabled {
+		return nil
+	}
+	return l
+}
+
+// checkRateLimit returns 429 Too Many Requests when a request to r exceeds its rate limit. Requests are let through when the store fails, so an outage of a shared store does not take down the hostnames.
+func (m *VhostsManager) checkRateLimit(c *fiber.Ctx, r *route) error {
+	l := m.rateLimitFor(r)
+	if l == nil {
+		return nil
+	}
+
+	key := r.info.Pattern
+	if r.info.Type == EntryDefault {
+		key = defaultAppName
+	}
+	if l.PerIP {
+		key += "|" + c.IP()
+	}
+
+	allowed, wait, err := l.take(c.UserContext(), key, time.Now())
+	if err != nil {
+		m.logger.Warn("Rate limit store failed", "pattern", r.info.Pattern, "error", err)
+		return nil
+	}
+	if !allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return fiber.ErrTooManyRequests
+	}
+	return nil
+}
+
+// memoryRateLimitStore is the default RateLimitStore and QuotaStore, keeping the counters of a single instance
+type memoryRateLimitStore struct {
+	mu     sync.Mutex
+	counts map[string]rateCount
+	// sweep is when expired counters are dropped next
+	sweep time.Time
+}
+
+// rateCount is a counter of the in-memory store
+type rateCount struct {
+	n       int64
+	expires time.Time
+}
+
+// newMemoryRateLimitStore creates an empty in-memory store
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{counts: make(map[string]rateCount)}
+}
+
+// Increment adds one to the counter of key
+func (s *memoryRateLimitStore) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return s.Add(ctx, key, 1, expiration)
+}
+
+// Add adds n to the counter of key
+func (s *memoryRateLimitStore) Add(_ context.Context, key string, n int64, expiration time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.sweep) {
+		for k, count := range s.counts {
+			if now.After(count.expires) {
+				delete(s.counts, k)
+			}
+		}
+		s.sweep = now.Add(time.Minute)
+	}
+
+	count, exists := s.counts[key]
+	if !exists || now.After(count.expires) {
+		count = rateCount{expires: now.Add(expiration)}
+	}
+	count.n += n
+	s.counts[key] = count
+	return count.n, nil
+}
+
+// Get returns the count of key
+func (s *memoryRateLimitStore) Get(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count, exists := s.counts[key]
+	if !exists || time.Now().After(count.expires) {
+		return 0, nil
+	}
+	return count.n, nil
+}
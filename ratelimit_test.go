@@ -0,0 +1,94 @@
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the rate limit weights the previous window by its overlap with the sliding window.
+func TestRateLimit_SlidingWindow(t *testing.T) {
+	limit := &RateLimit{Max: 10, Window: time.Minute}
+	start := time.Unix(0, 0).Add(1000 * time.Minute)
+	take := func(now time.Time) bool {
+		allowed, _, err := limit.take(context.Background(), "example.com", now)
+		assert.NoError(t, err)
+		return allowed
+	}
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, take(start))
+	}
+	allowed, wait, err := limit.take(context.Background(), "example.com", start.Add(15*time.Second))
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 45*time.Second, wait)
+
+	// Halfway through the next window, the 11 requests of the previous one count as 5.5
+	now := start.Add(90 * time.Second)
+	for i := 0; i < 4; i++ {
+		assert.True(t, take(now))
+	}
+	assert.False(t, take(now))
+
+	// Other keys have counters of their own
+	allowed, _, err = limit.take(context.Background(), "example.com|192.0.2.1", now)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+// failingStore is a RateLimitStore that is down
+type failingStore struct{}
+
+func (failingStore) Increment(context.Context, string, time.Duration) (int64, error) {
+	return 0, errors.New("store unavailable")
+}
+
+func (failingStore) Get(context.Context, string) (int64, error) {
+	return 0, errors.New("store unavailable")
+}
+
+// Test the middleware limits every hostname on its own with the limit of its registration.
+func TestVhostMiddleware_RateLimit(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	logger := &recordingLogger{}
+	manager := NewVhostsManager(Config{RateLimit: &RateLimit{Max: 2, Window: time.Hour}, Logger: logger})
+	assert.NoError(t, manager.AddHostname("a.example.com", app))
+	assert.NoError(t, manager.AddHostname("b.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("premium.example.com", app, HostConfig{RateLimit: &RateLimit{Max: 5, Window: time.Hour}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("internal.example.com", app, HostConfig{RateLimit: &RateLimit{Disabled: true}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("shared.example.com", app, HostConfig{RateLimit: &RateLimit{Max: 1, Store: failingStore{}}}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(hostname string) int {
+		resp, err := main.Test(httptest.NewRequest("GET", "http://"+hostname+"/", nil))
+		assert.NoError(t, err)
+		if resp.StatusCode == fiber.StatusTooManyRequests {
+			assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+		return resp.StatusCode
+	}
+
+	for hostname, allowed := range map[string]int{"a.example.com": 2, "b.example.com": 2, "premium.example.com": 5, "internal.example.com": 10, "shared.example.com": 10} {
+		for i := 0; i < allowed; i++ {
+			assert.Equal(t, fiber.StatusNoContent, request(hostname), hostname)
+		}
+		if allowed < 10 {
+			assert.Equal(t, fiber.StatusTooManyRequests, request(hostname), hostname)
+		}
+	}
+
+	// A failing store lets the requests through and logs the failure
+	assert.Len(t, logger.records, 10)
+	assert.Equal(t, "Rate limit store failed", logger.records[0].msg)
+}
@@ -0,0 +1,119 @@
+// This file contains the Reconciler, which periodically converges the vhost table with the desired state of a provider and reports the drift it had to correct.
+package fibervhosts
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultReconcileInterval is the default time between two reconciliations
+const defaultReconcileInterval = time.Minute
+
+// ReconcilerConfig defines the config for a Reconciler
+type ReconcilerConfig struct {
+	// Interval is the time between two reconciliations. Defaults to 1 minute.
+	Interval time.Duration
+}
+
+// ReconcileStats holds the counters of a Reconciler. Drift is the number of registrations a reconciliation had to add, update or remove because the table no longer matched the provider, for example after a watch missed events during a network partition.
+type ReconcileStats struct {
+	Runs     uint64
+	Failures uint64
+
+	// Added, Updated and Removed count the drift corrected over all runs
+	Added   uint64
+	Updated uint64
+	Removed uint64
+
+	// LastDrift is the number of registrations corrected by the last successful run
+	LastDrift int
+	LastRun   time.Time
+	LastError error
+}
+
+// Reconciler periodically re-reads the desired state of a provider with Load and converges the registrations it owns, as a safety net for watch-based sync. It applies under the same source as AttachProvider, so both can drive the same provider.
+type Reconciler struct {
+	manager  *VhostsManager
+	provider Provider
+	source   string
+	interval time.Duration
+
+	mu    sync.Mutex
+	stats ReconcileStats
+}
+
+// NewReconciler creates a Reconciler converging the manager with the provider
+func NewReconciler(manager *VhostsManager, provider Provider, config ...ReconcilerConfig) *Reconciler {
+	r := &Reconciler{
+		manager:  manager,
+		provider: provider,
+		source:   providerSource(provider),
+		interval: defaultReconcileInterval,
+	}
+	if len(config) > 0 && config[0].Interval > 0 {
+		r.interval = config[0].Interval
+	}
+	return r
+}
+
+// Reconcile loads the desired state of the provider once and converges the table with it. It returns the number of registrations that had to be added, updated or removed.
+func (r *Reconciler) Reconcile() (int, error) {
+	entries, err := r.provider.Load()
+	var changes []ChangeEvent
+	if err == nil {
+		changes, err = r.manager.applyProvider(r.source, entries)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Runs++
+	r.stats.LastRun = time.Now()
+	r.stats.LastError = err
+	if err != nil {
+		r.stats.Failures++
+		return 0, err
+	}
+
+	r.stats.LastDrift = len(changes)
+	for _, ev := range changes {
+		switch ev.Type {
+		case ChangeAdded:
+			r.stats.Added++
+		case ChangeUpdated:
+			r.stats.Updated++
+		case ChangeRemoved:
+			r.stats.Removed++
+		}
+	}
+	return len(changes), nil
+}
+
+// Run reconciles every ReconcilerConfig.Interval until ctx is done. Drift and errors are logged. Run blocks until ctx is done.
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		drift, err := r.Reconcile()
+		switch {
+		case err != nil:
+			r.manager.logger.Warn("Reconciling failed", "provider", r.source, "error", err)
+		case drift > 0:
+			r.manager.logger.Warn("Reconciler corrected drifted registrations", "provider", r.source, "drift", drift)
+		}
+	}
+}
+
+// Stats returns the counters of the reconciler
+func (r *Reconciler) Stats() ReconcileStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
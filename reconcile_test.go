@@ -0,0 +1,67 @@
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that reconciling corrects drift and counts it.
+func TestReconciler_Reconcile(t *testing.T) {
+	app := fiber.New()
+	manager := NewVhostsManager()
+	p := &fakeProvider{entries: []Entry{{Pattern: "a.example.com", App: app}, {Pattern: "b.example.com", App: app}}}
+	r := NewReconciler(manager, p)
+
+	drift, err := r.Reconcile()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, drift)
+
+	// Nothing to correct while in sync
+	drift, err = r.Reconcile()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, drift)
+
+	// A lost removal and a manual suspension are corrected
+	assert.NoError(t, manager.RemoveHostname("a.example.com"))
+	assert.NoError(t, manager.SuspendHostname("b.example.com"))
+	drift, err = r.Reconcile()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, drift)
+	assert.False(t, manager.IsSuspended("b.example.com"))
+
+	p.err = errors.New("unreachable")
+	_, err = r.Reconcile()
+	assert.Error(t, err)
+
+	stats := r.Stats()
+	assert.Equal(t, uint64(4), stats.Runs)
+	assert.Equal(t, uint64(1), stats.Failures)
+	assert.Equal(t, uint64(3), stats.Added)
+	assert.Equal(t, uint64(1), stats.Updated)
+	assert.Equal(t, 2, stats.LastDrift)
+	assert.Error(t, stats.LastError)
+}
+
+// Test that Run reconciles periodically until the context is done.
+func TestReconciler_Run(t *testing.T) {
+	manager := NewVhostsManager()
+	p := &fakeProvider{entries: []Entry{{Pattern: "a.example.com", App: fiber.New()}}}
+	r := NewReconciler(manager, p, ReconcilerConfig{Interval: 5 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		_, exists := manager.GetHostname("a.example.com")
+		return exists
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,52 @@
+// This file contains redirect registrations, which answer every request for a hostname with a redirect to another domain, like www to the apex domain, without a sub-app.
+package fibervhosts
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var ErrInvalidRedirect = errors.New("invalid redirect")
+
+// Redirect redirects all requests for a hostname to another URL, keeping their path and query
+type Redirect struct {
+	// Target is the URL the requests are redirected to, like "https://example.com". The path and query of the request are appended to it.
+	Target string `json:"target" yaml:"target" toml:"target"`
+	// Status is the redirect status. Defaults to 301 Moved Permanently.
+	Status int `json:"status,omitempty" yaml:"status,omitempty" toml:"status,omitempty"`
+}
+
+// AddRedirect registers a hostname or wildcard pattern that redirects all its requests to target, keeping their path and query, like "www.example.com" to "https://example.com" or "*.old.com" to "https://new.com". A status of 0 uses 301 Moved Permanently.
+func (m *VhostsManager) AddRedirect(hostname, target string, status int) error {
+	redirect := &Redirect{Target: target, Status: status}
+	if err := redirect.validate(); err != nil {
+		return err
+	}
+	return m.AddHostnameWithConfig(hostname, nil, HostConfig{Redirect: redirect})
+}
+
+// validate checks the target is an absolute http or https URL and the status a redirect status
+func (r *Redirect) validate() error {
+	u, err := url.Parse(r.Target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: target %q", ErrInvalidRedirect, r.Target)
+	}
+	switch r.Status {
+	case 0, fiber.StatusMovedPermanently, fiber.StatusFound, fiber.StatusSeeOther, fiber.StatusTemporaryRedirect, fiber.StatusPermanentRedirect:
+		return nil
+	}
+	return fmt.Errorf("%w: status %d", ErrInvalidRedirect, r.Status)
+}
+
+// redirect answers a request with the redirect to the target
+func (r *Redirect) redirect(c *fiber.Ctx) error {
+	status := r.Status
+	if status == 0 {
+		status = fiber.StatusMovedPermanently
+	}
+	return c.Redirect(strings.TrimSuffix(r.Target, "/")+string(c.Request().URI().RequestURI()), status)
+}
@@ -0,0 +1,37 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test redirect registrations redirect to their target, keeping the path and query.
+func TestVhostsManager_AddRedirect(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddRedirect("www.example.com", "https://example.com", 0))
+	assert.NoError(t, manager.AddRedirect("*.old.com", "https://new.com/", fiber.StatusPermanentRedirect))
+	assert.ErrorIs(t, manager.AddRedirect("www.example.org", "example.org", 0), ErrInvalidRedirect)
+	assert.ErrorIs(t, manager.AddRedirect("www.example.org", "https://example.org", fiber.StatusOK), ErrInvalidRedirect)
+	assert.ErrorIs(t, manager.AddRedirect("www.example.com", "https://example.net", 0), ErrHostExists)
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	for _, tc := range []struct {
+		target   string
+		status   int
+		location string
+	}{
+		{"http://www.example.com/", fiber.StatusMovedPermanently, "https://example.com/"},
+		{"http://www.example.com/docs?page=2", fiber.StatusMovedPermanently, "https://example.com/docs?page=2"},
+		{"http://blog.old.com/posts/1", fiber.StatusPermanentRedirect, "https://new.com/posts/1"},
+	} {
+		resp, err := main.Test(httptest.NewRequest("GET", tc.target, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.target)
+		assert.Equal(t, tc.location, resp.Header.Get(fiber.HeaderLocation), tc.target)
+	}
+}
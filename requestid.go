@@ -0,0 +1,52 @@
+// This file contains the request IDs of the middleware, which are generated or taken over from the client before dispatch so requests can be correlated across the main app, the sub-apps and the access log.
+package fibervhosts
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// maxRequestIDLength is the length above which a request ID sent by the client is replaced
+const maxRequestIDLength = 128
+
+// RequestID defines the request IDs of a hostname. Set it on Config for all hostnames, or on HostConfig to override it for a single registration.
+type RequestID struct {
+	// Header is the request and response header carrying the ID. Defaults to "X-Request-ID".
+	Header string
+	// Generator returns a new ID for requests without one. Defaults to a random UUID.
+	Generator func() string
+	// Disabled turns off request IDs, like for a single hostname whose sub-app sets its own
+	Disabled bool
+}
+
+// requestIDFor returns the request ID settings of a request matched to r, which is nil for unmatched requests. It returns nil when the request gets no ID.
+func (m *VhostsManager) requestIDFor(r *route) *RequestID {
+	p := m.requestID
+	if r != nil && r.config.RequestID != nil {
+		p = r.config.RequestID
+	}
+	if p == nil || p.Disabled {
+		return nil
+	}
+	return p
+}
+
+// apply sets the ID of a request, keeping the one sent by the client unless it is overly long, on the request and response headers and in c.Locals under LocalsRequestIDKey
+func (p *RequestID) apply(c *fiber.Ctx) {
+	header := p.Header
+	if header == "" {
+		header = fiber.HeaderXRequestID
+	}
+	// The ID outlives the request buffer in the access log, so it is copied
+	id := utils.CopyString(c.Get(header))
+	if id == "" || len(id) > maxRequestIDLength {
+		if p.Generator != nil {
+			id = p.Generator()
+		} else {
+			id = utils.UUIDv4()
+		}
+	}
+	c.Request().Header.Set(header, id)
+	c.Set(header, id)
+	c.Locals(LocalsRequestIDKey, id)
+}
@@ -0,0 +1,66 @@
+package fibervhosts
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test requests get an ID before dispatch, which the sub-app, the response and the access log share.
+func TestVhostMiddleware_RequestID(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		id, _ := c.Locals(LocalsRequestIDKey).(string)
+		return c.SendString(c.Get(fiber.HeaderXRequestID) + c.Get("X-Trace-Id") + "|" + id)
+	})
+
+	var logs bytes.Buffer
+	manager := NewVhostsManager(Config{RequestID: &RequestID{}, AccessLog: &AccessLog{Output: &logs, Format: AccessLogJSON}})
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("traced.example.com", app, HostConfig{RequestID: &RequestID{Header: "X-Trace-Id", Generator: func() string { return "trace-1" }}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("plain.example.com", app, HostConfig{RequestID: &RequestID{Disabled: true}}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(hostname, header, id string) (string, string) {
+		logs.Reset()
+		req := httptest.NewRequest("GET", "http://"+hostname+"/", nil)
+		if id != "" {
+			req.Header.Set(header, id)
+		}
+		resp, err := main.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+
+		var entry AccessLogEntry
+		assert.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+		assert.Equal(t, resp.Header.Get(header), entry.RequestID)
+		return resp.Header.Get(header), string(body)
+	}
+
+	id, body := request("www.example.com", fiber.HeaderXRequestID, "")
+	assert.Len(t, id, 36)
+	assert.Equal(t, id+"|"+id, body)
+
+	id, body = request("www.example.com", fiber.HeaderXRequestID, "from-client")
+	assert.Equal(t, "from-client", id)
+	assert.Equal(t, "from-client|from-client", body)
+
+	id, body = request("traced.example.com", "X-Trace-Id", "")
+	assert.Equal(t, "trace-1", id)
+	assert.Equal(t, "trace-1|trace-1", body)
+
+	id, body = request("plain.example.com", fiber.HeaderXRequestID, "")
+	assert.Empty(t, id)
+	assert.Equal(t, "|", body)
+
+	// The combined format has the ID quoted at the end
+	line := AccessLogEntry{Method: "GET", Path: "/", Protocol: "HTTP/1.1", Status: 200, RequestID: "from-client"}.appendCombined(nil)
+	assert.True(t, strings.HasSuffix(string(line), `"-" "-" "from-client"`+"\n"))
+}
@@ -0,0 +1,46 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test restarting a hostname swaps in a freshly built app and shuts down the old one.
+func TestVhostsManager_RestartHostname(t *testing.T) {
+	var builds, shutdowns atomic.Int32
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameFactory("tenant.example.com", func() (*fiber.App, error) {
+		generation := strconv.Itoa(int(builds.Add(1)))
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendString(generation) })
+		app.Hooks().OnShutdown(func() error {
+			shutdowns.Add(1)
+			return nil
+		})
+		return app, nil
+	}))
+	assert.NoError(t, manager.AddHostname("static.example.com", fiber.New()))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	body := func() string {
+		resp, err := main.Test(httptest.NewRequest("GET", "http://tenant.example.com/", nil))
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+	assert.Equal(t, "1", body())
+
+	assert.NoError(t, manager.RestartHostname("tenant.example.com"))
+	assert.Equal(t, int32(1), shutdowns.Load())
+	assert.Equal(t, "2", body())
+
+	assert.ErrorIs(t, manager.RestartHostname("missing.example.com"), ErrHostNotFound)
+	assert.ErrorIs(t, manager.RestartHostname("static.example.com"), ErrNoFactory)
+}
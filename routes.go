@@ -0,0 +1,65 @@
+// This file contains the route listing debug endpoint, which shows the routes of the sub-app a hostname is dispatched to, to diagnose why a URL is not found on a domain.
+package fibervhosts
+
+import "github.com/gofiber/fiber/v2"
+
+// RouteInfo describes a single route of a sub-app
+type RouteInfo struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Name     string `json:"name,omitempty"`
+	Handlers int    `json:"handlers"`
+}
+
+// HostRoutes is the route listing of the registration a hostname is dispatched to
+type HostRoutes struct {
+	Hostname string      `json:"hostname"`
+	Type     EntryType   `json:"type"`
+	Pattern  string      `json:"pattern,omitempty"`
+	AppName  string      `json:"app"`
+	Routes   []RouteInfo `json:"routes"`
+}
+
+// Routes returns the routes of the sub-app the middleware would dispatch a request for hostname to. Middleware mounted with Use is left out. It reports false if no registration matches.
+func (m *VhostsManager) Routes(hostname string) (HostRoutes, bool) {
+	e, ok := m.Match(hostname)
+	if !ok {
+		return HostRoutes{}, false
+	}
+
+	// Lazily built apps have no routes until their first request
+	var stack []fiber.Route
+	if e.App != nil {
+		stack = e.App.GetRoutes(true)
+	}
+	routes := make([]RouteInfo, len(stack))
+	for i, r := range stack {
+		routes[i] = RouteInfo{Method: r.Method, Path: r.Path, Name: r.Name, Handlers: len(r.Handlers)}
+	}
+	return HostRoutes{
+		Hostname: hostname,
+		Type:     e.Type,
+		Pattern:  e.Pattern,
+		AppName:  e.AppName,
+		Routes:   routes,
+	}, true
+}
+
+// RoutesHandler returns a debug handler responding with the routes of a hostname as JSON, see Routes. The hostname is read from the "hostname" route parameter, or else the "hostname" query parameter, like "/debug/routes/:hostname" or "/debug/routes?hostname=api.example.com". It exposes the internals of the sub-apps, so only mount it on an internal admin app.
+func (m *VhostsManager) RoutesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		hostname := c.Params("hostname")
+		if hostname == "" {
+			hostname = c.Query("hostname")
+		}
+		if hostname == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "missing hostname")
+		}
+
+		routes, ok := m.Routes(hostname)
+		if !ok {
+			return fiber.NewError(fiber.StatusNotFound, "no registration matches "+hostname)
+		}
+		return c.JSON(routes)
+	}
+}
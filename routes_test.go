@@ -0,0 +1,44 @@
+package fibervhosts
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the routes of the matched sub-app are listed by hostname.
+func TestVhostsManager_RoutesHandler(t *testing.T) {
+	api := fiber.New(fiber.Config{AppName: "api"})
+	api.Use(func(c *fiber.Ctx) error { return c.Next() })
+	api.Get("/users/:id", func(c *fiber.Ctx) error { return c.Next() }, func(c *fiber.Ctx) error { return nil }).Name("user")
+	api.Post("/users", func(c *fiber.Ctx) error { return nil })
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("*.example.com", api))
+
+	admin := fiber.New()
+	admin.Get("/debug/routes/:hostname", manager.RoutesHandler())
+	admin.Get("/debug/routes", manager.RoutesHandler())
+
+	resp, err := admin.Test(httptest.NewRequest("GET", "/debug/routes/tenant.example.com", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var routes HostRoutes
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&routes))
+	assert.Equal(t, "*.example.com", routes.Pattern)
+	assert.Equal(t, "api", routes.AppName)
+	assert.Contains(t, routes.Routes, RouteInfo{Method: "GET", Path: "/users/:id", Name: "user", Handlers: 2})
+	assert.Contains(t, routes.Routes, RouteInfo{Method: "POST", Path: "/users", Handlers: 1})
+
+	resp, err = admin.Test(httptest.NewRequest("GET", "/debug/routes?hostname=other.example.org", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = admin.Test(httptest.NewRequest("GET", "/debug/routes", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
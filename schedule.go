@@ -0,0 +1,194 @@
+// This file contains scheduled serving windows, which suspend and resume a hostname automatically, like a staging domain that is only served during business hours.
+package fibervhosts
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrInvalidSchedule is returned by SetSchedule for schedules without windows or with malformed times
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Schedule defines when a hostname is served, see SetSchedule
+type Schedule struct {
+	// Windows lists the times the hostname is served. Outside of all windows it is suspended.
+	Windows []ScheduleWindow
+	// Location is the time zone the windows are in. Defaults to time.Local.
+	Location *time.Location
+}
+
+// ScheduleWindow is a daily time range the hostname is served in
+type ScheduleWindow struct {
+	// Days limits the window to the weekdays it starts on. Defaults to every day.
+	Days []time.Weekday
+	// Start and End are clock times like "09:00". An End before or equal to Start ends the window on the next day, so "22:00" to "06:00" spans the night and "00:00" to "00:00" a whole day.
+	Start, End string
+}
+
+// hostSchedule is a parsed schedule attached to an entry
+type hostSchedule struct {
+	windows  []scheduleWindow
+	location *time.Location
+	timer    *time.Timer
+}
+
+// scheduleWindow is a parsed ScheduleWindow, with start and end as offsets from midnight
+type scheduleWindow struct {
+	days       []time.Weekday
+	start, end time.Duration
+}
+
+// SetSchedule attaches a schedule to a hostname, replacing any previous one. The hostname is suspended or resumed right away to match the schedule, and again at every window boundary; like SuspendHostname and ResumeHostname, these are versioned and reported as updates. SuspendHostname and ResumeHostname still work in between, until the next boundary.
+func (m *VhostsManager) SetSchedule(hostname string, schedule Schedule) error {
+	s, err := parseSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+		e.stopSchedule()
+		e.schedule = s
+		return m.applySchedule(e, s, time.Now()), nil
+	})
+}
+
+// ClearSchedule detaches the schedule of a hostname, leaving it suspended or resumed as it currently is
+func (m *VhostsManager) ClearSchedule(hostname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	if !exists {
+		return ErrHostNotFound
+	}
+	e.stopSchedule()
+	e.schedule = nil
+	return nil
+}
+
+// parseSchedule validates a schedule
+func parseSchedule(schedule Schedule) (*hostSchedule, error) {
+	if len(schedule.Windows) == 0 {
+		return nil, fmt.Errorf("%w: no windows", ErrInvalidSchedule)
+	}
+	s := &hostSchedule{location: schedule.Location}
+	if s.location == nil {
+		s.location = time.Local
+	}
+	for _, w := range schedule.Windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return nil, err
+		}
+		if end <= start {
+			end += 24 * time.Hour
+		}
+		s.windows = append(s.windows, scheduleWindow{slices.Clone(w.Days), start, end})
+	}
+	return s, nil
+}
+
+// parseClock parses a clock time like "09:00" into the offset from midnight
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%w: clock time %q", ErrInvalidSchedule, clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// applySchedule suspends or resumes the entry as scheduled at now and arms the timer for the next window boundary. It returns the change when the entry was suspended or resumed. The caller must hold the lock, see update.
+func (m *VhostsManager) applySchedule(e *entry, s *hostSchedule, now time.Time) []ChangeEvent {
+	suspended := !s.active(now)
+	var changes []ChangeEvent
+	if e.suspended.Load() != suspended {
+		previous := e.toEntry()
+		e.suspended.Store(suspended)
+		changes = append(changes, updated(previous, e))
+		if m.enableLog {
+			if suspended {
+				m.logger.Info("Schedule suspended hostname", "hostname", e.pattern)
+			} else {
+				m.logger.Info("Schedule resumed hostname", "hostname", e.pattern)
+			}
+		}
+	}
+	if !suspended {
+		e.draining.Store(false)
+	}
+
+	next := s.next(now)
+	s.timer = time.AfterFunc(next.Sub(now), func() {
+		_ = m.update(func() ([]ChangeEvent, error) {
+			table, key := m.tableFor(e.pattern)
+			// The entry was removed or its schedule replaced in the meantime, so the timer is not armed again
+			if table[key] != e || e.schedule != s {
+				return nil, nil
+			}
+			return m.applySchedule(e, s, time.Now()), nil
+		})
+	})
+	return changes
+}
+
+// stopSchedule stops the timer of the schedule of the entry, if any
+func (e *entry) stopSchedule() {
+	if e.schedule != nil {
+		e.schedule.timer.Stop()
+	}
+}
+
+// active reports whether t is inside one of the windows
+func (s *hostSchedule) active(t time.Time) bool {
+	t = t.In(s.location)
+	for day := -1; day <= 0; day++ {
+		midnight := time.Date(t.Year(), t.Month(), t.Day()+day, 0, 0, 0, 0, s.location)
+		for _, w := range s.windows {
+			if w.on(midnight.Weekday()) && !t.Before(clockAt(midnight, w.start)) && t.Before(clockAt(midnight, w.end)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// next returns the first window boundary after t
+func (s *hostSchedule) next(t time.Time) time.Time {
+	t = t.In(s.location)
+	var next time.Time
+	for day := -1; day <= 7; day++ {
+		midnight := time.Date(t.Year(), t.Month(), t.Day()+day, 0, 0, 0, 0, s.location)
+		for _, w := range s.windows {
+			if !w.on(midnight.Weekday()) {
+				continue
+			}
+			for _, boundary := range []time.Time{clockAt(midnight, w.start), clockAt(midnight, w.end)} {
+				if boundary.After(t) && (next.IsZero() || boundary.Before(next)) {
+					next = boundary
+				}
+			}
+		}
+	}
+	return next
+}
+
+// on reports whether the window starts on the given weekday
+func (w scheduleWindow) on(day time.Weekday) bool {
+	return len(w.days) == 0 || slices.Contains(w.days, day)
+}
+
+// clockAt returns the wall clock time offset from midnight, so windows keep their clock times across daylight saving changes
+func clockAt(midnight time.Time, offset time.Duration) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), 0, int(offset/time.Minute), 0, 0, midnight.Location())
+}
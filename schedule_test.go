@@ -0,0 +1,84 @@
+package fibervhosts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test schedule windows, including days and windows spanning midnight.
+func TestSchedule_Windows(t *testing.T) {
+	s, err := parseSchedule(Schedule{
+		Windows: []ScheduleWindow{
+			{Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, Start: "09:00", End: "18:00"},
+			{Days: []time.Weekday{time.Saturday}, Start: "22:00", End: "02:00"},
+		},
+		Location: time.UTC,
+	})
+	assert.NoError(t, err)
+
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time { return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC) }
+	assert.False(t, s.active(at(1, 8, 59)))
+	assert.True(t, s.active(at(1, 9, 0)))
+	assert.False(t, s.active(at(1, 18, 0)))
+	assert.True(t, s.active(at(6, 23, 0)))
+	assert.True(t, s.active(at(7, 1, 59)))
+	assert.False(t, s.active(at(7, 2, 0)))
+	assert.False(t, s.active(at(7, 12, 0)))
+
+	assert.Equal(t, at(1, 9, 0), s.next(at(1, 3, 0)))
+	assert.Equal(t, at(1, 18, 0), s.next(at(1, 9, 0)))
+	assert.Equal(t, at(6, 22, 0), s.next(at(5, 18, 0)))
+	assert.Equal(t, at(7, 2, 0), s.next(at(6, 23, 0)))
+	assert.Equal(t, at(8, 9, 0), s.next(at(7, 2, 0)))
+
+	_, err = parseSchedule(Schedule{})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+	_, err = parseSchedule(Schedule{Windows: []ScheduleWindow{{Start: "9am", End: "18:00"}}})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+}
+
+// Test a schedule suspends and resumes the hostname at the window boundaries.
+func TestVhostsManager_SetSchedule(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("staging.example.com", fiber.New()))
+	assert.ErrorIs(t, manager.SetSchedule("missing.example.com", Schedule{Windows: []ScheduleWindow{{Start: "00:00", End: "00:00"}}}), ErrHostNotFound)
+
+	// A window covering the whole day keeps the hostname served
+	assert.NoError(t, manager.SetSchedule("staging.example.com", Schedule{Windows: []ScheduleWindow{{Start: "00:00", End: "00:00"}}}))
+	assert.False(t, manager.IsSuspended("staging.example.com"))
+
+	// A window two days ahead suspends it right away
+	later := time.Now().AddDate(0, 0, 2).Weekday()
+	assert.NoError(t, manager.SetSchedule("staging.example.com", Schedule{Windows: []ScheduleWindow{{Days: []time.Weekday{later}, Start: "00:00", End: "00:01"}}}))
+	assert.True(t, manager.IsSuspended("staging.example.com"))
+
+	// Clearing keeps the current state
+	assert.NoError(t, manager.ClearSchedule("staging.example.com"))
+	assert.True(t, manager.IsSuspended("staging.example.com"))
+	assert.NoError(t, manager.ResumeHostname("staging.example.com"))
+}
+
+// Test the suspensions and resumptions of a schedule are versioned and reported like any other update.
+func TestVhostsManager_ScheduleEvents(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("staging.example.com", fiber.New()))
+	events, cancel := manager.Watch()
+	defer cancel()
+
+	version := manager.Version()
+	later := time.Now().AddDate(0, 0, 2).Weekday()
+	assert.NoError(t, manager.SetSchedule("staging.example.com", Schedule{Windows: []ScheduleWindow{{Days: []time.Weekday{later}, Start: "00:00", End: "00:01"}}}))
+	assert.Equal(t, version+1, manager.Version())
+	ev := receive(t, events)
+	assert.Equal(t, ChangeUpdated, ev.Type)
+	assert.True(t, ev.Entry.Suspended)
+	assert.False(t, ev.Previous.Suspended)
+
+	// Schedules matching the current state change nothing
+	assert.NoError(t, manager.SetSchedule("staging.example.com", Schedule{Windows: []ScheduleWindow{{Days: []time.Weekday{later}, Start: "00:00", End: "00:02"}}}))
+	assert.Equal(t, version+1, manager.Version())
+}
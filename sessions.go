@@ -0,0 +1,120 @@
+// This file contains the tenant isolation of fiber sessions and storages, which namespaces their keys by tenant or hostname so tenants sharing one process and one store cannot read each other's data.
+package fibervhosts
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+var ErrNamespacedReset = errors.New("namespaced storage cannot be reset")
+
+// namespacedStorage is the fiber.Storage returned by NamespacedStorage
+type namespacedStorage struct {
+	storage fiber.Storage
+	prefix  string
+}
+
+// NamespacedStorage returns a fiber.Storage keeping its keys in storage below prefix, like the Provisioning.StoragePrefix of a tenant. Reset fails with ErrNamespacedReset, as it would reset the other namespaces too, and Close leaves storage open, as it is shared.
+func NamespacedStorage(storage fiber.Storage, prefix string) fiber.Storage {
+	return &namespacedStorage{storage: storage, prefix: prefix}
+}
+
+// Get returns the value of key in the namespace
+func (s *namespacedStorage) Get(key string) ([]byte, error) {
+	return s.storage.Get(s.prefix + key)
+}
+
+// Set stores the value of key in the namespace
+func (s *namespacedStorage) Set(key string, val []byte, exp time.Duration) error {
+	return s.storage.Set(s.prefix+key, val, exp)
+}
+
+// Delete deletes key from the namespace
+func (s *namespacedStorage) Delete(key string) error {
+	return s.storage.Delete(s.prefix + key)
+}
+
+// Reset fails, see NamespacedStorage
+func (s *namespacedStorage) Reset() error {
+	return ErrNamespacedReset
+}
+
+// Close does nothing, see NamespacedStorage
+func (s *namespacedStorage) Close() error {
+	return nil
+}
+
+// TenantSessions keeps the sessions of a sub-app shared by many tenants apart: every tenant gets a session store of its own, whose keys are namespaced in the shared storage of the config. Requests belong to their tenant, see TenantResolver, or else to the registration they were matched by: exact hostnames and aliases each have their own sessions, while all hostnames of a wildcard registration or of the default app share theirs, so clients cannot create stores by making up Host headers.
+type TenantSessions struct {
+	config session.Config
+	// memory is the in-memory storage created without a Storage in the config, see Close
+	memory fiber.Storage
+
+	mu     sync.Mutex
+	stores map[string]*session.Store
+}
+
+// NewTenantSessions creates the session stores of the tenants with config. Without a Storage, the tenants share one in-memory storage, which is closed by Close.
+func NewTenantSessions(config ...session.Config) *TenantSessions {
+	s := &TenantSessions{stores: make(map[string]*session.Store)}
+	if len(config) > 0 {
+		s.config = config[0]
+	}
+	if s.config.Storage == nil {
+		s.memory = session.New().Storage
+		s.config.Storage = s.memory
+	}
+	return s
+}
+
+// Get returns the session of a request from the store of its tenant
+func (s *TenantSessions) Get(c *fiber.Ctx) (*session.Session, error) {
+	return s.Store(c).Get(c)
+}
+
+// Store returns the session store of the tenant of a request, creating it on first use
+func (s *TenantSessions) Store(c *fiber.Ctx) *session.Store {
+	namespace := sessionNamespace(c)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	store, exists := s.stores[namespace]
+	if !exists {
+		config := s.config
+		config.Storage = NamespacedStorage(config.Storage, namespace+":")
+		store = session.New(config)
+		s.stores[namespace] = store
+	}
+	return store
+}
+
+// Close stops the in-memory storage created without a Storage in the config. A Storage of the config is left open, as it is owned by the caller.
+func (s *TenantSessions) Close() error {
+	if s.memory == nil {
+		return nil
+	}
+	return s.memory.Close()
+}
+
+// sessionNamespace returns the namespace of the sessions of a request: its tenant, the exact hostname or alias it was matched by, or else its registration
+func sessionNamespace(c *fiber.Ctx) string {
+	if tenant, ok := GetTenant(c); ok {
+		return "tenant:" + tenant.ID
+	}
+	switch c.Locals(LocalsMatchTypeKey) {
+	case EntryHost:
+		// Exact matches are bounded by the registrations and their aliases
+		hostname, _ := c.Locals(LocalsHostnameKey).(string)
+		return "host:" + hostname
+	case EntryWildcard:
+		pattern, _ := c.Locals(LocalsPatternKey).(string)
+		return "pattern:" + pattern
+	case EntryDefault:
+		return "default"
+	}
+	return "unmatched"
+}
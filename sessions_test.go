@@ -0,0 +1,126 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// mapStorage is a fiber.Storage shared by the tenants
+type mapStorage struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *mapStorage) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key], nil
+}
+
+func (s *mapStorage) Set(key string, val []byte, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = val
+	return nil
+}
+
+func (s *mapStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+func (s *mapStorage) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = make(map[string][]byte)
+	return nil
+}
+
+func (s *mapStorage) Close() error {
+	return nil
+}
+
+// Test tenants sharing a sub-app and a storage cannot read each other's sessions, even with the same session ID.
+func TestTenantSessions(t *testing.T) {
+	storage := &mapStorage{data: make(map[string][]byte)}
+	sessions := NewTenantSessions(session.Config{Storage: storage})
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		sess, err := sessions.Get(c)
+		if err != nil {
+			return err
+		}
+		if user := c.Query("login"); user != "" {
+			sess.Set("user", user)
+			id := sess.ID()
+			if err := sess.Save(); err != nil {
+				return err
+			}
+			return c.SendString(id)
+		}
+		user, _ := sess.Get("user").(string)
+		return c.SendString(user)
+	})
+
+	manager := NewVhostsManager(Config{Tenants: &TenantResolver{Tenants: map[string]string{"shop.acme.com": "acme", "www.acme.com": "acme"}}})
+	assert.NoError(t, manager.AddSharedApp([]string{"app.example.com", "shop.acme.com", "www.acme.com", "www.globex.com"}, app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(target, id string) string {
+		req := httptest.NewRequest("GET", target, nil)
+		if id != "" {
+			req.Header.Set(fiber.HeaderCookie, "session_id="+id)
+		}
+		resp, err := main.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	id := request("http://shop.acme.com/?login=alice", "")
+	assert.Equal(t, "alice", request("http://shop.acme.com/", id))
+	assert.Equal(t, "alice", request("http://www.acme.com/", id))
+	assert.Equal(t, "", request("http://www.globex.com/", id))
+	assert.Equal(t, "", request("http://app.example.com/", id))
+	assert.Len(t, storage.data, 1)
+	for key := range storage.data {
+		assert.True(t, strings.HasPrefix(key, "tenant:acme:"), key)
+	}
+
+	assert.ErrorIs(t, NamespacedStorage(storage, "tenant:acme:").Reset(), ErrNamespacedReset)
+	assert.Len(t, storage.data, 1)
+}
+
+// Test requests without a tenant share the sessions of their wildcard registration, whatever their Host header.
+func TestTenantSessions_Wildcard(t *testing.T) {
+	sessions := NewTenantSessions()
+	defer sessions.Close()
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		sessions.Store(c)
+		return nil
+	})
+
+	manager := NewVhostsManager(Config{DefaultApp: app})
+	assert.NoError(t, manager.AddHostname("*.example.com", app))
+	assert.NoError(t, manager.AddHostname("www.example.org", app))
+	for _, hostname := range []string{"a.example.com", "b.example.com", "c.example.com", "unknown.net", "other.net", "www.example.org"} {
+		_, err := manager.Test(hostname, NewTestRequest("GET", "", "/", nil))
+		assert.NoError(t, err)
+	}
+	assert.Len(t, sessions.stores, 3)
+	assert.Contains(t, sessions.stores, "pattern:*.example.com")
+	assert.Contains(t, sessions.stores, "default")
+	assert.Contains(t, sessions.stores, "host:www.example.org")
+}
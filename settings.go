@@ -0,0 +1,38 @@
+// This file contains the per-vhost settings map, which parameterizes a sub-app shared by many hostnames, like the theme, the feature set or the API keys of every tenant.
+package fibervhosts
+
+import (
+	"maps"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Settings returns the settings of the registration matched by a request, see HostConfig.Settings. It returns nil for unmatched requests and registrations without settings; the map must not be modified.
+func Settings(c *fiber.Ctx) map[string]any {
+	settings, _ := c.Locals(LocalsSettingsKey).(map[string]any)
+	return settings
+}
+
+// Setting returns the setting key of the registration matched by a request as a T, and false if it is not set or not a T
+func Setting[T any](c *fiber.Ctx, key string) (T, bool) {
+	value, ok := Settings(c)[key].(T)
+	return value, ok
+}
+
+// SetSettings replaces the settings of a registered hostname or wildcard pattern, keeping the rest of its HostConfig. Requests see the new settings as soon as it returns.
+func (m *VhostsManager) SetSettings(hostname string, settings map[string]any) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+
+		previous := e.toEntry()
+		// Requests in flight keep reading the map of the previous lookup table
+		config := e.config
+		config.Settings = maps.Clone(settings)
+		e.setConfig(config)
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
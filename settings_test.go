@@ -0,0 +1,47 @@
+package fibervhosts
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test a shared sub-app reads the settings of the hostname a request was matched to.
+func TestVhostMiddleware_Settings(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		theme, _ := Setting[string](c, "theme")
+		seats, ok := Setting[int](c, "seats")
+		return c.SendString(fmt.Sprintf("%s %d %t %d", theme, seats, ok, len(Settings(c))))
+	})
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameWithConfig("acme.example.com", app, HostConfig{Settings: map[string]any{"theme": "dark", "seats": 5}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("globex.example.com", app, HostConfig{Settings: map[string]any{"theme": "light", "seats": "many"}}))
+	assert.NoError(t, manager.AddHostname("plain.example.com", app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(target string) string {
+		resp, err := main.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.Equal(t, "dark 5 true 2", request("http://acme.example.com/"))
+	assert.Equal(t, "light 0 false 2", request("http://globex.example.com/"))
+	assert.Equal(t, " 0 false 0", request("http://plain.example.com/"))
+
+	settings := map[string]any{"theme": "blue"}
+	assert.NoError(t, manager.SetSettings("acme.example.com", settings))
+	settings["theme"] = "changed"
+	assert.Equal(t, "blue 0 false 1", request("http://acme.example.com/"))
+	config, _ := manager.GetHostConfig("acme.example.com")
+	assert.Equal(t, map[string]any{"theme": "blue"}, config.Settings)
+	assert.ErrorIs(t, manager.SetSettings("missing.example.com", nil), ErrHostNotFound)
+}
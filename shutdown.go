@@ -0,0 +1,62 @@
+// This file contains the graceful shutdown of the sub-apps of the manager.
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Shutdown shuts down every registered sub-app and the default app concurrently with ShutdownWithContext, which runs their OnShutdown hooks, and then runs the OnStop callbacks of the registrations. An app registered for several hostnames is shut down once. The errors of all apps and callbacks are returned joined. The registrations themselves are kept.
+func (m *VhostsManager) Shutdown(ctx context.Context) error {
+	m.mu.RLock()
+	apps := make(map[*fiber.App]string)
+	var entries []*entry
+	collect := func(e *entry) {
+		entries = append(entries, e)
+		if _, seen := apps[e.app]; !seen && e.app != nil {
+			apps[e.app] = e.pattern
+		}
+	}
+	for _, e := range m.hosts {
+		collect(e)
+	}
+	for _, e := range m.wildcards {
+		collect(e)
+	}
+	if m.defaultApp != nil {
+		collect(m.defaultApp)
+	}
+	m.mu.RUnlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for app, pattern := range apps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := app.ShutdownWithContext(ctx); err != nil {
+				if pattern == "" {
+					pattern = defaultAppName
+				}
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", pattern, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, e := range entries {
+		if err := m.stopEntry(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
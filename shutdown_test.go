@@ -0,0 +1,33 @@
+package fibervhosts
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Shutdown shuts down every distinct sub-app once, including the default app.
+func TestVhostsManager_Shutdown(t *testing.T) {
+	var shutdowns atomic.Int32
+	newApp := func() *fiber.App {
+		app := fiber.New()
+		app.Hooks().OnShutdown(func() error {
+			shutdowns.Add(1)
+			return nil
+		})
+		return app
+	}
+
+	shared := newApp()
+	manager := NewVhostsManager(Config{DefaultApp: newApp()})
+	assert.NoError(t, manager.AddHostname("a.example.com", shared))
+	assert.NoError(t, manager.AddHostname("b.example.com", shared))
+	assert.NoError(t, manager.AddHostname("*.example.org", newApp()))
+
+	assert.NoError(t, manager.Shutdown(context.Background()))
+	assert.Equal(t, int32(3), shutdowns.Load())
+	assert.Len(t, manager.ListEntries(), 4)
+}
@@ -0,0 +1,34 @@
+// This file contains the slow request warning of the middleware, logging dispatched requests that take longer than the threshold of their hostname.
+package fibervhosts
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// slowThreshold returns the slow request threshold of r, or zero when slow requests are not logged
+func (m *VhostsManager) slowThreshold(r *route) time.Duration {
+	if r.config.SlowRequestThreshold != 0 {
+		return r.config.SlowRequestThreshold
+	}
+	return m.slowRequestThreshold
+}
+
+// checkSlow logs a warning to the request logger when a request dispatched to r took longer than its threshold. The fields are copied, as the logger may keep them beyond the request.
+func (m *VhostsManager) checkSlow(c *fiber.Ctx, r *route, logger Logger, elapsed time.Duration) {
+	threshold := m.slowThreshold(r)
+	if threshold <= 0 || elapsed <= threshold {
+		return
+	}
+	logger.Warn("Slow request",
+		"hostname", strings.Clone(c.Hostname()),
+		"pattern", r.info.Pattern,
+		"method", strings.Clone(c.Method()),
+		"path", string(c.Request().URI().Path()),
+		"status", c.Response().StatusCode(),
+		"duration", elapsed,
+		"threshold", threshold,
+	)
+}
@@ -0,0 +1,39 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test requests beyond the threshold of their hostname are logged as slow.
+func TestVhostMiddleware_SlowRequests(t *testing.T) {
+	app := fiber.New()
+	app.Get("/report", func(c *fiber.Ctx) error {
+		time.Sleep(20 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	logger := &recordingLogger{}
+	manager := NewVhostsManager(Config{Logger: logger, SlowRequestThreshold: 5 * time.Millisecond})
+	assert.NoError(t, manager.AddHostname("slow.example.com", app))
+	assert.NoError(t, manager.AddHostnameWithConfig("batch.example.com", app, HostConfig{SlowRequestThreshold: time.Second}))
+	assert.NoError(t, manager.AddHostnameWithConfig("quiet.example.com", app, HostConfig{SlowRequestThreshold: -1}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	for _, host := range []string{"slow.example.com", "batch.example.com", "quiet.example.com"} {
+		_, err := main.Test(httptest.NewRequest("GET", "http://"+host+"/report?id=1", nil))
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, logger.records, 1)
+	record := logger.records[0]
+	assert.Equal(t, "warn", record.level)
+	assert.Equal(t, "Slow request", record.msg)
+	assert.Equal(t, []any{"hostname", "slow.example.com", "pattern", "slow.example.com", "method", "GET", "path", "/report", "status", 200}, record.fields[:10])
+	assert.Greater(t, record.fields[11].(time.Duration), 5*time.Millisecond)
+}
@@ -0,0 +1,109 @@
+// This file contains the versioned snapshots of the vhost table and the rollback to a previous snapshot.
+package fibervhosts
+
+import (
+	"errors"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// defaultSnapshotHistory is the number of snapshots kept when Config.SnapshotHistory is not set
+const defaultSnapshotHistory = 10
+
+// ErrSnapshotNotFound is returned by Rollback when no snapshot with the requested version is kept
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is a copy of the vhost table at a given version. Its entries also carry the HostConfig, the app factory and the request handler of the registrations, which Rollback restores; the entries of lazily built registrations have no App, as it may be evicted.
+type Snapshot struct {
+	Version uint64
+	Time    time.Time
+	Entries []Entry
+}
+
+// Version returns the current version of the vhost table. It is incremented by every change.
+func (m *VhostsManager) Version() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.version
+}
+
+// Snapshot records the current vhost table in the snapshot history and returns it. Only the last Config.SnapshotHistory snapshots are kept. Taking a snapshot of an unchanged table replaces the previous snapshot of that version.
+func (m *VhostsManager) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []Entry
+	for _, table := range []map[string]*entry{m.hosts, m.wildcards} {
+		for _, e := range table {
+			entries = append(entries, e.snapshotEntry())
+		}
+	}
+	if m.defaultApp != nil {
+		entries = append(entries, m.defaultApp.snapshotEntry())
+	}
+	sortByEntry(entries, func(e Entry) (EntryType, string) { return e.Type, e.Pattern })
+
+	snap := Snapshot{Version: m.version, Time: time.Now(), Entries: entries}
+	if n := len(m.snapshots); n > 0 && m.snapshots[n-1].Version == snap.Version {
+		m.snapshots = m.snapshots[:n-1]
+	}
+	m.snapshots = append(m.snapshots, snap)
+	if len(m.snapshots) > m.snapshotHistory {
+		m.snapshots = m.snapshots[len(m.snapshots)-m.snapshotHistory:]
+	}
+	return snap
+}
+
+// Snapshots returns the kept snapshots, oldest first
+func (m *VhostsManager) Snapshots() []Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Snapshot(nil), m.snapshots...)
+}
+
+// Rollback restores the vhost table to the snapshot with the given version, including the HostConfig of the registrations. Registrations that still exist keep their state like stats; hooks and watchers are notified of every difference. The expiry of registrations restored after they were removed is not re-armed. Aliases are restored unless another registration took them since.
+func (m *VhostsManager) Rollback(version uint64) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		for _, snap := range m.snapshots {
+			if snap.Version == version {
+				changes := m.converge(snap.Entries, nil)
+				return append(changes, m.restoreAliases(snap.Entries)...), nil
+			}
+		}
+		return nil, ErrSnapshotNotFound
+	})
+}
+
+// entryState is the state of a registration a snapshot restores besides its Entry, see Rollback
+type entryState struct {
+	configRevision uint64
+	config         HostConfig
+	// factory builds the app of lazily built registrations, whose snapshot entries have no App, as their built app may be evicted since
+	factory *appFactory
+	handler *fasthttp.RequestHandler
+}
+
+// snapshotEntry returns the public description of the entry together with the state Rollback restores
+func (e *entry) snapshotEntry() Entry {
+	s := e.toEntry()
+	s.state = &entryState{configRevision: e.configRevision, config: e.config, factory: e.factory, handler: e.handler}
+	if e.factory != nil {
+		s.App = nil
+	}
+	return s
+}
+
+// restored reports whether the entry has the backend and the state of the snapshot entry d
+func (s *entryState) restored(e *entry, d Entry) bool {
+	return e.factory == s.factory && e.handler == s.handler && (s.factory != nil || e.app == d.App) && e.configRevision == s.configRevision
+}
+
+// restore gives the entry the backend and the state of the snapshot entry d back. The built app of a lazily built registration is kept as long as its factory is.
+func (e *entry) restore(d Entry) {
+	s := d.state
+	if e.factory != s.factory || e.handler != s.handler || s.factory == nil && e.app != d.App {
+		e.app, e.handler, e.factory = d.App, s.handler, s.factory
+	}
+	e.config, e.configRevision = s.config, s.configRevision
+}
@@ -0,0 +1,200 @@
+package fibervhosts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// Test the version counter is bumped by changes only.
+func TestVhostsManager_Version(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.Equal(t, uint64(0), manager.Version())
+
+	assert.NoError(t, manager.AddHostname("example.com", fiber.New()))
+	assert.Equal(t, uint64(1), manager.Version())
+
+	// Failed changes don't bump the version
+	assert.Error(t, manager.AddHostname("example.com", fiber.New()))
+	assert.Equal(t, uint64(1), manager.Version())
+}
+
+// Test rolling back to a snapshot after a bad bulk update.
+func TestVhostsManager_SnapshotRollback(t *testing.T) {
+	app := fiber.New()
+	other := fiber.New()
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("keep.com", app))
+	assert.NoError(t, manager.AddHostname("swap.com", app))
+	assert.NoError(t, manager.SetMetadata("keep.com", Metadata{Tags: []string{"gold"}}))
+
+	snap := manager.Snapshot()
+	assert.Equal(t, manager.Version(), snap.Version)
+	assert.Len(t, snap.Entries, 2)
+
+	// Bad update
+	assert.NoError(t, manager.RemoveHostname("keep.com"))
+	assert.NoError(t, manager.UpdateHostname("swap.com", other))
+	assert.NoError(t, manager.AddHostnames(map[string]*fiber.App{"junk1.com": other, "junk2.com": other}))
+
+	var events []ChangeType
+	manager.Hooks().OnAdd(func(Entry) { events = append(events, ChangeAdded) })
+	manager.Hooks().OnUpdate(func(Entry, Entry) { events = append(events, ChangeUpdated) })
+	manager.Hooks().OnRemove(func(Entry) { events = append(events, ChangeRemoved) })
+
+	assert.NoError(t, manager.Rollback(snap.Version))
+	assert.ElementsMatch(t, []string{"keep.com", "swap.com"}, manager.GetHostnames())
+	restored, _ := manager.GetHostname("swap.com")
+	assert.Equal(t, app, restored)
+	md, _ := manager.GetMetadata("keep.com")
+	assert.True(t, md.HasTag("gold"))
+	assert.ElementsMatch(t, []ChangeType{ChangeRemoved, ChangeRemoved, ChangeUpdated, ChangeAdded}, events)
+
+	assert.Equal(t, ErrSnapshotNotFound, manager.Rollback(12345))
+}
+
+// Test only the configured number of snapshots is kept.
+func TestVhostsManager_SnapshotHistory(t *testing.T) {
+	manager := NewVhostsManager(Config{SnapshotHistory: 2})
+	for _, hostname := range []string{"a.com", "b.com", "c.com"} {
+		assert.NoError(t, manager.AddHostname(hostname, fiber.New()))
+		manager.Snapshot()
+	}
+
+	snapshots := manager.Snapshots()
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, uint64(2), snapshots[0].Version)
+	assert.Equal(t, uint64(3), snapshots[1].Version)
+	assert.Equal(t, ErrSnapshotNotFound, manager.Rollback(1))
+}
+
+// Test rolling back keeps the factory of lazily built apps, whether they were built since the snapshot or not.
+func TestVhostsManager_RollbackFactory(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameFactory("lazy.com", func() (*fiber.App, error) {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendString("lazy") })
+		return app, nil
+	}))
+	snap := manager.Snapshot()
+
+	resp, err := manager.Test("lazy.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.NoError(t, manager.AddHostname("other.com", fiber.New()))
+	assert.NoError(t, manager.SuspendHostname("lazy.com"))
+
+	assert.NoError(t, manager.Rollback(snap.Version))
+	assert.Equal(t, []string{"lazy.com"}, manager.GetHostnames())
+	assert.False(t, manager.IsSuspended("lazy.com"))
+	for range 2 {
+		resp, err = manager.Test("lazy.com", NewTestRequest("GET", "", "/", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+}
+
+// Test rolling back the settings of a plain handler keeps the handler.
+func TestVhostsManager_RollbackRequestHandler(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddRequestHandler("fast.com", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+	}))
+	snap := manager.Snapshot()
+	assert.NoError(t, manager.SetMetadata("fast.com", Metadata{Tags: []string{"beta"}}))
+
+	assert.NoError(t, manager.Rollback(snap.Version))
+	md, _ := manager.GetMetadata("fast.com")
+	assert.False(t, md.HasTag("beta"))
+	resp, err := manager.Test("fast.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+}
+
+// Test rolling back restores the HostConfig of registrations, whether they were removed or reconfigured since the snapshot.
+func TestVhostsManager_RollbackHostConfig(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("secret") })
+	manager := NewVhostsManager()
+	config := HostConfig{Auth: BasicAuth("staging", map[string]string{"admin": "secret"})}
+	assert.NoError(t, manager.AddHostnameWithConfig("staging.example.com", app, config))
+	assert.NoError(t, manager.AddHostnameWithConfig("admin.example.com", app, config))
+	snap := manager.Snapshot()
+
+	assert.NoError(t, manager.RemoveHostname("staging.example.com"))
+	assert.NoError(t, manager.SetHostConfig("admin.example.com", HostConfig{}))
+	assert.NoError(t, manager.AddHostname("staging.example.com", app))
+
+	assert.NoError(t, manager.Rollback(snap.Version))
+	for _, hostname := range []string{"staging.example.com", "admin.example.com"} {
+		resp, err := manager.Test(hostname, NewTestRequest("GET", "", "/", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, hostname)
+	}
+
+	// Rolling back to an unchanged table changes nothing
+	version := manager.Version()
+	assert.NoError(t, manager.Rollback(snap.Version))
+	assert.Equal(t, version, manager.Version())
+}
+
+// Test rolling back after the app of a lazily built registration was evicted keeps its factory instead of the shut down app.
+func TestVhostsManager_RollbackEvictedFactory(t *testing.T) {
+	builds := 0
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostnameFactory("lazy.com", func() (*fiber.App, error) {
+		builds++
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendString("lazy") })
+		return app, nil
+	}))
+	resp, err := manager.Test("lazy.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	snap := manager.Snapshot()
+	assert.Nil(t, snap.Entries[0].App)
+
+	evicted, err := manager.EvictIdle(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, evicted)
+	assert.NoError(t, manager.Rollback(snap.Version))
+
+	assert.NoError(t, manager.RestartHostname("lazy.com"))
+	resp, err = manager.Test("lazy.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, builds)
+}
+
+// Test rolling back restores removed redirect and plain handler registrations, which have no App.
+func TestVhostsManager_RollbackRedirect(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddRedirect("www.example.com", "https://example.com", 0))
+	assert.NoError(t, manager.AddRequestHandler("fast.com", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+	}))
+	snap := manager.Snapshot()
+	assert.Equal(t, &Redirect{Target: "https://example.com"}, snap.Entries[1].Redirect)
+
+	assert.NoError(t, manager.RemoveHostname("www.example.com"))
+	assert.NoError(t, manager.RemoveHostname("fast.com"))
+	assert.NoError(t, manager.Rollback(snap.Version))
+
+	resp, err := manager.Test("www.example.com", NewTestRequest("GET", "", "/docs", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://example.com/docs", resp.Header.Get(fiber.HeaderLocation))
+	resp, err = manager.Test("fast.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+
+	// Redirects can be applied like apps
+	assert.NoError(t, manager.ApplyEntries("sync", []Entry{{Pattern: "old.com", Redirect: &Redirect{Target: "https://new.com", Status: fiber.StatusFound}}}))
+	resp, err = manager.Test("old.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://new.com/", resp.Header.Get(fiber.HeaderLocation))
+}
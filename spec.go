@@ -0,0 +1,92 @@
+// This file contains EntrySpec, the serializable description of a registration shared by config files, environment variables, exports and providers.
+package fibervhosts
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EntrySpec is the serializable description of a registration, referring to its app by name. An EntrySpec with an empty Hostname describes the default app.
+type EntrySpec struct {
+	Hostname  string    `json:"hostname" yaml:"hostname" toml:"hostname"`
+	App       string    `json:"app" yaml:"app" toml:"app"`
+	Suspended bool      `json:"suspended,omitempty" yaml:"suspended,omitempty" toml:"suspended,omitempty"`
+	Metadata  *Metadata `json:"metadata,omitempty" yaml:"metadata,omitempty" toml:"metadata,omitempty"`
+	Source    string    `json:"source,omitempty" yaml:"source,omitempty" toml:"source,omitempty"`
+	// Backend marks registrations not served by a plain sub-app, see Entry.Backend. Lazily built apps and plain handlers are resolved by their App name like sub-apps.
+	Backend BackendKind `json:"backend,omitempty" yaml:"backend,omitempty" toml:"backend,omitempty"`
+	// Redirect is the redirect of redirect registrations, which have no App
+	Redirect *Redirect `json:"redirect,omitempty" yaml:"redirect,omitempty" toml:"redirect,omitempty"`
+}
+
+// newEntrySpec returns the serializable form of a registration
+func newEntrySpec(e Entry) EntrySpec {
+	spec := EntrySpec{Hostname: e.Pattern, App: e.AppName, Suspended: e.Suspended, Source: e.Source, Backend: e.Backend, Redirect: e.Redirect}
+	if e.Redirect != nil {
+		spec.App = ""
+	}
+	if !e.Metadata.IsEmpty() {
+		md := e.Metadata
+		spec.Metadata = &md
+	}
+	return spec
+}
+
+// ParseEntrySpec parses the value stored for a hostname in a key/value store. The value is either a plain app name or a JSON object with the EntrySpec fields, like {"app":"web","suspended":true}. The hostname of a JSON value defaults to the given hostname.
+func ParseEntrySpec(hostname, value string) (EntrySpec, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "{") {
+		return EntrySpec{Hostname: hostname, App: value}, nil
+	}
+
+	var spec EntrySpec
+	if err := json.Unmarshal([]byte(value), &spec); err != nil {
+		return EntrySpec{}, err
+	}
+	if spec.Hostname == "" {
+		spec.Hostname = hostname
+	}
+	return spec, nil
+}
+
+// ResolveSpecs resolves specs into registrations, using the resolver to map app names to apps. Every app name is resolved once so shared apps stay shared. Unknown apps are left nil; the manager rejects such entries with ErrAppNotFound. Redirects are not resolved.
+func ResolveSpecs(specs []EntrySpec, resolver AppResolver) []Entry {
+	apps := make(map[string]*fiber.App)
+	lookup := func(name string) *fiber.App {
+		app, ok := apps[name]
+		if !ok {
+			app = resolver(name)
+			apps[name] = app
+		}
+		return app
+	}
+
+	entries := make([]Entry, 0, len(specs))
+	for _, spec := range specs {
+		e := Entry{
+			Type:      EntryHost,
+			Pattern:   spec.Hostname,
+			AppName:   spec.App,
+			Suspended: spec.Suspended,
+			Source:    spec.Source,
+			Redirect:  spec.Redirect,
+			Backend:   spec.Backend,
+		}
+		if spec.Redirect == nil {
+			e.App = lookup(spec.App)
+		}
+		switch {
+		case spec.Hostname == "":
+			e.Type = EntryDefault
+		case isWildcard(spec.Hostname):
+			e.Type = EntryWildcard
+		}
+		if spec.Metadata != nil {
+			e.Metadata = spec.Metadata.clone()
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
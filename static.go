@@ -0,0 +1,70 @@
+// This file contains static hosts, hostnames served from a directory of files without hand-building a sub-app, with an optional fallback to the index page for single-page apps.
+package fibervhosts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StaticConfig defines how AddStaticHost serves a directory
+type StaticConfig struct {
+	// Index is the file served for directories. Defaults to "index.html".
+	Index string
+	// SPA serves the index file of the root for GET and HEAD requests of paths without a file, so a single-page app can route them in the browser
+	SPA bool
+	// Browse lists the files of directories without an index file
+	Browse bool
+	// MaxAge sets the Cache-Control max-age of the files. Zero sends no Cache-Control header.
+	MaxAge time.Duration
+	// Compress serves the files compressed when the client accepts it, caching the compressed files next to them
+	Compress bool
+}
+
+// AddStaticHost adds a hostname serving the files of dir, see StaticConfig
+func (m *VhostsManager) AddStaticHost(hostname, dir string, config ...StaticConfig) error {
+	app, err := newStaticApp(dir, config...)
+	if err != nil {
+		return err
+	}
+	return m.AddHostname(hostname, app)
+}
+
+// newStaticApp builds the sub-app of a static host
+func newStaticApp(dir string, config ...StaticConfig) (*fiber.App, error) {
+	var cfg StaticConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Index == "" {
+		cfg.Index = "index.html"
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("static dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("static dir: %s is not a directory", dir)
+	}
+
+	app := fiber.New(fiber.Config{AppName: "static:" + dir, DisableStartupMessage: true})
+	app.Static("/", dir, fiber.Static{
+		Index:    cfg.Index,
+		Browse:   cfg.Browse,
+		MaxAge:   int(cfg.MaxAge / time.Second),
+		Compress: cfg.Compress,
+	})
+	if cfg.SPA {
+		index := filepath.Join(dir, cfg.Index)
+		app.Use(func(c *fiber.Ctx) error {
+			if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+				return fiber.ErrNotFound
+			}
+			return c.SendFile(index)
+		})
+	}
+	return app, nil
+}
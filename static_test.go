@@ -0,0 +1,51 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test static hosts serve the files of their directory, falling back to the index page for single-page apps.
+func TestVhostsManager_AddStaticHost(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>index</h1>"), 0o644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "assets"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte("console.log(1)"), 0o644))
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddStaticHost("docs.example.com", dir))
+	assert.NoError(t, manager.AddStaticHost("app.example.com", dir, StaticConfig{SPA: true}))
+	assert.Error(t, manager.AddStaticHost("missing.example.com", filepath.Join(dir, "missing")))
+	assert.Error(t, manager.AddStaticHost("file.example.com", filepath.Join(dir, "index.html")))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	for _, tc := range []struct {
+		method string
+		target string
+		status int
+		body   string
+	}{
+		{"GET", "http://docs.example.com/", fiber.StatusOK, "<h1>index</h1>"},
+		{"GET", "http://docs.example.com/assets/app.js", fiber.StatusOK, "console.log(1)"},
+		{"GET", "http://docs.example.com/settings", fiber.StatusNotFound, ""},
+		{"GET", "http://app.example.com/assets/app.js", fiber.StatusOK, "console.log(1)"},
+		{"GET", "http://app.example.com/settings/profile", fiber.StatusOK, "<h1>index</h1>"},
+		{"POST", "http://app.example.com/settings", fiber.StatusNotFound, ""},
+	} {
+		resp, err := main.Test(httptest.NewRequest(tc.method, tc.target, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, "%s %s", tc.method, tc.target)
+		if tc.body != "" {
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.body, string(body), tc.target)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// This file contains the per-vhost request counters tracked by the middleware and the Stats API exposing them.
+package fibervhosts
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// HostStats holds the traffic counters of a single registration
+type HostStats struct {
+	Type       EntryType
+	Pattern    string
+	Requests   uint64
+	Errors     uint64
+	LastAccess time.Time
+	// CertExpiresAt is the expiry of the certificate attached to the registration, or zero without one
+	CertExpiresAt time.Time
+}
+
+// hostStats holds the live counters of an entry. They are updated atomically by the middleware.
+type hostStats struct {
+	requests   atomic.Uint64
+	errors     atomic.Uint64
+	lastAccess atomic.Int64
+}
+
+// begin records the start of a request dispatched to the entry
+func (s *hostStats) begin() {
+	s.requests.Add(1)
+	s.lastAccess.Store(time.Now().UnixNano())
+}
+
+// end records the response status of a dispatched request. Server errors (5xx) are counted as errors.
+func (s *hostStats) end(status int) {
+	if status >= 500 {
+		s.errors.Add(1)
+	}
+}
+
+// snapshot returns the public view of the counters. The caller must hold the lock.
+func (s *hostStats) snapshot(e *entry) HostStats {
+	stats := HostStats{
+		Type:     e.kind,
+		Pattern:  e.pattern,
+		Requests: s.requests.Load(),
+		Errors:   s.errors.Load(),
+	}
+	if last := s.lastAccess.Load(); last != 0 {
+		stats.LastAccess = time.Unix(0, last)
+	}
+	if leaf := certLeaf(e.cert); leaf != nil {
+		stats.CertExpiresAt = leaf.NotAfter
+	}
+	return stats
+}
+
+// Stats returns the request counters of every registration, in the same order as ListEntries
+func (m *VhostsManager) Stats() []HostStats {
+	m.mu.RLock()
+	stats := make([]HostStats, 0, len(m.hosts)+len(m.wildcards)+1)
+	for _, e := range m.hosts {
+		stats = append(stats, e.stats.snapshot(e))
+	}
+	for _, e := range m.wildcards {
+		stats = append(stats, e.stats.snapshot(e))
+	}
+	if m.defaultApp != nil {
+		stats = append(stats, m.defaultApp.stats.snapshot(m.defaultApp))
+	}
+	m.mu.RUnlock()
+
+	sortByEntry(stats, func(s HostStats) (EntryType, string) { return s.Type, s.Pattern })
+	return stats
+}
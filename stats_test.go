@@ -0,0 +1,55 @@
+package fibervhosts
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the middleware tracks requests and errors per registration.
+func TestVhostsManager_Stats(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.ErrInternalServerError
+	})
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.NoError(t, manager.AddHostname("idle.com", app))
+	assert.NoError(t, manager.AddHostname("*.example.org", app))
+
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+
+	for _, target := range []struct{ host, path string }{
+		{"example.com", "/"},
+		{"example.com", "/fail"},
+		{"www.example.org", "/"},
+	} {
+		req := httptest.NewRequest("GET", target.path, nil)
+		req.Host = target.host
+		resp, err := mainApp.Test(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	stats := manager.Stats()
+	assert.Len(t, stats, 3)
+
+	assert.Equal(t, "example.com", stats[0].Pattern)
+	assert.Equal(t, uint64(2), stats[0].Requests)
+	assert.Equal(t, uint64(1), stats[0].Errors)
+	assert.False(t, stats[0].LastAccess.IsZero())
+
+	assert.Equal(t, "idle.com", stats[1].Pattern)
+	assert.Equal(t, uint64(0), stats[1].Requests)
+	assert.True(t, stats[1].LastAccess.IsZero())
+
+	assert.Equal(t, EntryWildcard, stats[2].Type)
+	assert.Equal(t, uint64(1), stats[2].Requests)
+}
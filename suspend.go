@@ -0,0 +1,39 @@
+// This file contains the methods to temporarily take a vhost out of rotation without removing its registration.
+package fibervhosts
+
+// SuspendHostname takes a hostname out of rotation while keeping its app registered. Requests for a suspended hostname are answered by Config.SuspendedHandler, or with 503 Service Unavailable by default.
+func (m *VhostsManager) SuspendHostname(hostname string) error {
+	return m.setSuspended(hostname, true)
+}
+
+// ResumeHostname puts a suspended or drained hostname back into rotation
+func (m *VhostsManager) ResumeHostname(hostname string) error {
+	return m.setSuspended(hostname, false)
+}
+
+// IsSuspended reports whether a hostname is registered and currently suspended
+func (m *VhostsManager) IsSuspended(hostname string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	return exists && e.suspended.Load()
+}
+
+// setSuspended updates the suspended flag of a registered hostname
+func (m *VhostsManager) setSuspended(hostname string, suspended bool) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+
+		previous := e.toEntry()
+		e.suspended.Store(suspended)
+		if !suspended {
+			e.draining.Store(false)
+		}
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+}
@@ -0,0 +1,72 @@
+package fibervhosts
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test suspending and resuming a hostname.
+func TestVhostsManager_SuspendResume(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("live")
+	})
+
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.Equal(t, ErrHostNotFound, manager.SuspendHostname("unknown.com"))
+
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+
+	request := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = "example.com"
+		resp, err := mainApp.Test(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.NoError(t, manager.SuspendHostname("example.com"))
+	assert.True(t, manager.IsSuspended("example.com"))
+	assert.Equal(t, fiber.StatusServiceUnavailable, request())
+
+	// The registration is kept while suspended
+	_, exists := manager.GetHostname("example.com")
+	assert.True(t, exists)
+	assert.True(t, manager.ListEntries()[0].Suspended)
+
+	assert.NoError(t, manager.ResumeHostname("example.com"))
+	assert.False(t, manager.IsSuspended("example.com"))
+	assert.Equal(t, fiber.StatusOK, request())
+}
+
+// Test a custom response for suspended hostnames.
+func TestVhostsManager_SuspendedHandler(t *testing.T) {
+	manager := NewVhostsManager(Config{
+		SuspendedHandler: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTeapot).SendString("on a break")
+		},
+	})
+	assert.NoError(t, manager.AddHostname("*.example.com", fiber.New()))
+	assert.NoError(t, manager.SuspendHostname("*.example.com"))
+
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "www.example.com"
+	resp, err := mainApp.Test(req)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, fiber.StatusTeapot, resp.StatusCode)
+	assert.Equal(t, "on a break", string(body))
+}
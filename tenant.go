@@ -0,0 +1,97 @@
+// This file contains the tenant resolution of the middleware, which maps the matched hostname of a request to the tenant it belongs to and hands the tenant to the sub-app, so multi-tenant apps need not wrap the middleware to find it.
+package fibervhosts
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Tenant is the tenant a request belongs to, exposed in c.Locals under LocalsTenantKey, see GetTenant
+type Tenant struct {
+	// ID identifies the tenant, as resolved by the TenantResolver
+	ID string `json:"id"`
+	// Hostname is the requested hostname, lowercase and without a port
+	Hostname string `json:"hostname"`
+	// Pattern is the pattern of the matched registration, like "*.example.com", which is empty for the default app
+	Pattern string `json:"pattern"`
+	// Capture is the part of the hostname matched by the "*" of a wildcard pattern, like "acme" of "acme.example.com" for "*.example.com". It is empty for exact matches.
+	Capture  string   `json:"capture,omitempty"`
+	Metadata Metadata `json:"metadata,omitempty"`
+}
+
+// TenantResolver resolves the tenant of the requests for a hostname. Set it on Config for all hostnames, or on HostConfig to override it for a single registration.
+//
+// The tenant ID is looked up in Tenants by the hostname and then by the wildcard capture, then taken from the metadata of the registration under MetadataKey, and finally asked from Resolve. Requests get their Tenant before the per-vhost policies like Auth run, so they can use it too.
+type TenantResolver struct {
+	// Tenants maps hostnames and wildcard captures to tenant IDs, like "shop.example.com" or "acme" of "*.example.com". It must not be changed once the resolver is in use.
+	Tenants map[string]string
+	// MetadataKey takes the tenant ID from this key of the Metadata of the registration, like "tenant"
+	MetadataKey string
+	// Resolve returns the tenant ID of the requests the other sources have none for, like from a database. The Tenant it gets has no ID yet. An empty ID leaves the request without a tenant; an error fails the request.
+	Resolve func(c *fiber.Ctx, tenant Tenant) (string, error)
+	// Required answers requests without a tenant with 404 Not Found instead of dispatching them
+	Required bool
+	// Disabled turns off tenant resolution, like for a single shared hostname
+	Disabled bool
+}
+
+// GetTenant returns the tenant of a request resolved by the middleware, and false if the request has none
+func GetTenant(c *fiber.Ctx) (Tenant, bool) {
+	tenant, ok := c.Locals(LocalsTenantKey).(Tenant)
+	return tenant, ok
+}
+
+// tenantResolverFor returns the tenant resolver of a registration with config, or nil when its requests have no tenants
+func (m *VhostsManager) tenantResolverFor(config HostConfig) *TenantResolver {
+	p := m.tenants
+	if config.Tenants != nil {
+		p = config.Tenants
+	}
+	if p == nil || p.Disabled {
+		return nil
+	}
+	return p
+}
+
+// resolveTenant resolves the tenant of a request matching r and exposes it in its Locals
+func (m *VhostsManager) resolveTenant(c *fiber.Ctx, r *route) error {
+	p := m.tenantResolverFor(r.config)
+	if p == nil {
+		return nil
+	}
+
+	// The hostname points into the request buffer, which is reused once the request is done
+	tenant := Tenant{
+		Hostname: strings.Clone(normalizeHostname(c.Hostname())),
+		Pattern:  r.info.Pattern,
+		Metadata: r.info.Metadata,
+	}
+	if isWildcard(tenant.Pattern) {
+		tenant.Capture = strings.TrimSuffix(tenant.Hostname, tenant.Pattern[1:])
+	}
+
+	id, ok := p.Tenants[tenant.Hostname]
+	if !ok && tenant.Capture != "" {
+		id, ok = p.Tenants[tenant.Capture]
+	}
+	if !ok && p.MetadataKey != "" {
+		id = tenant.Metadata.Get(p.MetadataKey)
+	}
+	if id == "" && p.Resolve != nil {
+		var err error
+		if id, err = p.Resolve(c, tenant); err != nil {
+			return err
+		}
+	}
+
+	if id == "" {
+		if p.Required {
+			return fiber.ErrNotFound
+		}
+		return nil
+	}
+	tenant.ID = id
+	c.Locals(LocalsTenantKey, tenant)
+	return nil
+}
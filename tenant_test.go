@@ -0,0 +1,76 @@
+package fibervhosts
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test requests get the tenant of their hostname from the lookup table, the metadata or the callback.
+func TestVhostMiddleware_Tenants(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		tenant, ok := GetTenant(c)
+		if !ok {
+			return c.SendString("none")
+		}
+		return c.SendString(tenant.ID + " " + tenant.Hostname + " " + tenant.Pattern + " " + tenant.Capture)
+	})
+
+	var resolved Tenant
+	manager := NewVhostsManager(Config{Tenants: &TenantResolver{
+		Tenants:     map[string]string{"shop.example.com": "t-shop", "acme": "t-acme"},
+		MetadataKey: "tenant",
+		Resolve: func(c *fiber.Ctx, tenant Tenant) (string, error) {
+			resolved = tenant
+			switch tenant.Capture {
+			case "broken":
+				return "", errors.New("tenant database down")
+			case "unknown":
+				return "", nil
+			}
+			return "db-" + tenant.Capture, nil
+		},
+	}})
+	assert.NoError(t, manager.AddHostname("shop.example.com", app))
+	assert.NoError(t, manager.AddHostname("*.example.com", app))
+	assert.NoError(t, manager.AddHostname("legacy.example.org", app))
+	assert.NoError(t, manager.SetMetadata("legacy.example.org", Metadata{Values: map[string]string{"tenant": "t-legacy"}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("strict.example.net", app, HostConfig{Tenants: &TenantResolver{Required: true}}))
+	assert.NoError(t, manager.AddHostnameWithConfig("shared.example.net", app, HostConfig{Tenants: &TenantResolver{Disabled: true}}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	request := func(target string) (int, string) {
+		resp, err := main.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for _, tc := range []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"http://shop.example.com/", fiber.StatusOK, "t-shop shop.example.com shop.example.com "},
+		{"http://ACME.example.com:8080/", fiber.StatusOK, "t-acme acme.example.com *.example.com acme"},
+		{"http://legacy.example.org/", fiber.StatusOK, "t-legacy legacy.example.org legacy.example.org "},
+		{"http://a.b.example.com/", fiber.StatusOK, "db-a.b a.b.example.com *.example.com a.b"},
+		{"http://unknown.example.com/", fiber.StatusOK, "none"},
+		{"http://broken.example.com/", fiber.StatusInternalServerError, ""},
+		{"http://strict.example.net/", fiber.StatusNotFound, ""},
+		{"http://shared.example.net/", fiber.StatusOK, "none"},
+	} {
+		status, body := request(tc.target)
+		assert.Equal(t, tc.status, status, tc.target)
+		if tc.status == fiber.StatusOK {
+			assert.Equal(t, tc.body, body, tc.target)
+		}
+	}
+	assert.Empty(t, resolved.ID)
+}
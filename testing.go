@@ -0,0 +1,29 @@
+// This file contains the helpers to test the registrations of a manager, which run requests through the middleware and the dispatch chain without wiring a main app.
+package fibervhosts
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Test runs req for hostname through VhostMiddleware and the dispatch chain of the matching registration, like fiber.App.Test for a main app mounting the manager, and returns the response. An empty hostname keeps the host of req. There is no timeout, so streamed responses can be read as they arrive.
+func (m *VhostsManager) Test(hostname string, req *http.Request) (*http.Response, error) {
+	m.testOnce.Do(func() {
+		m.testApp = fiber.New(fiber.Config{DisableStartupMessage: true})
+		m.testApp.Use(VhostMiddleware(m))
+	})
+	if hostname != "" {
+		req.Host = hostname
+	}
+	return m.testApp.Test(req, -1)
+}
+
+// NewTestRequest returns a request for path of hostname to be passed to Test, like httptest.NewRequest
+func NewTestRequest(method, hostname, path string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, path, body)
+	req.Host = hostname
+	return req
+}
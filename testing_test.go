@@ -0,0 +1,32 @@
+package fibervhosts
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test requests run through the middleware and dispatch chain of the hostname they are tested for.
+func TestVhostsManager_Test(t *testing.T) {
+	app := fiber.New()
+	app.Post("/echo", func(c *fiber.Ctx) error {
+		return c.SendString(c.Hostname() + " " + string(c.Body()))
+	})
+
+	manager := NewVhostsManager(Config{RequestID: &RequestID{}})
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+
+	resp, err := manager.Test("", NewTestRequest("POST", "www.example.com", "/echo", strings.NewReader("hello")))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "www.example.com hello", string(body))
+	assert.NotEmpty(t, resp.Header.Get("X-Request-ID"))
+
+	resp, err = manager.Test("other.example.com", NewTestRequest("POST", "www.example.com", "/echo", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,77 @@
+// This file contains the per-vhost request timeout, which stops waiting for a sub-app that does not answer in time so a slow tenant cannot tie up the workers of the main app.
+package fibervhosts
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// timeoutFor returns the request timeout of a registration with config; requests do not time out when it is not positive
+func (m *VhostsManager) timeoutFor(config HostConfig) time.Duration {
+	if config.RequestTimeout != 0 {
+		return config.RequestTimeout
+	}
+	return m.requestTimeout
+}
+
+// dispatchWithTimeout dispatches requests to b in a goroutine of their own and answers with 504 Gateway Timeout when b does not finish within timeout. The context of the request, see fiber.Ctx.UserContext, is canceled at the timeout, so the sub-app can stop its work.
+//
+// The goroutine serves a private copy of the request, which keeps running until b returns: its response is copied back only when b finishes in time, so the late response of the sub-app never races with the timeout response, which goes through the error handlers like any other error. The timeout ends when b returns, so a streamed body, like a gRPC-Web stream, is served without it; it keeps the context, which is otherwise canceled when b returns. Upgrade requests like WebSocket handshakes are dispatched without the timeout, as the connection they hijack cannot be handed over from the private copy.
+func (m *VhostsManager) dispatchWithTimeout(b backend, timeout time.Duration) dispatchFunc {
+	// The private request contexts log through the Logger of the manager
+	var logger fasthttp.Logger = fasthttpLogger{m.logger}
+	return func(c *fiber.Ctx) error {
+		fctx := c.Context()
+		if fctx.Request.Header.ConnectionUpgrade() {
+			b.requestHandler()(fctx)
+			return nil
+		}
+		ctx, cancel := context.WithCancel(c.UserContext())
+		c.SetUserContext(ctx)
+		streaming := false
+		defer func() {
+			if !streaming {
+				cancel()
+			}
+		}()
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		// The goroutine only uses its copy, as fctx is answered and reused by the server once the main app is done with it
+		sub := new(fasthttp.RequestCtx)
+		sub.Init2(fctx.Conn(), logger, true)
+		fctx.Request.CopyTo(&sub.Request)
+		fctx.VisitUserValuesAll(func(key, value any) {
+			sub.SetUserValue(key, value)
+		})
+		done := make(chan any, 1)
+		go func() {
+			defer func() {
+				done <- recover()
+			}()
+			b.requestHandler()(sub)
+		}()
+
+		select {
+		case p := <-done:
+			// Panics are passed on to the panic recovery of the dispatch chain
+			if p != nil {
+				panic(p)
+			}
+			sub.Response.CopyTo(&fctx.Response)
+			if sub.Response.IsBodyStream() {
+				fctx.Response.SetBodyStream(sub.Response.BodyStream(), sub.Response.Header.ContentLength())
+				streaming = true
+			}
+			return nil
+		case <-timer.C:
+			cancel()
+			m.logger.Warn("Request timed out", "hostname", strings.Clone(c.Hostname()), "timeout", timeout)
+			return fiber.ErrGatewayTimeout
+		}
+	}
+}
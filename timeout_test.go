@@ -0,0 +1,137 @@
+package fibervhosts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// Test requests taking longer than the timeout of their hostname are answered with 504, and their context is canceled.
+func TestVhostMiddleware_RequestTimeout(t *testing.T) {
+	canceled := make(chan struct{})
+	slow := fiber.New()
+	slow.Get("/", func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		close(canceled)
+		return nil
+	})
+	fast := fiber.New()
+	fast.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("fast")
+	})
+
+	manager := NewVhostsManager(Config{RequestTimeout: 20 * time.Millisecond})
+	assert.NoError(t, manager.AddHostname("slow.example.com", slow))
+	assert.NoError(t, manager.AddHostname("fast.example.com", fast))
+	assert.NoError(t, manager.AddHostnameWithConfig("untimed.example.com", fast, HostConfig{RequestTimeout: -1}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	start := time.Now()
+	resp, err := main.Test(httptest.NewRequest("GET", "http://slow.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+	assert.Less(t, time.Since(start), time.Second)
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("context of the timed out request was not canceled")
+	}
+
+	for _, hostname := range []string{"fast.example.com", "untimed.example.com"} {
+		resp, err = main.Test(httptest.NewRequest("GET", "http://"+hostname+"/", nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "fast", string(body))
+	}
+
+	assert.Equal(t, uint64(1), manager.Stats()[1].Errors)
+}
+
+// Test panics of a sub-app with a timeout are recovered like without one.
+func TestVhostMiddleware_RequestTimeoutPanic(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		panic("test panic")
+	})
+	manager := NewVhostsManager(Config{RecoverFromPanic: true})
+	assert.NoError(t, manager.AddHostnameWithConfig("www.example.com", app, HostConfig{RequestTimeout: time.Second}))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	resp, err := main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+}
+
+// Test streamed bodies are sent in full after the timeout, with the context of the request still alive.
+func TestVhostMiddleware_RequestTimeoutStream(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			fmt.Fprint(w, "first ")
+			_ = w.Flush()
+			time.Sleep(50 * time.Millisecond)
+			fmt.Fprintf(w, "second err=%v", ctx.Err())
+		})
+		return nil
+	})
+	manager := NewVhostsManager(Config{RequestTimeout: 20 * time.Millisecond})
+	assert.NoError(t, manager.AddHostname("www.example.com", app))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	resp, err := main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil), -1)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "first second err=<nil>", string(body))
+}
+
+// Test sub-apps writing their response after the timeout do not touch the 504 response of the request.
+func TestVhostMiddleware_RequestTimeoutLateResponse(t *testing.T) {
+	written := make(chan struct{})
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		defer close(written)
+		time.Sleep(30 * time.Millisecond)
+		return c.SendString("late")
+	})
+	manager := NewVhostsManager(Config{AccessLog: &AccessLog{Output: io.Discard}})
+	assert.NoError(t, manager.AddHostnameWithConfig("www.example.com", app, HostConfig{RequestTimeout: 10 * time.Millisecond, HSTS: &HSTS{MaxAge: time.Hour}}))
+
+	resp, err := manager.Test("www.example.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+	assert.NotEqual(t, "late", string(body))
+	<-written
+}
+
+// Test sub-apps served under a timeout log through the Logger of the manager.
+func TestVhostMiddleware_RequestTimeoutLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	manager := NewVhostsManager(Config{Logger: logger, RequestTimeout: time.Second, AccessLog: &AccessLog{Disabled: true}})
+	assert.NoError(t, manager.AddRequestHandler("www.example.com", func(ctx *fasthttp.RequestCtx) {
+		ctx.Logger().Printf("serving %s", "late")
+	}))
+
+	_, err := manager.Test("www.example.com", NewTestRequest("GET", "", "/", nil))
+	assert.NoError(t, err)
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if assert.Len(t, logger.records, 1) {
+		assert.Equal(t, "error", logger.records[0].level)
+		assert.Contains(t, logger.records[0].msg, "serving late")
+	}
+}
@@ -0,0 +1,65 @@
+// This file contains expiring vhost registrations, used for short-lived domains like preview or review apps.
+package fibervhosts
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AddHostnameWithTTL adds a sub-app for a given hostname that is removed automatically once the ttl has elapsed. The optional onExpire callbacks are invoked with the hostname after the entry has been removed. Renaming or updating the hostname keeps the expiry; removing it cancels the expiry.
+func (m *VhostsManager) AddHostnameWithTTL(hostname string, app *fiber.App, ttl time.Duration, onExpire ...func(hostname string)) error {
+	if hostname == "" {
+		return ErrInvalidHostname
+	}
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		if _, exists := table[key]; exists {
+			return nil, ErrHostExists
+		}
+
+		e := newEntry(hostname, app)
+		e.expiresAt = time.Now().Add(ttl)
+		e.expiry = time.AfterFunc(ttl, func() {
+			m.expire(e, onExpire)
+		})
+		table[key] = e
+		return []ChangeEvent{added(e)}, nil
+	})
+}
+
+// expire removes the entry if it is still registered and invokes the expiry callbacks
+func (m *VhostsManager) expire(e *entry, onExpire []func(hostname string)) {
+	var hostname string
+	_ = m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(e.pattern)
+		if table[key] != e {
+			// The entry was removed or replaced in the meantime
+			return nil, nil
+		}
+		delete(table, key)
+		hostname = e.pattern
+		return []ChangeEvent{removed(e)}, nil
+	})
+	if hostname == "" {
+		return
+	}
+
+	if m.enableLog {
+		m.logger.Info("Registration expired", "hostname", hostname)
+	}
+	for _, fn := range onExpire {
+		fn(hostname)
+	}
+}
+
+// stopExpiry cancels the pending expiry of the entry, if any
+func (e *entry) stopExpiry() {
+	if e.expiry != nil {
+		e.expiry.Stop()
+	}
+}
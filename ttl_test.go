@@ -0,0 +1,60 @@
+package fibervhosts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test an entry added with a TTL is removed once it expires.
+func TestVhostsManager_AddHostnameWithTTL(t *testing.T) {
+	manager := NewVhostsManager()
+	app := fiber.New()
+
+	assert.Equal(t, ErrInvalidTTL, manager.AddHostnameWithTTL("preview.example.com", app, 0))
+
+	expired := make(chan string, 1)
+	err := manager.AddHostnameWithTTL("preview.example.com", app, 20*time.Millisecond, func(hostname string) {
+		expired <- hostname
+	})
+	assert.NoError(t, err)
+
+	_, exists := manager.GetHostname("preview.example.com")
+	assert.True(t, exists)
+	assert.False(t, manager.ListEntries()[0].ExpiresAt.IsZero())
+
+	select {
+	case hostname := <-expired:
+		assert.Equal(t, "preview.example.com", hostname)
+	case <-time.After(time.Second):
+		t.Fatal("entry did not expire")
+	}
+
+	_, exists = manager.GetHostname("preview.example.com")
+	assert.False(t, exists)
+}
+
+// Test removing an entry cancels its expiry and a re-added entry is kept.
+func TestVhostsManager_AddHostnameWithTTL_Removed(t *testing.T) {
+	manager := NewVhostsManager()
+	app := fiber.New()
+
+	called := make(chan string, 1)
+	err := manager.AddHostnameWithTTL("preview.example.com", app, 20*time.Millisecond, func(hostname string) {
+		called <- hostname
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, manager.RemoveHostname("preview.example.com"))
+	assert.NoError(t, manager.AddHostname("preview.example.com", app))
+
+	select {
+	case <-called:
+		t.Fatal("expiry callback fired for a removed entry")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_, exists := manager.GetHostname("preview.example.com")
+	assert.True(t, exists)
+}
@@ -0,0 +1,120 @@
+// This file contains bulk update transactions, which stage many changes to the vhost table and commit them at once, so requests never observe a partially applied sync.
+package fibervhosts
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrTxDone is returned by Tx.Commit for transactions that were already committed or rolled back
+var ErrTxDone = errors.New("transaction already committed or rolled back")
+
+// Tx stages changes to the vhost table until Commit, see Begin. A Tx is not safe for concurrent use.
+type Tx struct {
+	m    *VhostsManager
+	ops  []txOp
+	done bool
+}
+
+// txOp is a staged change
+type txOp struct {
+	kind     ChangeType
+	hostname string
+	app      *fiber.App
+}
+
+// Begin starts a transaction. Its changes are only validated and applied by Commit, in one step under the write lock: either all of them become visible to requests at once, or none do.
+func (m *VhostsManager) Begin() *Tx {
+	return &Tx{m: m}
+}
+
+// AddHostname stages adding a sub-app for a hostname or wildcard pattern
+func (tx *Tx) AddHostname(hostname string, app *fiber.App) {
+	tx.ops = append(tx.ops, txOp{ChangeAdded, hostname, app})
+}
+
+// UpdateHostname stages replacing the sub-app of a registered hostname or wildcard pattern
+func (tx *Tx) UpdateHostname(hostname string, app *fiber.App) {
+	tx.ops = append(tx.ops, txOp{ChangeUpdated, hostname, app})
+}
+
+// RemoveHostname stages removing a registered hostname or wildcard pattern
+func (tx *Tx) RemoveHostname(hostname string) {
+	tx.ops = append(tx.ops, txOp{kind: ChangeRemoved, hostname: hostname})
+}
+
+// Len returns the number of staged changes
+func (tx *Tx) Len() int {
+	return len(tx.ops)
+}
+
+// Commit applies the staged changes in order. They are validated first, taking the earlier changes of the transaction into account; if any of them fails, like adding a hostname that exists, the error is returned and nothing is applied. Hooks and watchers are notified of every change once the transaction is applied.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	m := tx.m
+	return m.update(func() ([]ChangeEvent, error) {
+		if err := tx.validate(); err != nil {
+			return nil, err
+		}
+
+		changes := make([]ChangeEvent, 0, len(tx.ops))
+		for _, op := range tx.ops {
+			table, key := m.tableFor(op.hostname)
+			switch op.kind {
+			case ChangeAdded:
+				e := newEntry(op.hostname, op.app)
+				table[key] = e
+				changes = append(changes, added(e))
+			case ChangeUpdated:
+				e := table[key]
+				previous := e.toEntry()
+				e.app, e.handler, e.factory = op.app, nil, nil
+				changes = append(changes, updated(previous, e))
+			case ChangeRemoved:
+				e := table[key]
+				delete(table, key)
+				e.stopExpiry()
+				changes = append(changes, removed(e))
+			}
+		}
+		return changes, nil
+	})
+}
+
+// Rollback discards the staged changes
+func (tx *Tx) Rollback() {
+	tx.ops, tx.done = nil, true
+}
+
+// validate checks the staged changes against the table as the earlier changes leave it. The caller must hold the write lock.
+func (tx *Tx) validate() error {
+	// registered overrides the table for the hostnames changed so far
+	registered := make(map[string]bool)
+	exists := func(hostname string) bool {
+		if ok, changed := registered[hostname]; changed {
+			return ok
+		}
+		table, key := tx.m.tableFor(hostname)
+		_, ok := table[key]
+		return ok
+	}
+
+	for _, op := range tx.ops {
+		switch {
+		case op.hostname == "" || op.hostname == "*.":
+			return ErrInvalidHostname
+		case op.kind == ChangeAdded && exists(op.hostname):
+			return fmt.Errorf("%w: %s", ErrHostExists, op.hostname)
+		case op.kind != ChangeAdded && !exists(op.hostname):
+			return fmt.Errorf("%w: %s", ErrHostNotFound, op.hostname)
+		}
+		registered[op.hostname] = op.kind != ChangeRemoved
+	}
+	return nil
+}
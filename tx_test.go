@@ -0,0 +1,57 @@
+package fibervhosts
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test a transaction applies all staged changes at once, or none of them when one fails.
+func TestVhostsManager_Begin(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("old.example.com", fiber.New()))
+	assert.NoError(t, manager.AddHostname("keep.example.com", fiber.New()))
+	version := manager.Version()
+	table := manager.lookup.Load()
+
+	// A failing change discards the whole transaction
+	tx := manager.Begin()
+	tx.AddHostname("new.example.com", fiber.New())
+	tx.AddHostname("keep.example.com", fiber.New())
+	assert.ErrorIs(t, tx.Commit(), ErrHostExists)
+	assert.ErrorIs(t, tx.Commit(), ErrTxDone)
+	_, exists := manager.GetHostname("new.example.com")
+	assert.False(t, exists)
+	assert.Equal(t, version, manager.Version())
+	assert.Same(t, table, manager.lookup.Load())
+
+	// Later changes see the earlier ones
+	updated := fiber.New()
+	tx = manager.Begin()
+	tx.AddHostname("new.example.com", fiber.New())
+	tx.UpdateHostname("new.example.com", updated)
+	tx.RemoveHostname("old.example.com")
+	tx.AddHostname("*.example.org", fiber.New())
+	assert.Equal(t, 4, tx.Len())
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, version+1, manager.Version())
+
+	app, exists := manager.GetHostname("new.example.com")
+	assert.True(t, exists)
+	assert.Same(t, updated, app)
+	_, exists = manager.GetHostname("old.example.com")
+	assert.False(t, exists)
+	assert.Len(t, manager.ListEntries(), 3)
+
+	tx = manager.Begin()
+	tx.RemoveHostname("old.example.com")
+	assert.ErrorIs(t, tx.Commit(), ErrHostNotFound)
+
+	tx = manager.Begin()
+	tx.RemoveHostname("keep.example.com")
+	tx.Rollback()
+	assert.ErrorIs(t, tx.Commit(), ErrTxDone)
+	_, exists = manager.GetHostname("keep.example.com")
+	assert.True(t, exists)
+}
@@ -0,0 +1,100 @@
+// This file contains the passthrough of protocol upgrades like WebSocket. Sub-apps upgrade connections themselves, as they run on the fasthttp request of the main app and can hijack its connection; proxy hosts tunnel the upgraded connection to their upstream.
+package fibervhosts
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// isUpgrade reports whether the request asks to upgrade the connection to another protocol, like a WebSocket handshake
+func isUpgrade(c *fiber.Ctx) bool {
+	if len(c.Request().Header.Peek(fiber.HeaderUpgrade)) == 0 {
+		return false
+	}
+	for _, token := range strings.Split(c.Get(fiber.HeaderConnection), ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// tunnelUpgrade sends the upgrade request req to a connection of its own to up, whose request in flight it finishes. When the upstream switches protocols, its response is sent to the client and the connection of the client is hijacked and tunneled to the upstream until either side closes it. Other responses, like a rejected handshake, are sent as they are.
+func (m *VhostsManager) tunnelUpgrade(c *fiber.Ctx, req *fasthttp.Request, up *upstream, timeout time.Duration) error {
+	req.Header.Set(fiber.HeaderConnection, "Upgrade")
+	req.Header.Set(fiber.HeaderUpgrade, c.Get(fiber.HeaderUpgrade))
+
+	conn, err := up.dial()
+	if err != nil {
+		up.active.Add(-1)
+		m.logger.Warn("Proxying upgrade failed", "hostname", strings.Clone(c.Hostname()), "upstream", up.url, "error", err)
+		return fiber.ErrBadGateway
+	}
+	br := bufio.NewReader(conn)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	if err := handshake(conn, br, req, resp, timeout); err != nil {
+		conn.Close()
+		up.active.Add(-1)
+		m.logger.Warn("Proxying upgrade failed", "hostname", strings.Clone(c.Hostname()), "upstream", up.url, "error", err)
+		return fiber.ErrBadGateway
+	}
+
+	resp.Header.CopyTo(&c.Response().Header)
+	if resp.StatusCode() != fiber.StatusSwitchingProtocols {
+		conn.Close()
+		up.active.Add(-1)
+		for _, header := range hopHeaders {
+			c.Response().Header.Del(header)
+		}
+		c.Response().SetBody(resp.Body())
+		return nil
+	}
+
+	c.Context().Hijack(func(client net.Conn) {
+		defer up.active.Add(-1)
+		defer conn.Close()
+		done := make(chan struct{}, 2)
+		go func() {
+			_, _ = io.Copy(conn, client)
+			done <- struct{}{}
+		}()
+		go func() {
+			// The reader holds what the upstream sent right after its response
+			_, _ = io.Copy(client, br)
+			done <- struct{}{}
+		}()
+		<-done
+		// Unblock the other direction; the server closes the connection of the client once both are done
+		conn.Close()
+		_ = client.SetDeadline(time.Now())
+		<-done
+	})
+	return nil
+}
+
+// handshake writes req to conn and reads the response of the upstream into resp, within timeout
+func handshake(conn net.Conn, br *bufio.Reader, req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	// Parsing the URI makes Write send the path in the request line instead of the absolute URI, as fasthttp.HostClient does
+	req.URI()
+	bw := bufio.NewWriter(conn)
+	if err := req.Write(bw); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if err := resp.Read(br); err != nil {
+		return err
+	}
+	return conn.SetDeadline(time.Time{})
+}
@@ -0,0 +1,108 @@
+package fibervhosts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoUpgrade switches protocols with the header of the response and echoes everything sent on the connection afterwards
+func echoUpgrade(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderUpgrade) != "echo" {
+		return fiber.ErrUpgradeRequired
+	}
+	c.Set(fiber.HeaderUpgrade, "echo")
+	c.Set(fiber.HeaderConnection, "Upgrade")
+	c.Set("X-Host", c.Hostname())
+	c.Status(fiber.StatusSwitchingProtocols)
+	c.Context().Hijack(func(conn net.Conn) {
+		_, _ = io.Copy(conn, conn)
+	})
+	return nil
+}
+
+// Test upgrade requests are handed to the matched sub-app, which hijacks the connection, and tunneled to the upstream of proxy hosts.
+func TestVhostMiddleware_Upgrade(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" || r.Header.Get("Connection") != "Upgrade" {
+			http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// The first message is sent together with the response
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\nX-Host: %s\r\n\r\nhello ", r.Host)
+		_ = rw.Flush()
+		_, _ = io.Copy(conn, rw)
+	}))
+	defer upstream.Close()
+
+	sub := fiber.New()
+	sub.Get("/ws", echoUpgrade)
+
+	manager := NewVhostsManager(Config{Logger: &recordingLogger{}})
+	assert.NoError(t, manager.AddHostname("ws.example.com", sub))
+	assert.NoError(t, manager.AddHostnameWithConfig("timeout.example.com", sub, HostConfig{RequestTimeout: time.Second, Compression: &Compression{}}))
+	assert.NoError(t, manager.AddProxyHost("proxy.example.com", upstream.URL, ProxyConfig{PreserveHost: true}))
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(VhostMiddleware(manager))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	upgrade := func(host, protocol string) (*http.Response, net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		assert.NoError(t, err)
+		fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: %s\r\nConnection: keep-alive, Upgrade\r\nUpgrade: %s\r\n\r\n", host, protocol)
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		assert.NoError(t, err)
+		return resp, conn, br
+	}
+	echo := func(conn net.Conn, br *bufio.Reader, message string) string {
+		_, err := conn.Write([]byte(message))
+		assert.NoError(t, err)
+		buf := make([]byte, len(message))
+		_, err = io.ReadFull(br, buf)
+		assert.NoError(t, err)
+		return string(buf)
+	}
+
+	for _, host := range []string{"ws.example.com", "timeout.example.com"} {
+		resp, conn, br := upgrade(host, "echo")
+		assert.Equal(t, fiber.StatusSwitchingProtocols, resp.StatusCode)
+		assert.Equal(t, host, resp.Header.Get("X-Host"))
+		assert.Equal(t, "ping", echo(conn, br, "ping"))
+		assert.Equal(t, "pong", echo(conn, br, "pong"))
+		conn.Close()
+	}
+
+	resp, conn, br := upgrade("proxy.example.com", "echo")
+	assert.Equal(t, fiber.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "echo", resp.Header.Get("Upgrade"))
+	assert.Equal(t, "proxy.example.com", resp.Header.Get("X-Host"))
+	hello := make([]byte, len("hello "))
+	_, err = io.ReadFull(br, hello)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello ", string(hello))
+	assert.Equal(t, "ping", echo(conn, br, "ping"))
+	conn.Close()
+
+	resp, conn, _ = upgrade("proxy.example.com", "other")
+	assert.Equal(t, fiber.StatusUpgradeRequired, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Upgrade"))
+	conn.Close()
+}
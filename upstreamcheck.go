@@ -0,0 +1,210 @@
+// This file contains the active health checks of proxy upstreams, which take failing upstreams out of the rotation of their pool and put them back once they recover.
+package fibervhosts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// UpstreamCheck defines the health checks of the upstreams of a proxy host, see ProxyConfig.HealthCheck
+type UpstreamCheck struct {
+	// Path is requested on every upstream, below the path of its URL. A response below 400 means healthy. Defaults to "/".
+	Path string
+	// Timeout bounds every single probe. Defaults to 5 seconds.
+	Timeout time.Duration
+	// Failures is the number of failed probes in a row taking an upstream out of rotation. Defaults to 3.
+	Failures int
+	// Successes is the number of successful probes in a row putting an upstream back into rotation. Defaults to 2.
+	Successes int
+}
+
+// UpstreamHealth is the health of an upstream of a proxy host, see Upstreams
+type UpstreamHealth struct {
+	Type    EntryType `json:"type"`
+	Pattern string    `json:"pattern"`
+	URL     string    `json:"url"`
+	Healthy bool      `json:"healthy"`
+	// Active is the number of requests in flight to the upstream
+	Active int64 `json:"active"`
+	// Changes counts how often the upstream went out of or back into rotation, so flapping upstreams stand out
+	Changes   uint64    `json:"changes"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// upstreamHealth is the health check state of an upstream
+type upstreamHealth struct {
+	mu sync.Mutex
+	// streak counts the probes in a row disagreeing with the current state of the upstream
+	streak    int
+	changes   uint64
+	err       string
+	checkedAt time.Time
+}
+
+// proxyTarget is a proxy host to check, captured under the lock
+type proxyTarget struct {
+	entry Entry
+	pool  *proxyPool
+}
+
+// proxyTargets returns the registered proxy hosts, optionally only those with health checks. The caller must not hold the lock.
+func (m *VhostsManager) proxyTargets(checked bool) []proxyTarget {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var targets []proxyTarget
+	collect := func(e *entry) {
+		if e.pool == nil || e.pool.app != e.app || (checked && e.pool.check == nil) {
+			return
+		}
+		targets = append(targets, proxyTarget{e.toEntry(), e.pool})
+	}
+	for _, e := range m.hosts {
+		collect(e)
+	}
+	for _, e := range m.wildcards {
+		collect(e)
+	}
+	return targets
+}
+
+// CheckUpstreams probes the upstreams of all proxy hosts with a ProxyConfig.HealthCheck once, concurrently. Upstreams crossing the thresholds of their check go out of or back into rotation, which is logged and reported to the OnUpstreamHealth hooks.
+func (m *VhostsManager) CheckUpstreams(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, target := range m.proxyTargets(true) {
+		for _, up := range target.pool.upstreams {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ctx.Err() != nil {
+					return
+				}
+				if changed := up.record(target.pool.check, up.probe(target.pool.check)); changed {
+					m.upstreamChanged(target, up)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// RunUpstreamChecks calls CheckUpstreams every interval until ctx is done
+func (m *VhostsManager) RunUpstreamChecks(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		m.CheckUpstreams(ctx)
+	}
+}
+
+// Upstreams returns the health of the upstreams of all proxy hosts, in the same order as ListEntries and in the order of their pools
+func (m *VhostsManager) Upstreams() []UpstreamHealth {
+	var upstreams []UpstreamHealth
+	for _, target := range m.proxyTargets(false) {
+		for _, up := range target.pool.upstreams {
+			upstreams = append(upstreams, up.report(target.entry))
+		}
+	}
+	sortByEntry(upstreams, func(u UpstreamHealth) (EntryType, string) { return u.Type, u.Pattern })
+	return upstreams
+}
+
+// upstreamChanged logs an upstream going out of or back into rotation and runs the OnUpstreamHealth hooks
+func (m *VhostsManager) upstreamChanged(target proxyTarget, up *upstream) {
+	health := up.report(target.entry)
+	if health.Healthy {
+		m.logger.Info("Upstream back in rotation", "hostname", health.Pattern, "upstream", health.URL, "changes", health.Changes)
+	} else {
+		m.logger.Warn("Upstream out of rotation", "hostname", health.Pattern, "upstream", health.URL, "changes", health.Changes, "error", health.Error)
+	}
+	m.hooks.executeOnUpstreamHealth(target.entry, health)
+}
+
+// probe requests the health check path of the upstream and returns why it is unhealthy, or nil
+func (up *upstream) probe(check *UpstreamCheck) error {
+	path, timeout := check.Path, check.Timeout
+	if path == "" {
+		path = "/"
+	}
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(up.origin + up.base + "/" + strings.TrimPrefix(path, "/"))
+	req.Header.SetHost(up.host)
+	if err := up.client.DoTimeout(req, resp, timeout); err != nil {
+		return err
+	}
+	defer resp.CloseBodyStream()
+	if status := resp.StatusCode(); status >= fasthttp.StatusBadRequest {
+		return fmt.Errorf("status %d", status)
+	}
+	return nil
+}
+
+// record applies the result of a probe and reports whether the upstream went out of or back into rotation
+func (up *upstream) record(check *UpstreamCheck, err error) bool {
+	failures, successes := check.Failures, check.Successes
+	if failures <= 0 {
+		failures = 3
+	}
+	if successes <= 0 {
+		successes = 2
+	}
+
+	h := &up.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkedAt = time.Now()
+	h.err = ""
+	if err != nil {
+		h.err = err.Error()
+	}
+
+	down := up.down.Load()
+	if (err != nil) != down {
+		h.streak++
+	} else {
+		h.streak = 0
+	}
+	if (down && h.streak < successes) || (!down && h.streak < failures) {
+		return false
+	}
+	h.streak = 0
+	h.changes++
+	up.down.Store(!down)
+	return true
+}
+
+// report returns the health of the upstream of a proxy host
+func (up *upstream) report(e Entry) UpstreamHealth {
+	h := &up.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return UpstreamHealth{
+		Type:      e.Type,
+		Pattern:   e.Pattern,
+		URL:       up.url,
+		Healthy:   !up.down.Load(),
+		Active:    up.active.Load(),
+		Changes:   h.changes,
+		Error:     h.err,
+		CheckedAt: h.checkedAt,
+	}
+}
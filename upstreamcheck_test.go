@@ -0,0 +1,91 @@
+package fibervhosts
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test failing upstreams go out of rotation after the failure threshold and come back once they recover, with hooks on every change.
+func TestVhostsManager_CheckUpstreams(t *testing.T) {
+	var failing atomic.Bool
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "a")
+	}))
+	defer healthy.Close()
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/health" && failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "b")
+	}))
+	defer flaky.Close()
+
+	logger := &recordingLogger{}
+	manager := NewVhostsManager(Config{Logger: logger})
+	var changes []UpstreamHealth
+	manager.Hooks().OnUpstreamHealth(func(e Entry, upstream UpstreamHealth) {
+		assert.Equal(t, "www.example.com", e.Pattern)
+		changes = append(changes, upstream)
+	})
+	check := &UpstreamCheck{Path: "health", Failures: 2, Successes: 1}
+	assert.NoError(t, manager.AddProxyPool("www.example.com", []string{healthy.URL + "/v1", flaky.URL + "/v1"}, ProxyConfig{HealthCheck: check}))
+	assert.NoError(t, manager.AddProxyHost("unchecked.example.com", healthy.URL))
+
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+	served := func(n int) string {
+		var s string
+		for i := 0; i < n; i++ {
+			resp, err := main.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+			assert.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			s += string(body)
+		}
+		return s
+	}
+
+	failing.Store(true)
+	manager.CheckUpstreams(context.Background())
+	assert.Empty(t, changes)
+	assert.Equal(t, "abab", served(4))
+
+	manager.CheckUpstreams(context.Background())
+	if assert.Len(t, changes, 1) {
+		assert.False(t, changes[0].Healthy)
+		assert.Equal(t, flaky.URL+"/v1", changes[0].URL)
+		assert.Equal(t, "status 503", changes[0].Error)
+	}
+	assert.Equal(t, "aaaa", served(4))
+	if assert.Len(t, logger.records, 1) {
+		assert.Equal(t, "Upstream out of rotation", logger.records[0].msg)
+	}
+
+	upstreams := manager.Upstreams()
+	if assert.Len(t, upstreams, 3) {
+		assert.Equal(t, "unchecked.example.com", upstreams[0].Pattern)
+		assert.True(t, upstreams[1].Healthy)
+		assert.False(t, upstreams[2].Healthy)
+		assert.Equal(t, uint64(1), upstreams[2].Changes)
+	}
+
+	failing.Store(false)
+	manager.CheckUpstreams(context.Background())
+	if assert.Len(t, changes, 2) {
+		assert.True(t, changes[1].Healthy)
+		assert.Equal(t, uint64(2), changes[1].Changes)
+	}
+	assert.Equal(t, "abab", served(4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, manager.RunUpstreamChecks(ctx, time.Millisecond), context.Canceled)
+}
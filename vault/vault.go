@@ -0,0 +1,255 @@
+// Package vault provides a fibervhosts.CertificateSource reading per-host certificates from HashiCorp Vault, so keys live in the secrets manager instead of on disk.
+//
+// In KV mode the certificate of a hostname is read from the KV v2 secret "<prefix><hostname>", holding the PEM encoded chain and key in the fields "certificate" and "private_key". In PKI mode a fresh certificate is issued for the hostname by a role of the PKI secrets engine. Attach the source to a registration with VhostsManager.SetCertificateSource; the certificate is fetched again once its lease expires, see VhostsManager.WatchCertificates.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+)
+
+// Mode selects the Vault secrets engine certificates are read from
+type Mode int
+
+const (
+	// ModeKV reads stored certificates from a KV v2 secrets engine
+	ModeKV Mode = iota
+	// ModePKI issues certificates with a role of the PKI secrets engine
+	ModePKI
+)
+
+// Config defines the config for the Vault certificate source
+type Config struct {
+	// Address is the base URL of the Vault server. Defaults to $VAULT_ADDR, or "http://127.0.0.1:8200".
+	Address string
+	// Token authenticates the requests. Defaults to $VAULT_TOKEN.
+	Token string
+	// Namespace is the Vault Enterprise namespace. Optional.
+	Namespace string
+	// Mode selects the secrets engine. Defaults to ModeKV.
+	Mode Mode
+	// Mount is the mount path of the secrets engine. Defaults to "secret" in KV mode and "pki" in PKI mode.
+	Mount string
+	// Prefix is prepended to the hostname to get the secret path in KV mode. Defaults to "vhosts/".
+	Prefix string
+	// CertificateField and KeyField name the fields of a KV secret holding the PEM encoded chain and key. Default to "certificate" and "private_key".
+	CertificateField string
+	KeyField         string
+	// Role is the PKI role issuing the certificates. Required in PKI mode.
+	Role string
+	// TTL is the requested validity of issued certificates in PKI mode. Defaults to the TTL of the role.
+	TTL time.Duration
+	// CacheTTL is how long a KV certificate is cached when Vault reports no lease, which is always the case for KV v2. Defaults to 1 hour.
+	CacheTTL time.Duration
+	// Client is used for all requests. Defaults to a client with a 30 second timeout.
+	Client *http.Client
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Address:          "http://127.0.0.1:8200",
+	Prefix:           "vhosts/",
+	CertificateField: "certificate",
+	KeyField:         "private_key",
+	CacheTTL:         time.Hour,
+}
+
+// renewAfter is the part of the validity of an issued certificate after which it is issued again
+const renewAfter = 2.0 / 3
+
+// Source fetches certificates from Vault
+type Source struct {
+	config Config
+}
+
+var _ fibervhosts.CertificateSource = (*Source)(nil)
+
+// New creates a new Vault certificate source
+func New(config Config) (*Source, error) {
+	if config.Address == "" {
+		config.Address = os.Getenv("VAULT_ADDR")
+	}
+	if config.Address == "" {
+		config.Address = ConfigDefault.Address
+	}
+	if config.Token == "" {
+		config.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if config.Mount == "" {
+		config.Mount = "secret"
+		if config.Mode == ModePKI {
+			config.Mount = "pki"
+		}
+	}
+	if config.Prefix == "" {
+		config.Prefix = ConfigDefault.Prefix
+	}
+	if config.CertificateField == "" {
+		config.CertificateField = ConfigDefault.CertificateField
+	}
+	if config.KeyField == "" {
+		config.KeyField = ConfigDefault.KeyField
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = ConfigDefault.CacheTTL
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if config.Mode == ModePKI && config.Role == "" {
+		return nil, errors.New("vault: a PKI role is required")
+	}
+	config.Address = strings.TrimRight(config.Address, "/")
+	config.Mount = strings.Trim(config.Mount, "/")
+
+	return &Source{config: config}, nil
+}
+
+// FetchCertificate reads or issues the certificate of a hostname and returns how long it may be cached
+func (s *Source) FetchCertificate(ctx context.Context, hostname string) (*tls.Certificate, time.Duration, error) {
+	if s.config.Mode == ModePKI {
+		return s.issue(ctx, hostname)
+	}
+	return s.read(ctx, hostname)
+}
+
+// read reads the certificate of hostname from the KV secrets engine
+func (s *Source) read(ctx context.Context, hostname string) (*tls.Certificate, time.Duration, error) {
+	path := "/v1/" + s.config.Mount + "/data/" + escapePath(s.config.Prefix+hostname)
+	result, err := s.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var data struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		return nil, 0, fmt.Errorf("vault: decode %s: %w", path, err)
+	}
+	certPEM, _ := data.Data[s.config.CertificateField].(string)
+	keyPEM, _ := data.Data[s.config.KeyField].(string)
+	if certPEM == "" || keyPEM == "" {
+		return nil, 0, fmt.Errorf("vault: %s lacks the %q or %q field", path, s.config.CertificateField, s.config.KeyField)
+	}
+
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, 0, fmt.Errorf("vault: %s: %w", path, err)
+	}
+	lease := time.Duration(result.LeaseDuration) * time.Second
+	if lease <= 0 {
+		lease = s.config.CacheTTL
+	}
+	return &cert, lease, nil
+}
+
+// issue issues a certificate for hostname with the PKI role
+func (s *Source) issue(ctx context.Context, hostname string) (*tls.Certificate, time.Duration, error) {
+	path := "/v1/" + s.config.Mount + "/issue/" + url.PathEscape(s.config.Role)
+	req := map[string]string{"common_name": hostname}
+	if s.config.TTL > 0 {
+		req["ttl"] = s.config.TTL.String()
+	}
+	result, err := s.do(ctx, http.MethodPost, path, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var data struct {
+		Certificate string   `json:"certificate"`
+		PrivateKey  string   `json:"private_key"`
+		IssuingCA   string   `json:"issuing_ca"`
+		CAChain     []string `json:"ca_chain"`
+	}
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		return nil, 0, fmt.Errorf("vault: decode %s: %w", path, err)
+	}
+
+	chain := []string{data.Certificate}
+	if len(data.CAChain) > 0 {
+		chain = append(chain, data.CAChain...)
+	} else if data.IssuingCA != "" {
+		chain = append(chain, data.IssuingCA)
+	}
+	cert, err := tls.X509KeyPair([]byte(strings.Join(chain, "\n")), []byte(data.PrivateKey))
+	if err != nil {
+		return nil, 0, fmt.Errorf("vault: %s: %w", path, err)
+	}
+	// Issue a new certificate well before this one expires
+	lease := time.Duration(float64(time.Until(cert.Leaf.NotAfter)) * renewAfter)
+	return &cert, lease, nil
+}
+
+// secret is the response envelope of the Vault API
+type secret struct {
+	LeaseDuration int             `json:"lease_duration"`
+	Data          json.RawMessage `json:"data"`
+	Errors        []string        `json:"errors"`
+}
+
+// do sends a request to the Vault API and decodes the response envelope
+func (s *Source) do(ctx context.Context, method, path string, body any) (*secret, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.config.Address+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.config.Token)
+	if s.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.config.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result secret
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("vault: decode %s: %w", path, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("vault: %s: %w", path, fibervhosts.ErrNoCertificate)
+	case resp.StatusCode != http.StatusOK:
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("vault: %s: %s", path, strings.Join(result.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault: %s: unexpected status %s", path, resp.Status)
+	}
+	return &result, nil
+}
+
+// escapePath escapes every segment of a secret path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
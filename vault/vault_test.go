@@ -0,0 +1,131 @@
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCertificate returns a PEM encoded self-signed certificate and key for name, valid for the given duration
+func testCertificate(t *testing.T, name string, validity time.Duration) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(validity),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+// Test KV mode reads certificates from secrets below the prefix.
+func TestSource_KV(t *testing.T) {
+	certPEM, keyPEM := testCertificate(t, "www.example.com", time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret-token", r.Header.Get("X-Vault-Token"))
+		assert.Equal(t, "tenants", r.Header.Get("X-Vault-Namespace"))
+		if r.URL.Path != "/v1/kv/data/certs/www.example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []string{}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"lease_duration": 0,
+			"data": map[string]any{
+				"data":     map[string]any{"certificate": certPEM, "private_key": keyPEM},
+				"metadata": map[string]any{"version": 3},
+			},
+		})
+	}))
+	defer server.Close()
+
+	source, err := New(Config{Address: server.URL, Token: "secret-token", Namespace: "tenants", Mount: "kv", Prefix: "certs/", CacheTTL: 10 * time.Minute})
+	assert.NoError(t, err)
+
+	manager := fibervhosts.NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("www.example.com", fiber.New()))
+	assert.NoError(t, manager.AddHostname("api.example.com", fiber.New()))
+
+	ctx := context.Background()
+	assert.NoError(t, manager.SetCertificateSource(ctx, "www.example.com", source))
+	cert, ok := manager.GetCertificateFor("www.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"www.example.com"}, cert.Leaf.DNSNames)
+
+	_, lease, err := source.FetchCertificate(ctx, "www.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, lease)
+
+	err = manager.SetCertificateSource(ctx, "api.example.com", source)
+	assert.ErrorIs(t, err, fibervhosts.ErrNoCertificate)
+}
+
+// Test PKI mode issues certificates and renews them after two thirds of their validity.
+func TestSource_PKI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/pki/issue/web", r.URL.Path)
+		var req map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "72h0m0s", req["ttl"])
+
+		certPEM, keyPEM := testCertificate(t, req["common_name"], 72*time.Hour)
+		caPEM, _ := testCertificate(t, "ca", 720*time.Hour)
+		json.NewEncoder(w).Encode(map[string]any{
+			"lease_duration": 0,
+			"data": map[string]any{
+				"certificate": certPEM,
+				"private_key": keyPEM,
+				"issuing_ca":  caPEM,
+				"ca_chain":    []string{caPEM},
+			},
+		})
+	}))
+	defer server.Close()
+
+	_, err := New(Config{Address: server.URL, Mode: ModePKI})
+	assert.Error(t, err)
+
+	source, err := New(Config{Address: server.URL, Mode: ModePKI, Role: "web", TTL: 72 * time.Hour})
+	assert.NoError(t, err)
+
+	cert, lease, err := source.FetchCertificate(context.Background(), "*.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*.example.com"}, cert.Leaf.DNSNames)
+	assert.Len(t, cert.Certificate, 2)
+	assert.InDelta(t, float64(48*time.Hour), float64(lease), float64(time.Minute))
+}
+
+// Test Vault errors are reported.
+func TestSource_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+	}))
+	defer server.Close()
+
+	source, err := New(Config{Address: server.URL})
+	assert.NoError(t, err)
+	_, _, err = source.FetchCertificate(context.Background(), "www.example.com")
+	assert.ErrorContains(t, err, "permission denied")
+}
@@ -3,47 +3,288 @@
 package fibervhosts
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"maps"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/log"
-	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/valyala/fasthttp"
 )
 
 var (
 	ErrInvalidHostname = errors.New("invalid hostname")
 	ErrHostExists      = errors.New("host already exists")
 	ErrHostNotFound    = errors.New("host not found")
+	ErrAppNotFound     = errors.New("app not found")
+	ErrInvalidTTL      = errors.New("invalid ttl")
 )
 
 // VhostsManager is a struct that holds a map of hostnames to sub-apps and provides methods to add and retrieve sub-apps based on hostnames in a thread-safe manner using RWMutex for locking and unlocking the map of hosts.
 type VhostsManager struct {
-	mu         sync.RWMutex
-	hosts      map[string]*fiber.App
-	wildcards  map[string]*fiber.App
-	defaultApp *fiber.App
-	enableLog  bool
+	mu        sync.RWMutex
+	hosts     map[string]*entry
+	wildcards map[string]*entry
+	// aliases maps the aliases of shared registrations to their entries, see AddAliases
+	aliases    map[string]*entry
+	defaultApp *entry
+	// lookup is the table the middleware matches requests against, see publishLookup
+	lookup    atomic.Pointer[lookupTable]
+	enableLog bool
+	// recoverFromPanic wraps the dispatch chains with panic recovery, see Config.RecoverFromPanic
+	recoverFromPanic bool
+	panicHandler     PanicHandler
+	accessLog        *AccessLog
+	logger           Logger
+
+	lookupCacheSize      int
+	slowRequestThreshold time.Duration
+	requestTimeout       time.Duration
+	bodyLimit            int
+	rateLimit            *RateLimit
+	quota                *Quota
+	compression          *Compression
+	cache                *Cache
+	features             FeatureFlags
+	tenants              *TenantResolver
+	requestID            *RequestID
+	logPolicy            *LogPolicy
+	errorRate            *ErrorRatePolicy
+
+	notFoundHandler fiber.Handler
+	// fallThrough passes unmatched requests on to the main app, see Config.Fallthrough
+	fallThrough      bool
+	suspendedHandler fiber.Handler
+	drainHandler     fiber.Handler
+	hooks            *Hooks
+
+	// testApp mounts the manager for Test
+	testOnce sync.Once
+	testApp  *fiber.App
+
+	watchMu  sync.Mutex
+	watchers map[*watcher]struct{}
+
+	// notifyMu guards the changes queued for the hooks and watchers in the order they were applied, see notifyPending
+	notifyMu  sync.Mutex
+	notifying bool
+	pending   [][]ChangeEvent
+
+	version         uint64
+	snapshots       []Snapshot
+	snapshotHistory int
+
+	defaultCert       *tls.Certificate
+	certExpiryWarning time.Duration
+}
+
+// entry is a single registration in the manager, holding the sub-app together with its per-host state
+type entry struct {
+	kind    EntryType
+	pattern string
+	app     *fiber.App
+	// handler serves the requests of registrations without a sub-app, see AddRequestHandler. It is boxed so snapshots can tell whether it changed.
+	handler *fasthttp.RequestHandler
+	// pool holds the upstreams of a proxy host, see AddProxyPool. It only applies while app is the app of the pool, as swaps and rollbacks replace the app.
+	pool *proxyPool
+	// aliases are the hostnames served by the entry besides its pattern, see AddAliases
+	aliases []string
+	factory *appFactory
+	stats   hostStats
+
+	staged   *fiber.App
+	previous *fiber.App
+
+	suspended atomic.Bool
+	pending   atomic.Bool
+	draining  atomic.Bool
+	// maintenance is the page served while the hostname is in maintenance mode, see SetMaintenance
+	maintenance atomic.Pointer[maintenancePage]
+	// features are the feature flags of the entry, nil until they are set, see SetFeatures
+	features atomic.Pointer[FeatureFlags]
+	inflight atomic.Int64
+	// evictions counts the evictions of the app, see EvictIdle. Routes captured before one dispatch to an app that is shut down.
+	evictions atomic.Uint64
+	stopped   atomic.Bool
+	metadata  Metadata
+	source    string
+
+	expiresAt time.Time
+	expiry    *time.Timer
+	schedule  *hostSchedule
+
+	cert       *tls.Certificate
+	certFiles  *certFiles
+	certSource *certSource
+	certWarned *tls.Certificate
+
+	config HostConfig
+	// configRevision identifies config among all the HostConfigs given to entries, so snapshots can tell whether it changed. It is zero for the empty config of new entries.
+	configRevision uint64
+	errorRate      errorWindow
+}
+
+// newEntry creates an entry for the given hostname pattern and app
+func newEntry(pattern string, app *fiber.App) *entry {
+	kind := EntryHost
+	if isWildcard(pattern) {
+		kind = EntryWildcard
+	}
+	return &entry{kind: kind, pattern: pattern, app: app}
+}
+
+// newDefaultEntry creates the entry for the default app, or returns nil if app is nil
+func newDefaultEntry(app *fiber.App) *entry {
+	if app == nil {
+		return nil
+	}
+	return &entry{kind: EntryDefault, app: app}
+}
+
+// toEntry returns the public description of the entry
+func (e *entry) toEntry() Entry {
+	fallback := e.pattern
+	if e.kind == EntryDefault {
+		fallback = defaultAppName
+	}
+	return Entry{
+		Type:      e.kind,
+		Pattern:   e.pattern,
+		App:       e.app,
+		AppName:   appName(e.app, fallback),
+		Suspended: e.suspended.Load(),
+		Pending:   e.pending.Load(),
+		ExpiresAt: e.expiresAt,
+		Metadata:  e.metadata.clone(),
+		Source:    e.source,
+		Aliases:   e.sortedAliases(),
+		Redirect:  e.config.Redirect,
+		Backend:   e.backendKind(),
+	}
 }
 
 type Config struct {
-	DefaultApp       *fiber.App
-	EnableLogging    bool
+	DefaultApp    *fiber.App
+	EnableLogging bool
+	// RecoverFromPanic turns panics of sub-apps into 500 Internal Server Error responses instead of passing them on to the main app
 	RecoverFromPanic bool
+	// PanicHandler responds to requests whose sub-app panicked when RecoverFromPanic is set, see HostConfig.PanicHandler. Defaults to a 500 Internal Server Error response.
+	PanicHandler PanicHandler
+
+	// NotFoundHandler responds to requests for hostnames without a registration, like with a "domain not configured" page or a redirect. Defaults to a 404 Not Found response.
+	NotFoundHandler fiber.Handler
+
+	// Fallthrough passes requests for hostnames without a registration on to the next handlers of the main app with c.Next, instead of answering them with NotFoundHandler or 404, so vhost routing can be mixed with routes of the main app
+	Fallthrough bool
+
+	// SuspendedHandler responds to requests for suspended hostnames. Defaults to a 503 Service Unavailable response.
+	SuspendedHandler fiber.Handler
+
+	// DrainHandler responds to requests for draining hostnames, like with a redirect to another instance. Defaults to a 503 Service Unavailable response with a Retry-After header.
+	DrainHandler fiber.Handler
+
+	// SnapshotHistory is the number of snapshots kept for Rollback. Defaults to 10.
+	SnapshotHistory int
+
+	// CertExpiryWarning is how long before expiry the OnCertExpiring hooks fire for a certificate. Defaults to 30 days.
+	CertExpiryWarning time.Duration
+
+	// RequestID gives every request an ID before dispatch, see HostConfig.RequestID. The ID is included in the access log. Nil disables request IDs.
+	RequestID *RequestID
+
+	// AccessLog writes an access log entry for every request. Defaults to the combined format on stdout when EnableLogging is set, and to no access log otherwise.
+	AccessLog *AccessLog
+
+	// Logger receives the logs of the manager and the middleware. Defaults to fiber's log package.
+	Logger Logger
+
+	// SlowRequestThreshold logs a warning for dispatched requests taking longer than this, see HostConfig.SlowRequestThreshold. Defaults to 0, which disables the warning.
+	SlowRequestThreshold time.Duration
+
+	// RequestTimeout answers requests with 504 Gateway Timeout when their sub-app takes longer than this, see HostConfig.RequestTimeout. The sub-app keeps running in the background until it returns; the context of the request is canceled so it can stop early. Defaults to 0, which disables the timeout.
+	RequestTimeout time.Duration
+
+	// BodyLimit is the maximum size in bytes of request bodies, see HostConfig.BodyLimit. As the server reads bodies up to the BodyLimit of the main app first, it can only lower that limit. Defaults to 0, which disables the limit.
+	BodyLimit int
+
+	// Compression compresses the responses of every hostname, see HostConfig.Compression. Nil leaves compression to the sub-apps.
+	Compression *Compression
+
+	// Cache caches the responses of every hostname, see HostConfig.Cache and Cache. Nil disables the response cache.
+	Cache *Cache
+
+	// Features are the feature flags of every hostname until they are set with SetFeatures or SetFeature
+	Features FeatureFlags
+
+	// Tenants resolves the tenant of the requests of every hostname, see HostConfig.Tenants and TenantResolver. Nil leaves requests without tenants.
+	Tenants *TenantResolver
+
+	// RateLimit limits the requests of every hostname, see HostConfig.RateLimit. Nil disables rate limiting.
+	RateLimit *RateLimit
+
+	// Quota caps the requests and bytes per window of every hostname, see HostConfig.Quota and Quota. Nil disables quotas.
+	Quota *Quota
+
+	// Logging sets the log level and sampling rate of the requests of all hostnames, see HostConfig.Logging. Nil logs every request.
+	Logging *LogPolicy
+
+	// ErrorRate tracks the rolling error rate of all hostnames for the OnErrorRate hooks, see HostConfig.ErrorRate. Nil disables tracking.
+	ErrorRate *ErrorRatePolicy
+
+	// LookupCacheSize is the number of hostnames whose lookup is cached, so repeat requests skip matching. The cache is emptied on every change of the table. Defaults to 0, which disables the cache.
+	LookupCacheSize int
 }
 
 // NewVhostsManager creates a new VhostsManager instance with an empty map of hosts and returns a pointer to it
 func NewVhostsManager(config ...Config) *VhostsManager {
 	m := &VhostsManager{
-		hosts:     make(map[string]*fiber.App),
-		wildcards: make(map[string]*fiber.App),
+		hosts:     make(map[string]*entry),
+		wildcards: make(map[string]*entry),
+		aliases:   make(map[string]*entry),
 	}
+	m.hooks = newHooks()
+	m.snapshotHistory = defaultSnapshotHistory
+	m.certExpiryWarning = defaultCertExpiryWarning
+	m.logger = fiberLogger{}
 
 	if len(config) > 0 {
-		m.defaultApp = config[0].DefaultApp
+		m.defaultApp = newDefaultEntry(config[0].DefaultApp)
 		m.enableLog = config[0].EnableLogging
+		m.recoverFromPanic = config[0].RecoverFromPanic
+		m.panicHandler = config[0].PanicHandler
+		m.notFoundHandler = config[0].NotFoundHandler
+		m.fallThrough = config[0].Fallthrough
+		m.suspendedHandler = config[0].SuspendedHandler
+		m.drainHandler = config[0].DrainHandler
+		m.accessLog = config[0].AccessLog
+		m.slowRequestThreshold = config[0].SlowRequestThreshold
+		m.requestTimeout = config[0].RequestTimeout
+		m.bodyLimit = config[0].BodyLimit
+		m.rateLimit = config[0].RateLimit
+		m.quota = config[0].Quota
+		m.compression = config[0].Compression
+		m.cache = config[0].Cache
+		m.features = maps.Clone(config[0].Features)
+		m.tenants = config[0].Tenants
+		m.requestID = config[0].RequestID
+		m.logPolicy = config[0].Logging
+		m.errorRate = config[0].ErrorRate
+		m.lookupCacheSize = config[0].LookupCacheSize
+		if config[0].Logger != nil {
+			m.logger = config[0].Logger
+		}
+		if config[0].SnapshotHistory > 0 {
+			m.snapshotHistory = config[0].SnapshotHistory
+		}
+		if config[0].CertExpiryWarning > 0 {
+			m.certExpiryWarning = config[0].CertExpiryWarning
+		}
 	}
+	m.buildLookup()
 
 	return m
 }
@@ -54,55 +295,121 @@ func (m *VhostsManager) AddHostname(hostname string, app *fiber.App) error {
 		return ErrInvalidHostname
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	// Handle wildcard hostnames
-	if strings.HasPrefix(hostname, "*.") {
-		suffix := hostname[2:]
-		if _, exists := m.wildcards[suffix]; exists {
-			return ErrHostExists
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		if _, exists := table[key]; exists {
+			return nil, ErrHostExists
 		}
-		m.wildcards[suffix] = app
-		return nil
-	}
 
-	if _, exists := m.hosts[hostname]; exists {
-		return ErrHostExists
-	}
+		e := newEntry(hostname, app)
+		table[key] = e
+		return []ChangeEvent{added(e)}, nil
+	})
+}
 
-	m.hosts[hostname] = app
-	return nil
+// AddHostnames adds several sub-apps at once. All entries are validated first and then applied under a single lock, so either every hostname is registered or, on any invalid or conflicting hostname, none are.
+func (m *VhostsManager) AddHostnames(apps map[string]*fiber.App) error {
+	return m.update(func() ([]ChangeEvent, error) {
+		// Validate everything before touching the maps
+		for hostname := range apps {
+			if hostname == "" {
+				return nil, ErrInvalidHostname
+			}
+			table, key := m.tableFor(hostname)
+			if _, exists := table[key]; exists {
+				return nil, fmt.Errorf("%w: %s", ErrHostExists, hostname)
+			}
+		}
+
+		changes := make([]ChangeEvent, 0, len(apps))
+		for hostname, app := range apps {
+			table, key := m.tableFor(hostname)
+			e := newEntry(hostname, app)
+			table[key] = e
+			changes = append(changes, added(e))
+		}
+		return changes, nil
+	})
 }
 
 // RemoveHostname removes a sub-app for a given hostname from the manager
 func (m *VhostsManager) RemoveHostname(hostname string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+
+		delete(table, key)
+		e.stopExpiry()
+		return []ChangeEvent{removed(e)}, nil
+	})
+}
 
-	if strings.HasPrefix(hostname, "*.") {
-		suffix := hostname[2:]
-		if _, exists := m.wildcards[suffix]; !exists {
-			return ErrHostNotFound
+// UpdateHostname replaces the sub-app of an existing hostname atomically, so requests never observe a window where the hostname is missing
+func (m *VhostsManager) UpdateHostname(hostname string, app *fiber.App) error {
+	_, err := m.SwapApp(hostname, app)
+	return err
+}
+
+// SwapApp replaces the sub-app of an existing hostname atomically and returns the previous sub-app
+func (m *VhostsManager) SwapApp(hostname string, app *fiber.App) (*fiber.App, error) {
+	var old *fiber.App
+	err := m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		e, exists := table[key]
+		if !exists {
+			return nil, ErrHostNotFound
 		}
-		delete(m.wildcards, suffix)
-		return nil
-	}
 
-	if _, exists := m.hosts[hostname]; !exists {
-		return ErrHostNotFound
+		previous := e.toEntry()
+		old = e.app
+		e.app, e.handler, e.factory = app, nil, nil
+		return []ChangeEvent{updated(previous, e)}, nil
+	})
+	return old, err
+}
+
+// RenameHostname moves the sub-app registered for oldHostname to newHostname in one locked operation. It returns ErrHostExists if newHostname is already registered.
+func (m *VhostsManager) RenameHostname(oldHostname, newHostname string) error {
+	if newHostname == "" {
+		return ErrInvalidHostname
 	}
 
-	delete(m.hosts, hostname)
-	return nil
+	return m.update(func() ([]ChangeEvent, error) {
+		oldTable, oldKey := m.tableFor(oldHostname)
+		e, exists := oldTable[oldKey]
+		if !exists {
+			return nil, ErrHostNotFound
+		}
+
+		newTable, newKey := m.tableFor(newHostname)
+		if _, exists := newTable[newKey]; exists {
+			return nil, ErrHostExists
+		}
+
+		// Move the entry itself so its state (like stats) follows the rename
+		previous := removed(e)
+		delete(oldTable, oldKey)
+		renamed := newEntry(newHostname, e.app)
+		e.kind, e.pattern = renamed.kind, renamed.pattern
+		// The certificate was issued for the old name
+		e.cert, e.certFiles, e.certSource = nil, nil, nil
+		newTable[newKey] = e
+		return []ChangeEvent{previous, added(e)}, nil
+	})
 }
 
 // GetHostname returns the sub-app for a given hostname if it exists
 func (m *VhostsManager) GetHostname(hostname string) (*fiber.App, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	app, exists := m.hosts[hostname]
-	return app, exists
+	e, exists := m.hosts[hostname]
+	if !exists {
+		return nil, false
+	}
+	return e.app, true
 }
 
 // GetHostnames returns a list of all hostnames in the manager
@@ -116,58 +423,244 @@ func (m *VhostsManager) GetHostnames() []string {
 	return hostnames
 }
 
-// SetDefaultApp sets the default app to be used when no matching hostname is found
+// SetDefaultApp sets the default app to be used when no matching hostname is found. Passing nil removes the default app.
 func (m *VhostsManager) SetDefaultApp(app *fiber.App) {
+	_ = m.update(func() ([]ChangeEvent, error) {
+		e := m.defaultApp
+		switch {
+		case e == nil && app == nil:
+			return nil, nil
+		case e == nil:
+			m.defaultApp = newDefaultEntry(app)
+			return []ChangeEvent{added(m.defaultApp)}, nil
+		case app == nil:
+			m.defaultApp = nil
+			return []ChangeEvent{removed(e)}, nil
+		default:
+			previous := e.toEntry()
+			e.app = app
+			return []ChangeEvent{updated(previous, e)}, nil
+		}
+	})
+}
+
+// Clear removes all hosts, wildcards and the default app from the manager. The OnRemove hooks are invoked for every removed registration.
+func (m *VhostsManager) Clear() {
+	_ = m.update(func() ([]ChangeEvent, error) {
+		return m.converge(nil, nil), nil
+	})
+}
+
+// update runs fn under the write lock, publishes the changed lookup table and, once the lock is released, notifies the hooks of the changes it made. Nothing is notified when fn returns an error.
+func (m *VhostsManager) update(fn func() ([]ChangeEvent, error)) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.defaultApp = app
+	changes, err := fn()
+	if err == nil && len(changes) > 0 {
+		m.version++
+		m.publishLookup(changedPatterns(changes)...)
+		// Queued under the lock, so concurrent updates are notified in the order they were applied
+		m.notifyMu.Lock()
+		m.pending = append(m.pending, changes)
+		m.notifyMu.Unlock()
+	}
+	m.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	m.notifyPending()
+	return nil
 }
 
-// findMatchingApp finds the sub-app for a given hostname, trying exact match first, then wildcard match, and finally returning the default app if no match is found
-func (m *VhostsManager) findMatchingApp(hostname string) *fiber.App {
+// isWildcard reports whether the hostname is a wildcard pattern like "*.example.com"
+func isWildcard(hostname string) bool {
+	return strings.HasPrefix(hostname, "*.")
+}
+
+// tableFor returns the map a hostname is stored in and its key within that map. Wildcard hostnames are stored by their suffix in the wildcards map. The caller must hold the lock.
+func (m *VhostsManager) tableFor(hostname string) (map[string]*entry, string) {
+	if isWildcard(hostname) {
+		return m.wildcards, hostname[2:]
+	}
+	return m.hosts, hostname
+}
+
+// findMatchingEntry finds the entry for a given hostname, trying exact match first, then wildcard match, and finally returning the default app entry if no match is found. The caller must hold the lock; requests are matched against the lookup table instead.
+func (m *VhostsManager) findMatchingEntry(hostname string) *entry {
 	// First try exact match
-	if app, exists := m.hosts[hostname]; exists {
-		return app
+	if e, exists := m.hosts[hostname]; exists {
+		return e
 	}
 
 	// Then try wildcard match
-	parts := strings.Split(hostname, ".")
-	if len(parts) > 1 {
-		domain := strings.Join(parts[1:], ".")
-		if app, exists := m.wildcards[domain]; exists {
-			return app
-		}
+	if e := m.findWildcard(hostname); e != nil {
+		return e
 	}
 
 	return m.defaultApp
 }
 
-// VhostMiddleware mounts a specific sub-app based on the hostname. If the hostname is not found, it returns a 404 response. This middleware is intended to be used with the main app to route requests to different sub-apps based on the hostname.
-func VhostMiddleware(manager *VhostsManager) fiber.Handler {
-	// Create recover middleware if enabled
-	recoverHandler := recover.New()
+// findWildcard finds the wildcard entry with the longest suffix matching a hostname, or nil if there is none. The caller must hold the lock, which keeps the lookup table in line with the maps.
+func (m *VhostsManager) findWildcard(hostname string) *entry {
+	if r := m.lookup.Load().wildcards.match(hostname); r != nil {
+		return r.entry
+	}
+	return nil
+}
 
-	return func(c *fiber.Ctx) error {
+// VhostMiddleware mounts a specific sub-app based on the hostname. If the hostname is not found, it returns a 404 response, or passes the request on to the main app with Config.Fallthrough. This middleware is intended to be used with the main app to route requests to different sub-apps based on the hostname. Upgrade requests like WebSocket handshakes are dispatched like any other request: the sub-app hijacks the connection of the main app, see fasthttp.RequestCtx.Hijack, and proxy hosts tunnel it to their upstream.
+//
+// The manager may be changed concurrently with requests. Every request is matched against one published lookup table without taking the lock, so it sees either all or none of a change, including a committed transaction, and is dispatched with the settings of its registration as of that table.
+func VhostMiddleware(manager HostManager) fiber.Handler {
+	return manager.Handler()
+}
+
+// Handler returns the handler serving the requests of the manager, see VhostMiddleware
+func (m *VhostsManager) Handler() fiber.Handler {
+	return vhostHandler(m)
+}
+
+// vhostHandler matches and dispatches requests, see VhostMiddleware
+func vhostHandler(manager *VhostsManager) fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
 		hostname := c.Hostname()
 
+		start := time.Now()
+		var r *route
+		logger, sampled := manager.logger, true
+		defer func() {
+			if sampled {
+				manager.logAccess(c, r, start, err)
+			}
+		}()
+
+		// Matching reads the published lookup table, so it takes no lock and never observes a change half applied
+		r = manager.lookup.Load().resolve(hostname)
+		setMatchLocals(c, r)
+		if requestID := manager.requestIDFor(r); requestID != nil {
+			requestID.apply(c)
+		}
+		if r == nil {
+			manager.hooks.executeOnNoMatch(c)
+			if manager.fallThrough {
+				return c.Next()
+			}
+			if manager.enableLog {
+				// The hostname points into the request buffer, so loggers keeping it get a copy
+				logger.Warn("No application found", "hostname", strings.Clone(hostname))
+			}
+			if manager.notFoundHandler != nil {
+				return manager.notFoundHandler(c)
+			}
+			return fiber.ErrNotFound
+		}
+		e := r.entry
+		c.Locals(LocalsFeaturesKey, manager.featuresOf(e))
+		if handle := r.config.ErrorHandler; handle != nil {
+			defer func() {
+				if err != nil {
+					err = handle(c, err)
+				}
+			}()
+		}
+		logger, sampled = manager.requestLogger(r)
+		// Count the request before checking the drain flag, so DrainHostname never misses it
+		e.inflight.Add(1)
+		defer e.inflight.Add(-1)
+		manager.hooks.executeOnMatch(c, r)
 		if manager.enableLog {
-			log.Infof("Processing request for hostname: %s", hostname)
+			logger.Debug("Dispatching request", "hostname", strings.Clone(hostname), "pattern", r.info.Pattern)
 		}
 
-		app := manager.findMatchingApp(hostname)
-		if app == nil {
+		if e.suspended.Load() {
 			if manager.enableLog {
-				log.Warnf("No application found for hostname: %s", hostname)
+				logger.Info("Hostname is suspended", "hostname", strings.Clone(hostname))
 			}
-			return fiber.ErrNotFound
+			if manager.suspendedHandler != nil {
+				return manager.suspendedHandler(c)
+			}
+			return fiber.ErrServiceUnavailable
+		}
+		if e.pending.Load() {
+			return respondPending(c)
+		}
+		if page := e.maintenance.Load(); page != nil {
+			return page.respond(c, logger)
+		}
+		if e.draining.Load() {
+			return manager.respondDraining(c)
 		}
 
-		// Wrap the handler with panic recovery if enabled
-		if manager.enableLog {
-			app.Use(recoverHandler)
+		if err := manager.resolveTenant(c, r); err != nil {
+			return err
+		}
+		if redirect := r.config.Redirect; redirect != nil {
+			return redirect.redirect(c)
+		}
+		if filter := r.config.IPFilter; filter != nil {
+			if err := filter.check(c); err != nil {
+				return err
+			}
+		}
+		if policy := r.config.HTTPSRedirect; policy != nil {
+			if redirected, err := policy.redirect(c); redirected {
+				return err
+			}
+		}
+		if auth := r.config.ClientAuth; auth != nil {
+			if err := auth.check(c); err != nil {
+				return err
+			}
+		}
+		if err := manager.checkBodyLimit(c, r); err != nil {
+			return err
+		}
+		if err := manager.checkRateLimit(c, r); err != nil {
+			return err
+		}
+		if err := manager.checkQuota(c, r); err != nil {
+			return err
+		}
+		if policy := r.config.CORS; policy != nil {
+			if answered, err := policy.handle(c); answered {
+				return err
+			}
+		}
+		if auth := r.config.Auth; auth != nil {
+			if err := auth(c); err != nil {
+				return err
+			}
 		}
 
-		app.Handler()(c.Context())
-		return nil
+		e.stats.begin()
+
+		dispatch := r.dispatch
+		if r.evictions != e.evictions.Load() {
+			// The app was evicted since the request was matched, see EvictIdle
+			dispatch = nil
+		}
+		if dispatch == nil {
+			app, err := manager.resolveApp(e)
+			if err != nil {
+				logger.Error("Building app failed", "hostname", strings.Clone(hostname), "error", err)
+				return fiber.ErrServiceUnavailable
+			}
+			dispatch = manager.builtDispatch(hostname, e, app, r.config)
+		}
+
+		dispatched := time.Now()
+		err = dispatch(c)
+		manager.checkSlow(c, r, logger, time.Since(dispatched))
+		if pages := r.config.ErrorPages; pages != nil {
+			err = pages.render(c, err, logger)
+		}
+		if hsts := r.config.HSTS; hsts != nil {
+			hsts.apply(c)
+		}
+		manager.countQuotaBytes(c, r)
+		status := responseStatus(c, err)
+		e.stats.end(status)
+		manager.trackErrorRate(r, status)
+		return err
 	}
 }
@@ -208,7 +208,7 @@ func TestNewVhostsManager_WithConfig(t *testing.T) {
 	manager := NewVhostsManager(config)
 
 	// Test properties were set correctly
-	assert.Equal(t, defaultApp, manager.defaultApp)
+	assert.Equal(t, defaultApp, manager.defaultApp.app)
 	assert.True(t, manager.enableLog)
 
 	// Ensure maps were initialized
@@ -231,6 +231,63 @@ func TestVhostMiddleware_NoDefaultApp(t *testing.T) {
 	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
 }
 
+// Non-existing hostname should be answered by the NotFoundHandler when set.
+func TestVhostMiddleware_NotFoundHandler(t *testing.T) {
+	manager := NewVhostsManager(Config{
+		NotFoundHandler: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "domain not configured", "hostname": c.Hostname()})
+		},
+	})
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "nonexistent.com"
+	resp, err := mainApp.Test(req)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.JSONEq(t, `{"error":"domain not configured","hostname":"nonexistent.com"}`, string(body))
+}
+
+// Non-existing hostname should be passed on to the routes of the main app with Fallthrough.
+func TestVhostMiddleware_Fallthrough(t *testing.T) {
+	manager := NewVhostsManager(Config{Fallthrough: true})
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("vhost")
+	})
+	assert.NoError(t, manager.AddHostname("example.com", app))
+
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+	mainApp.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("main")
+	})
+
+	for hostname, want := range map[string]string{"example.com": "vhost", "nonexistent.com": "main"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = hostname
+		resp, err := mainApp.Test(req)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, want, string(body))
+	}
+
+	// Routes the main app does not have end in its own 404
+	req := httptest.NewRequest("GET", "/missing", nil)
+	req.Host = "nonexistent.com"
+	resp, err := mainApp.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
 // With logging enabled, the middleware should log the hostname.
 func TestVhostMiddleware_Logging(t *testing.T) {
 	manager := NewVhostsManager(Config{
@@ -291,3 +348,121 @@ func TestVhostsManager_AddLongHostname(t *testing.T) {
 	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 
 }
+
+// Test AddHostnames registers all entries at once.
+func TestVhostsManager_AddHostnames(t *testing.T) {
+	manager := NewVhostsManager()
+	app1 := fiber.New()
+	app2 := fiber.New()
+
+	err := manager.AddHostnames(map[string]*fiber.App{
+		"example1.com":  app1,
+		"example2.com":  app2,
+		"*.example.org": app2,
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"example1.com", "example2.com"}, manager.GetHostnames())
+	assert.Equal(t, app2, manager.wildcards["example.org"].app)
+}
+
+// Test AddHostnames leaves the table untouched when any entry conflicts.
+func TestVhostsManager_AddHostnames_Atomic(t *testing.T) {
+	manager := NewVhostsManager()
+	app := fiber.New()
+	assert.NoError(t, manager.AddHostname("taken.com", app))
+
+	err := manager.AddHostnames(map[string]*fiber.App{
+		"new1.com":  app,
+		"new2.com":  app,
+		"taken.com": app,
+	})
+	assert.ErrorIs(t, err, ErrHostExists)
+	assert.ElementsMatch(t, []string{"taken.com"}, manager.GetHostnames())
+
+	err = manager.AddHostnames(map[string]*fiber.App{
+		"new1.com": app,
+		"":         app,
+	})
+	assert.Equal(t, ErrInvalidHostname, err)
+	assert.ElementsMatch(t, []string{"taken.com"}, manager.GetHostnames())
+}
+
+// Test UpdateHostname and SwapApp replace an existing entry.
+func TestVhostsManager_UpdateHostname(t *testing.T) {
+	manager := NewVhostsManager()
+	oldApp := fiber.New()
+	newApp := fiber.New()
+	newApp.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("new app")
+	})
+
+	// Updating an unknown hostname fails
+	err := manager.UpdateHostname("example.com", newApp)
+	assert.Equal(t, ErrHostNotFound, err)
+
+	assert.NoError(t, manager.AddHostname("example.com", oldApp))
+	assert.NoError(t, manager.UpdateHostname("example.com", newApp))
+
+	retrieved, exists := manager.GetHostname("example.com")
+	assert.True(t, exists)
+	assert.Equal(t, newApp, retrieved)
+
+	// SwapApp returns the previous app, also for wildcards
+	assert.NoError(t, manager.AddHostname("*.example.org", oldApp))
+	previous, err := manager.SwapApp("*.example.org", newApp)
+	assert.NoError(t, err)
+	assert.Equal(t, oldApp, previous)
+
+	mainApp := fiber.New()
+	mainApp.Use(VhostMiddleware(manager))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "www.example.org"
+	resp, err := mainApp.Test(req)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "new app", string(body))
+}
+
+// Test RenameHostname moves an app to a new hostname.
+func TestVhostsManager_RenameHostname(t *testing.T) {
+	manager := NewVhostsManager()
+	app := fiber.New()
+	other := fiber.New()
+
+	assert.Equal(t, ErrHostNotFound, manager.RenameHostname("old.com", "new.com"))
+
+	assert.NoError(t, manager.AddHostname("old.com", app))
+	assert.NoError(t, manager.AddHostname("taken.com", other))
+
+	// Target already registered
+	assert.Equal(t, ErrHostExists, manager.RenameHostname("old.com", "taken.com"))
+	assert.Equal(t, ErrInvalidHostname, manager.RenameHostname("old.com", ""))
+
+	assert.NoError(t, manager.RenameHostname("old.com", "new.com"))
+	_, exists := manager.GetHostname("old.com")
+	assert.False(t, exists)
+	retrieved, exists := manager.GetHostname("new.com")
+	assert.True(t, exists)
+	assert.Equal(t, app, retrieved)
+
+	// Renaming into a wildcard pattern
+	assert.NoError(t, manager.RenameHostname("new.com", "*.new.com"))
+	assert.Equal(t, app, manager.wildcards["new.com"].app)
+}
+
+// Test Clear removes every registration.
+func TestVhostsManager_Clear(t *testing.T) {
+	app := fiber.New()
+	manager := NewVhostsManager(Config{DefaultApp: app})
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.NoError(t, manager.AddHostname("*.example.org", app))
+
+	manager.Clear()
+	assert.Empty(t, manager.ListEntries())
+
+	// The manager is still usable after clearing
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.ElementsMatch(t, []string{"example.com"}, manager.GetHostnames())
+}
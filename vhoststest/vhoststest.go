@@ -0,0 +1,217 @@
+// Package vhoststest provides an in-memory fake of fibervhosts.HostManager, so applications embedding the package can unit test the code managing their hostnames without a real manager.
+//
+// The fake keeps the registered sub-apps in a map and matches requests by their hostname, normalized like the real manager does. It implements none of the policies of HostConfig, which it records as is.
+package vhoststest
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Manager is the fake fibervhosts.HostManager. It is safe for concurrent use.
+type Manager struct {
+	// Err, when set, is returned by every method returning an error, without changing the registrations, to test the error paths of the callers
+	Err error
+
+	mu         sync.RWMutex
+	hosts      map[string]*host
+	defaultApp *fiber.App
+}
+
+// host is a registration of the fake
+type host struct {
+	app       *fiber.App
+	config    fibervhosts.HostConfig
+	metadata  fibervhosts.Metadata
+	suspended bool
+}
+
+var _ fibervhosts.HostManager = (*Manager)(nil)
+
+// NewManager creates an empty fake
+func NewManager() *Manager {
+	return &Manager{hosts: make(map[string]*host)}
+}
+
+// Handler dispatches requests to the sub-app of their hostname, or else to the default app. Unknown hostnames are answered with 404 Not Found and suspended ones with 503 Service Unavailable.
+func (m *Manager) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		m.mu.RLock()
+		app := m.defaultApp
+		h, exists := m.host(c.Hostname())
+		if exists {
+			app = h.app
+		}
+		suspended := exists && h.suspended
+		m.mu.RUnlock()
+
+		switch {
+		case suspended:
+			return fiber.ErrServiceUnavailable
+		case app == nil:
+			return fiber.ErrNotFound
+		}
+		app.Handler()(c.Context())
+		return nil
+	}
+}
+
+// host returns the registration of a request hostname, matched as requested first, like a registration including the port, then normalized like the real manager does, see fibervhosts.NormalizeHostname. The caller must hold the lock.
+func (m *Manager) host(hostname string) (*host, bool) {
+	if h, exists := m.hosts[hostname]; exists {
+		return h, true
+	}
+	h, exists := m.hosts[fibervhosts.NormalizeHostname(hostname)]
+	return h, exists
+}
+
+// AddHostname registers app for hostname
+func (m *Manager) AddHostname(hostname string, app *fiber.App) error {
+	return m.AddHostnameWithConfig(hostname, app, fibervhosts.HostConfig{})
+}
+
+// AddHostnameWithConfig registers app for hostname, recording config, see Config
+func (m *Manager) AddHostnameWithConfig(hostname string, app *fiber.App, config fibervhosts.HostConfig) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	if hostname == "" {
+		return fibervhosts.ErrInvalidHostname
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.hosts[hostname]; exists {
+		return fibervhosts.ErrHostExists
+	}
+	m.hosts[hostname] = &host{app: app, config: config}
+	return nil
+}
+
+// UpdateHostname replaces the sub-app of a registered hostname
+func (m *Manager) UpdateHostname(hostname string, app *fiber.App) error {
+	return m.with(hostname, func(h *host) {
+		h.app = app
+	})
+}
+
+// RemoveHostname removes the registration of hostname
+func (m *Manager) RemoveHostname(hostname string) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.hosts[hostname]; !exists {
+		return fibervhosts.ErrHostNotFound
+	}
+	delete(m.hosts, hostname)
+	return nil
+}
+
+// GetHostname returns the sub-app of a registered hostname
+func (m *Manager) GetHostname(hostname string) (*fiber.App, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	h, exists := m.hosts[hostname]
+	if !exists {
+		return nil, false
+	}
+	return h.app, true
+}
+
+// GetHostnames returns the registered hostnames, sorted
+func (m *Manager) GetHostnames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	hostnames := make([]string, 0, len(m.hosts))
+	for hostname := range m.hosts {
+		hostnames = append(hostnames, hostname)
+	}
+	slices.Sort(hostnames)
+	return hostnames
+}
+
+// SetDefaultApp sets the app serving unknown hostnames. Passing nil removes it.
+func (m *Manager) SetDefaultApp(app *fiber.App) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaultApp = app
+}
+
+// SuspendHostname marks a registered hostname as suspended
+func (m *Manager) SuspendHostname(hostname string) error {
+	return m.with(hostname, func(h *host) {
+		h.suspended = true
+	})
+}
+
+// ResumeHostname clears the suspended mark of a registered hostname
+func (m *Manager) ResumeHostname(hostname string) error {
+	return m.with(hostname, func(h *host) {
+		h.suspended = false
+	})
+}
+
+// IsSuspended reports whether a hostname is registered and suspended
+func (m *Manager) IsSuspended(hostname string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	h, exists := m.hosts[hostname]
+	return exists && h.suspended
+}
+
+// SetMetadata replaces the metadata of a registered hostname
+func (m *Manager) SetMetadata(hostname string, md fibervhosts.Metadata) error {
+	md = cloneMetadata(md)
+	return m.with(hostname, func(h *host) {
+		h.metadata = md
+	})
+}
+
+// GetMetadata returns the metadata of a registered hostname
+func (m *Manager) GetMetadata(hostname string) (fibervhosts.Metadata, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	h, exists := m.hosts[hostname]
+	if !exists {
+		return fibervhosts.Metadata{}, false
+	}
+	return cloneMetadata(h.metadata), true
+}
+
+// Config returns the HostConfig a hostname was registered with, to assert on it in tests
+func (m *Manager) Config(hostname string) (fibervhosts.HostConfig, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	h, exists := m.hosts[hostname]
+	if !exists {
+		return fibervhosts.HostConfig{}, false
+	}
+	return h.config, true
+}
+
+// with changes the registration of hostname under the lock
+func (m *Manager) with(hostname string, fn func(h *host)) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, exists := m.hosts[hostname]
+	if !exists {
+		return fibervhosts.ErrHostNotFound
+	}
+	fn(h)
+	return nil
+}
+
+// cloneMetadata returns a deep copy of md, so callers cannot modify the stored metadata
+func cloneMetadata(md fibervhosts.Metadata) fibervhosts.Metadata {
+	md.Values = maps.Clone(md.Values)
+	md.Tags = slices.Clone(md.Tags)
+	return md
+}
@@ -0,0 +1,77 @@
+package vhoststest
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	fibervhosts "github.com/boomhut/fiber-vhosts2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the fake dispatches through VhostMiddleware and keeps its registrations like a manager.
+func TestManager(t *testing.T) {
+	site := fiber.New()
+	site.Get("/", func(c *fiber.Ctx) error { return c.SendString("site") })
+
+	manager := NewManager()
+	app := fiber.New()
+	app.Use(fibervhosts.VhostMiddleware(manager))
+
+	assert.NoError(t, manager.AddHostnameWithConfig("www.example.com", site, fibervhosts.HostConfig{Settings: map[string]any{"plan": "pro"}}))
+	assert.ErrorIs(t, manager.AddHostname("www.example.com", site), fibervhosts.ErrHostExists)
+	assert.Equal(t, []string{"www.example.com"}, manager.GetHostnames())
+	config, ok := manager.Config("www.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "pro", config.Settings["plan"])
+
+	resp, err := app.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "site", string(body))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "http://other.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	assert.NoError(t, manager.SuspendHostname("www.example.com"))
+	assert.True(t, manager.IsSuspended("www.example.com"))
+	resp, err = app.Test(httptest.NewRequest("GET", "http://www.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	md := fibervhosts.Metadata{Values: map[string]string{"owner": "team-a"}}
+	assert.NoError(t, manager.SetMetadata("www.example.com", md))
+	md.Values["owner"] = "team-b"
+	got, ok := manager.GetMetadata("www.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "team-a", got.Get("owner"))
+
+	failure := errors.New("store down")
+	manager.Err = failure
+	assert.ErrorIs(t, manager.RemoveHostname("www.example.com"), failure)
+	manager.Err = nil
+	assert.NoError(t, manager.RemoveHostname("www.example.com"))
+	assert.ErrorIs(t, manager.ResumeHostname("www.example.com"), fibervhosts.ErrHostNotFound)
+}
+
+// Test requests are matched with their hostname normalized like the real manager does, without the port and a trailing dot.
+func TestManager_NormalizedHostname(t *testing.T) {
+	site := fiber.New()
+	site.Get("/", func(c *fiber.Ctx) error { return c.SendString("site") })
+
+	manager := NewManager()
+	app := fiber.New()
+	app.Use(fibervhosts.VhostMiddleware(manager))
+	assert.NoError(t, manager.AddHostname("www.example.com", site))
+	assert.NoError(t, manager.AddHostname("[::1]", site))
+
+	for _, target := range []string{"http://www.example.com:8080/", "http://WWW.example.com./", "http://www.example.com.:8080/", "http://[::1]:8080/"} {
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "site", string(body), target)
+	}
+}
@@ -0,0 +1,75 @@
+// This file contains the warm-up of registrations, which holds a new hostname in a pending state until its warm-up function (priming caches, opening database pools) has completed.
+package fibervhosts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WarmUp prepares the sub-app of a registration before it receives traffic, see AddHostnameWithWarmUp
+type WarmUp func(ctx context.Context) error
+
+// AddHostnameWithWarmUp registers a sub-app for a hostname in a pending state, runs warmUp and then puts the hostname live. Requests for a pending hostname are answered with 503 Service Unavailable and a Retry-After header. If warmUp fails, the registration is removed again and the error returned. AddHostnameWithWarmUp blocks until the warm-up is done; run it in a goroutine to register in the background.
+func (m *VhostsManager) AddHostnameWithWarmUp(ctx context.Context, hostname string, app *fiber.App, warmUp WarmUp) error {
+	if hostname == "" {
+		return ErrInvalidHostname
+	}
+
+	var pending *entry
+	err := m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		if _, exists := table[key]; exists {
+			return nil, ErrHostExists
+		}
+
+		pending = newEntry(hostname, app)
+		pending.pending.Store(true)
+		table[key] = pending
+		return []ChangeEvent{added(pending)}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	warmErr := warmUp(ctx)
+
+	_ = m.update(func() ([]ChangeEvent, error) {
+		table, key := m.tableFor(hostname)
+		// Skip registrations replaced or removed meanwhile
+		if table[key] != pending {
+			return nil, nil
+		}
+		if warmErr != nil {
+			delete(table, key)
+			pending.stopExpiry()
+			return []ChangeEvent{removed(pending)}, nil
+		}
+		previous := pending.toEntry()
+		pending.pending.Store(false)
+		return []ChangeEvent{updated(previous, pending)}, nil
+	})
+	if warmErr != nil {
+		return fmt.Errorf("warm up %s: %w", hostname, warmErr)
+	}
+	return nil
+}
+
+// IsPending reports whether a hostname is registered and still warming up
+func (m *VhostsManager) IsPending(hostname string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, key := m.tableFor(hostname)
+	e, exists := table[key]
+	return exists && e.pending.Load()
+}
+
+// pendingRetryAfter is the Retry-After in seconds sent for pending hostnames
+const pendingRetryAfter = "1"
+
+// respondPending answers a request for a hostname that is still warming up
+func respondPending(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderRetryAfter, pendingRetryAfter)
+	return fiber.ErrServiceUnavailable
+}
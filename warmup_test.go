@@ -0,0 +1,62 @@
+package fibervhosts
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test a hostname answers 503 while warming up and goes live afterwards.
+func TestVhostsManager_AddHostnameWithWarmUp(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("live") })
+
+	manager := NewVhostsManager()
+	main := fiber.New()
+	main.Use(VhostMiddleware(manager))
+
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- manager.AddHostnameWithWarmUp(context.Background(), "api.example.com", app, func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+	}()
+
+	assert.Eventually(t, func() bool { return manager.IsPending("api.example.com") }, time.Second, time.Millisecond)
+	resp, err := main.Test(httptest.NewRequest("GET", "http://api.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.False(t, manager.IsPending("api.example.com"))
+
+	resp, err = main.Test(httptest.NewRequest("GET", "http://api.example.com/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+// Test a failed warm-up removes the registration again.
+func TestVhostsManager_AddHostnameWithWarmUp_Error(t *testing.T) {
+	manager := NewVhostsManager()
+	var events []ChangeType
+	manager.Hooks().OnAdd(func(Entry) { events = append(events, ChangeAdded) })
+	manager.Hooks().OnRemove(func(Entry) { events = append(events, ChangeRemoved) })
+
+	err := manager.AddHostnameWithWarmUp(context.Background(), "api.example.com", fiber.New(), func(ctx context.Context) error {
+		return errors.New("cache unavailable")
+	})
+	assert.ErrorContains(t, err, "cache unavailable")
+
+	_, exists := manager.GetHostname("api.example.com")
+	assert.False(t, exists)
+	assert.Equal(t, []ChangeType{ChangeAdded, ChangeRemoved}, events)
+}
@@ -0,0 +1,129 @@
+// This file contains the watcher API emitting structured change events for every modification of the vhost table.
+package fibervhosts
+
+import "sync"
+
+// ChangeType describes the kind of change made to the vhost table
+type ChangeType string
+
+const (
+	// ChangeAdded is emitted when a registration is added
+	ChangeAdded ChangeType = "added"
+	// ChangeUpdated is emitted when a registration is changed in place
+	ChangeUpdated ChangeType = "updated"
+	// ChangeRemoved is emitted when a registration is removed
+	ChangeRemoved ChangeType = "removed"
+)
+
+// ChangeEvent describes a single change made to the vhost table. Previous is only set for ChangeUpdated events and holds the registration as it was before the change. A rename is emitted as a removal of the old pattern followed by an addition of the new one.
+type ChangeEvent struct {
+	Type     ChangeType
+	Entry    Entry
+	Previous Entry
+
+	// entry is the removed entry of ChangeRemoved events, for its OnStop callback
+	entry *entry
+}
+
+// maxWatchQueue is the number of events queued for a watcher before it is unsubscribed, see Watch
+const maxWatchQueue = 10000
+
+// watcher delivers change events to a single subscriber. Events are queued so a slow subscriber never blocks the manager, and are delivered in order.
+type watcher struct {
+	mu     sync.Mutex
+	queue  []ChangeEvent
+	signal chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	out    chan ChangeEvent
+}
+
+// Watch subscribes to changes of the vhost table. Every add, update and remove is emitted on the returned channel, in the order the changes were applied. The cancel function unsubscribes and closes the channel; it is safe to call more than once. A subscriber falling more than 10000 events behind is unsubscribed and its channel closed rather than dropping events, so a channel closed without cancel means the subscriber has to list the table again, see ListEntries, and watch anew.
+func (m *VhostsManager) Watch() (<-chan ChangeEvent, func()) {
+	w := &watcher{
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		out:    make(chan ChangeEvent),
+	}
+
+	m.watchMu.Lock()
+	if m.watchers == nil {
+		m.watchers = make(map[*watcher]struct{})
+	}
+	m.watchers[w] = struct{}{}
+	m.watchMu.Unlock()
+
+	go w.run()
+
+	cancel := func() {
+		m.watchMu.Lock()
+		delete(m.watchers, w)
+		m.watchMu.Unlock()
+		w.stop()
+	}
+	return w.out, cancel
+}
+
+// publish queues the events for every watcher, unsubscribing the watchers falling too far behind
+func (m *VhostsManager) publish(events []ChangeEvent) {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+
+	for w := range m.watchers {
+		if !w.push(events) {
+			delete(m.watchers, w)
+			w.stop()
+			m.logger.Warn("Unsubscribed a watcher falling behind", "queued", maxWatchQueue)
+		}
+	}
+}
+
+// push queues events and wakes up the delivery goroutine. It reports false, queuing nothing, when the queue would exceed maxWatchQueue.
+func (w *watcher) push(events []ChangeEvent) bool {
+	w.mu.Lock()
+	if len(w.queue)+len(events) > maxWatchQueue {
+		w.mu.Unlock()
+		return false
+	}
+	w.queue = append(w.queue, events...)
+	w.mu.Unlock()
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// stop ends the delivery and closes the channel of the watcher
+func (w *watcher) stop() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+// run delivers queued events until the watcher is cancelled
+func (w *watcher) run() {
+	defer close(w.out)
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.signal:
+		}
+
+		w.mu.Lock()
+		pending := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, ev := range pending {
+			select {
+			case w.out <- ev:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
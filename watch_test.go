@@ -0,0 +1,131 @@
+package fibervhosts
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// receive reads the next event from the channel or fails the test after a timeout
+func receive(t *testing.T, events <-chan ChangeEvent) ChangeEvent {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for change event")
+		return ChangeEvent{}
+	}
+}
+
+// Test Watch emits events for every table change in order.
+func TestVhostsManager_Watch(t *testing.T) {
+	manager := NewVhostsManager()
+	app := fiber.New()
+	other := fiber.New()
+
+	events, cancel := manager.Watch()
+	defer cancel()
+
+	// Changes are queued, so the manager never waits for the subscriber
+	assert.NoError(t, manager.AddHostname("example.com", app))
+	assert.NoError(t, manager.UpdateHostname("example.com", other))
+	assert.NoError(t, manager.RemoveHostname("example.com"))
+
+	ev := receive(t, events)
+	assert.Equal(t, ChangeAdded, ev.Type)
+	assert.Equal(t, "example.com", ev.Entry.Pattern)
+
+	ev = receive(t, events)
+	assert.Equal(t, ChangeUpdated, ev.Type)
+	assert.Equal(t, app, ev.Previous.App)
+	assert.Equal(t, other, ev.Entry.App)
+
+	ev = receive(t, events)
+	assert.Equal(t, ChangeRemoved, ev.Type)
+}
+
+// Test cancelling a watcher closes its channel.
+func TestVhostsManager_WatchCancel(t *testing.T) {
+	manager := NewVhostsManager()
+	events, cancel := manager.Watch()
+	cancel()
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed")
+	}
+
+	// Changes after cancelling don't block
+	assert.NoError(t, manager.AddHostname("example.com", fiber.New()))
+}
+
+// Test changes to the state and settings of a registration are versioned and reported like any other update.
+func TestVhostsManager_WatchRegistrationChanges(t *testing.T) {
+	manager := NewVhostsManager()
+	assert.NoError(t, manager.AddHostname("example.com", fiber.New()))
+	events, cancel := manager.Watch()
+	defer cancel()
+
+	changes := []func() error{
+		func() error { return manager.SuspendHostname("example.com") },
+		func() error { return manager.ResumeHostname("example.com") },
+		func() error { return manager.SetHostConfig("example.com", HostConfig{RequestTimeout: time.Second}) },
+		func() error { return manager.StageApp("example.com", fiber.New()) },
+		func() error { return manager.SetSettings("example.com", map[string]any{"plan": "pro"}) },
+		func() error { return manager.SetFeature("example.com", "beta", true) },
+	}
+	for i, change := range changes {
+		version := manager.Version()
+		assert.NoError(t, change())
+		assert.Equal(t, version+1, manager.Version(), i)
+		ev := receive(t, events)
+		assert.Equal(t, ChangeUpdated, ev.Type, i)
+		assert.Equal(t, "example.com", ev.Entry.Pattern, i)
+	}
+	assert.ErrorIs(t, manager.SuspendHostname("missing.com"), ErrHostNotFound)
+}
+
+// Test concurrent changes are notified in the order they were applied, even while a hook of the first one is slow.
+func TestVhostsManager_WatchOrder(t *testing.T) {
+	manager := NewVhostsManager()
+	events, cancel := manager.Watch()
+	defer cancel()
+	manager.Hooks().OnAdd(func(Entry) { time.Sleep(20 * time.Millisecond) })
+
+	go func() {
+		assert.NoError(t, manager.AddHostname("example.com", fiber.New()))
+	}()
+	assert.Eventually(t, func() bool { _, exists := manager.GetHostname("example.com"); return exists }, time.Second, time.Millisecond)
+	assert.NoError(t, manager.RemoveHostname("example.com"))
+
+	assert.Equal(t, ChangeAdded, receive(t, events).Type)
+	assert.Equal(t, ChangeRemoved, receive(t, events).Type)
+}
+
+// Test a watcher falling too far behind is unsubscribed and its channel closed.
+func TestVhostsManager_WatchOverflow(t *testing.T) {
+	manager := NewVhostsManager()
+	events, cancel := manager.Watch()
+	defer cancel()
+
+	app := fiber.New()
+	entries := make([]Entry, maxWatchQueue+1)
+	for i := range entries {
+		entries[i] = Entry{Pattern: fmt.Sprintf("%d.example.com", i), App: app}
+	}
+	assert.NoError(t, manager.ApplyEntries("bulk", entries))
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed")
+	}
+}
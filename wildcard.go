@@ -0,0 +1,63 @@
+// This file contains the wildcard matcher of the lookup table, a tree of hostname labels stored from the top-level domain down, so a hostname is matched in O(labels) against any number of wildcard patterns.
+package fibervhosts
+
+import "strings"
+
+// wildcardNode is a node of the wildcard tree. The path from the root to a node spells a domain suffix, top-level domain first; a route on the node is the wildcard registered for that suffix.
+//
+// A pattern like "*.example.com" matches hostnames with one or more labels in front of example.com, and the longest registered suffix wins, so "*.api.example.com" takes precedence over "*.example.com" for "v1.api.example.com". Labels of "*" inside the suffix, like in "*.*.example.com", match exactly one label; a literal label takes precedence over them.
+type wildcardNode struct {
+	children map[string]*wildcardNode
+	route    *route
+}
+
+// wildcardLabel is the label matching any single label inside a wildcard suffix
+const wildcardLabel = "*"
+
+// insert registers the route of a wildcard pattern by its suffix, like "example.com" for "*.example.com"
+func (n *wildcardNode) insert(suffix string, r *route) {
+	for suffix != "" {
+		var label string
+		label, suffix = lastLabel(suffix)
+		child := n.children[label]
+		if child == nil {
+			if n.children == nil {
+				n.children = make(map[string]*wildcardNode)
+			}
+			child = &wildcardNode{}
+			n.children[label] = child
+		}
+		n = child
+	}
+	n.route = r
+}
+
+// match returns the route of the longest wildcard suffix matching host, or nil if there is none. host is the part of the hostname in front of the suffix spelled by n.
+func (n *wildcardNode) match(host string) *route {
+	// A wildcard needs at least one label in front of its suffix
+	if host == "" {
+		return nil
+	}
+
+	label, rest := lastLabel(host)
+	if child := n.children[label]; child != nil {
+		if r := child.match(rest); r != nil {
+			return r
+		}
+	}
+	if child := n.children[wildcardLabel]; child != nil {
+		if r := child.match(rest); r != nil {
+			return r
+		}
+	}
+	return n.route
+}
+
+// lastLabel splits the last label off a hostname, without allocating
+func lastLabel(hostname string) (label, rest string) {
+	i := strings.LastIndexByte(hostname, '.')
+	if i < 0 {
+		return hostname, ""
+	}
+	return hostname[i+1:], hostname[:i]
+}
@@ -0,0 +1,40 @@
+package fibervhosts
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test wildcards match any depth, with the longest suffix and literal labels taking precedence.
+func TestVhostsManager_WildcardLongestSuffix(t *testing.T) {
+	manager := NewVhostsManager()
+	for _, pattern := range []string{"*.example.com", "*.api.example.com", "*.*.example.org", "*.eu.*.example.org", "exact.api.example.com"} {
+		assert.NoError(t, manager.AddHostname(pattern, fiber.New()))
+	}
+
+	for hostname, pattern := range map[string]string{
+		"www.example.com":          "*.example.com",
+		"a.b.c.example.com":        "*.example.com",
+		"v1.api.example.com":       "*.api.example.com",
+		"x.v1.api.example.com":     "*.api.example.com",
+		"api.example.com":          "*.example.com",
+		"exact.api.example.com":    "exact.api.example.com",
+		"a.shop.example.org":       "*.*.example.org",
+		"a.eu.shop.example.org":    "*.eu.*.example.org",
+		"eu.shop.example.org":      "*.*.example.org",
+		"example.com":              "",
+		"shop.example.org":         "",
+		"www.example.com.evil.com": "",
+	} {
+		e, ok := manager.Match(hostname)
+		assert.Equal(t, pattern != "", ok, hostname)
+		assert.Equal(t, pattern, e.Pattern, hostname)
+	}
+
+	// Removing a nested wildcard falls back to the shorter suffix
+	assert.NoError(t, manager.RemoveHostname("*.api.example.com"))
+	e, _ := manager.Match("v1.api.example.com")
+	assert.Equal(t, "*.example.com", e.Pattern)
+}